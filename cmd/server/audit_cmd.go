@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hieubanhh/dbhubMCP/internal/audit"
+)
+
+const auditUsage = `Usage: dbhub-mcp-server audit verify FILE...
+
+Checks the hash chain of an audit log written with hash_chain enabled.
+Pass rotated files oldest first, followed by the current file, to also
+check that no file is missing in between.
+`
+
+// runAuditCommand implements the "audit" subcommand
+func runAuditCommand(args []string) error {
+	if len(args) < 2 || args[0] != "verify" {
+		fmt.Fprint(os.Stderr, auditUsage)
+		return fmt.Errorf("expected 'audit verify FILE...'")
+	}
+
+	prev, total := "", 0
+	for _, path := range args[1:] {
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		var n int
+		prev, n, err = audit.Verify(f, prev)
+		f.Close()
+		total += n
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		fmt.Fprintf(os.Stdout, "%s: %d entries OK\n", path, n)
+	}
+	fmt.Fprintf(os.Stdout, "Verified %d entries\n", total)
+	return nil
+}
@@ -2,15 +2,18 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/hieubanhh/dbhubMCP/internal/audit"
 	"github.com/hieubanhh/dbhubMCP/internal/config"
 	"github.com/hieubanhh/dbhubMCP/internal/database"
 	"github.com/hieubanhh/dbhubMCP/internal/mcp"
+	"github.com/hieubanhh/dbhubMCP/internal/secrets"
 	"github.com/hieubanhh/dbhubMCP/internal/security"
 )
 
@@ -19,44 +22,35 @@ func main() {
 	log.SetOutput(os.Stderr)
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
-	// Load configuration from environment
-	cfg, err := config.LoadFromEnv()
+	// Subcommands run once against the database and exit
+	if len(os.Args) > 1 && os.Args[1] == "schema" {
+		if err := runSchemaCommand(os.Args[2:]); err != nil {
+			log.Fatalf("[FATAL] %v", err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		if err := runAuditCommand(os.Args[2:]); err != nil {
+			log.Fatalf("[FATAL] %v", err)
+		}
+		return
+	}
+
+	configPath := flag.String("config", os.Getenv("DBHUB_CONFIG"), "path to a YAML or TOML configuration file")
+	flag.Parse()
+
+	// Load configuration from the config file and environment
+	cfg, err := config.Load(*configPath)
 	if err != nil {
 		log.Fatalf("[FATAL] Failed to load configuration: %v", err)
 	}
+	if *configPath != "" {
+		log.Printf("[INFO] Loaded configuration from %s", *configPath)
+	}
 
-	log.Printf("[INFO] Starting MCP Server for %s database", cfg.DBType)
-	log.Printf("[INFO] Database: %s@%s:%d/%s", cfg.DBUser, cfg.DBHost, cfg.DBPort, cfg.DBName)
-	log.Printf("[INFO] Max connections: %d, Max rows: %d, Query timeout: %v",
-		cfg.DBMaxConns, cfg.MaxRows, cfg.QueryTimeout)
-
-	// Create database adapter based on type
-	var adapter database.Adapter
-	switch cfg.DBType {
-	case "mysql":
-		adapter = database.NewMySQLAdapter(
-			cfg.DBHost,
-			cfg.DBPort,
-			cfg.DBName,
-			cfg.DBUser,
-			cfg.DBPassword,
-			cfg.DBMaxConns,
-			cfg.DBMaxIdleConns,
-			cfg.DBConnTimeout,
-		)
-	case "postgres":
-		adapter = database.NewPostgresAdapter(
-			cfg.DBHost,
-			cfg.DBPort,
-			cfg.DBName,
-			cfg.DBUser,
-			cfg.DBPassword,
-			cfg.DBMaxConns,
-			cfg.DBMaxIdleConns,
-			cfg.DBConnTimeout,
-		)
-	default:
-		log.Fatalf("[FATAL] Unsupported database type: %s", cfg.DBType)
+	log.Printf("[INFO] Starting MCP Server with %d databases", len(cfg.Connections))
+	if cfg.SchemaCacheTTL > 0 {
+		log.Printf("[INFO] Schema metadata cache TTL: %v", cfg.SchemaCacheTTL)
 	}
 
 	// Create SQL validator
@@ -64,18 +58,22 @@ func main() {
 
 	// Create transport based on configuration
 	var transport mcp.MessageTransport
+	var httpTransport *mcp.HTTPTransport
 	switch cfg.TransportType {
 	case "stdio":
 		transport = mcp.NewStdioTransport()
 	case "http":
-		transport = mcp.NewHTTPTransport(mcp.HTTPTransportConfig{
-			Addr:        cfg.HTTPAddr,
-			CORSOrigins: cfg.HTTPCORSOrigins,
-			APIKey:      cfg.HTTPAPIKey,
-		})
+		httpTransport = mcp.NewHTTPTransport(httpTransportConfig(cfg))
+		transport = httpTransport
 		log.Printf("[INFO] HTTP server will listen on %s", cfg.HTTPAddr)
-		if cfg.HTTPAPIKey != "" {
-			log.Printf("[INFO] API key authentication enabled")
+		if cfg.HTTPAPIKey != "" || len(cfg.HTTPKeys) > 0 {
+			log.Printf("[INFO] API key authentication enabled (%d named keys, %d roles)", len(cfg.HTTPKeys), len(cfg.Roles))
+		}
+		if cfg.HTTPTokenValidator != nil {
+			log.Printf("[INFO] OAuth bearer tokens accepted from %s", cfg.HTTPOAuth.Issuer)
+		}
+		if cfg.HTTPTLSClientCA != "" {
+			log.Printf("[INFO] Client certificates verified against %s", cfg.HTTPTLSClientCA)
 		}
 		if len(cfg.HTTPCORSOrigins) > 0 {
 			log.Printf("[INFO] CORS origins: %v", cfg.HTTPCORSOrigins)
@@ -85,7 +83,68 @@ func main() {
 	}
 
 	// Create MCP server with injected transport
-	server := mcp.NewServer(transport, adapter, validator, cfg.MaxRows)
+	server := mcp.NewServer(transport, validator)
+
+	// Register every database; each connects on first use
+	for i := range cfg.Connections {
+		conn := &cfg.Connections[i]
+		adapter, err := newAdapter(conn)
+		if err != nil {
+			log.Fatalf("[FATAL] %v", err)
+		}
+
+		// Cache schema metadata; information_schema is slow on large catalogs
+		if cfg.SchemaCacheTTL > 0 {
+			adapter = database.NewCachedAdapter(adapter, cfg.SchemaCacheTTL)
+		}
+
+		if err := server.AddConnection(mcp.Connection{
+			Name:    conn.Name,
+			Adapter: adapter,
+			Limits:  connectionLimits(conn),
+		}); err != nil {
+			log.Fatalf("[FATAL] %v", err)
+		}
+
+		log.Printf("[INFO] Database %s (%s): %s", conn.Name, conn.Type, conn.Address())
+		log.Printf("[INFO] Max connections: %d, Max rows: %d, Query timeout: %v",
+			conn.MaxConns, conn.MaxRows, conn.QueryTimeout)
+		if conn.MaxQueryCost > 0 || conn.MaxEstimatedRows > 0 {
+			log.Printf("[INFO] Queries on %s are explained first and rejected above cost %.0f or %d estimated rows (0 = no limit)",
+				conn.Name, conn.MaxQueryCost, conn.MaxEstimatedRows)
+		}
+		if conn.ExplainAnalyze {
+			log.Printf("[WARN] EXPLAIN ANALYZE enabled for %s: explain_query may execute queries", conn.Name)
+		}
+	}
+	if err := server.SetDefaultConnection(cfg.DefaultConnection); err != nil {
+		log.Fatalf("[FATAL] %v", err)
+	}
+
+	if cfg.RateLimits.Enabled() {
+		server.SetRateLimits(cfg.RateLimits)
+		log.Printf("[INFO] Rate limits per client: %s", cfg.RateLimits)
+	}
+	if cfg.AuditPath != "" {
+		sink, err := audit.OpenFile(audit.FileOptions{
+			Path:     cfg.AuditPath,
+			MaxSize:  int64(cfg.AuditMaxSizeMB) << 20,
+			MaxFiles: cfg.AuditMaxFiles,
+		})
+		if err != nil {
+			log.Fatalf("[FATAL] %v", err)
+		}
+		auditLog := audit.NewLogger(sink, cfg.AuditHashChain)
+		defer auditLog.Close()
+		server.SetAuditLogger(auditLog)
+		log.Printf("[INFO] Auditing tool calls to %s (hash chain: %v)", cfg.AuditPath, cfg.AuditHashChain)
+	}
+	if cfg.CredentialRefresh > 0 {
+		server.EnableCredentialRefresh(cfg.CredentialRefresh)
+	}
+	if cfg.SchemaPollInterval > 0 {
+		server.EnableSchemaChangeDetection(cfg.SchemaPollInterval)
+	}
 
 	// Setup context with cancellation
 	ctx, cancel := context.WithCancel(context.Background())
@@ -101,6 +160,25 @@ func main() {
 		cancel()
 	}()
 
+	// Reload limits and HTTP access settings on SIGHUP or when the config file changes
+	reload := &reloader{path: *configPath, server: server, transport: httpTransport, current: cfg}
+	hupChan := make(chan os.Signal, 1)
+	signal.Notify(hupChan, syscall.SIGHUP)
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hupChan:
+				reload.reload("SIGHUP")
+			}
+		}
+	}()
+	if *configPath != "" {
+		go reload.watch(ctx)
+	}
+
 	// Run server
 	if err := server.Run(ctx); err != nil {
 		log.Fatalf("[FATAL] Server error: %v", err)
@@ -109,12 +187,68 @@ func main() {
 	log.Printf("[INFO] Server shutdown complete")
 }
 
-func init() {
-	// Print startup banner to stderr
-	fmt.Fprintln(os.Stderr, "")
-	fmt.Fprintln(os.Stderr, "╔═══════════════════════════════════════╗")
-	fmt.Fprintln(os.Stderr, "║     DBHub MCP Server v1.0.0           ║")
-	fmt.Fprintln(os.Stderr, "║  Database Operations via MCP Protocol ║")
-	fmt.Fprintln(os.Stderr, "╚═══════════════════════════════════════╝")
-	fmt.Fprintln(os.Stderr, "")
+// httpTransportConfig returns the HTTP transport settings of cfg
+func httpTransportConfig(cfg *config.Config) mcp.HTTPTransportConfig {
+	var tls *mcp.HTTPTLSConfig
+	if cfg.HTTPTLSCert != "" {
+		tls = &mcp.HTTPTLSConfig{
+			CertFile:     cfg.HTTPTLSCert,
+			KeyFile:      cfg.HTTPTLSKey,
+			ClientCAFile: cfg.HTTPTLSClientCA,
+			ClientAuth:   cfg.HTTPTLSClientAuth,
+		}
+	}
+	return mcp.HTTPTransportConfig{
+		Addr:        cfg.HTTPAddr,
+		CORSOrigins: cfg.HTTPCORSOrigins,
+		APIKey:      cfg.HTTPAPIKey,
+		APIKeys:     cfg.HTTPKeys,
+		OAuth:       cfg.HTTPTokenValidator,
+		TLS:         tls,
+		ClientCerts: cfg.HTTPClientCerts,
+	}
+}
+
+// newAdapter creates the database adapter for a configured connection
+func newAdapter(conn *config.ConnectionConfig) (database.Adapter, error) {
+	opts := database.ConnectionOptions{
+		DSN:      conn.DSN,
+		Host:     conn.Host,
+		Port:     conn.Port,
+		Socket:   conn.Socket,
+		Database: conn.Database,
+		User:     conn.User,
+		Password: conn.Password,
+		TLS: database.TLSOptions{
+			Mode:       conn.SSLMode,
+			CAFile:     conn.SSLCA,
+			CertFile:   conn.SSLCert,
+			KeyFile:    conn.SSLKey,
+			ServerName: conn.SSLServerName,
+		},
+		MaxConns:     conn.MaxConns,
+		MaxIdleConns: conn.MaxIdleConns,
+		ConnTimeout:  conn.ConnTimeout,
+	}
+
+	// Password files and commands are read at connect time and on refresh
+	switch {
+	case conn.PasswordFile != "":
+		opts.PasswordProvider = secrets.FileProvider{Path: conn.PasswordFile}
+	case conn.PasswordCommand != "":
+		provider, err := secrets.NewCommandProvider(conn.PasswordCommand)
+		if err != nil {
+			return nil, fmt.Errorf("connection %s: %w", conn.Name, err)
+		}
+		opts.PasswordProvider = provider
+	}
+
+	switch conn.Type {
+	case "mysql":
+		return database.NewMySQLAdapter(opts), nil
+	case "postgres":
+		return database.NewPostgresAdapter(opts), nil
+	default:
+		return nil, fmt.Errorf("unsupported database type: %s", conn.Type)
+	}
 }
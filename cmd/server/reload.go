@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/config"
+	"github.com/hieubanhh/dbhubMCP/internal/mcp"
+)
+
+// configPollInterval is how often the config file is checked for changes
+const configPollInterval = 2 * time.Second
+
+// reloader re-reads the configuration and applies the settings that can
+// change while the server runs: query and rate limits, access policies,
+// masking rules, roles, the HTTP API keys, OAuth settings, client
+// certificate roles and CORS origins. Other changes are logged as requiring
+// a restart.
+type reloader struct {
+	path      string
+	server    *mcp.Server
+	transport *mcp.HTTPTransport // nil with the stdio transport
+
+	mu      sync.Mutex
+	current *config.Config // settings in effect
+}
+
+// reload loads the configuration again and applies it. An invalid
+// configuration is rejected as a whole and the current settings are kept.
+func (r *reloader) reload(reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cfg, err := config.Load(r.path)
+	if err != nil {
+		log.Printf("[ERROR] Failed to reload configuration (%s), keeping the current settings: %v", reason, err)
+		return
+	}
+
+	changes := config.Diff(r.current, cfg)
+	if len(changes) == 0 {
+		log.Printf("[INFO] Configuration reloaded (%s): no changes", reason)
+		return
+	}
+	for _, change := range changes {
+		if change.Restart {
+			log.Printf("[WARN] Configuration change requires a restart and was not applied: %s", change)
+		} else {
+			log.Printf("[INFO] Configuration change applied: %s", change)
+		}
+	}
+
+	applied := *r.current
+	applied.Connections = append([]config.ConnectionConfig(nil), r.current.Connections...)
+	for i := range applied.Connections {
+		conn := &applied.Connections[i]
+		loaded, ok := cfg.Connection(conn.Name)
+		if !ok {
+			continue
+		}
+		conn.MaxRows, conn.QueryTimeout, conn.ExplainAnalyze = loaded.MaxRows, loaded.QueryTimeout, loaded.ExplainAnalyze
+		conn.MaxQueryCost, conn.MaxEstimatedRows = loaded.MaxQueryCost, loaded.MaxEstimatedRows
+		conn.Policy, conn.Masker, conn.MaskHashKey = loaded.Policy, loaded.Masker, loaded.MaskHashKey
+		if err := r.server.SetLimits(conn.Name, connectionLimits(conn)); err != nil {
+			log.Printf("[ERROR] Failed to apply limits of %s: %v", conn.Name, err)
+		}
+	}
+	applied.RateLimits = cfg.RateLimits
+	r.server.SetRateLimits(cfg.RateLimits)
+	if r.transport != nil {
+		applied.HTTPAPIKey, applied.HTTPCORSOrigins = cfg.HTTPAPIKey, cfg.HTTPCORSOrigins
+		applied.HTTPAPIKeys, applied.HTTPKeys, applied.Roles = cfg.HTTPAPIKeys, cfg.HTTPKeys, cfg.Roles
+		applied.HTTPOAuth, applied.HTTPTokenValidator = cfg.HTTPOAuth, cfg.HTTPTokenValidator
+		applied.HTTPClientCertRoles, applied.HTTPClientCerts = cfg.HTTPClientCertRoles, cfg.HTTPClientCerts
+		r.transport.UpdateAccess(httpTransportConfig(&applied))
+	}
+	r.current = &applied
+}
+
+// watch reloads whenever the config file's modification time or size
+// changes. It returns when ctx is cancelled.
+func (r *reloader) watch(ctx context.Context) {
+	last, err := os.Stat(r.path)
+	if err != nil {
+		log.Printf("[WARN] Cannot watch config file %s: %v", r.path, err)
+	}
+
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(r.path)
+		if err != nil {
+			// Editors may replace the file; wait for it to reappear
+			continue
+		}
+		if last != nil && info.ModTime().Equal(last.ModTime()) && info.Size() == last.Size() {
+			continue
+		}
+		last = info
+		r.reload("config file changed")
+	}
+}
+
+// connectionLimits returns the server limits of a configured connection
+func connectionLimits(conn *config.ConnectionConfig) mcp.Limits {
+	return mcp.Limits{
+		MaxRows:          conn.MaxRows,
+		QueryTimeout:     conn.QueryTimeout,
+		ExplainAnalyze:   conn.ExplainAnalyze,
+		MaxCost:          conn.MaxQueryCost,
+		MaxEstimatedRows: conn.MaxEstimatedRows,
+		Policy:           conn.Policy,
+		Masker:           conn.Masker,
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/config"
+	"github.com/hieubanhh/dbhubMCP/internal/schema"
+)
+
+const schemaUsage = `Usage: dbhub-mcp-server schema dump [flags]
+
+Writes a snapshot of the database schema using the same configuration
+file and environment as the server.
+
+Flags:
+`
+
+// runSchemaCommand implements the "schema" subcommand
+func runSchemaCommand(args []string) error {
+	if len(args) == 0 || args[0] != "dump" {
+		fmt.Fprint(os.Stderr, schemaUsage)
+		return fmt.Errorf("expected 'schema dump'")
+	}
+
+	fs := flag.NewFlagSet("schema dump", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, schemaUsage)
+		fs.PrintDefaults()
+	}
+	formatFlag := fs.String("format", "ddl", "output format: ddl, json or markdown")
+	schemaFlag := fs.String("schema", "", "comma-separated glob patterns of schemas to include")
+	tableFlag := fs.String("table", "", "comma-separated glob patterns of tables to include")
+	maxTokens := fs.Int("max-tokens", 0, "approximate token budget; details are dropped to fit (0 = unlimited)")
+	output := fs.String("o", "", "write to file instead of stdout")
+	timeout := fs.Duration("timeout", 10*time.Minute, "overall timeout for reading the schema")
+	configPath := fs.String("config", os.Getenv("DBHUB_CONFIG"), "path to a YAML or TOML configuration file")
+	connection := fs.String("connection", "", "name of the connection to dump (default: the default connection)")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	format, err := schema.ParseFormat(*formatFlag)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	conn := cfg.Default()
+	if *connection != "" {
+		var ok bool
+		if conn, ok = cfg.Connection(*connection); !ok {
+			return fmt.Errorf("unknown connection: %s", *connection)
+		}
+	}
+
+	adapter, err := newAdapter(conn)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	if err := adapter.Connect(ctx); err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer adapter.Close()
+
+	snap, err := schema.BuildSnapshot(ctx, adapter, schema.Filter{
+		SchemaPatterns: schema.ParsePatterns(*schemaFlag),
+		TablePatterns:  schema.ParsePatterns(*tableFlag),
+	})
+	if err != nil {
+		return err
+	}
+
+	out, level, err := schema.RenderWithBudget(snap, format, *maxTokens)
+	if err != nil {
+		return err
+	}
+	if level != schema.DetailFull {
+		fmt.Fprintf(os.Stderr, "Reduced to %s detail to fit %d tokens\n", level, *maxTokens)
+	}
+
+	if *output == "" {
+		_, err = fmt.Fprintln(os.Stdout, out)
+		return err
+	}
+	return os.WriteFile(*output, []byte(out+"\n"), 0o644)
+}
@@ -12,7 +12,7 @@ if [ -f .env ]; then
     echo "✓ Found .env file"
     export $(cat .env | grep -v '^#' | xargs)
 else
-    echo "⚠ No .env file found, using defaults from config.go"
+    echo "⚠ No .env file found, DB_PASSWORD must be set in the environment"
 fi
 
 # Display current configuration
@@ -40,12 +40,12 @@ echo ""
 
 # Test MySQL connection manually
 echo "2. Testing MySQL connection..."
-echo "   Attempting: mysql -h ${DB_HOST:-localhost} -P ${DB_PORT:-3309} -u ${DB_USER:-root} -p${DB_PASSWORD:-123456}"
+echo "   Attempting: mysql -h ${DB_HOST:-localhost} -P ${DB_PORT:-3309} -u ${DB_USER:-root} -p****"
 echo ""
 
 if command -v mysql &> /dev/null; then
     # Try to connect and show databases
-    mysql -h ${DB_HOST:-localhost} -P ${DB_PORT:-3309} -u ${DB_USER:-root} -p${DB_PASSWORD:-123456} -e "SHOW DATABASES;" 2>&1
+    mysql -h ${DB_HOST:-localhost} -P ${DB_PORT:-3309} -u "${DB_USER:-root}" -p"${DB_PASSWORD}" -e "SHOW DATABASES;" 2>&1
 
     if [ $? -eq 0 ]; then
         echo ""
@@ -54,18 +54,18 @@ if command -v mysql &> /dev/null; then
 
         # Check if the target database exists
         echo "3. Checking if database '${DB_NAME:-test}' exists..."
-        DB_EXISTS=$(mysql -h ${DB_HOST:-localhost} -P ${DB_PORT:-3309} -u ${DB_USER:-root} -p${DB_PASSWORD:-123456} -e "SHOW DATABASES LIKE '${DB_NAME:-test}';" 2>&1 | grep -c "${DB_NAME:-test}")
+        DB_EXISTS=$(mysql -h ${DB_HOST:-localhost} -P ${DB_PORT:-3309} -u "${DB_USER:-root}" -p"${DB_PASSWORD}" -e "SHOW DATABASES LIKE '${DB_NAME:-test}';" 2>&1 | grep -c "${DB_NAME:-test}")
 
         if [ "$DB_EXISTS" -gt 0 ]; then
             echo "✓ Database '${DB_NAME:-test}' exists"
             echo ""
             echo "4. Listing tables in '${DB_NAME:-test}'..."
-            mysql -h ${DB_HOST:-localhost} -P ${DB_PORT:-3309} -u ${DB_USER:-root} -p${DB_PASSWORD:-123456} ${DB_NAME:-test} -e "SHOW TABLES;" 2>&1
+            mysql -h ${DB_HOST:-localhost} -P ${DB_PORT:-3309} -u "${DB_USER:-root}" -p"${DB_PASSWORD}" ${DB_NAME:-test} -e "SHOW TABLES;" 2>&1
         else
             echo "✗ Database '${DB_NAME:-test}' does NOT exist"
             echo ""
             echo "Available databases:"
-            mysql -h ${DB_HOST:-localhost} -P ${DB_PORT:-3309} -u ${DB_USER:-root} -p${DB_PASSWORD:-123456} -e "SHOW DATABASES;" 2>&1
+            mysql -h ${DB_HOST:-localhost} -P ${DB_PORT:-3309} -u "${DB_USER:-root}" -p"${DB_PASSWORD}" -e "SHOW DATABASES;" 2>&1
         fi
     else
         echo ""
@@ -87,7 +87,7 @@ echo "=========================================="
 echo ""
 echo "If connection failed:"
 echo "  1. Check MySQL is running: sudo systemctl status mysql (Linux) or check Services (Windows)"
-echo "  2. Verify port: netstat -an | grep 3309"
+echo "  2. Verify port: netstat -an | grep ${DB_PORT:-3309}"
 echo "  3. Update .env with correct values"
 echo "  4. Create database if needed: mysql -u root -p -e \"CREATE DATABASE test;\""
 echo ""
@@ -3,7 +3,9 @@ module github.com/hieubanhh/dbhubMCP
 go 1.21
 
 require (
+	github.com/BurntSushi/toml v1.3.2
 	github.com/go-sql-driver/mysql v1.7.1
 	github.com/joho/godotenv v1.5.1
 	github.com/lib/pq v1.10.9
+	gopkg.in/yaml.v3 v3.0.1
 )
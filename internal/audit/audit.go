@@ -0,0 +1,200 @@
+// Package audit records tool calls to an append-only log of JSON lines,
+// optionally chained with SHA-256 hashes so that edited, removed or
+// reordered entries can be detected
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// Event is one tool call
+type Event struct {
+	Time       time.Time              `json:"time"`
+	Client     string                 `json:"client"` // e.g. "ip:10.0.0.1" or "stdio"
+	Identity   string                 `json:"identity,omitempty"`
+	Role       string                 `json:"role,omitempty"`
+	ClientInfo *ClientInfo            `json:"client_info,omitempty"`
+	Tool       string                 `json:"tool"`
+	Arguments  map[string]interface{} `json:"arguments,omitempty"`
+	Database   string                 `json:"database,omitempty"`
+	SQL        []string               `json:"sql,omitempty"`        // normalized queries the call validated
+	Validation string                 `json:"validation,omitempty"` // "passed", or "rejected" by any query check
+	Status     string                 `json:"status"`               // "ok", "error" or "rate_limited"
+	Error      string                 `json:"error,omitempty"`
+	DurationMS float64                `json:"duration_ms"`
+	Rows       int                    `json:"rows"`
+
+	// Hash of the previous entry when entries are chained
+	PrevHash string `json:"prev_hash,omitempty"`
+}
+
+// ClientInfo is the name and version a client reported in initialize
+type ClientInfo struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Sink stores encoded entries. Write receives one JSON object without a
+// trailing newline; it must not keep the slice.
+type Sink interface {
+	Write(line []byte) error
+	Close() error
+}
+
+// lastHasher is implemented by sinks that can read back the hash of the
+// last entry, so a restarted server continues the chain
+type lastHasher interface {
+	LastHash() (string, error)
+}
+
+// hashField is appended to chained entries; it is always the last member
+const hashField = `,"hash":"`
+
+// Logger encodes events and writes them to a sink
+type Logger struct {
+	mu    sync.Mutex
+	sink  Sink
+	chain bool
+	prev  string
+}
+
+// NewLogger returns a logger writing to sink. With chain, every entry
+// carries the hash of the previous one and its own hash, computed over the
+// entry without it.
+func NewLogger(sink Sink, chain bool) *Logger {
+	l := &Logger{sink: sink, chain: chain}
+	if h, ok := sink.(lastHasher); ok && chain {
+		prev, err := h.LastHash()
+		if err != nil {
+			log.Printf("[WARN] Audit log hash chain restarts: %v", err)
+		}
+		l.prev = prev
+	}
+	return l
+}
+
+// Log writes an event, setting its time if unset
+func (l *Logger) Log(e Event) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Time = e.Time.UTC()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.chain {
+		e.PrevHash = l.prev
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit event: %w", err)
+	}
+	var hash string
+	if l.chain {
+		hash = hashOf(line)
+		line = append(line[:len(line)-1], hashField+hash+`"}`...)
+	}
+	if err := l.sink.Write(line); err != nil {
+		return fmt.Errorf("failed to write audit event: %w", err)
+	}
+	if l.chain {
+		l.prev = hash
+	}
+	return nil
+}
+
+// Close closes the sink
+func (l *Logger) Close() error {
+	return l.sink.Close()
+}
+
+func hashOf(entry []byte) string {
+	sum := sha256.Sum256(entry)
+	return hex.EncodeToString(sum[:])
+}
+
+// splitHash separates a chained entry into the entry as hashed and its hash
+func splitHash(line []byte) ([]byte, string, error) {
+	i := bytes.LastIndex(line, []byte(hashField))
+	if i < 0 || !bytes.HasSuffix(line, []byte(`"}`)) || len(line)-i != len(hashField)+sha256.Size*2+2 {
+		return nil, "", errors.New("entry has no hash")
+	}
+	entry := append(line[:i:i], '}')
+	return entry, string(line[i+len(hashField) : len(line)-2]), nil
+}
+
+// Verify checks the hash chain of the entries read from r. prev is the
+// hash of the entry before them, as returned for the previous file of the
+// log; with "" the chain may start anywhere. It returns the hash of the
+// last entry and the number of entries checked.
+func Verify(r io.Reader, prev string) (string, int, error) {
+	br := bufio.NewReader(r)
+	n := 0
+	for {
+		line, err := br.ReadBytes('\n')
+		line = bytes.TrimSpace(line)
+		if len(line) > 0 {
+			n++
+			entry, hash, splitErr := splitHash(line)
+			if splitErr != nil {
+				return prev, n, fmt.Errorf("entry %d: %w", n, splitErr)
+			}
+			if hashOf(entry) != hash {
+				return prev, n, fmt.Errorf("entry %d: hash mismatch, the entry was modified", n)
+			}
+			var e struct {
+				PrevHash string `json:"prev_hash"`
+			}
+			if err := json.Unmarshal(entry, &e); err != nil {
+				return prev, n, fmt.Errorf("entry %d: %w", n, err)
+			}
+			if e.PrevHash != prev && (prev != "" || n > 1) {
+				return prev, n, fmt.Errorf("entry %d: chain broken, an entry before it was removed or reordered", n)
+			}
+			prev = hash
+		}
+		if err == io.EOF {
+			return prev, n, nil
+		}
+		if err != nil {
+			return prev, n, fmt.Errorf("failed to read audit log: %w", err)
+		}
+	}
+}
+
+// WriterSink writes entries as lines to an io.Writer, such as os.Stderr
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink returns a sink writing to w
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (s *WriterSink) Write(line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err := s.w.Write(append(line[:len(line):len(line)], '\n'))
+	return err
+}
+
+// Close closes the writer if it is an io.Closer
+func (s *WriterSink) Close() error {
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogger_Plain(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(NewWriterSink(&buf), false)
+
+	err := l.Log(Event{
+		Time:      time.Date(2026, 3, 14, 12, 0, 0, 0, time.FixedZone("CET", 3600)),
+		Client:    "ip:10.0.0.1",
+		Identity:  "ci",
+		Tool:      "execute_readonly_query",
+		Arguments: map[string]interface{}{"query": "SELECT 1"},
+		Status:    "ok",
+		Rows:      1,
+	})
+	if err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+
+	var e map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+		t.Fatalf("Expected one JSON line, got %q: %v", buf.String(), err)
+	}
+	if e["time"] != "2026-03-14T11:00:00Z" || e["identity"] != "ci" || e["rows"] != 1.0 {
+		t.Errorf("Unexpected entry %v", e)
+	}
+	if _, ok := e["hash"]; ok {
+		t.Errorf("Expected no hash without chaining, got %v", e)
+	}
+}
+
+func TestLogger_Chain(t *testing.T) {
+	var buf bytes.Buffer
+	l := NewLogger(NewWriterSink(&buf), true)
+	for _, tool := range []string{"list_tables", "describe_table", "execute_readonly_query"} {
+		if err := l.Log(Event{Client: "stdio", Tool: tool, Status: "ok"}); err != nil {
+			t.Fatalf("Log failed: %v", err)
+		}
+	}
+	log := buf.String()
+
+	last, n, err := Verify(strings.NewReader(log), "")
+	if err != nil || n != 3 || len(last) != 64 {
+		t.Fatalf("Expected a valid chain of 3 entries, got %q, %d, %v", last, n, err)
+	}
+
+	lines := strings.SplitAfter(log, "\n")
+	tests := []struct {
+		name string
+		log  string
+		want string
+	}{
+		{"modified", strings.Replace(log, "describe_table", "list_indexes", 1), "entry 2: hash mismatch"},
+		{"removed", lines[0] + lines[2], "entry 2: chain broken"},
+		{"reordered", lines[1] + lines[0] + lines[2], "entry 2: chain broken"},
+		{"unchained", `{"tool":"list_tables"}` + "\n", "entry 1: entry has no hash"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Verify(strings.NewReader(tt.log), ""); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// A later part of the log verifies on its own, and against the hash of
+	// the part before it
+	first, _, _ := Verify(strings.NewReader(lines[0]), "")
+	if _, _, err := Verify(strings.NewReader(lines[1]+lines[2]), first); err != nil {
+		t.Errorf("Expected the rest to continue the chain, got %v", err)
+	}
+	if _, _, err := Verify(strings.NewReader(lines[2]), first); err == nil {
+		t.Error("Expected a gap after the previous file to be detected")
+	}
+}
@@ -0,0 +1,205 @@
+package audit
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileOptions configures a FileSink
+type FileOptions struct {
+	Path     string
+	MaxSize  int64 // bytes after which the file is rotated; 0 never rotates
+	MaxFiles int   // rotated files kept; 0 keeps all
+}
+
+// rotatedLayout is the time format in the names of rotated files
+const rotatedLayout = "20060102T150405.000000000"
+
+// FileSink appends entries to a JSON Lines file. When the file would grow
+// past MaxSize it is renamed with the time of rotation, e.g.
+// audit-20260314T120000.000000000.jsonl, and a new file is started.
+type FileSink struct {
+	mu   sync.Mutex
+	opts FileOptions
+	file *os.File
+	size int64
+}
+
+// OpenFile opens the log at opts.Path for appending, creating it and its
+// directory if needed
+func OpenFile(opts FileOptions) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	s := &FileSink{opts: opts}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.opts.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	s.file, s.size = f, info.Size()
+	return nil
+}
+
+func (s *FileSink) Write(line []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := int64(len(line)) + 1
+	if s.opts.MaxSize > 0 && s.size > 0 && s.size+n > s.opts.MaxSize {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+	written, err := s.file.Write(append(line[:len(line):len(line)], '\n'))
+	s.size += int64(written)
+	return err
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
+
+// rotate renames the current file and starts a new one, then removes the
+// oldest rotated files beyond MaxFiles
+func (s *FileSink) rotate() error {
+	if err := s.file.Close(); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	base, ext := s.nameParts()
+	rotated := base + "-" + time.Now().UTC().Format(rotatedLayout) + ext
+	var err error
+	if _, statErr := os.Stat(rotated); statErr == nil {
+		err = fmt.Errorf("%s already exists", rotated)
+	} else {
+		err = os.Rename(s.opts.Path, rotated)
+	}
+	if err != nil {
+		// Keep writing to the current file rather than losing entries
+		if openErr := s.open(); openErr != nil {
+			return openErr
+		}
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	if err := s.open(); err != nil {
+		return err
+	}
+
+	if s.opts.MaxFiles > 0 {
+		files := s.rotatedFiles()
+		for len(files) > s.opts.MaxFiles {
+			if err := os.Remove(files[0]); err != nil {
+				return fmt.Errorf("failed to remove old audit log: %w", err)
+			}
+			files = files[1:]
+		}
+	}
+	return nil
+}
+
+// nameParts splits the path into the part before the extension and the
+// extension
+func (s *FileSink) nameParts() (string, string) {
+	ext := filepath.Ext(s.opts.Path)
+	return strings.TrimSuffix(s.opts.Path, ext), ext
+}
+
+// rotatedFiles lists the rotated files, oldest first. Other files sharing
+// the name, such as audit-archive.jsonl, are left out.
+func (s *FileSink) rotatedFiles() []string {
+	base, ext := s.nameParts()
+	matches, _ := filepath.Glob(base + "-*" + ext)
+	var files []string
+	for _, file := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(file, base+"-"), ext)
+		if _, err := time.Parse(rotatedLayout, stamp); err == nil && len(stamp) == len(rotatedLayout) {
+			files = append(files, file)
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// LastHash returns the hash of the last entry in the log, looking at the
+// newest rotated file when the current one is empty
+func (s *FileSink) LastHash() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.opts.Path
+	if s.size == 0 {
+		files := s.rotatedFiles()
+		if len(files) == 0 {
+			return "", nil
+		}
+		path = files[len(files)-1]
+	}
+	line, err := lastLine(path)
+	if err != nil || line == nil {
+		return "", err
+	}
+	_, hash, err := splitHash(line)
+	if err != nil {
+		return "", fmt.Errorf("last entry of %s: %w", path, err)
+	}
+	return hash, nil
+}
+
+// lastLine reads the last non-empty line of a file, reading backwards so
+// large logs are not read whole
+func lastLine(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audit log: %w", err)
+	}
+
+	const chunk = 64 * 1024
+	var tail []byte
+	for end := info.Size(); end > 0; {
+		start := end - chunk
+		if start < 0 {
+			start = 0
+		}
+		buf := make([]byte, end-start)
+		if _, err := f.ReadAt(buf, start); err != nil && err != io.EOF {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+		tail = append(buf, tail...)
+		trimmed := bytes.TrimRight(tail, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+		end = start
+	}
+	tail = bytes.TrimRight(tail, "\n")
+	if len(tail) == 0 {
+		return nil, nil
+	}
+	return tail, nil
+}
@@ -0,0 +1,94 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileSink_Rotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "audit.jsonl")
+	sink, err := OpenFile(FileOptions{Path: path, MaxSize: 400, MaxFiles: 2})
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	// Files that share the name but were not rotated by the sink
+	stray := []string{filepath.Join(filepath.Dir(path), "audit-archive.jsonl"), filepath.Join(filepath.Dir(path), "audit-http.jsonl")}
+	for _, file := range stray {
+		if err := os.WriteFile(file, []byte(`{"tool":"other"}`+"\n"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	l := NewLogger(sink, true)
+	for i := 0; i < 12; i++ {
+		if err := l.Log(Event{Client: "stdio", Tool: "list_tables", Status: "ok"}); err != nil {
+			t.Fatalf("Log failed: %v", err)
+		}
+	}
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	for _, file := range stray {
+		if _, err := os.Stat(file); err != nil {
+			t.Errorf("Expected %s to be kept: %v", filepath.Base(file), err)
+		}
+	}
+	rotated, _ := filepath.Glob(filepath.Join(filepath.Dir(path), "audit-2*.jsonl"))
+	if len(rotated) != 2 {
+		t.Fatalf("Expected 2 rotated files to be kept, got %v", rotated)
+	}
+	info, err := os.Stat(path)
+	if err != nil || info.Size() == 0 || info.Size() > 400 {
+		t.Fatalf("Expected a current file of at most 400 bytes, got %v, %v", info, err)
+	}
+	if info.Mode().Perm() != 0o600 {
+		t.Errorf("Expected the log to be private, got %v", info.Mode().Perm())
+	}
+
+	// The chain continues across files and after a restart, from the newest
+	// rotated file rather than the stray ones
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	sink, err = OpenFile(FileOptions{Path: path, MaxSize: 400, MaxFiles: 2})
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	l = NewLogger(sink, true)
+	if err := l.Log(Event{Client: "stdio", Tool: "describe_table", Status: "ok"}); err != nil {
+		t.Fatalf("Log failed: %v", err)
+	}
+	l.Close()
+
+	rotated, _ = filepath.Glob(filepath.Join(filepath.Dir(path), "audit-2*.jsonl"))
+	prev := ""
+	for _, file := range append(rotated, path) {
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prev, _, err = Verify(f, prev)
+		f.Close()
+		if err != nil {
+			t.Errorf("%s: %v", filepath.Base(file), err)
+		}
+	}
+}
+
+func TestFileSink_LastHash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := OpenFile(FileOptions{Path: path})
+	if err != nil {
+		t.Fatalf("OpenFile failed: %v", err)
+	}
+	defer sink.Close()
+
+	if hash, err := sink.LastHash(); hash != "" || err != nil {
+		t.Errorf("Expected no hash for a new log, got %q, %v", hash, err)
+	}
+	sink.Write([]byte(`{"tool":"list_tables"}`))
+	if _, err := sink.LastHash(); err == nil {
+		t.Error("Expected an error for an unchained entry")
+	}
+}
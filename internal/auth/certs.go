@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"crypto/x509"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// CertRule maps client certificates whose subject common name matches
+// Pattern to Role
+type CertRule struct {
+	Pattern string // e.g. "reporting-*"
+	Role    *Role  // nil allows everything
+}
+
+// ClientCerts identifies clients by their verified TLS certificate
+type ClientCerts struct {
+	rules []CertRule
+}
+
+// NewClientCerts returns the rules that Identify applies in order
+func NewClientCerts(rules ...CertRule) (*ClientCerts, error) {
+	for _, r := range rules {
+		if _, err := path.Match(r.Pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid client certificate pattern %q: %w", r.Pattern, err)
+		}
+	}
+	return &ClientCerts{rules: rules}, nil
+}
+
+// Identify returns the identity of a verified client certificate, named by
+// its subject common name, with the role of the first matching rule.
+// Without rules every certificate is accepted without a role; with rules, a
+// certificate that matches none is rejected.
+func (c *ClientCerts) Identify(cert *x509.Certificate) (*Identity, bool) {
+	name := cert.Subject.CommonName
+	if name == "" {
+		name = cert.Subject.String()
+	}
+	if c == nil || len(c.rules) == 0 {
+		return &Identity{Name: name}, true
+	}
+	for _, r := range c.rules {
+		if ok, _ := path.Match(r.Pattern, name); ok {
+			return &Identity{Name: name, Role: r.Role}, true
+		}
+	}
+	return nil, false
+}
+
+// String lists the rules for configuration diffs
+func (c *ClientCerts) String() string {
+	if c == nil || len(c.rules) == 0 {
+		return "(none)"
+	}
+	rules := make([]string, len(c.rules))
+	for i, r := range c.rules {
+		rules[i] = r.Pattern
+		if r.Role != nil {
+			rules[i] += "=" + r.Role.Name
+		}
+	}
+	return strings.Join(rules, ",")
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"testing"
+)
+
+func TestClientCerts_Identify(t *testing.T) {
+	reporting := &Role{Name: "reporting"}
+	certs, err := NewClientCerts(CertRule{Pattern: "reporting-*", Role: reporting}, CertRule{Pattern: "ops"})
+	if err != nil {
+		t.Fatalf("NewClientCerts failed: %v", err)
+	}
+
+	tests := []struct {
+		subject pkix.Name
+		want    string // identity name; empty when rejected
+		role    string
+	}{
+		{pkix.Name{CommonName: "reporting-eu"}, "reporting-eu", "reporting"},
+		{pkix.Name{CommonName: "ops"}, "ops", ""},
+		{pkix.Name{CommonName: "intruder"}, "", ""},
+	}
+	for _, tt := range tests {
+		identity, ok := certs.Identify(&x509.Certificate{Subject: tt.subject})
+		if tt.want == "" {
+			if ok {
+				t.Errorf("%s: expected rejection, got %v", tt.subject, identity)
+			}
+			continue
+		}
+		if !ok || identity.Name != tt.want || (identity.Role != nil) != (tt.role != "") ||
+			(identity.Role != nil && identity.Role.Name != tt.role) {
+			t.Errorf("%s: unexpected identity %v with role %s", tt.subject, identity, identity.Role)
+		}
+	}
+
+	var none *ClientCerts
+	identity, ok := none.Identify(&x509.Certificate{Subject: pkix.Name{Organization: []string{"Example"}}})
+	if !ok || identity.Name != "O=Example" || identity.Role != nil {
+		t.Errorf("Expected any certificate to be accepted without rules, got %v", identity)
+	}
+	if certs.String() != "reporting-*=reporting,ops" {
+		t.Errorf("Unexpected String %q", certs.String())
+	}
+	if _, err := NewClientCerts(CertRule{Pattern: "["}); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+}
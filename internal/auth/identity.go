@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	"github.com/hieubanhh/dbhubMCP/internal/security"
+)
+
+// Identity is the authenticated client a request was made by
+type Identity struct {
+	Name string // e.g. the API key name
+	Role *Role  // nil allows everything
+}
+
+func (i *Identity) String() string {
+	if i == nil {
+		return "(anonymous)"
+	}
+	if i.Role == nil {
+		return i.Name
+	}
+	return fmt.Sprintf("%s (role %s)", i.Name, i.Role.Name)
+}
+
+// Role limits the tools, databases, tables and rows an identity may use
+type Role struct {
+	Name      string
+	Tools     []string         // tool name patterns; empty allows every tool
+	Databases []string         // connection name patterns; empty allows every database
+	Policy    *security.Policy // schemas, tables and columns; nil allows all
+	MaxRows   int              // caps the max rows of every database; 0 keeps them
+}
+
+// NewRole validates the tool and database patterns of a role
+func NewRole(name string, tools, databases []string, policy *security.Policy, maxRows int) (*Role, error) {
+	for _, p := range append(append([]string(nil), tools...), databases...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("role %s: invalid pattern %q: %w", name, p, err)
+		}
+	}
+	if maxRows < 0 {
+		return nil, fmt.Errorf("role %s: max_rows must not be negative", name)
+	}
+	return &Role{Name: name, Tools: tools, Databases: databases, Policy: policy, MaxRows: maxRows}, nil
+}
+
+func (r *Role) String() string {
+	if r == nil {
+		return "(none)"
+	}
+	return fmt.Sprintf("tools=%v databases=%v max_rows=%d policy={%s}", r.Tools, r.Databases, r.MaxRows, r.Policy)
+}
+
+// AllowsTool reports whether the role may call the tool. A nil role allows
+// every tool.
+func (r *Role) AllowsTool(name string) bool {
+	return r == nil || matchAny(r.Tools, name)
+}
+
+// AllowsDatabase reports whether the role may use the named connection. A
+// nil role allows every database.
+func (r *Role) AllowsDatabase(name string) bool {
+	return r == nil || matchAny(r.Databases, name)
+}
+
+// RowLimit returns the row limit of a database with maxRows for the role
+func (r *Role) RowLimit(maxRows int) int {
+	if r == nil || r.MaxRows == 0 || (maxRows > 0 && maxRows < r.MaxRows) {
+		return maxRows
+	}
+	return r.MaxRows
+}
+
+// matchAny reports whether name matches one of the patterns; no patterns
+// match everything
+func matchAny(patterns []string, name string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessError reports a tool or database that the caller's role does not allow
+type AccessError struct {
+	Object string // e.g. "tool execute_query", "database billing"
+	Role   string
+}
+
+func (e *AccessError) Error() string {
+	return fmt.Sprintf("access to %s is denied for role %s", e.Object, e.Role)
+}
+
+type contextKey struct{}
+
+// WithIdentity returns a context carrying the identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identity)
+}
+
+// FromContext returns the identity of the request, or nil when the
+// transport does not authenticate clients
+func FromContext(ctx context.Context) *Identity {
+	identity, _ := ctx.Value(contextKey{}).(*Identity)
+	return identity
+}
+
+// RoleFromContext returns the role of the request's identity; nil allows
+// everything
+func RoleFromContext(ctx context.Context) *Role {
+	if identity := FromContext(ctx); identity != nil {
+		return identity.Role
+	}
+	return nil
+}
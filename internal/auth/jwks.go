@@ -0,0 +1,253 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwksRefresh is how often keys are loaded again, so rotated keys are
+// picked up, and jwksMinRefresh how often a token with an unknown key id
+// may trigger a reload
+const (
+	jwksRefresh    = time.Hour
+	jwksMinRefresh = time.Minute
+)
+
+// jwk is one key of a JSON Web Key Set
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// publicKey is a parsed JWK
+type publicKey struct {
+	kid string
+	alg string // optional; the only algorithm the key may be used with
+	key crypto.PublicKey
+}
+
+// KeySet loads the public keys of a JWKS from a file or URL on first use
+// and again when it is stale or a token names an unknown key
+type KeySet struct {
+	source string // file path or http(s) URL
+	client *http.Client
+
+	mu      sync.Mutex
+	keys    []publicKey
+	loaded  time.Time
+	tried   time.Time
+	lastErr error
+
+	// Closed when the load in progress finishes; nil when none is
+	loading chan struct{}
+}
+
+// NewKeySet returns the key set at source, a file path or an http(s) URL
+func NewKeySet(source string) *KeySet {
+	return &KeySet{source: source, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// lookup returns the keys that may verify a token with the key id and
+// algorithm. An empty kid matches every key. Keys are loaded without holding
+// the lock, so a slow JWKS endpoint only delays the lookup that loads them;
+// others use the current keys, or wait when none were loaded yet.
+func (s *KeySet) lookup(ctx context.Context, kid, alg string) ([]crypto.PublicKey, error) {
+	s.mu.Lock()
+	now := time.Now()
+	stale := s.loaded.IsZero() || now.Sub(s.loaded) > jwksRefresh
+	matches := s.match(kid, alg)
+	switch {
+	case (stale || len(matches) == 0) && s.loading == nil && now.Sub(s.tried) >= jwksMinRefresh:
+		s.tried = now
+		done := make(chan struct{})
+		s.loading = done
+		s.mu.Unlock()
+
+		// Other lookups share the result, so the caller going away must not
+		// cancel it; the client timeout bounds it
+		keys, err := s.load(context.WithoutCancel(ctx))
+
+		s.mu.Lock()
+		if err != nil {
+			s.lastErr = err
+		} else {
+			s.keys, s.loaded, s.lastErr = keys, time.Now(), nil
+		}
+		s.loading = nil
+		close(done)
+		matches = s.match(kid, alg)
+	case s.loading != nil && s.loaded.IsZero():
+		done := s.loading
+		s.mu.Unlock()
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to load signing keys: %w", ctx.Err())
+		}
+		s.mu.Lock()
+		matches = s.match(kid, alg)
+	}
+	lastErr := s.lastErr
+	s.mu.Unlock()
+
+	if len(matches) == 0 {
+		if lastErr != nil {
+			return nil, fmt.Errorf("failed to load signing keys: %w", lastErr)
+		}
+		return nil, fmt.Errorf("no signing key matches key id %q", kid)
+	}
+	return matches, nil
+}
+
+func (s *KeySet) match(kid, alg string) []crypto.PublicKey {
+	var keys []crypto.PublicKey
+	for _, k := range s.keys {
+		if (kid == "" || k.kid == kid) && (k.alg == "" || k.alg == alg) {
+			keys = append(keys, k.key)
+		}
+	}
+	return keys
+}
+
+// load reads and parses the key set
+func (s *KeySet) load(ctx context.Context) ([]publicKey, error) {
+	var data []byte
+	var err error
+	if strings.HasPrefix(s.source, "http://") || strings.HasPrefix(s.source, "https://") {
+		data, err = s.fetch(ctx)
+	} else {
+		data, err = os.ReadFile(s.source)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseJWKS(data)
+}
+
+func (s *KeySet) fetch(ctx context.Context) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.source, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JWKS request: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch JWKS: %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// parseJWKS parses the signature keys of a JWKS, skipping encryption keys
+// and key types that are not supported
+func parseJWKS(data []byte) ([]publicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("failed to parse JWKS: %w", err)
+	}
+
+	var keys []publicKey
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("JWKS key %d: %w", i+1, err)
+		}
+		if key != nil {
+			keys = append(keys, publicKey{kid: k.Kid, alg: k.Alg, key: key})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("JWKS has no signature keys")
+	}
+	return keys, nil
+}
+
+// publicKey decodes an RSA, EC or Ed25519 key; other key types return nil
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on curve %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, nil
+	}
+}
+
+func decodeInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	_ "crypto/sha256" // registers SHA-256 for crypto.Hash
+	_ "crypto/sha512" // registers SHA-384 and SHA-512
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// jwtHeader is the protected header of a signed JWT
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// signedToken is a JWT in compact form split into its parts
+type signedToken struct {
+	header    jwtHeader
+	claims    map[string]interface{}
+	signed    []byte // header.payload, the input of the signature
+	signature []byte
+}
+
+// parseToken decodes a compact JWT without verifying it
+func parseToken(token string) (*signedToken, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("token is not a signed JWT")
+	}
+	t := &signedToken{signed: []byte(parts[0] + "." + parts[1])}
+
+	header, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode token header: %w", err)
+	}
+	if err := json.Unmarshal(header, &t.header); err != nil {
+		return nil, fmt.Errorf("failed to parse token header: %w", err)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode token claims: %w", err)
+	}
+	dec := json.NewDecoder(strings.NewReader(string(payload)))
+	dec.UseNumber()
+	if err := dec.Decode(&t.claims); err != nil {
+		return nil, fmt.Errorf("failed to parse token claims: %w", err)
+	}
+	if t.signature, err = base64.RawURLEncoding.DecodeString(parts[2]); err != nil {
+		return nil, fmt.Errorf("failed to decode token signature: %w", err)
+	}
+	return t, nil
+}
+
+// algorithm is a JWS signature algorithm
+type algorithm struct {
+	hash crypto.Hash
+	kind string // "RSA", "PSS", "EC" or "OKP"
+	size int    // ECDSA coordinate size in bytes
+}
+
+// algorithms are the supported JWS algorithms; "none" and HMAC are not
+var algorithms = map[string]algorithm{
+	"RS256": {crypto.SHA256, "RSA", 0},
+	"RS384": {crypto.SHA384, "RSA", 0},
+	"RS512": {crypto.SHA512, "RSA", 0},
+	"PS256": {crypto.SHA256, "PSS", 0},
+	"PS384": {crypto.SHA384, "PSS", 0},
+	"PS512": {crypto.SHA512, "PSS", 0},
+	"ES256": {crypto.SHA256, "EC", 32},
+	"ES384": {crypto.SHA384, "EC", 48},
+	"ES512": {crypto.SHA512, "EC", 66},
+	"EdDSA": {0, "OKP", 0},
+}
+
+// verify checks the token's signature with key
+func (t *signedToken) verify(key crypto.PublicKey) error {
+	alg, ok := algorithms[t.header.Alg]
+	if !ok {
+		return fmt.Errorf("unsupported signing algorithm %q", t.header.Alg)
+	}
+
+	var digest []byte
+	if alg.hash != 0 {
+		h := alg.hash.New()
+		h.Write(t.signed)
+		digest = h.Sum(nil)
+	}
+
+	valid := false
+	switch k := key.(type) {
+	case *rsa.PublicKey:
+		switch alg.kind {
+		case "RSA":
+			valid = rsa.VerifyPKCS1v15(k, alg.hash, digest, t.signature) == nil
+		case "PSS":
+			valid = rsa.VerifyPSS(k, alg.hash, digest, t.signature, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash}) == nil
+		default:
+			return fmt.Errorf("key does not match algorithm %s", t.header.Alg)
+		}
+	case *ecdsa.PublicKey:
+		if alg.kind != "EC" || (k.Curve.Params().BitSize+7)/8 != alg.size {
+			return fmt.Errorf("key does not match algorithm %s", t.header.Alg)
+		}
+		if len(t.signature) == 2*alg.size {
+			r := new(big.Int).SetBytes(t.signature[:alg.size])
+			s := new(big.Int).SetBytes(t.signature[alg.size:])
+			valid = ecdsa.Verify(k, digest, r, s)
+		}
+	case ed25519.PublicKey:
+		if alg.kind != "OKP" {
+			return fmt.Errorf("key does not match algorithm %s", t.header.Alg)
+		}
+		valid = ed25519.Verify(k, t.signed, t.signature)
+	default:
+		return fmt.Errorf("unsupported key type %T", key)
+	}
+	if !valid {
+		return fmt.Errorf("invalid token signature")
+	}
+	return nil
+}
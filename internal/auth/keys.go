@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// APIKey is an API key known by its SHA-256 hash, so that the key itself
+// is never stored
+type APIKey struct {
+	Name string
+	Hash []byte // SHA-256 of the key
+	Role *Role  // nil allows everything
+}
+
+// HashKey returns the SHA-256 hash of an API key
+func HashKey(key string) []byte {
+	sum := sha256.Sum256([]byte(key))
+	return sum[:]
+}
+
+// ParseKeyHash decodes a hex SHA-256 hash, as printed by sha256sum
+func ParseKeyHash(s string) ([]byte, error) {
+	hash, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(hash) != sha256.Size {
+		return nil, fmt.Errorf("key hash must be 64 hex characters (a SHA-256 hash)")
+	}
+	return hash, nil
+}
+
+// Keys authenticates requests by API key
+type Keys struct {
+	keys []APIKey
+}
+
+// NewKeys returns the keys that Authenticate accepts
+func NewKeys(keys ...APIKey) *Keys {
+	return &Keys{keys: keys}
+}
+
+// Len returns the number of keys; authentication is disabled without keys
+func (k *Keys) Len() int {
+	if k == nil {
+		return 0
+	}
+	return len(k.keys)
+}
+
+// Authenticate returns the identity of the key. The hash of the key is
+// compared with every known hash in constant time, so neither the position
+// of a match nor a shared prefix shows in the response time.
+func (k *Keys) Authenticate(key string) (*Identity, bool) {
+	if k == nil || key == "" {
+		return nil, false
+	}
+	hash := HashKey(key)
+	var match *APIKey
+	for i := range k.keys {
+		if subtle.ConstantTimeCompare(hash, k.keys[i].Hash) == 1 && match == nil {
+			match = &k.keys[i]
+		}
+	}
+	if match == nil {
+		return nil, false
+	}
+	return &Identity{Name: match.Name, Role: match.Role}, true
+}
+
+// String lists the key names and roles for configuration diffs, without
+// the hashes
+func (k *Keys) String() string {
+	if k.Len() == 0 {
+		return "(none)"
+	}
+	names := make([]string, len(k.keys))
+	for i, key := range k.keys {
+		names[i] = key.Name
+		if key.Role != nil {
+			names[i] += "=" + key.Role.Name
+		}
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"encoding/hex"
+	"testing"
+)
+
+func TestKeys_Authenticate(t *testing.T) {
+	analyst := &Role{Name: "analyst"}
+	keys := NewKeys(
+		APIKey{Name: "ci", Hash: HashKey("ci-secret"), Role: analyst},
+		APIKey{Name: "admin", Hash: HashKey("admin-secret")},
+	)
+
+	identity, ok := keys.Authenticate("ci-secret")
+	if !ok || identity.Name != "ci" || identity.Role != analyst {
+		t.Errorf("Expected the ci identity, got %v", identity)
+	}
+	identity, ok = keys.Authenticate("admin-secret")
+	if !ok || identity.Name != "admin" || identity.Role != nil {
+		t.Errorf("Expected the unrestricted admin identity, got %v", identity)
+	}
+	for _, key := range []string{"", "ci-secre", "wrong"} {
+		if _, ok := keys.Authenticate(key); ok {
+			t.Errorf("Expected %q to be rejected", key)
+		}
+	}
+	if keys.String() != "ci=analyst,admin" {
+		t.Errorf("Expected names and roles without hashes, got %s", keys)
+	}
+}
+
+func TestParseKeyHash(t *testing.T) {
+	hash, err := ParseKeyHash(hex.EncodeToString(HashKey("k")) + "\n")
+	if err != nil || string(hash) != string(HashKey("k")) {
+		t.Errorf("Expected the hash to round-trip, got %x, %v", hash, err)
+	}
+	for _, bad := range []string{"", "k", "abcd", hex.EncodeToString(HashKey("k"))[:62]} {
+		if _, err := ParseKeyHash(bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestRole(t *testing.T) {
+	role, err := NewRole("analyst", []string{"list_*", "execute_readonly_query"}, []string{"reporting"}, nil, 100)
+	if err != nil {
+		t.Fatalf("NewRole failed: %v", err)
+	}
+	if !role.AllowsTool("list_tables") || !role.AllowsTool("execute_readonly_query") || role.AllowsTool("sample_rows") {
+		t.Errorf("Unexpected tool permissions for %s", role)
+	}
+	if !role.AllowsDatabase("reporting") || role.AllowsDatabase("billing") {
+		t.Errorf("Unexpected database permissions for %s", role)
+	}
+	for maxRows, want := range map[int]int{1000: 100, 50: 50, 0: 100} {
+		if got := role.RowLimit(maxRows); got != want {
+			t.Errorf("RowLimit(%d): expected %d, got %d", maxRows, want, got)
+		}
+	}
+
+	var none *Role
+	if !none.AllowsTool("anything") || !none.AllowsDatabase("anything") || none.RowLimit(10) != 10 {
+		t.Error("Expected a nil role to allow everything")
+	}
+	if _, err := NewRole("bad", []string{"["}, nil, nil, 0); err == nil {
+		t.Error("Expected an invalid pattern to be rejected")
+	}
+}
+
+func TestContext(t *testing.T) {
+	ctx := context.Background()
+	if FromContext(ctx) != nil || RoleFromContext(ctx) != nil {
+		t.Error("Expected no identity in a plain context")
+	}
+	role := &Role{Name: "analyst"}
+	ctx = WithIdentity(ctx, &Identity{Name: "ci", Role: role})
+	if FromContext(ctx).Name != "ci" || RoleFromContext(ctx) != role {
+		t.Errorf("Expected the identity to be carried, got %v", FromContext(ctx))
+	}
+}
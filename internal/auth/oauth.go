@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OAuthConfig describes how bearer tokens issued by an OAuth 2.1
+// authorization server are validated
+type OAuthConfig struct {
+	Issuer   string // required "iss" claim
+	Audience string // required in the "aud" claim, usually the resource URL
+	JWKS     string // file path or http(s) URL of the issuer's signing keys
+
+	// Resource identifies this server in the protected resource metadata;
+	// defaults to Audience. AuthorizationServers defaults to Issuer.
+	Resource             string
+	AuthorizationServers []string
+
+	// Scopes maps each scope to the tool name patterns it grants. Without
+	// scopes, a valid token may call every tool.
+	Scopes map[string][]string
+
+	Leeway time.Duration // clock skew allowed for exp and nbf
+}
+
+// TokenValidator authenticates requests by OAuth bearer token
+type TokenValidator struct {
+	config OAuthConfig
+	keys   *KeySet
+	now    func() time.Time
+}
+
+// NewTokenValidator checks the configuration; signing keys are loaded on
+// first use
+func NewTokenValidator(config OAuthConfig) (*TokenValidator, error) {
+	if config.Issuer == "" {
+		return nil, fmt.Errorf("issuer is required")
+	}
+	if config.Audience == "" {
+		return nil, fmt.Errorf("audience is required")
+	}
+	if config.JWKS == "" {
+		return nil, fmt.Errorf("a JWKS file or URL is required")
+	}
+	for scope, tools := range config.Scopes {
+		for _, p := range tools {
+			if _, err := path.Match(p, ""); err != nil {
+				return nil, fmt.Errorf("scope %s: invalid pattern %q: %w", scope, p, err)
+			}
+		}
+	}
+	if config.Resource == "" {
+		config.Resource = config.Audience
+	}
+	if len(config.AuthorizationServers) == 0 {
+		config.AuthorizationServers = []string{config.Issuer}
+	}
+	return &TokenValidator{config: config, keys: NewKeySet(config.JWKS), now: time.Now}, nil
+}
+
+func (v *TokenValidator) String() string {
+	if v == nil {
+		return "(none)"
+	}
+	return fmt.Sprintf("issuer=%s audience=%s jwks=%s scopes=%v", v.config.Issuer, v.config.Audience, v.config.JWKS, v.config.Scopes)
+}
+
+// TokenError reports a bearer token that was rejected. Scope is set when
+// the token is valid but grants none of the configured scopes.
+type TokenError struct {
+	Reason string
+	Scope  bool
+}
+
+func (e *TokenError) Error() string {
+	return e.Reason
+}
+
+// Validate verifies a bearer token's signature, issuer, audience and
+// lifetime, and returns the identity of its subject. The identity's role
+// allows the tools that the token's scopes map to.
+func (v *TokenValidator) Validate(ctx context.Context, token string) (*Identity, error) {
+	t, err := parseToken(token)
+	if err != nil {
+		return nil, &TokenError{Reason: err.Error()}
+	}
+	keys, err := v.keys.lookup(ctx, t.header.Kid, t.header.Alg)
+	if err != nil {
+		return nil, &TokenError{Reason: err.Error()}
+	}
+	err = fmt.Errorf("invalid token signature")
+	for _, key := range keys {
+		if err = t.verify(key); err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return nil, &TokenError{Reason: err.Error()}
+	}
+
+	if iss, _ := t.claims["iss"].(string); iss != v.config.Issuer {
+		return nil, &TokenError{Reason: fmt.Sprintf("token issuer %q is not trusted", iss)}
+	}
+	if !containsString(stringsClaim(t.claims["aud"]), v.config.Audience) {
+		return nil, &TokenError{Reason: "token audience does not include this server"}
+	}
+	now := v.now()
+	exp, ok := timeClaim(t.claims["exp"])
+	if !ok {
+		return nil, &TokenError{Reason: "token has no expiry"}
+	}
+	if !now.Before(exp.Add(v.config.Leeway)) {
+		return nil, &TokenError{Reason: "token has expired"}
+	}
+	if nbf, ok := timeClaim(t.claims["nbf"]); ok && now.Add(v.config.Leeway).Before(nbf) {
+		return nil, &TokenError{Reason: "token is not valid yet"}
+	}
+
+	// The name keys quotas and the audit log, so a token must carry one
+	var name string
+	for _, claim := range []string{"sub", "client_id", "azp"} {
+		if name, _ = t.claims[claim].(string); name != "" {
+			break
+		}
+	}
+	if name == "" {
+		return nil, &TokenError{Reason: "token has no sub, client_id or azp claim"}
+	}
+	identity := &Identity{Name: name}
+	if len(v.config.Scopes) == 0 {
+		return identity, nil
+	}
+
+	var granted, tools []string
+	for _, scope := range tokenScopes(t.claims) {
+		if patterns, ok := v.config.Scopes[scope]; ok {
+			granted = append(granted, scope)
+			tools = append(tools, patterns...)
+		}
+	}
+	if len(tools) == 0 {
+		return nil, &TokenError{Reason: "token grants none of the required scopes", Scope: true}
+	}
+	identity.Role = &Role{Name: "scope " + strings.Join(granted, " "), Tools: tools}
+	return identity, nil
+}
+
+// ProtectedResourceMetadata is the OAuth protected resource metadata
+// (RFC 9728) of this server
+type ProtectedResourceMetadata struct {
+	Resource               string   `json:"resource"`
+	AuthorizationServers   []string `json:"authorization_servers"`
+	ScopesSupported        []string `json:"scopes_supported,omitempty"`
+	BearerMethodsSupported []string `json:"bearer_methods_supported"`
+}
+
+// Metadata returns the protected resource metadata
+func (v *TokenValidator) Metadata() ProtectedResourceMetadata {
+	return ProtectedResourceMetadata{
+		Resource:               v.config.Resource,
+		AuthorizationServers:   v.config.AuthorizationServers,
+		ScopesSupported:        v.Scopes(),
+		BearerMethodsSupported: []string{"header"},
+	}
+}
+
+// Scopes returns the configured scopes, sorted
+func (v *TokenValidator) Scopes() []string {
+	scopes := make([]string, 0, len(v.config.Scopes))
+	for scope := range v.config.Scopes {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+	return scopes
+}
+
+// tokenScopes reads the space-separated "scope" claim, or the "scp" array
+// that some issuers use
+func tokenScopes(claims map[string]interface{}) []string {
+	if scope, ok := claims["scope"].(string); ok {
+		return strings.Fields(scope)
+	}
+	return stringsClaim(claims["scp"])
+}
+
+// stringsClaim reads a claim that is a string or an array of strings
+func stringsClaim(v interface{}) []string {
+	switch v := v.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// timeClaim reads a NumericDate claim
+func timeClaim(v interface{}) (time.Time, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(f), 0), true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,285 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var b64 = base64.RawURLEncoding
+
+// testKeys are a locally generated RSA, P-256 and Ed25519 key
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+	ed  ed25519.PrivateKey
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate Ed25519 key: %v", err)
+	}
+	return &testKeys{rsa: rsaKey, ec: ecKey, ed: edKey}
+}
+
+// jwks returns the public keys as a JWKS
+func (k *testKeys) jwks() []byte {
+	pad := func(n *big.Int) string { return b64.EncodeToString(n.FillBytes(make([]byte, 32))) }
+	set := map[string]interface{}{"keys": []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "n": b64.EncodeToString(k.rsa.N.Bytes()), "e": b64.EncodeToString(big.NewInt(int64(k.rsa.E)).Bytes())},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "alg": "ES256", "x": pad(k.ec.X), "y": pad(k.ec.Y)},
+		{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": b64.EncodeToString(k.ed.Public().(ed25519.PublicKey))},
+		{"kty": "RSA", "kid": "enc", "use": "enc", "n": "AQAB", "e": "AQAB"},
+	}}
+	data, _ := json.Marshal(set)
+	return data
+}
+
+// sign returns a compact JWT signed with the key named by kid
+func (k *testKeys) sign(t *testing.T, alg, kid string, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	var err error
+	switch alg {
+	case "RS256":
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k.rsa, crypto.SHA256, digest[:])
+	case "PS256":
+		sig, err = rsa.SignPSS(rand.Reader, k.rsa, crypto.SHA256, digest[:], &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, k.ec, digest[:])
+		if err == nil {
+			sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	case "EdDSA":
+		sig = ed25519.Sign(k.ed, []byte(signed))
+	}
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return signed + "." + b64.EncodeToString(sig)
+}
+
+func validClaims(now time.Time) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":   "https://auth.example",
+		"aud":   []string{"https://mcp.example/mcp"},
+		"sub":   "alice",
+		"exp":   now.Add(time.Hour).Unix(),
+		"scope": "db:read",
+	}
+}
+
+func newTestValidator(t *testing.T, jwks string) *TokenValidator {
+	t.Helper()
+	v, err := NewTokenValidator(OAuthConfig{
+		Issuer:   "https://auth.example",
+		Audience: "https://mcp.example/mcp",
+		JWKS:     jwks,
+		Scopes: map[string][]string{
+			"db:read":  {"list_*", "describe_table"},
+			"db:query": {"execute_readonly_query"},
+		},
+		Leeway: 30 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewTokenValidator failed: %v", err)
+	}
+	return v
+}
+
+func TestTokenValidator_Validate(t *testing.T) {
+	keys := newTestKeys(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, keys.jwks(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	v := newTestValidator(t, path)
+	now := time.Now()
+
+	with := func(key string, value interface{}) map[string]interface{} {
+		claims := validClaims(now)
+		if value == nil {
+			delete(claims, key)
+		} else {
+			claims[key] = value
+		}
+		return claims
+	}
+	other := newTestKeys(t)
+
+	tests := []struct {
+		name  string
+		token string
+		want  string // error substring; empty for success
+	}{
+		{"RS256", keys.sign(t, "RS256", "rsa", validClaims(now)), ""},
+		{"PS256", keys.sign(t, "PS256", "rsa", validClaims(now)), ""},
+		{"ES256", keys.sign(t, "ES256", "ec", validClaims(now)), ""},
+		{"EdDSA", keys.sign(t, "EdDSA", "ed", validClaims(now)), ""},
+		{"no kid", keys.sign(t, "RS256", "", validClaims(now)), ""},
+		{"string audience", keys.sign(t, "RS256", "rsa", with("aud", "https://mcp.example/mcp")), ""},
+		{"within leeway", keys.sign(t, "RS256", "rsa", with("exp", now.Add(-10*time.Second).Unix())), ""},
+		{"other key", other.sign(t, "RS256", "rsa", validClaims(now)), "invalid token signature"},
+		{"alg mismatch", keys.sign(t, "RS256", "ec", validClaims(now)), "no signing key"},
+		{"none", b64.EncodeToString([]byte(`{"alg":"none"}`)) + "." + b64.EncodeToString([]byte(`{}`)) + ".", "unsupported signing algorithm"},
+		{"expired", keys.sign(t, "RS256", "rsa", with("exp", now.Add(-time.Minute).Unix())), "expired"},
+		{"no expiry", keys.sign(t, "RS256", "rsa", with("exp", nil)), "no expiry"},
+		{"not yet valid", keys.sign(t, "RS256", "rsa", with("nbf", now.Add(time.Hour).Unix())), "not valid yet"},
+		{"issuer", keys.sign(t, "RS256", "rsa", with("iss", "https://evil.example")), "not trusted"},
+		{"audience", keys.sign(t, "RS256", "rsa", with("aud", "https://other.example")), "audience"},
+		{"no scope", keys.sign(t, "RS256", "rsa", with("scope", "profile")), "none of the required scopes"},
+		{"no subject", keys.sign(t, "RS256", "rsa", with("sub", "")), "no sub, client_id or azp"},
+		{"garbage", "not-a-token", "not a signed JWT"},
+	}
+	for _, tt := range tests {
+		identity, err := v.Validate(context.Background(), tt.token)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			} else if identity.Name != "alice" || !identity.Role.AllowsTool("list_tables") || identity.Role.AllowsTool("execute_readonly_query") {
+				t.Errorf("%s: unexpected identity %v with role %s", tt.name, identity, identity.Role)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	claims := with("sub", nil)
+	claims["azp"] = "reporting-agent"
+	if identity, err := v.Validate(context.Background(), keys.sign(t, "RS256", "rsa", claims)); err != nil || identity.Name != "reporting-agent" {
+		t.Errorf("Expected azp to name a token without sub, got %v, %v", identity, err)
+	}
+}
+
+func TestTokenValidator_ScopesAndURL(t *testing.T) {
+	keys := newTestKeys(t)
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write(keys.jwks())
+	}))
+	defer server.Close()
+	v := newTestValidator(t, server.URL)
+
+	claims := validClaims(time.Now())
+	delete(claims, "scope")
+	claims["scp"] = []string{"db:read", "db:query"}
+	identity, err := v.Validate(context.Background(), keys.sign(t, "ES256", "ec", claims))
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+	if !identity.Role.AllowsTool("execute_readonly_query") || identity.Role.AllowsTool("sample_rows") {
+		t.Errorf("Expected both scopes to map to tools, got %s", identity.Role)
+	}
+	if _, err := v.Validate(context.Background(), keys.sign(t, "ES256", "ec", claims)); err != nil || fetches != 1 {
+		t.Errorf("Expected the keys to be cached, got %d fetches, %v", fetches, err)
+	}
+
+	var tokenErr *TokenError
+	claims["scp"] = []string{"profile"}
+	_, err = v.Validate(context.Background(), keys.sign(t, "ES256", "ec", claims))
+	if !errors.As(err, &tokenErr) || !tokenErr.Scope {
+		t.Errorf("Expected an insufficient scope error, got %v", err)
+	}
+
+	meta := v.Metadata()
+	if meta.Resource != "https://mcp.example/mcp" || meta.AuthorizationServers[0] != "https://auth.example" ||
+		strings.Join(meta.ScopesSupported, " ") != "db:query db:read" {
+		t.Errorf("Unexpected metadata %+v", meta)
+	}
+}
+
+func TestKeySet_LoadDoesNotBlockLookups(t *testing.T) {
+	keys := newTestKeys(t)
+	release := make(chan struct{})
+	var slow atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if slow.Load() {
+			<-release
+		}
+		w.Write(keys.jwks())
+	}))
+	defer server.Close()
+	defer close(release)
+
+	set := NewKeySet(server.URL)
+	if _, err := set.lookup(context.Background(), "ec", "ES256"); err != nil {
+		t.Fatalf("lookup failed: %v", err)
+	}
+
+	// An unknown key id reloads the keys from a stalled endpoint
+	slow.Store(true)
+	set.mu.Lock()
+	set.tried = time.Time{}
+	set.mu.Unlock()
+	go set.lookup(context.Background(), "rotated", "ES256")
+	for loading := false; !loading; {
+		set.mu.Lock()
+		loading = set.loading != nil
+		set.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := set.lookup(context.Background(), "ec", "ES256")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("lookup failed: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected a known key to be found while the keys are reloading")
+	}
+}
+
+func TestNewTokenValidator_Errors(t *testing.T) {
+	tests := map[string]OAuthConfig{
+		"issuer":   {Audience: "a", JWKS: "k"},
+		"audience": {Issuer: "i", JWKS: "k"},
+		"JWKS":     {Issuer: "i", Audience: "a"},
+		"pattern":  {Issuer: "i", Audience: "a", JWKS: "k", Scopes: map[string][]string{"s": {"["}}},
+	}
+	for want, config := range tests {
+		if _, err := NewTokenValidator(config); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error containing %q, got %v", want, err)
+		}
+	}
+}
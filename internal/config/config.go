@@ -2,30 +2,63 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+
+	"github.com/hieubanhh/dbhubMCP/internal/auth"
+	"github.com/hieubanhh/dbhubMCP/internal/ratelimit"
+	"github.com/hieubanhh/dbhubMCP/internal/security"
 )
 
 // Config holds all configuration for the MCP server
 type Config struct {
-	// Database configuration
-	DBType         string // "mysql" or "postgres"
-	DBHost         string
-	DBPort         int
-	DBName         string
-	DBUser         string
-	DBPassword     string
-	DBMaxConns     int
-	DBMaxIdleConns int
-	DBConnTimeout  time.Duration
-
-	// Query execution limits
-	QueryTimeout time.Duration
-	MaxRows      int
+	// Database connections, sorted by name
+	Connections       []ConnectionConfig
+	DefaultConnection string
+
+	// Query execution limits, inherited by connections that do not set their own
+	QueryTimeout   time.Duration
+	MaxRows        int
+	ExplainAnalyze bool // allow EXPLAIN ANALYZE, which executes the query
+
+	// Planner estimates above which queries are rejected before they run;
+	// 0 disables each check
+	MaxQueryCost     float64
+	MaxEstimatedRows int
+
+	// Request rates and daily quotas of each client
+	RateLimits ratelimit.Limits
+
+	// Audit log of tool calls, written when AuditPath is set
+	AuditPath      string
+	AuditMaxSizeMB int  // rotate the file at this size; 0 never rotates
+	AuditMaxFiles  int  // rotated files kept; 0 keeps all
+	AuditHashChain bool // chain entries with SHA-256 hashes
+
+	// How often password files and commands are read again to pick up
+	// rotated credentials; 0 disables refreshing
+	CredentialRefresh time.Duration
+
+	// Access policy patterns, merged into every connection's own patterns
+	PolicyAllow security.PolicyRules
+	PolicyDeny  security.PolicyRules
+
+	// Masking rules applied before every connection's own rules, and the
+	// key of the hash strategy
+	MaskRules   []security.MaskRule
+	MaskHashKey string
+
+	// Roles that limit what clients of named API keys may use
+	Roles []RoleConfig
+
+	// Schema metadata caching
+	SchemaCacheTTL     time.Duration // 0 disables the cache
+	SchemaPollInterval time.Duration // 0 disables change detection
 
 	// Server configuration
 	LogLevel string
@@ -34,49 +67,533 @@ type Config struct {
 	TransportType   string   // "stdio" or "http"
 	HTTPAddr        string   // ":8080"
 	HTTPCORSOrigins []string // ["*"]
-	HTTPAPIKey      string   // Optional
+	HTTPAPIKey      string   // Optional shared key with unrestricted access
+	HTTPAPIKeys     []APIKeyConfig
+
+	// Named API keys compiled from HTTPAPIKeys, with their roles
+	HTTPKeys []auth.APIKey
+
+	// OAuth bearer token settings of the HTTP transport and the validator
+	// built from them; nil when no issuer is configured
+	HTTPOAuth          auth.OAuthConfig
+	HTTPTokenValidator *auth.TokenValidator
+
+	// HTTPS settings; the certificate files are reloaded when they change
+	HTTPTLSCert       string
+	HTTPTLSKey        string
+	HTTPTLSClientCA   string // verify client certificates signed by these CAs
+	HTTPTLSClientAuth string // "require" (default) or "optional"
+
+	// Roles of verified client certificates by subject common name, and
+	// the rules compiled from them
+	HTTPClientCertRoles []ClientCertConfig
+	HTTPClientCerts     *auth.ClientCerts
+}
+
+// ClientCertConfig maps client certificates whose subject common name
+// matches Subject to a role
+type ClientCertConfig struct {
+	Subject string // glob pattern, e.g. "reporting-*"
+	Role    string
+}
+
+// RoleConfig describes a role and the role compiled from it
+type RoleConfig struct {
+	Name        string
+	Tools       []string // tool name patterns; empty allows every tool
+	Databases   []string // connection name patterns; empty allows every database
+	MaxRows     int      // caps max_rows of every connection; 0 keeps it
+	PolicyAllow security.PolicyRules
+	PolicyDeny  security.PolicyRules
+	Role        *auth.Role
+}
+
+// APIKeyConfig is a named API key of the HTTP transport. Only the SHA-256
+// hash of the key is configured, e.g. from: printf %s "$KEY" | sha256sum
+type APIKeyConfig struct {
+	Name      string
+	KeySHA256 string
+	Role      string
+}
+
+// ConnectionConfig describes one named database connection and the limits
+// applied to queries against it
+type ConnectionConfig struct {
+	Name string
+
+	Type     string // "mysql" or "postgres"; inferred from DSN when empty
+	DSN      string // postgres:// or mysql:// URL, used instead of the discrete fields
+	Host     string
+	Port     int
+	Socket   string // Unix socket path (MySQL) or directory (PostgreSQL)
+	Database string
+	User     string
+	Password string // overrides the DSN password when set
+
+	// Alternatives to Password that are read at connect time and on every
+	// credential refresh
+	PasswordFile    string // file holding the password, e.g. a Docker or Kubernetes secret
+	PasswordCommand string // command printing the password, e.g. "vault kv get -field=password secret/db"
+
+	// TLS settings
+	SSLMode       string // disable, prefer (MySQL only), require, verify-ca or verify-full
+	SSLCA         string // CA certificate file
+	SSLCert       string // client certificate file
+	SSLKey        string // client key file
+	SSLServerName string // name verified against the server certificate
+
+	MaxConns     int
+	MaxIdleConns int
+	ConnTimeout  time.Duration
+
+	QueryTimeout   time.Duration
+	MaxRows        int
+	ExplainAnalyze bool
+
+	// Cost guard thresholds, inherited when 0
+	MaxQueryCost     float64
+	MaxEstimatedRows int
+
+	// Access policy patterns of this connection and the policy compiled
+	// from them together with the global patterns
+	PolicyAllow security.PolicyRules
+	PolicyDeny  security.PolicyRules
+	Policy      *security.Policy
+
+	// Masking rules of this connection, the hash key (inherited when empty)
+	// and the masker built from them after the global rules; nil when no
+	// rules are set
+	MaskRules   []security.MaskRule
+	MaskHashKey string
+	Masker      *security.Masker
+
+	explainAnalyze *bool // set when the file overrides the global setting
+}
+
+// Address describes the connection target for logs, without credentials
+func (c ConnectionConfig) Address() string {
+	if c.DSN != "" {
+		u, err := url.Parse(c.DSN)
+		if err != nil {
+			return "invalid dsn"
+		}
+		u.RawQuery = ""
+		return u.Redacted()
+	}
+	if c.Socket != "" {
+		return fmt.Sprintf("%s@unix(%s)/%s", c.User, c.Socket, c.Database)
+	}
+	return fmt.Sprintf("%s@%s:%d/%s", c.User, c.Host, c.Port, c.Database)
+}
+
+// Connection returns the named connection
+func (c *Config) Connection(name string) (*ConnectionConfig, bool) {
+	for i := range c.Connections {
+		if c.Connections[i].Name == name {
+			return &c.Connections[i], true
+		}
+	}
+	return nil, false
 }
 
+// Default returns the default connection
+func (c *Config) Default() *ConnectionConfig {
+	conn, _ := c.Connection(c.DefaultConnection)
+	return conn
+}
+
+// defaultConnectionName is the name of the connection configured purely
+// from DB_* environment variables
+const defaultConnectionName = "default"
+
 // LoadFromEnv loads configuration from environment variables
 func LoadFromEnv() (*Config, error) {
+	return Load("")
+}
+
+// Load reads the configuration file at path, if any, and then applies
+// environment variables on top of it. Without a file, a single connection
+// named "default" is built from the DB_* variables.
+func Load(path string) (*Config, error) {
 	godotenv.Load()
 
 	cfg := &Config{
-		DBType:         getEnv("DB_TYPE", "mysql"),
-		DBHost:         getEnv("DB_HOST", "localhost"),
-		DBPort:         getEnvInt("DB_PORT", 3309),
-		DBName:         getEnv("DB_NAME", "test"),
-		DBUser:         getEnv("DB_USER", "root"),
-		DBPassword:     getEnv("DB_PASSWORD", "123456"),
-		DBMaxConns:     getEnvInt("DB_MAX_CONNS", 10),
-		DBMaxIdleConns: getEnvInt("DB_MAX_IDLE_CONNS", 5),
-		DBConnTimeout:  time.Duration(getEnvInt("DB_CONN_TIMEOUT_SEC", 10)) * time.Second,
-		QueryTimeout:   time.Duration(getEnvInt("QUERY_TIMEOUT_SEC", 30)) * time.Second,
-		MaxRows:        getEnvInt("MAX_ROWS", 1000),
-		LogLevel:       getEnv("LOG_LEVEL", "info"),
+		QueryTimeout:      30 * time.Second,
+		MaxRows:           1000,
+		SchemaCacheTTL:    300 * time.Second,
+		LogLevel:          "info",
+		TransportType:     "stdio",
+		HTTPAddr:          ":8080",
+		HTTPCORSOrigins:   []string{"*"},
+		DefaultConnection: defaultConnectionName,
+		AuditMaxSizeMB:    100,
+	}
 
-		// Transport configuration
-		TransportType:   getEnv("TRANSPORT_TYPE", "stdio"),
-		HTTPAddr:        getEnv("HTTP_ADDR", ":8080"),
-		HTTPCORSOrigins: getEnvSlice("HTTP_CORS_ORIGINS", []string{"*"}),
-		HTTPAPIKey:      getEnv("HTTP_API_KEY", ""),
+	if path != "" {
+		if err := loadFile(path, cfg); err != nil {
+			return nil, err
+		}
+	} else {
+		cfg.Connections = []ConnectionConfig{{
+			Name:     defaultConnectionName,
+			Type:     "mysql",
+			Host:     "localhost",
+			Port:     3309,
+			Database: "test",
+			User:     "root",
+		}}
 	}
 
-	// Validate required fields
-	if cfg.DBName == "" {
-		return nil, fmt.Errorf("DB_NAME is required")
+	applyEnv(cfg)
+
+	if err := cfg.resolve(); err != nil {
+		return nil, err
 	}
-	if cfg.DBUser == "" {
-		return nil, fmt.Errorf("DB_USER is required")
+	return cfg, nil
+}
+
+// applyEnv overrides settings with any environment variables that are set.
+// DB_* variables apply to the default connection.
+func applyEnv(cfg *Config) {
+	cfg.QueryTimeout = getEnvSeconds("QUERY_TIMEOUT_SEC", cfg.QueryTimeout)
+	cfg.MaxRows = getEnvInt("MAX_ROWS", cfg.MaxRows)
+	cfg.ExplainAnalyze = getEnvBool("EXPLAIN_ANALYZE_ENABLED", cfg.ExplainAnalyze)
+	cfg.MaxQueryCost = getEnvFloat("QUERY_MAX_COST", cfg.MaxQueryCost)
+	cfg.MaxEstimatedRows = getEnvInt("QUERY_MAX_ESTIMATED_ROWS", cfg.MaxEstimatedRows)
+	cfg.RateLimits.RequestsPerMinute = getEnvInt("RATE_LIMIT_PER_MINUTE", cfg.RateLimits.RequestsPerMinute)
+	cfg.RateLimits.Burst = getEnvInt("RATE_LIMIT_BURST", cfg.RateLimits.Burst)
+	cfg.RateLimits.ExpensivePerMinute = getEnvInt("RATE_LIMIT_EXPENSIVE_PER_MINUTE", cfg.RateLimits.ExpensivePerMinute)
+	cfg.RateLimits.DailyRows = getEnvInt("QUOTA_DAILY_ROWS", cfg.RateLimits.DailyRows)
+	cfg.RateLimits.DailyQueryTime = getEnvSeconds("QUOTA_DAILY_QUERY_TIME_SEC", cfg.RateLimits.DailyQueryTime)
+	cfg.AuditPath = getEnv("AUDIT_LOG_PATH", cfg.AuditPath)
+	cfg.AuditMaxSizeMB = getEnvInt("AUDIT_LOG_MAX_SIZE_MB", cfg.AuditMaxSizeMB)
+	cfg.AuditMaxFiles = getEnvInt("AUDIT_LOG_MAX_FILES", cfg.AuditMaxFiles)
+	cfg.AuditHashChain = getEnvBool("AUDIT_LOG_HASH_CHAIN", cfg.AuditHashChain)
+	cfg.CredentialRefresh = getEnvSeconds("CREDENTIAL_REFRESH_SEC", cfg.CredentialRefresh)
+	cfg.PolicyAllow.Schemas = getEnvSlice("SECURITY_ALLOW_SCHEMAS", cfg.PolicyAllow.Schemas)
+	cfg.PolicyAllow.Tables = getEnvSlice("SECURITY_ALLOW_TABLES", cfg.PolicyAllow.Tables)
+	cfg.PolicyAllow.Columns = getEnvSlice("SECURITY_ALLOW_COLUMNS", cfg.PolicyAllow.Columns)
+	cfg.PolicyDeny.Schemas = getEnvSlice("SECURITY_DENY_SCHEMAS", cfg.PolicyDeny.Schemas)
+	cfg.PolicyDeny.Tables = getEnvSlice("SECURITY_DENY_TABLES", cfg.PolicyDeny.Tables)
+	cfg.PolicyDeny.Columns = getEnvSlice("SECURITY_DENY_COLUMNS", cfg.PolicyDeny.Columns)
+	cfg.PolicyAllow.Functions = getEnvSlice("SECURITY_ALLOW_FUNCTIONS", cfg.PolicyAllow.Functions)
+	cfg.PolicyDeny.Functions = getEnvSlice("SECURITY_DENY_FUNCTIONS", cfg.PolicyDeny.Functions)
+	cfg.PolicyAllow.Clauses = getEnvSlice("SECURITY_ALLOW_CLAUSES", cfg.PolicyAllow.Clauses)
+	cfg.MaskHashKey = getEnv("MASK_HASH_KEY", cfg.MaskHashKey)
+	cfg.SchemaCacheTTL = getEnvSeconds("SCHEMA_CACHE_TTL_SEC", cfg.SchemaCacheTTL)
+	cfg.SchemaPollInterval = getEnvSeconds("SCHEMA_CHANGE_POLL_SEC", cfg.SchemaPollInterval)
+	cfg.LogLevel = getEnv("LOG_LEVEL", cfg.LogLevel)
+
+	// Transport configuration
+	cfg.TransportType = getEnv("TRANSPORT_TYPE", cfg.TransportType)
+	cfg.HTTPAddr = getEnv("HTTP_ADDR", cfg.HTTPAddr)
+	cfg.HTTPCORSOrigins = getEnvSlice("HTTP_CORS_ORIGINS", cfg.HTTPCORSOrigins)
+	cfg.HTTPAPIKey = getEnv("HTTP_API_KEY", cfg.HTTPAPIKey)
+	cfg.HTTPOAuth.Issuer = getEnv("OAUTH_ISSUER", cfg.HTTPOAuth.Issuer)
+	cfg.HTTPOAuth.Audience = getEnv("OAUTH_AUDIENCE", cfg.HTTPOAuth.Audience)
+	cfg.HTTPOAuth.JWKS = getEnv("OAUTH_JWKS", cfg.HTTPOAuth.JWKS)
+	cfg.HTTPTLSCert = getEnv("HTTP_TLS_CERT", cfg.HTTPTLSCert)
+	cfg.HTTPTLSKey = getEnv("HTTP_TLS_KEY", cfg.HTTPTLSKey)
+	cfg.HTTPTLSClientCA = getEnv("HTTP_TLS_CLIENT_CA", cfg.HTTPTLSClientCA)
+	cfg.HTTPTLSClientAuth = getEnv("HTTP_TLS_CLIENT_AUTH", cfg.HTTPTLSClientAuth)
+
+	cfg.DefaultConnection = getEnv("DB_DEFAULT_CONNECTION", cfg.DefaultConnection)
+	conn, ok := cfg.Connection(cfg.DefaultConnection)
+	if !ok {
+		return
 	}
-	if cfg.DBType != "mysql" && cfg.DBType != "postgres" {
-		return nil, fmt.Errorf("DB_TYPE must be 'mysql' or 'postgres', got: %s", cfg.DBType)
+	if dsn := os.Getenv("DATABASE_URL"); dsn != "" {
+		conn.DSN = dsn
+		conn.Type = dsnType(dsn)
 	}
-	if cfg.TransportType != "stdio" && cfg.TransportType != "http" {
-		return nil, fmt.Errorf("TRANSPORT_TYPE must be 'stdio' or 'http', got: %s", cfg.TransportType)
+	conn.Type = getEnv("DB_TYPE", conn.Type)
+	conn.Host = getEnv("DB_HOST", conn.Host)
+	conn.Port = getEnvInt("DB_PORT", conn.Port)
+	conn.Database = getEnv("DB_NAME", conn.Database)
+	conn.User = getEnv("DB_USER", conn.User)
+	// A password source from the environment replaces the file's
+	switch {
+	case os.Getenv("DB_PASSWORD") != "":
+		conn.Password, conn.PasswordFile, conn.PasswordCommand = os.Getenv("DB_PASSWORD"), "", ""
+	case os.Getenv("DB_PASSWORD_FILE") != "":
+		conn.Password, conn.PasswordFile, conn.PasswordCommand = "", os.Getenv("DB_PASSWORD_FILE"), ""
+	case os.Getenv("DB_PASSWORD_COMMAND") != "":
+		conn.Password, conn.PasswordFile, conn.PasswordCommand = "", "", os.Getenv("DB_PASSWORD_COMMAND")
 	}
+	conn.Socket = getEnv("DB_SOCKET", conn.Socket)
+	conn.SSLMode = getEnv("DB_SSL_MODE", conn.SSLMode)
+	conn.SSLCA = getEnv("DB_SSL_CA", conn.SSLCA)
+	conn.SSLCert = getEnv("DB_SSL_CERT", conn.SSLCert)
+	conn.SSLKey = getEnv("DB_SSL_KEY", conn.SSLKey)
+	conn.SSLServerName = getEnv("DB_SSL_SERVER_NAME", conn.SSLServerName)
+	conn.MaxConns = getEnvInt("DB_MAX_CONNS", conn.MaxConns)
+	conn.MaxIdleConns = getEnvInt("DB_MAX_IDLE_CONNS", conn.MaxIdleConns)
+	conn.ConnTimeout = getEnvSeconds("DB_CONN_TIMEOUT_SEC", conn.ConnTimeout)
+}
 
-	return cfg, nil
+// resolve fills connection defaults, inherits global limits and validates
+func (c *Config) resolve() error {
+	if len(c.Connections) == 0 {
+		return fmt.Errorf("at least one database connection is required")
+	}
+	if _, ok := c.Connection(c.DefaultConnection); !ok {
+		if len(c.Connections) != 1 || c.DefaultConnection != defaultConnectionName {
+			return fmt.Errorf("default connection %q is not defined", c.DefaultConnection)
+		}
+		c.DefaultConnection = c.Connections[0].Name
+	}
+
+	for i := range c.Connections {
+		conn := &c.Connections[i]
+		if conn.DSN != "" {
+			scheme := dsnType(conn.DSN)
+			if scheme == "" {
+				return fmt.Errorf("connection %s: dsn must start with postgres://, postgresql:// or mysql://", conn.Name)
+			}
+			if conn.Type == "" {
+				conn.Type = scheme
+			}
+			if conn.Type != scheme {
+				return fmt.Errorf("connection %s: type %s does not match the %s dsn", conn.Name, conn.Type, scheme)
+			}
+		}
+		if conn.Type != "mysql" && conn.Type != "postgres" {
+			return fmt.Errorf("connection %s: type must be 'mysql' or 'postgres', got: %s", conn.Name, conn.Type)
+		}
+		if conn.DSN == "" {
+			if conn.Database == "" {
+				return fmt.Errorf("connection %s: database name is required", conn.Name)
+			}
+			if conn.User == "" {
+				return fmt.Errorf("connection %s: user is required", conn.Name)
+			}
+		}
+
+		switch conn.SSLMode {
+		case "", "disable", "prefer", "require", "verify-ca", "verify-full":
+		default:
+			return fmt.Errorf("connection %s: ssl_mode must be disable, prefer, require, verify-ca or verify-full, got: %s", conn.Name, conn.SSLMode)
+		}
+		if conn.SSLMode == "prefer" && conn.Type == "postgres" {
+			return fmt.Errorf("connection %s: ssl_mode prefer is only supported for mysql", conn.Name)
+		}
+		if (conn.SSLCert == "") != (conn.SSLKey == "") {
+			return fmt.Errorf("connection %s: ssl_cert and ssl_key must be set together", conn.Name)
+		}
+		if err := conn.validatePassword(); err != nil {
+			return err
+		}
+
+		if conn.Host == "" {
+			conn.Host = "localhost"
+		}
+		if conn.Port == 0 {
+			conn.Port = 3306
+			if conn.Type == "postgres" {
+				conn.Port = 5432
+			}
+		}
+		if conn.MaxConns == 0 {
+			conn.MaxConns = 10
+		}
+		if conn.MaxIdleConns == 0 {
+			conn.MaxIdleConns = 5
+		}
+		if conn.ConnTimeout == 0 {
+			conn.ConnTimeout = 10 * time.Second
+		}
+		if conn.QueryTimeout == 0 {
+			conn.QueryTimeout = c.QueryTimeout
+		}
+		if conn.MaxRows == 0 {
+			conn.MaxRows = c.MaxRows
+		}
+		if conn.MaxQueryCost < 0 || conn.MaxEstimatedRows < 0 || c.MaxQueryCost < 0 || c.MaxEstimatedRows < 0 {
+			return fmt.Errorf("connection %s: max_cost and max_estimated_rows must not be negative", conn.Name)
+		}
+		if conn.MaxQueryCost == 0 {
+			conn.MaxQueryCost = c.MaxQueryCost
+		}
+		if conn.MaxEstimatedRows == 0 {
+			conn.MaxEstimatedRows = c.MaxEstimatedRows
+		}
+		conn.ExplainAnalyze = c.ExplainAnalyze
+		if conn.explainAnalyze != nil {
+			conn.ExplainAnalyze = *conn.explainAnalyze
+		}
+
+		// Built-in function and clause denials apply even without patterns
+		policy, err := security.NewPolicy(c.PolicyAllow.Merge(conn.PolicyAllow), c.PolicyDeny.Merge(conn.PolicyDeny))
+		if err != nil {
+			return fmt.Errorf("connection %s: invalid security policy: %w", conn.Name, err)
+		}
+		conn.Policy = policy
+
+		if conn.MaskHashKey == "" {
+			conn.MaskHashKey = c.MaskHashKey
+		}
+		rules := append(append([]security.MaskRule(nil), c.MaskRules...), conn.MaskRules...)
+		conn.Masker = nil
+		if len(rules) > 0 {
+			masker, err := security.NewMasker(rules, conn.MaskHashKey)
+			if err != nil {
+				return fmt.Errorf("connection %s: invalid masking rules: %w", conn.Name, err)
+			}
+			conn.Masker = masker
+		}
+	}
+
+	if err := c.resolveKeys(); err != nil {
+		return err
+	}
+
+	rl := c.RateLimits
+	if rl.RequestsPerMinute < 0 || rl.Burst < 0 || rl.ExpensivePerMinute < 0 || rl.DailyRows < 0 || rl.DailyQueryTime < 0 {
+		return fmt.Errorf("rate limits and quotas must not be negative")
+	}
+	if rl.Burst > 0 && rl.RequestsPerMinute == 0 {
+		return fmt.Errorf("RATE_LIMIT_BURST requires RATE_LIMIT_PER_MINUTE")
+	}
+	if c.AuditMaxSizeMB < 0 || c.AuditMaxFiles < 0 {
+		return fmt.Errorf("AUDIT_LOG_MAX_SIZE_MB and AUDIT_LOG_MAX_FILES must not be negative")
+	}
+
+	if c.TransportType != "stdio" && c.TransportType != "http" {
+		return fmt.Errorf("TRANSPORT_TYPE must be 'stdio' or 'http', got: %s", c.TransportType)
+	}
+
+	return nil
+}
+
+// resolveKeys compiles the roles, the named API keys and client
+// certificates that map to them, and the OAuth and TLS settings
+func (c *Config) resolveKeys() error {
+	roles := make(map[string]*auth.Role)
+	for i := range c.Roles {
+		r := &c.Roles[i]
+		var policy *security.Policy
+		if !emptyRules(r.PolicyAllow) || !emptyRules(r.PolicyDeny) {
+			var err error
+			if policy, err = security.NewObjectPolicy(r.PolicyAllow, r.PolicyDeny); err != nil {
+				return fmt.Errorf("role %s: invalid policy: %w", r.Name, err)
+			}
+		}
+		role, err := auth.NewRole(r.Name, r.Tools, r.Databases, policy, r.MaxRows)
+		if err != nil {
+			return err
+		}
+		r.Role = role
+		roles[r.Name] = role
+	}
+
+	c.HTTPKeys = nil
+	names := make(map[string]bool)
+	hashes := make(map[string]bool)
+	for _, k := range c.HTTPAPIKeys {
+		if k.Name == "" {
+			return fmt.Errorf("api key name is required")
+		}
+		if names[k.Name] {
+			return fmt.Errorf("api key %s is defined twice", k.Name)
+		}
+		names[k.Name] = true
+		hash, err := auth.ParseKeyHash(k.KeySHA256)
+		if err != nil {
+			return fmt.Errorf("api key %s: %w", k.Name, err)
+		}
+		if hashes[string(hash)] {
+			return fmt.Errorf("api key %s: the same key is used by another api key", k.Name)
+		}
+		hashes[string(hash)] = true
+		role, ok := roles[k.Role]
+		if !ok {
+			return fmt.Errorf("api key %s: role %q is not defined", k.Name, k.Role)
+		}
+		c.HTTPKeys = append(c.HTTPKeys, auth.APIKey{Name: k.Name, Hash: hash, Role: role})
+	}
+
+	c.HTTPTokenValidator = nil
+	if o := c.HTTPOAuth; o.Issuer != "" || o.Audience != "" || o.JWKS != "" || len(o.Scopes) > 0 {
+		validator, err := auth.NewTokenValidator(o)
+		if err != nil {
+			return fmt.Errorf("invalid oauth settings: %w", err)
+		}
+		c.HTTPTokenValidator = validator
+	}
+
+	if (c.HTTPTLSCert == "") != (c.HTTPTLSKey == "") {
+		return fmt.Errorf("HTTP_TLS_CERT and HTTP_TLS_KEY must be set together")
+	}
+	if c.HTTPTLSClientCA != "" && c.HTTPTLSCert == "" {
+		return fmt.Errorf("HTTP_TLS_CLIENT_CA requires HTTP_TLS_CERT and HTTP_TLS_KEY")
+	}
+	switch c.HTTPTLSClientAuth {
+	case "", "require", "optional":
+	default:
+		return fmt.Errorf("HTTP_TLS_CLIENT_AUTH must be 'require' or 'optional', got: %s", c.HTTPTLSClientAuth)
+	}
+	if c.HTTPTLSClientAuth != "" && c.HTTPTLSClientCA == "" {
+		return fmt.Errorf("HTTP_TLS_CLIENT_AUTH requires HTTP_TLS_CLIENT_CA")
+	}
+	if len(c.HTTPClientCertRoles) > 0 && c.HTTPTLSClientCA == "" {
+		return fmt.Errorf("client certificate roles require HTTP_TLS_CLIENT_CA")
+	}
+	var rules []auth.CertRule
+	for _, cc := range c.HTTPClientCertRoles {
+		role, ok := roles[cc.Role]
+		if !ok {
+			return fmt.Errorf("client certificate %s: role %q is not defined", cc.Subject, cc.Role)
+		}
+		rules = append(rules, auth.CertRule{Pattern: cc.Subject, Role: role})
+	}
+	certs, err := auth.NewClientCerts(rules...)
+	if err != nil {
+		return err
+	}
+	c.HTTPClientCerts = certs
+	return nil
+}
+
+// emptyRules reports whether no pattern is set
+func emptyRules(r security.PolicyRules) bool {
+	return len(r.Schemas)+len(r.Tables)+len(r.Columns)+len(r.Functions)+len(r.Clauses) == 0
+}
+
+// validatePassword requires exactly one password source, unless the DSN
+// carries a password or a client certificate authenticates the connection
+func (c *ConnectionConfig) validatePassword() error {
+	sources := 0
+	for _, value := range []string{c.Password, c.PasswordFile, c.PasswordCommand} {
+		if value != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return fmt.Errorf("connection %s: set only one of password, password_file and password_command", c.Name)
+	}
+	if sources == 1 || c.SSLCert != "" {
+		return nil
+	}
+	if c.DSN != "" {
+		if u, err := url.Parse(c.DSN); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("connection %s: password is required; set password, password_file or password_command (DB_PASSWORD, DB_PASSWORD_FILE or DB_PASSWORD_COMMAND)", c.Name)
+}
+
+// dsnType returns the database type named by a DSN's scheme
+func dsnType(dsn string) string {
+	switch {
+	case strings.HasPrefix(dsn, "postgres://"), strings.HasPrefix(dsn, "postgresql://"):
+		return "postgres"
+	case strings.HasPrefix(dsn, "mysql://"):
+		return "mysql"
+	default:
+		return ""
+	}
 }
 
 func getEnv(key, defaultValue string) string {
@@ -95,6 +612,33 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if floatVal, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatVal
+		}
+	}
+	return defaultValue
+}
+
+func getEnvSeconds(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if intVal, err := strconv.Atoi(value); err == nil {
+			return time.Duration(intVal) * time.Second
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolVal, err := strconv.ParseBool(value); err == nil {
+			return boolVal
+		}
+	}
+	return defaultValue
+}
+
 func getEnvSlice(key string, defaultValue []string) []string {
 	value := os.Getenv(key)
 	if value == "" {
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hieubanhh/dbhubMCP/internal/auth"
+)
+
+// Change is one setting that differs between two configurations
+type Change struct {
+	Field   string // file key, e.g. "connections.app.max_rows"
+	Old     string
+	New     string
+	Restart bool // the running server cannot apply the change
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.Old, c.New)
+}
+
+// Diff lists the settings that differ from old to new. Query limits, rate
+// limits, access policies, masking rules, roles, the HTTP API keys, OAuth
+// settings, client certificate roles and CORS origins can be applied while
+// running; everything else is reported with Restart set. Secrets are never
+// included in values.
+func Diff(old, new *Config) []Change {
+	var changes []Change
+	add := func(field string, o, n interface{}, restart bool) {
+		ov, nv := fmt.Sprint(o), fmt.Sprint(n)
+		if ov != nv {
+			changes = append(changes, Change{Field: field, Old: ov, New: nv, Restart: restart})
+		}
+	}
+	addSecret := func(field, o, n string, restart bool) {
+		if o == n {
+			return
+		}
+		change := Change{Field: field, Old: redact(o), New: redact(n), Restart: restart}
+		if o != "" && n != "" {
+			change.New = "(changed)"
+		}
+		changes = append(changes, change)
+	}
+
+	add("default_connection", old.DefaultConnection, new.DefaultConnection, true)
+	add("limits.rate", old.RateLimits, new.RateLimits, false)
+
+	for _, oc := range old.Connections {
+		if _, ok := new.Connection(oc.Name); !ok {
+			changes = append(changes, Change{Field: "connections." + oc.Name, Old: oc.Address(), New: "(removed)", Restart: true})
+		}
+	}
+	for _, nc := range new.Connections {
+		oc, ok := old.Connection(nc.Name)
+		if !ok {
+			changes = append(changes, Change{Field: "connections." + nc.Name, Old: "(none)", New: nc.Address(), Restart: true})
+			continue
+		}
+
+		prefix := "connections." + nc.Name + "."
+		add(prefix+"max_rows", oc.MaxRows, nc.MaxRows, false)
+		add(prefix+"query_timeout_sec", oc.QueryTimeout.Seconds(), nc.QueryTimeout.Seconds(), false)
+		add(prefix+"explain_analyze", oc.ExplainAnalyze, nc.ExplainAnalyze, false)
+		add(prefix+"max_cost", oc.MaxQueryCost, nc.MaxQueryCost, false)
+		add(prefix+"max_estimated_rows", oc.MaxEstimatedRows, nc.MaxEstimatedRows, false)
+		add(prefix+"policy", oc.Policy, nc.Policy, false)
+		add(prefix+"masking", oc.Masker, nc.Masker, false)
+		addSecret(prefix+"masking.hash_key", oc.MaskHashKey, nc.MaskHashKey, false)
+
+		add(prefix+"type", oc.Type, nc.Type, true)
+		add(prefix+"address", oc.Address(), nc.Address(), true)
+		addSecret(prefix+"dsn", oc.DSN, nc.DSN, true)
+		addSecret(prefix+"password", oc.Password, nc.Password, true)
+		add(prefix+"password_file", oc.PasswordFile, nc.PasswordFile, true)
+		add(prefix+"password_command", oc.PasswordCommand, nc.PasswordCommand, true)
+		add(prefix+"ssl_mode", oc.SSLMode, nc.SSLMode, true)
+		add(prefix+"ssl_ca", oc.SSLCA, nc.SSLCA, true)
+		add(prefix+"ssl_cert", oc.SSLCert, nc.SSLCert, true)
+		add(prefix+"ssl_key", oc.SSLKey, nc.SSLKey, true)
+		add(prefix+"ssl_server_name", oc.SSLServerName, nc.SSLServerName, true)
+		add(prefix+"max_conns", oc.MaxConns, nc.MaxConns, true)
+		add(prefix+"max_idle_conns", oc.MaxIdleConns, nc.MaxIdleConns, true)
+		add(prefix+"conn_timeout_sec", oc.ConnTimeout.Seconds(), nc.ConnTimeout.Seconds(), true)
+	}
+
+	add("transport.cors_origins", strings.Join(old.HTTPCORSOrigins, ","), strings.Join(new.HTTPCORSOrigins, ","), false)
+	addSecret("transport.api_key", old.HTTPAPIKey, new.HTTPAPIKey, false)
+	add("transport.api_keys", auth.NewKeys(old.HTTPKeys...), auth.NewKeys(new.HTTPKeys...), false)
+	addSecret("transport.api_keys.key_sha256", keyHashes(old.HTTPAPIKeys), keyHashes(new.HTTPAPIKeys), false)
+	add("security.roles", roles(old.Roles), roles(new.Roles), false)
+	add("transport.oauth", old.HTTPTokenValidator, new.HTTPTokenValidator, false)
+	add("transport.tls.client_certs", old.HTTPClientCerts, new.HTTPClientCerts, false)
+	add("transport.type", old.TransportType, new.TransportType, true)
+	add("transport.http_addr", old.HTTPAddr, new.HTTPAddr, true)
+	add("transport.tls.cert", old.HTTPTLSCert, new.HTTPTLSCert, true)
+	add("transport.tls.key", old.HTTPTLSKey, new.HTTPTLSKey, true)
+	add("transport.tls.client_ca", old.HTTPTLSClientCA, new.HTTPTLSClientCA, true)
+	add("transport.tls.client_auth", old.HTTPTLSClientAuth, new.HTTPTLSClientAuth, true)
+
+	add("schema.cache_ttl_sec", old.SchemaCacheTTL.Seconds(), new.SchemaCacheTTL.Seconds(), true)
+	add("schema.change_poll_sec", old.SchemaPollInterval.Seconds(), new.SchemaPollInterval.Seconds(), true)
+	add("security.credential_refresh_sec", old.CredentialRefresh.Seconds(), new.CredentialRefresh.Seconds(), true)
+	add("audit.path", old.AuditPath, new.AuditPath, true)
+	add("audit.max_size_mb", old.AuditMaxSizeMB, new.AuditMaxSizeMB, true)
+	add("audit.max_files", old.AuditMaxFiles, new.AuditMaxFiles, true)
+	add("audit.hash_chain", old.AuditHashChain, new.AuditHashChain, true)
+	add("log_level", old.LogLevel, new.LogLevel, true)
+
+	return changes
+}
+
+// keyHashes joins the configured key hashes to detect rotated keys
+func keyHashes(keys []APIKeyConfig) string {
+	hashes := make([]string, len(keys))
+	for i, k := range keys {
+		hashes[i] = k.Name + "=" + k.KeySHA256
+	}
+	return strings.Join(hashes, ",")
+}
+
+// roles describes the compiled roles
+func roles(configs []RoleConfig) string {
+	described := make([]string, len(configs))
+	for i, r := range configs {
+		described[i] = r.Name + ": " + r.Role.String()
+	}
+	return "[" + strings.Join(described, "; ") + "]"
+}
+
+// redact hides a secret value in a Change
+func redact(secret string) string {
+	if secret == "" {
+		return "(unset)"
+	}
+	return "(set)"
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/auth"
+	"github.com/hieubanhh/dbhubMCP/internal/ratelimit"
+	"github.com/hieubanhh/dbhubMCP/internal/security"
+)
+
+func TestDiff(t *testing.T) {
+	base := func() *Config {
+		return &Config{
+			DefaultConnection: "app",
+			Connections: []ConnectionConfig{{
+				Name: "app", Type: "postgres", Host: "db", Port: 5432, Database: "app", User: "ro",
+				Password: "old-secret", MaxRows: 100, QueryTimeout: 30 * time.Second,
+			}},
+			TransportType:   "http",
+			HTTPAddr:        ":8080",
+			HTTPCORSOrigins: []string{"*"},
+			HTTPAPIKey:      "key-1",
+			LogLevel:        "info",
+		}
+	}
+
+	if changes := Diff(base(), base()); len(changes) != 0 {
+		t.Fatalf("Expected no changes, got %v", changes)
+	}
+
+	updated := base()
+	updated.Connections[0].MaxRows = 500
+	updated.Connections[0].MaxQueryCost = 10000
+	updated.Connections[0].Password = "new-secret"
+	updated.Connections[0].Policy, _ = security.NewPolicy(security.PolicyRules{}, security.PolicyRules{Tables: []string{"payments"}})
+	updated.HTTPAPIKey = "key-2"
+	analyst := &auth.Role{Name: "analyst", MaxRows: 10}
+	updated.Roles = []RoleConfig{{Name: "analyst", MaxRows: 10, Role: analyst}}
+	updated.HTTPAPIKeys = []APIKeyConfig{{Name: "ci", KeySHA256: strings.Repeat("ab", 32), Role: "analyst"}}
+	updated.HTTPKeys = []auth.APIKey{{Name: "ci", Hash: auth.HashKey("ci-key"), Role: analyst}}
+	updated.HTTPCORSOrigins = []string{"https://a.example", "https://b.example"}
+	updated.HTTPAddr = ":9090"
+	updated.HTTPTLSCert = "/etc/dbhub/server.crt"
+	updated.RateLimits = ratelimit.Limits{RequestsPerMinute: 60}
+	updated.AuditPath = "/var/log/dbhub/audit.jsonl"
+	updated.HTTPClientCerts, _ = auth.NewClientCerts(auth.CertRule{Pattern: "reporting-*", Role: analyst})
+	updated.Connections = append(updated.Connections, ConnectionConfig{Name: "reporting", User: "u", Host: "h", Port: 3306, Database: "r"})
+
+	changes := make(map[string]Change)
+	for _, c := range Diff(base(), updated) {
+		changes[c.Field] = c
+		if strings.Contains(c.Old+c.New, "secret") || strings.Contains(c.Old+c.New, "key-") {
+			t.Errorf("Secret leaked in %v", c)
+		}
+	}
+
+	want := map[string]bool{
+		"connections.app.max_rows":      false,
+		"connections.app.max_cost":      false,
+		"connections.app.policy":        false,
+		"transport.api_key":             false,
+		"transport.api_keys":            false,
+		"transport.api_keys.key_sha256": false,
+		"security.roles":                false,
+		"transport.cors_origins":        false,
+		"transport.tls.client_certs":    false,
+		"limits.rate":                   false,
+		"connections.app.password":      true,
+		"transport.http_addr":           true,
+		"transport.tls.cert":            true,
+		"audit.path":                    true,
+		"connections.reporting":         true,
+	}
+	if len(changes) != len(want) {
+		t.Errorf("Expected %d changes, got %v", len(want), changes)
+	}
+	for field, restart := range want {
+		c, ok := changes[field]
+		if !ok {
+			t.Errorf("Missing change for %s", field)
+			continue
+		}
+		if c.Restart != restart {
+			t.Errorf("%s: expected restart=%v, got %v", field, restart, c.Restart)
+		}
+	}
+	if c := changes["connections.app.max_rows"]; c.Old != "100" || c.New != "500" {
+		t.Errorf("Unexpected max_rows change: %v", c)
+	}
+	if c := changes["transport.api_keys"]; c.Old != "(none)" || c.New != "ci=analyst" {
+		t.Errorf("Unexpected api_keys change: %v", c)
+	}
+	if c := changes["transport.api_keys.key_sha256"]; strings.Contains(c.New, "abab") {
+		t.Errorf("Key hash leaked in %v", c)
+	}
+}
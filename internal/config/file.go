@@ -0,0 +1,373 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+
+	"github.com/hieubanhh/dbhubMCP/internal/auth"
+	"github.com/hieubanhh/dbhubMCP/internal/ratelimit"
+	"github.com/hieubanhh/dbhubMCP/internal/security"
+)
+
+// fileConfig is the layout of a YAML or TOML configuration file
+type fileConfig struct {
+	DefaultConnection string                    `yaml:"default_connection" toml:"default_connection"`
+	Connections       map[string]fileConnection `yaml:"connections" toml:"connections"`
+	Limits            fileLimits                `yaml:"limits" toml:"limits"`
+	Schema            fileSchema                `yaml:"schema" toml:"schema"`
+	Transport         fileTransport             `yaml:"transport" toml:"transport"`
+	Security          fileSecurity              `yaml:"security" toml:"security"`
+	Audit             fileAudit                 `yaml:"audit" toml:"audit"`
+	LogLevel          string                    `yaml:"log_level" toml:"log_level"`
+}
+
+type fileConnection struct {
+	Type            string `yaml:"type" toml:"type"`
+	DSN             string `yaml:"dsn" toml:"dsn"`
+	Host            string `yaml:"host" toml:"host"`
+	Port            int    `yaml:"port" toml:"port"`
+	Database        string `yaml:"database" toml:"database"`
+	User            string `yaml:"user" toml:"user"`
+	Password        string `yaml:"password" toml:"password"`
+	PasswordFile    string `yaml:"password_file" toml:"password_file"`
+	PasswordCommand string `yaml:"password_command" toml:"password_command"`
+	Socket          string `yaml:"socket" toml:"socket"`
+	SSLMode         string `yaml:"ssl_mode" toml:"ssl_mode"`
+	SSLCA           string `yaml:"ssl_ca" toml:"ssl_ca"`
+	SSLCert         string `yaml:"ssl_cert" toml:"ssl_cert"`
+	SSLKey          string `yaml:"ssl_key" toml:"ssl_key"`
+	SSLServerName   string `yaml:"ssl_server_name" toml:"ssl_server_name"`
+	MaxConns        int    `yaml:"max_conns" toml:"max_conns"`
+	MaxIdleConns    int    `yaml:"max_idle_conns" toml:"max_idle_conns"`
+	ConnTimeoutSec  int    `yaml:"conn_timeout_sec" toml:"conn_timeout_sec"`
+	QueryTimeoutSec int    `yaml:"query_timeout_sec" toml:"query_timeout_sec"`
+	MaxRows         int    `yaml:"max_rows" toml:"max_rows"`
+	ExplainAnalyze  *bool  `yaml:"explain_analyze" toml:"explain_analyze"`
+
+	// Cost guard thresholds, see fileLimits
+	MaxCost          float64 `yaml:"max_cost" toml:"max_cost"`
+	MaxEstimatedRows int     `yaml:"max_estimated_rows" toml:"max_estimated_rows"`
+
+	Policy  filePolicy  `yaml:"policy" toml:"policy"`
+	Masking fileMasking `yaml:"masking" toml:"masking"`
+}
+
+type fileLimits struct {
+	QueryTimeoutSec    int `yaml:"query_timeout_sec" toml:"query_timeout_sec"`
+	MaxRows            int `yaml:"max_rows" toml:"max_rows"`
+	RequestsPerMinute  int `yaml:"requests_per_minute" toml:"requests_per_minute"`
+	Burst              int `yaml:"burst" toml:"burst"`
+	ExpensivePerMinute int `yaml:"expensive_per_minute" toml:"expensive_per_minute"`
+	DailyRows          int `yaml:"daily_rows" toml:"daily_rows"`
+	DailyQueryTimeSec  int `yaml:"daily_query_time_sec" toml:"daily_query_time_sec"`
+
+	// Queries whose planner estimates exceed these are rejected
+	MaxCost          float64 `yaml:"max_cost" toml:"max_cost"`
+	MaxEstimatedRows int     `yaml:"max_estimated_rows" toml:"max_estimated_rows"`
+}
+
+type fileAudit struct {
+	Path      string `yaml:"path" toml:"path"`
+	MaxSizeMB *int   `yaml:"max_size_mb" toml:"max_size_mb"`
+	MaxFiles  int    `yaml:"max_files" toml:"max_files"`
+	HashChain bool   `yaml:"hash_chain" toml:"hash_chain"`
+}
+
+type fileSchema struct {
+	CacheTTLSec   *int `yaml:"cache_ttl_sec" toml:"cache_ttl_sec"`
+	ChangePollSec int  `yaml:"change_poll_sec" toml:"change_poll_sec"`
+}
+
+type fileTransport struct {
+	Type        string       `yaml:"type" toml:"type"`
+	HTTPAddr    string       `yaml:"http_addr" toml:"http_addr"`
+	CORSOrigins []string     `yaml:"cors_origins" toml:"cors_origins"`
+	APIKey      string       `yaml:"api_key" toml:"api_key"`
+	APIKeys     []fileAPIKey `yaml:"api_keys" toml:"api_keys"`
+	OAuth       fileOAuth    `yaml:"oauth" toml:"oauth"`
+	TLS         fileTLS      `yaml:"tls" toml:"tls"`
+}
+
+type fileTLS struct {
+	Cert        string           `yaml:"cert" toml:"cert"`
+	Key         string           `yaml:"key" toml:"key"`
+	ClientCA    string           `yaml:"client_ca" toml:"client_ca"`
+	ClientAuth  string           `yaml:"client_auth" toml:"client_auth"`
+	ClientCerts []fileClientCert `yaml:"client_certs" toml:"client_certs"`
+}
+
+type fileClientCert struct {
+	Subject string `yaml:"subject" toml:"subject"`
+	Role    string `yaml:"role" toml:"role"`
+}
+
+type fileOAuth struct {
+	Issuer               string              `yaml:"issuer" toml:"issuer"`
+	Audience             string              `yaml:"audience" toml:"audience"`
+	JWKSFile             string              `yaml:"jwks_file" toml:"jwks_file"`
+	JWKSURL              string              `yaml:"jwks_url" toml:"jwks_url"`
+	Resource             string              `yaml:"resource" toml:"resource"`
+	AuthorizationServers []string            `yaml:"authorization_servers" toml:"authorization_servers"`
+	Scopes               map[string][]string `yaml:"scopes" toml:"scopes"`
+	LeewaySec            int                 `yaml:"leeway_sec" toml:"leeway_sec"`
+}
+
+type fileAPIKey struct {
+	Name      string `yaml:"name" toml:"name"`
+	KeySHA256 string `yaml:"key_sha256" toml:"key_sha256"`
+	Role      string `yaml:"role" toml:"role"`
+}
+
+type fileSecurity struct {
+	ExplainAnalyze       bool                `yaml:"explain_analyze" toml:"explain_analyze"`
+	CredentialRefreshSec int                 `yaml:"credential_refresh_sec" toml:"credential_refresh_sec"`
+	Policy               filePolicy          `yaml:"policy" toml:"policy"`
+	Masking              fileMasking         `yaml:"masking" toml:"masking"`
+	Roles                map[string]fileRole `yaml:"roles" toml:"roles"`
+}
+
+type fileRole struct {
+	Tools     []string   `yaml:"tools" toml:"tools"`
+	Databases []string   `yaml:"databases" toml:"databases"`
+	MaxRows   int        `yaml:"max_rows" toml:"max_rows"`
+	Policy    filePolicy `yaml:"policy" toml:"policy"`
+}
+
+type filePolicy struct {
+	Allow filePatterns `yaml:"allow" toml:"allow"`
+	Deny  filePatterns `yaml:"deny" toml:"deny"`
+}
+
+type filePatterns struct {
+	Schemas   []string `yaml:"schemas" toml:"schemas"`
+	Tables    []string `yaml:"tables" toml:"tables"`
+	Columns   []string `yaml:"columns" toml:"columns"`
+	Functions []string `yaml:"functions" toml:"functions"`
+	Clauses   []string `yaml:"clauses" toml:"clauses"`
+}
+
+func (p filePatterns) rules() security.PolicyRules {
+	return security.PolicyRules{Schemas: p.Schemas, Tables: p.Tables, Columns: p.Columns, Functions: p.Functions, Clauses: p.Clauses}
+}
+
+type fileMasking struct {
+	HashKey string         `yaml:"hash_key" toml:"hash_key"`
+	Rules   []fileMaskRule `yaml:"rules" toml:"rules"`
+}
+
+type fileMaskRule struct {
+	Columns  []string `yaml:"columns" toml:"columns"`
+	Detect   []string `yaml:"detect" toml:"detect"`
+	Strategy string   `yaml:"strategy" toml:"strategy"`
+}
+
+func (m fileMasking) rules() []security.MaskRule {
+	rules := make([]security.MaskRule, len(m.Rules))
+	for i, r := range m.Rules {
+		rules[i] = security.MaskRule{Columns: r.Columns, Detect: r.Detect, Strategy: security.MaskStrategy(r.Strategy)}
+	}
+	return rules
+}
+
+// envReference matches ${VAR} and ${VAR:-default}
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// interpolate replaces environment references in the raw file. Referencing
+// an unset variable without a default is an error, so a missing secret is
+// never silently replaced by an empty string.
+func interpolate(data []byte) ([]byte, error) {
+	var missing []string
+	out := envReference.ReplaceAllFunc(data, func(ref []byte) []byte {
+		m := envReference.FindSubmatch(ref)
+		if value, ok := os.LookupEnv(string(m[1])); ok {
+			return []byte(value)
+		}
+		if bytes.Contains(ref, []byte(":-")) {
+			return m[2]
+		}
+		missing = append(missing, string(m[1]))
+		return ref
+	})
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("undefined environment variables: %s", strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// loadFile reads a YAML or TOML configuration file, chosen by extension, into cfg
+func loadFile(path string, cfg *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	if data, err = interpolate(data); err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	var fc fileConfig
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&fc)
+	case ".toml":
+		var md toml.MetaData
+		md, err = toml.Decode(string(data), &fc)
+		if err == nil && len(md.Undecoded()) > 0 {
+			err = fmt.Errorf("unknown keys: %v", md.Undecoded())
+		}
+	default:
+		return fmt.Errorf("unsupported config file extension %q, use .yaml, .yml or .toml", filepath.Ext(path))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	if len(fc.Connections) == 0 {
+		return fmt.Errorf("config file %s defines no connections", path)
+	}
+	if fc.DefaultConnection == "" && len(fc.Connections) > 1 {
+		return fmt.Errorf("config file %s: default_connection is required when several connections are defined", path)
+	}
+
+	names := make([]string, 0, len(fc.Connections))
+	for name := range fc.Connections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fconn := fc.Connections[name]
+		cfg.Connections = append(cfg.Connections, ConnectionConfig{
+			Name:            name,
+			Type:            fconn.Type,
+			DSN:             fconn.DSN,
+			Host:            fconn.Host,
+			Port:            fconn.Port,
+			Database:        fconn.Database,
+			User:            fconn.User,
+			Password:        fconn.Password,
+			PasswordFile:    fconn.PasswordFile,
+			PasswordCommand: fconn.PasswordCommand,
+			Socket:          fconn.Socket,
+			SSLMode:         fconn.SSLMode,
+			SSLCA:           fconn.SSLCA,
+			SSLCert:         fconn.SSLCert,
+			SSLKey:          fconn.SSLKey,
+			SSLServerName:   fconn.SSLServerName,
+			MaxConns:        fconn.MaxConns,
+			MaxIdleConns:    fconn.MaxIdleConns,
+			ConnTimeout:     time.Duration(fconn.ConnTimeoutSec) * time.Second,
+			QueryTimeout:    time.Duration(fconn.QueryTimeoutSec) * time.Second,
+			MaxRows:         fconn.MaxRows,
+			explainAnalyze:  fconn.ExplainAnalyze,
+			PolicyAllow:     fconn.Policy.Allow.rules(),
+			PolicyDeny:      fconn.Policy.Deny.rules(),
+			MaskRules:       fconn.Masking.rules(),
+			MaskHashKey:     fconn.Masking.HashKey,
+
+			MaxQueryCost:     fconn.MaxCost,
+			MaxEstimatedRows: fconn.MaxEstimatedRows,
+		})
+	}
+	cfg.DefaultConnection = fc.DefaultConnection
+	if cfg.DefaultConnection == "" {
+		cfg.DefaultConnection = names[0]
+	}
+
+	if fc.Limits.QueryTimeoutSec > 0 {
+		cfg.QueryTimeout = time.Duration(fc.Limits.QueryTimeoutSec) * time.Second
+	}
+	if fc.Limits.MaxRows > 0 {
+		cfg.MaxRows = fc.Limits.MaxRows
+	}
+	cfg.MaxQueryCost = fc.Limits.MaxCost
+	cfg.MaxEstimatedRows = fc.Limits.MaxEstimatedRows
+	cfg.RateLimits = ratelimit.Limits{
+		RequestsPerMinute:  fc.Limits.RequestsPerMinute,
+		Burst:              fc.Limits.Burst,
+		ExpensivePerMinute: fc.Limits.ExpensivePerMinute,
+		DailyRows:          fc.Limits.DailyRows,
+		DailyQueryTime:     time.Duration(fc.Limits.DailyQueryTimeSec) * time.Second,
+	}
+	if fc.Schema.CacheTTLSec != nil {
+		cfg.SchemaCacheTTL = time.Duration(*fc.Schema.CacheTTLSec) * time.Second
+	}
+	cfg.SchemaPollInterval = time.Duration(fc.Schema.ChangePollSec) * time.Second
+	cfg.ExplainAnalyze = fc.Security.ExplainAnalyze
+	cfg.CredentialRefresh = time.Duration(fc.Security.CredentialRefreshSec) * time.Second
+	cfg.AuditPath = fc.Audit.Path
+	if fc.Audit.MaxSizeMB != nil {
+		cfg.AuditMaxSizeMB = *fc.Audit.MaxSizeMB
+	}
+	cfg.AuditMaxFiles = fc.Audit.MaxFiles
+	cfg.AuditHashChain = fc.Audit.HashChain
+	cfg.PolicyAllow = fc.Security.Policy.Allow.rules()
+	cfg.PolicyDeny = fc.Security.Policy.Deny.rules()
+	cfg.MaskRules = fc.Security.Masking.rules()
+	cfg.MaskHashKey = fc.Security.Masking.HashKey
+	roleNames := make([]string, 0, len(fc.Security.Roles))
+	for name := range fc.Security.Roles {
+		roleNames = append(roleNames, name)
+	}
+	sort.Strings(roleNames)
+	for _, name := range roleNames {
+		r := fc.Security.Roles[name]
+		cfg.Roles = append(cfg.Roles, RoleConfig{
+			Name:        name,
+			Tools:       r.Tools,
+			Databases:   r.Databases,
+			MaxRows:     r.MaxRows,
+			PolicyAllow: r.Policy.Allow.rules(),
+			PolicyDeny:  r.Policy.Deny.rules(),
+		})
+	}
+	if fc.LogLevel != "" {
+		cfg.LogLevel = fc.LogLevel
+	}
+
+	if fc.Transport.Type != "" {
+		cfg.TransportType = fc.Transport.Type
+	}
+	if fc.Transport.HTTPAddr != "" {
+		cfg.HTTPAddr = fc.Transport.HTTPAddr
+	}
+	if fc.Transport.CORSOrigins != nil {
+		cfg.HTTPCORSOrigins = fc.Transport.CORSOrigins
+	}
+	cfg.HTTPAPIKey = fc.Transport.APIKey
+	for _, k := range fc.Transport.APIKeys {
+		cfg.HTTPAPIKeys = append(cfg.HTTPAPIKeys, APIKeyConfig{Name: k.Name, KeySHA256: k.KeySHA256, Role: k.Role})
+	}
+	oauth := fc.Transport.OAuth
+	if oauth.JWKSFile != "" && oauth.JWKSURL != "" {
+		return fmt.Errorf("config file %s: set only one of transport.oauth.jwks_file and jwks_url", path)
+	}
+	cfg.HTTPOAuth = auth.OAuthConfig{
+		Issuer:               oauth.Issuer,
+		Audience:             oauth.Audience,
+		JWKS:                 oauth.JWKSFile + oauth.JWKSURL,
+		Resource:             oauth.Resource,
+		AuthorizationServers: oauth.AuthorizationServers,
+		Scopes:               oauth.Scopes,
+		Leeway:               time.Duration(oauth.LeewaySec) * time.Second,
+	}
+	cfg.HTTPTLSCert = fc.Transport.TLS.Cert
+	cfg.HTTPTLSKey = fc.Transport.TLS.Key
+	cfg.HTTPTLSClientCA = fc.Transport.TLS.ClientCA
+	cfg.HTTPTLSClientAuth = fc.Transport.TLS.ClientAuth
+	for _, cc := range fc.Transport.TLS.ClientCerts {
+		cfg.HTTPClientCertRoles = append(cfg.HTTPClientCertRoles, ClientCertConfig{Subject: cc.Subject, Role: cc.Role})
+	}
+
+	return nil
+}
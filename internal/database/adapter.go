@@ -3,6 +3,10 @@ package database
 import (
 	"context"
 	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/security"
 )
 
 // TableInfo represents metadata about a database table
@@ -12,21 +16,77 @@ type TableInfo struct {
 	TableType   string `json:"table_type,omitempty"`
 }
 
-// ColumnInfo represents metadata about a table column
+// ColumnInfo represents metadata about a table column. TableSchema and
+// TableName are only set by ListColumns.
 type ColumnInfo struct {
+	TableSchema   string `json:"table_schema,omitempty"`
+	TableName     string `json:"table_name,omitempty"`
 	ColumnName    string `json:"column_name"`
 	DataType      string `json:"data_type"`
 	IsNullable    string `json:"is_nullable"`
 	ColumnDefault string `json:"column_default,omitempty"`
 	ColumnKey     string `json:"column_key,omitempty"`
 	Extra         string `json:"extra,omitempty"`
+	ColumnType    string `json:"column_type,omitempty"`
+	Comment       string `json:"comment,omitempty"`
+}
+
+// ForeignKeyInfo represents a foreign key constraint on a table. TableSchema and
+// TableName identify the referencing table and are only set by ListForeignKeys.
+type ForeignKeyInfo struct {
+	TableSchema       string   `json:"table_schema,omitempty"`
+	TableName         string   `json:"table_name,omitempty"`
+	ConstraintName    string   `json:"constraint_name"`
+	Columns           []string `json:"columns"`
+	ReferencedSchema  string   `json:"referenced_schema,omitempty"`
+	ReferencedTable   string   `json:"referenced_table"`
+	ReferencedColumns []string `json:"referenced_columns"`
+	OnUpdate          string   `json:"on_update,omitempty"`
+	OnDelete          string   `json:"on_delete,omitempty"`
+}
+
+// IndexInfo represents an index defined on a table
+type IndexInfo struct {
+	IndexName string   `json:"index_name"`
+	Columns   []string `json:"columns"`
+	IsUnique  bool     `json:"is_unique"`
+	IsPrimary bool     `json:"is_primary,omitempty"`
+	IndexType string   `json:"index_type,omitempty"`
+}
+
+// TableDetail represents the full definition of a table: columns, keys and indexes
+type TableDetail struct {
+	TableInfo
+	Comment        string           `json:"comment,omitempty"`
+	Columns        []ColumnInfo     `json:"columns"`
+	PrimaryKey     []string         `json:"primary_key,omitempty"`
+	ForeignKeys    []ForeignKeyInfo `json:"foreign_keys,omitempty"`
+	Indexes        []IndexInfo      `json:"indexes,omitempty"`
+	ViewDefinition string           `json:"view_definition,omitempty"`
 }
 
 // QueryResult represents the result of a query execution
 type QueryResult struct {
-	Columns []string                 `json:"columns"`
-	Rows    []map[string]interface{} `json:"rows"`
-	RowCount int                     `json:"row_count"`
+	Columns       []string                 `json:"columns"`
+	Rows          []map[string]interface{} `json:"rows"`
+	RowCount      int                      `json:"row_count"`
+	MaskedColumns []security.MaskedColumn  `json:"masked_columns,omitempty"`
+	Truncated     bool                     `json:"truncated,omitempty"` // more than maxRows rows were available
+}
+
+// ExplainOptions controls how a query plan is produced
+type ExplainOptions struct {
+	Analyze bool          // execute the query to report actual rows and timings
+	Timeout time.Duration // statement timeout, enforced by the database
+}
+
+// HypotheticalIndexResult is the planner's estimate for a query when a
+// hypothetical index exists
+type HypotheticalIndexResult struct {
+	Statement  string  `json:"statement"`
+	CostBefore float64 `json:"cost_before"`
+	CostAfter  float64 `json:"cost_after"`
+	Used       bool    `json:"used"`
 }
 
 // Adapter defines the interface for database operations
@@ -40,23 +100,107 @@ type Adapter interface {
 	// Ping checks if the database connection is alive
 	Ping(ctx context.Context) error
 
+	// RefreshCredentials asks the password provider for the current secret
+	// and, if it changed, replaces the connection pool. It reports whether
+	// the pool was replaced.
+	RefreshCredentials(ctx context.Context) (bool, error)
+
 	// ListTables returns a list of all tables in the database
 	ListTables(ctx context.Context) ([]TableInfo, error)
 
 	// DescribeTable returns column information for a specific table
 	DescribeTable(ctx context.Context, tableName string) ([]ColumnInfo, error)
 
+	// DescribeTableDetailed returns columns, primary key, foreign keys and indexes
+	// for a table. The name may be qualified as schema.table.
+	DescribeTableDetailed(ctx context.Context, tableName string) (*TableDetail, error)
+
+	// ListForeignKeys returns every foreign key in the database
+	ListForeignKeys(ctx context.Context) ([]ForeignKeyInfo, error)
+
+	// ListColumns returns the columns of every table in the database
+	ListColumns(ctx context.Context) ([]ColumnInfo, error)
+
+	// SchemaFingerprint returns a cheap value that changes whenever tables or
+	// columns are created, altered or dropped
+	SchemaFingerprint(ctx context.Context) (string, error)
+
+	// ProfileTable computes per-column value statistics, sampling large
+	// tables, inside a read-only transaction
+	ProfileTable(ctx context.Context, tableName string, opts ProfileOptions) (*TableProfile, error)
+
+	// ListTableStats returns catalog row estimates and sizes for every table
+	ListTableStats(ctx context.Context) ([]TableStats, error)
+
+	// TableStats returns row estimates, sizes and maintenance times for a
+	// table, optionally with an exact row count
+	TableStats(ctx context.Context, tableName string, opts TableStatsOptions) (*TableStats, error)
+
+	// SampleRows returns a deterministic random sample of rows from a table,
+	// inside a read-only transaction
+	SampleRows(ctx context.Context, tableName string, opts SampleOptions) (*SampleResult, error)
+
 	// ExecuteQuery executes a read-only query and returns results
 	ExecuteQuery(ctx context.Context, query string, maxRows int) (*QueryResult, error)
 
 	// ExplainQuery returns the query execution plan
-	ExplainQuery(ctx context.Context, query string) (*QueryResult, error)
+	ExplainQuery(ctx context.Context, query string, opts ExplainOptions) (*QueryResult, error)
+
+	// ExplainQueryJSON returns the query execution plan as the database's
+	// native JSON document
+	ExplainQueryJSON(ctx context.Context, query string, opts ExplainOptions) ([]byte, error)
+
+	// EvaluateHypotheticalIndexes estimates the cost of a query with each of
+	// the given CREATE INDEX statements, without building the indexes. It
+	// requires the hypopg extension and is only supported on PostgreSQL.
+	EvaluateHypotheticalIndexes(ctx context.Context, query string, statements []string, timeout time.Duration) ([]HypotheticalIndexResult, error)
 
 	// GetDBType returns the database type (mysql, postgres, etc.)
 	GetDBType() string
 }
 
-// rowsToResult converts sql.Rows to QueryResult
+// SplitTableName splits an optionally schema-qualified table name into its
+// schema and table parts. Quotes and backticks around either part are removed.
+func SplitTableName(name string) (schema, table string) {
+	name = strings.TrimSpace(name)
+	if idx := strings.LastIndex(name, "."); idx >= 0 {
+		schema, table = name[:idx], name[idx+1:]
+	} else {
+		table = name
+	}
+	return unquoteIdentifier(schema), unquoteIdentifier(table)
+}
+
+// QuoteIdentifier quotes a single identifier for the given database type,
+// escaping any embedded quote characters.
+func QuoteIdentifier(dbType, ident string) string {
+	if dbType == "mysql" {
+		return "`" + strings.ReplaceAll(ident, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
+
+// QuoteQualifiedName quotes a schema and table pair, omitting the schema when empty
+func QuoteQualifiedName(dbType, schema, table string) string {
+	if schema == "" {
+		return QuoteIdentifier(dbType, table)
+	}
+	return QuoteIdentifier(dbType, schema) + "." + QuoteIdentifier(dbType, table)
+}
+
+func unquoteIdentifier(ident string) string {
+	ident = strings.TrimSpace(ident)
+	if len(ident) >= 2 {
+		first, last := ident[0], ident[len(ident)-1]
+		if (first == '"' && last == '"') || (first == '`' && last == '`') {
+			return ident[1 : len(ident)-1]
+		}
+	}
+	return ident
+}
+
+// rowsToResult converts sql.Rows to QueryResult, reading at most maxRows
+// rows. Truncated is set when another row follows.
 func rowsToResult(rows *sql.Rows, maxRows int) (*QueryResult, error) {
 	columns, err := rows.Columns()
 	if err != nil {
@@ -79,6 +223,7 @@ func rowsToResult(rows *sql.Rows, maxRows int) (*QueryResult, error) {
 	rowCount := 0
 	for rows.Next() {
 		if rowCount >= maxRows {
+			result.Truncated = true
 			break
 		}
 
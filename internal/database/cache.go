@@ -0,0 +1,124 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Invalidator is implemented by adapters that cache schema metadata
+type Invalidator interface {
+	// Invalidate drops all cached metadata
+	Invalidate()
+}
+
+// CachedAdapter wraps an Adapter and caches schema metadata (table lists,
+// column and key definitions) for a fixed TTL. Queries, plans and pings are
+// passed straight through. Cached slices are shared between callers and must
+// not be modified.
+type CachedAdapter struct {
+	Adapter
+
+	ttl     time.Duration
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	now     func() time.Time
+}
+
+type cacheEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// NewCachedAdapter wraps inner with a metadata cache
+func NewCachedAdapter(inner Adapter, ttl time.Duration) *CachedAdapter {
+	return &CachedAdapter{
+		Adapter: inner,
+		ttl:     ttl,
+		entries: make(map[string]cacheEntry),
+		now:     time.Now,
+	}
+}
+
+// Invalidate drops all cached metadata
+func (c *CachedAdapter) Invalidate() {
+	c.mu.Lock()
+	c.entries = make(map[string]cacheEntry)
+	c.mu.Unlock()
+}
+
+// cached returns the value stored under key, loading it on a miss. Errors are
+// not cached. Concurrent misses for the same key may both load.
+func (c *CachedAdapter) cached(key string, load func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok && c.now().Before(entry.expires) {
+		return entry.value, nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = cacheEntry{value: value, expires: c.now().Add(c.ttl)}
+	c.mu.Unlock()
+	return value, nil
+}
+
+// ListTables returns the cached table list
+func (c *CachedAdapter) ListTables(ctx context.Context) ([]TableInfo, error) {
+	v, err := c.cached("tables", func() (interface{}, error) {
+		return c.Adapter.ListTables(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]TableInfo), nil
+}
+
+// DescribeTable returns the cached column list of a table
+func (c *CachedAdapter) DescribeTable(ctx context.Context, tableName string) ([]ColumnInfo, error) {
+	v, err := c.cached("describe:"+tableName, func() (interface{}, error) {
+		return c.Adapter.DescribeTable(ctx, tableName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]ColumnInfo), nil
+}
+
+// DescribeTableDetailed returns the cached full definition of a table
+func (c *CachedAdapter) DescribeTableDetailed(ctx context.Context, tableName string) (*TableDetail, error) {
+	v, err := c.cached("detail:"+tableName, func() (interface{}, error) {
+		return c.Adapter.DescribeTableDetailed(ctx, tableName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*TableDetail), nil
+}
+
+// ListForeignKeys returns the cached foreign key list
+func (c *CachedAdapter) ListForeignKeys(ctx context.Context) ([]ForeignKeyInfo, error) {
+	v, err := c.cached("foreign_keys", func() (interface{}, error) {
+		return c.Adapter.ListForeignKeys(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]ForeignKeyInfo), nil
+}
+
+// ListColumns returns the cached column list of every table
+func (c *CachedAdapter) ListColumns(ctx context.Context) ([]ColumnInfo, error) {
+	v, err := c.cached("columns", func() (interface{}, error) {
+		return c.Adapter.ListColumns(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]ColumnInfo), nil
+}
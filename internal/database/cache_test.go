@@ -0,0 +1,99 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// countingAdapter records how often metadata is loaded
+type countingAdapter struct {
+	Adapter
+	listCalls     int
+	describeCalls int
+	failDescribe  bool
+}
+
+func (a *countingAdapter) ListTables(ctx context.Context) ([]TableInfo, error) {
+	a.listCalls++
+	return []TableInfo{{TableName: "users"}}, nil
+}
+
+func (a *countingAdapter) DescribeTable(ctx context.Context, tableName string) ([]ColumnInfo, error) {
+	a.describeCalls++
+	if a.failDescribe {
+		return nil, errors.New("boom")
+	}
+	return []ColumnInfo{{ColumnName: "id"}}, nil
+}
+
+func (a *countingAdapter) GetDBType() string {
+	return "postgres"
+}
+
+func TestCachedAdapter_CachesUntilTTL(t *testing.T) {
+	inner := &countingAdapter{}
+	cache := NewCachedAdapter(inner, time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.ListTables(ctx); err != nil {
+			t.Fatalf("ListTables failed: %v", err)
+		}
+	}
+	if inner.listCalls != 1 {
+		t.Errorf("Expected 1 underlying call, got %d", inner.listCalls)
+	}
+
+	now = now.Add(2 * time.Minute)
+	cache.ListTables(ctx)
+	if inner.listCalls != 2 {
+		t.Errorf("Expected reload after TTL, got %d calls", inner.listCalls)
+	}
+}
+
+func TestCachedAdapter_Invalidate(t *testing.T) {
+	inner := &countingAdapter{}
+	cache := NewCachedAdapter(inner, time.Hour)
+	ctx := context.Background()
+
+	cache.DescribeTable(ctx, "users")
+	cache.DescribeTable(ctx, "orders")
+	cache.DescribeTable(ctx, "users")
+	if inner.describeCalls != 2 {
+		t.Errorf("Expected one call per table, got %d", inner.describeCalls)
+	}
+
+	cache.Invalidate()
+	cache.DescribeTable(ctx, "users")
+	if inner.describeCalls != 3 {
+		t.Errorf("Expected reload after Invalidate, got %d calls", inner.describeCalls)
+	}
+}
+
+func TestCachedAdapter_DoesNotCacheErrors(t *testing.T) {
+	inner := &countingAdapter{failDescribe: true}
+	cache := NewCachedAdapter(inner, time.Hour)
+	ctx := context.Background()
+
+	if _, err := cache.DescribeTable(ctx, "users"); err == nil {
+		t.Fatal("Expected error")
+	}
+	inner.failDescribe = false
+	if _, err := cache.DescribeTable(ctx, "users"); err != nil {
+		t.Errorf("Expected retry to succeed, got %v", err)
+	}
+	if inner.describeCalls != 2 {
+		t.Errorf("Expected 2 calls, got %d", inner.describeCalls)
+	}
+}
+
+func TestCachedAdapter_PassesThrough(t *testing.T) {
+	cache := NewCachedAdapter(&countingAdapter{}, time.Hour)
+	if cache.GetDBType() != "postgres" {
+		t.Errorf("Expected pass-through GetDBType, got %s", cache.GetDBType())
+	}
+}
@@ -0,0 +1,100 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// poolRetireDelay is how long a pool replaced by a credential rotation stays
+// open, so that queries already holding it can finish
+const poolRetireDelay = time.Minute
+
+// rotatingPool holds a connection pool that is reopened when the password
+// returned by the connection's secret provider changes
+type rotatingPool struct {
+	db atomic.Pointer[sql.DB]
+
+	mu       sync.Mutex
+	password string // password the current pool was opened with
+}
+
+// get returns the current pool, or nil before Connect
+func (p *rotatingPool) get() *sql.DB {
+	return p.db.Load()
+}
+
+// open resolves the password and opens the first pool
+func (p *rotatingPool) open(ctx context.Context, opts ConnectionOptions, open func(ctx context.Context, password string) (*sql.DB, error)) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	password, err := opts.password(ctx)
+	if err != nil {
+		return err
+	}
+	db, err := open(ctx, password)
+	if err != nil {
+		return err
+	}
+
+	p.db.Store(db)
+	p.password = password
+	return nil
+}
+
+// refresh asks the secret provider for the password again and, when it has
+// changed, swaps in a pool opened with it. The old pool is kept until the new
+// one has been verified and is closed after poolRetireDelay.
+func (p *rotatingPool) refresh(ctx context.Context, opts ConnectionOptions, open func(ctx context.Context, password string) (*sql.DB, error)) (bool, error) {
+	if opts.PasswordProvider == nil {
+		return false, nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.db.Load() == nil {
+		return false, fmt.Errorf("database not connected")
+	}
+	password, err := opts.password(ctx)
+	if err != nil {
+		return false, err
+	}
+	if password == p.password {
+		return false, nil
+	}
+
+	db, err := open(ctx, password)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect with rotated credentials: %w", err)
+	}
+
+	old := p.db.Swap(db)
+	p.password = password
+	time.AfterFunc(poolRetireDelay, func() { old.Close() })
+	return true, nil
+}
+
+// close closes the current pool
+func (p *rotatingPool) close() error {
+	if db := p.db.Swap(nil); db != nil {
+		return db.Close()
+	}
+	return nil
+}
+
+// password returns the configured password, asking the provider when set
+func (o ConnectionOptions) password(ctx context.Context) (string, error) {
+	if o.PasswordProvider == nil {
+		return o.Password, nil
+	}
+	password, err := o.PasswordProvider.Secret(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password from %s: %w", o.PasswordProvider, err)
+	}
+	return password, nil
+}
@@ -0,0 +1,105 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+// stubConnector lets sql.OpenDB build pools that are never used
+type stubConnector struct{}
+
+func (stubConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (stubConnector) Driver() driver.Driver {
+	return nil
+}
+
+// stubSecret returns whatever value the test sets
+type stubSecret struct {
+	value string
+	err   error
+}
+
+func (s *stubSecret) Secret(ctx context.Context) (string, error) {
+	return s.value, s.err
+}
+
+func (s *stubSecret) String() string {
+	return "stub"
+}
+
+func TestRotatingPool_Refresh(t *testing.T) {
+	secret := &stubSecret{value: "first"}
+	opts := ConnectionOptions{PasswordProvider: secret}
+
+	var opened []string
+	var failOpen bool
+	open := func(ctx context.Context, password string) (*sql.DB, error) {
+		if failOpen {
+			return nil, errors.New("access denied")
+		}
+		opened = append(opened, password)
+		return sql.OpenDB(stubConnector{}), nil
+	}
+
+	var pool rotatingPool
+	ctx := context.Background()
+	if _, err := pool.refresh(ctx, opts, open); err == nil {
+		t.Error("Expected refresh before Connect to fail")
+	}
+	if err := pool.open(ctx, opts, open); err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	first := pool.get()
+
+	if rotated, err := pool.refresh(ctx, opts, open); err != nil || rotated {
+		t.Errorf("Expected no rotation for an unchanged secret, got %v, %v", rotated, err)
+	}
+
+	secret.value = "second"
+	failOpen = true
+	if rotated, err := pool.refresh(ctx, opts, open); err == nil || rotated || pool.get() != first {
+		t.Errorf("Expected a failed rotation to keep the old pool, got %v, %v", rotated, err)
+	}
+
+	failOpen = false
+	if rotated, err := pool.refresh(ctx, opts, open); err != nil || !rotated || pool.get() == first {
+		t.Errorf("Expected the pool to be replaced, got %v, %v", rotated, err)
+	}
+	if len(opened) != 2 || opened[0] != "first" || opened[1] != "second" {
+		t.Errorf("Expected pools opened with each password, got %v", opened)
+	}
+
+	secret.err = errors.New("vault sealed")
+	if _, err := pool.refresh(ctx, opts, open); err == nil {
+		t.Error("Expected provider errors to be reported")
+	}
+
+	if err := pool.close(); err != nil || pool.get() != nil {
+		t.Errorf("Expected close to drop the pool, got %v", err)
+	}
+}
+
+func TestRotatingPool_StaticPassword(t *testing.T) {
+	var pool rotatingPool
+	open := func(ctx context.Context, password string) (*sql.DB, error) {
+		if password != "static" {
+			t.Errorf("Expected the static password, got %q", password)
+		}
+		return sql.OpenDB(stubConnector{}), nil
+	}
+
+	opts := ConnectionOptions{Password: "static"}
+	if err := pool.open(context.Background(), opts, open); err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	if rotated, err := pool.refresh(context.Background(), opts, open); rotated || err != nil {
+		t.Errorf("Expected refresh to be a no-op without a provider, got %v, %v", rotated, err)
+	}
+	pool.close()
+}
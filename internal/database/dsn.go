@@ -0,0 +1,345 @@
+package database
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+
+	"github.com/hieubanhh/dbhubMCP/internal/secrets"
+)
+
+// ConnectionOptions describes how to reach a database
+type ConnectionOptions struct {
+	// DSN is a postgres:// or mysql:// URL. When set, Host, Port, Socket,
+	// Database and User are ignored; Password still overrides the URL.
+	DSN string
+
+	Host     string
+	Port     int
+	Socket   string // Unix socket path (MySQL) or socket directory (PostgreSQL), used instead of Host and Port
+	Database string
+	User     string
+	Password string
+
+	// PasswordProvider, when set, supplies the password instead of Password
+	// and is consulted again by RefreshCredentials
+	PasswordProvider secrets.SecretProvider
+
+	TLS TLSOptions
+
+	MaxConns     int
+	MaxIdleConns int
+	ConnTimeout  time.Duration
+}
+
+// TLSOptions configures encryption of the database connection
+type TLSOptions struct {
+	Mode       string // disable, prefer (MySQL only), require, verify-ca or verify-full; empty keeps the driver default
+	CAFile     string // CA certificate used to verify the server
+	CertFile   string // client certificate
+	KeyFile    string // client private key
+	ServerName string // name verified against the server certificate instead of the host
+}
+
+// TLS modes, named after libpq's sslmode
+const (
+	TLSDisable    = "disable"
+	TLSPrefer     = "prefer"
+	TLSRequire    = "require"
+	TLSVerifyCA   = "verify-ca"
+	TLSVerifyFull = "verify-full"
+)
+
+// validateTLSMode checks that mode is one of the supported TLS modes
+func validateTLSMode(mode string) error {
+	switch mode {
+	case "", TLSDisable, TLSPrefer, TLSRequire, TLSVerifyCA, TLSVerifyFull:
+		return nil
+	default:
+		return fmt.Errorf("ssl mode must be one of disable, prefer, require, verify-ca or verify-full, got: %s", mode)
+	}
+}
+
+// mysqlConfig builds the driver configuration for a MySQL connection. The
+// driver escapes every field, so passwords may contain any character.
+func mysqlConfig(opts ConnectionOptions) (*mysql.Config, error) {
+	cfg := mysql.NewConfig()
+
+	if opts.DSN != "" {
+		u, err := url.Parse(opts.DSN)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse DSN: %w", err)
+		}
+		if u.Scheme != "mysql" {
+			return nil, fmt.Errorf("unsupported DSN scheme for MySQL: %s", u.Scheme)
+		}
+
+		// Query parameters use the driver's names (tls, charset, loc, ...)
+		query := u.Query()
+		socket := query.Get("socket")
+		query.Del("socket")
+		if len(query) > 0 {
+			if cfg, err = mysql.ParseDSN("/?" + query.Encode()); err != nil {
+				return nil, fmt.Errorf("failed to parse DSN parameters: %w", err)
+			}
+			// tls=true was resolved against the default address; let the
+			// driver resolve it again once the real address is set
+			if cfg.TLSConfig == "true" {
+				cfg.TLS = nil
+			}
+		}
+
+		if u.User != nil {
+			cfg.User = u.User.Username()
+			cfg.Passwd, _ = u.User.Password()
+		}
+		cfg.DBName = strings.TrimPrefix(u.Path, "/")
+		if socket != "" {
+			cfg.Net = "unix"
+			cfg.Addr = socket
+		} else {
+			cfg.Net = "tcp"
+			cfg.Addr = u.Host
+			if u.Port() == "" {
+				cfg.Addr = net.JoinHostPort(u.Hostname(), "3306")
+			}
+		}
+	} else {
+		cfg.User = opts.User
+		cfg.DBName = opts.Database
+		if opts.Socket != "" {
+			cfg.Net = "unix"
+			cfg.Addr = opts.Socket
+		} else {
+			cfg.Net = "tcp"
+			cfg.Addr = net.JoinHostPort(opts.Host, strconv.Itoa(opts.Port))
+		}
+	}
+	if opts.Password != "" {
+		cfg.Passwd = opts.Password
+	}
+
+	cfg.ParseTime = true
+	if opts.ConnTimeout > 0 {
+		cfg.Timeout = opts.ConnTimeout
+	}
+
+	host, _, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		host = cfg.Addr
+	}
+
+	switch opts.TLS.Mode {
+	case "":
+		if opts.TLS.CAFile != "" || opts.TLS.CertFile != "" {
+			return nil, fmt.Errorf("ssl mode is required when TLS certificates are configured")
+		}
+	case TLSDisable:
+		cfg.TLS = nil
+		cfg.TLSConfig = "false"
+	default:
+		tlsCfg, err := clientTLSConfig(opts.TLS, host)
+		if err != nil {
+			return nil, err
+		}
+		cfg.TLS = tlsCfg
+		cfg.AllowFallbackToPlaintext = opts.TLS.Mode == TLSPrefer
+	}
+
+	return cfg, nil
+}
+
+// clientTLSConfig builds a crypto/tls configuration for the given mode. Only
+// verify-full checks the server name; verify-ca checks the chain alone.
+func clientTLSConfig(opts TLSOptions, host string) (*tls.Config, error) {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	var roots *x509.CertPool
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CAFile)
+		}
+		cfg.RootCAs = roots
+	}
+
+	switch opts.Mode {
+	case TLSPrefer, TLSRequire:
+		cfg.InsecureSkipVerify = true
+	case TLSVerifyCA:
+		// Verify the chain ourselves, since crypto/tls always checks the name
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyChain(rawCerts, roots)
+		}
+	case TLSVerifyFull:
+		cfg.ServerName = host
+		if opts.ServerName != "" {
+			cfg.ServerName = opts.ServerName
+		}
+	default:
+		return nil, validateTLSMode(opts.Mode)
+	}
+
+	return cfg, nil
+}
+
+// verifyChain checks a peer certificate chain against roots, or the system
+// pool when roots is nil
+func verifyChain(rawCerts [][]byte, roots *x509.CertPool) error {
+	if len(rawCerts) == 0 {
+		return fmt.Errorf("server did not present a certificate")
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("failed to parse server certificate: %w", err)
+		}
+		certs[i] = cert
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates})
+	return err
+}
+
+// postgresConnString builds a lib/pq key=value connection string with every
+// value quoted. When a TLS server name differs from the host, the name is
+// passed as the host for verification and dialAddr is the real address.
+func postgresConnString(opts ConnectionOptions) (connStr, dialAddr string, err error) {
+	params := make(map[string]string)
+
+	if opts.DSN != "" {
+		u, err := url.Parse(opts.DSN)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to parse DSN: %w", err)
+		}
+		if u.Scheme != "postgres" && u.Scheme != "postgresql" {
+			return "", "", fmt.Errorf("unsupported DSN scheme for PostgreSQL: %s", u.Scheme)
+		}
+		if u.User != nil {
+			params["user"] = u.User.Username()
+			if password, ok := u.User.Password(); ok {
+				params["password"] = password
+			}
+		}
+		if host := u.Hostname(); host != "" {
+			params["host"] = host
+		}
+		if port := u.Port(); port != "" {
+			params["port"] = port
+		}
+		if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+			params["dbname"] = db
+		}
+		// Query parameters use libpq's names (sslmode, application_name, host=/socket/dir, ...)
+		for key, values := range u.Query() {
+			params[key] = values[len(values)-1]
+		}
+	} else {
+		params["host"] = opts.Host
+		params["port"] = strconv.Itoa(opts.Port)
+		if opts.Socket != "" {
+			params["host"] = opts.Socket
+		}
+		params["user"] = opts.User
+		params["dbname"] = opts.Database
+		// Unencrypted unless configured, as before TLS options existed
+		params["sslmode"] = TLSDisable
+	}
+	if opts.Password != "" {
+		params["password"] = opts.Password
+	}
+	if opts.ConnTimeout > 0 {
+		params["connect_timeout"] = strconv.Itoa(int(opts.ConnTimeout.Seconds()))
+	}
+
+	switch opts.TLS.Mode {
+	case "":
+	case TLSPrefer:
+		return "", "", fmt.Errorf("ssl mode prefer is not supported for PostgreSQL; use require or disable")
+	default:
+		if err := validateTLSMode(opts.TLS.Mode); err != nil {
+			return "", "", err
+		}
+		params["sslmode"] = opts.TLS.Mode
+	}
+	for key, value := range map[string]string{
+		"sslrootcert": opts.TLS.CAFile,
+		"sslcert":     opts.TLS.CertFile,
+		"sslkey":      opts.TLS.KeyFile,
+	} {
+		if value != "" {
+			params[key] = value
+		}
+	}
+
+	if name := opts.TLS.ServerName; name != "" && name != params["host"] && !strings.HasPrefix(params["host"], "/") {
+		port := params["port"]
+		if port == "" {
+			port = "5432"
+		}
+		dialAddr = net.JoinHostPort(params["host"], port)
+		params["host"] = name
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	escaper := strings.NewReplacer(`'`, `\'`, `\`, `\\`)
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"='"+escaper.Replace(params[key])+"'")
+	}
+	return strings.Join(pairs, " "), dialAddr, nil
+}
+
+// fixedDialer connects to one address whatever address lib/pq asks for, so
+// that the host in the connection string only serves TLS verification
+type fixedDialer struct {
+	addr string
+	d    net.Dialer
+}
+
+func (f fixedDialer) Dial(network, _ string) (net.Conn, error) {
+	return f.d.Dial(network, f.addr)
+}
+
+func (f fixedDialer) DialTimeout(network, _ string, timeout time.Duration) (net.Conn, error) {
+	d := f.d
+	d.Timeout = timeout
+	return d.Dial(network, f.addr)
+}
+
+func (f fixedDialer) DialContext(ctx context.Context, network, _ string) (net.Conn, error) {
+	return f.d.DialContext(ctx, network, f.addr)
+}
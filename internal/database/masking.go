@@ -0,0 +1,71 @@
+package database
+
+import (
+	"context"
+
+	"github.com/hieubanhh/dbhubMCP/internal/security"
+)
+
+// MaskingAdapter wraps an Adapter and masks sensitive values in the rows of
+// query results, samples and profiles, reporting the masked columns
+type MaskingAdapter struct {
+	Adapter
+	masker *security.Masker
+}
+
+// NewMaskingAdapter wraps inner with masking rules
+func NewMaskingAdapter(inner Adapter, masker *security.Masker) *MaskingAdapter {
+	return &MaskingAdapter{Adapter: inner, masker: masker}
+}
+
+// ResolveTable implements security.Catalog
+func (a *MaskingAdapter) ResolveTable(ctx context.Context, schema, table string) (string, []string, bool) {
+	return resolveTable(ctx, a.Adapter, schema, table)
+}
+
+// ExecuteQuery runs a query and masks its result
+func (a *MaskingAdapter) ExecuteQuery(ctx context.Context, query string, maxRows int) (*QueryResult, error) {
+	result, err := a.Adapter.ExecuteQuery(ctx, query, maxRows)
+	if err != nil {
+		return nil, err
+	}
+	result.MaskedColumns = a.masker.MaskQueryResult(ctx, a.GetDBType(), query, a, result.Columns, result.Rows)
+	return result, nil
+}
+
+// SampleRows samples a table and masks the sample
+func (a *MaskingAdapter) SampleRows(ctx context.Context, tableName string, opts SampleOptions) (*SampleResult, error) {
+	sample, err := a.Adapter.SampleRows(ctx, tableName, opts)
+	if err != nil {
+		return nil, err
+	}
+	schema, table := SplitTableName(sample.TableName)
+	sample.MaskedColumns = a.masker.MaskTableRows(schema, table, sample.Columns, sample.Rows)
+	return sample, nil
+}
+
+// ProfileTable profiles a table and masks the values it reports
+func (a *MaskingAdapter) ProfileTable(ctx context.Context, tableName string, opts ProfileOptions) (*TableProfile, error) {
+	profile, err := a.Adapter.ProfileTable(ctx, tableName, opts)
+	if err != nil {
+		return nil, err
+	}
+	schema, table := SplitTableName(profile.TableName)
+	for i := range profile.Columns {
+		c := &profile.Columns[i]
+		values := []*string{c.Min, c.Max}
+		for _, top := range c.TopValues {
+			values = append(values, top.Value)
+		}
+		report, masked := a.masker.MaskTableValues(schema, table, c.ColumnName, values)
+		if !masked {
+			continue
+		}
+		c.Min, c.Max = values[0], values[1]
+		for k := range c.TopValues {
+			c.TopValues[k].Value = values[2+k]
+		}
+		profile.MaskedColumns = append(profile.MaskedColumns, report)
+	}
+	return profile, nil
+}
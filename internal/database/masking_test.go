@@ -0,0 +1,72 @@
+package database
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hieubanhh/dbhubMCP/internal/security"
+)
+
+// rowsAdapter returns fixed rows for every query, sample and profile
+type rowsAdapter struct {
+	catalogAdapter
+}
+
+func (a *rowsAdapter) ExecuteQuery(ctx context.Context, query string, maxRows int) (*QueryResult, error) {
+	return &QueryResult{
+		Columns:  []string{"id", "email"},
+		Rows:     []map[string]interface{}{{"id": int64(1), "email": "jane@example.com"}},
+		RowCount: 1,
+	}, nil
+}
+
+func (a *rowsAdapter) SampleRows(ctx context.Context, tableName string, opts SampleOptions) (*SampleResult, error) {
+	result, _ := a.ExecuteQuery(ctx, "", 0)
+	return &SampleResult{TableName: "public.users", QueryResult: *result}, nil
+}
+
+func (a *rowsAdapter) ProfileTable(ctx context.Context, tableName string, opts ProfileOptions) (*TableProfile, error) {
+	min, top := "a@example.com", "jane@example.com"
+	return &TableProfile{TableName: "public.users", Columns: []ColumnProfile{
+		{ColumnName: "id"},
+		{ColumnName: "email", Min: &min, TopValues: []ValueCount{{Value: &top, Count: 2}}},
+	}}, nil
+}
+
+func TestMaskingAdapter(t *testing.T) {
+	masker, err := security.NewMasker([]security.MaskRule{{Columns: []string{"users.email"}, Strategy: security.MaskPartial}}, "")
+	if err != nil {
+		t.Fatalf("NewMasker failed: %v", err)
+	}
+	inner := &rowsAdapter{catalogAdapter{tables: map[string][]string{"public.users": {"id", "email"}}}}
+	adapter := NewMaskingAdapter(inner, masker)
+	ctx := context.Background()
+
+	result, err := adapter.ExecuteQuery(ctx, "SELECT id, email FROM users", 10)
+	if err != nil {
+		t.Fatalf("ExecuteQuery failed: %v", err)
+	}
+	if result.Rows[0]["email"] != "j***@example.com" || result.Rows[0]["id"] != int64(1) {
+		t.Errorf("Expected only email to be masked, got %v", result.Rows[0])
+	}
+	if len(result.MaskedColumns) != 1 || result.MaskedColumns[0].Reason != "matches public.users.email" {
+		t.Errorf("Expected email to be reported, got %v", result.MaskedColumns)
+	}
+
+	sample, err := adapter.SampleRows(ctx, "users", SampleOptions{})
+	if err != nil {
+		t.Fatalf("SampleRows failed: %v", err)
+	}
+	if sample.Rows[0]["email"] != "j***@example.com" || len(sample.MaskedColumns) != 1 {
+		t.Errorf("Expected the sample to be masked, got %v %v", sample.Rows, sample.MaskedColumns)
+	}
+
+	profile, err := adapter.ProfileTable(ctx, "users", ProfileOptions{})
+	if err != nil {
+		t.Fatalf("ProfileTable failed: %v", err)
+	}
+	email := profile.Columns[1]
+	if *email.Min != "a***@example.com" || *email.TopValues[0].Value != "j***@example.com" || len(profile.MaskedColumns) != 1 {
+		t.Errorf("Expected profile values to be masked, got %+v %v", email, profile.MaskedColumns)
+	}
+}
@@ -3,83 +3,85 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"time"
 
-	_ "github.com/go-sql-driver/mysql"
+	"github.com/go-sql-driver/mysql"
 )
 
 // MySQLAdapter implements the Adapter interface for MySQL
 type MySQLAdapter struct {
-	db             *sql.DB
-	host           string
-	port           int
-	dbName         string
-	user           string
-	password       string
-	maxConns       int
-	maxIdleConns   int
-	connTimeout    time.Duration
+	pool   rotatingPool
+	opts   ConnectionOptions
+	dbName string // resolved from opts on Connect
 }
 
 // NewMySQLAdapter creates a new MySQL adapter
-func NewMySQLAdapter(host string, port int, dbName, user, password string, maxConns, maxIdleConns int, connTimeout time.Duration) *MySQLAdapter {
-	return &MySQLAdapter{
-		host:         host,
-		port:         port,
-		dbName:       dbName,
-		user:         user,
-		password:     password,
-		maxConns:     maxConns,
-		maxIdleConns: maxIdleConns,
-		connTimeout:  connTimeout,
-	}
+func NewMySQLAdapter(opts ConnectionOptions) *MySQLAdapter {
+	return &MySQLAdapter{opts: opts}
 }
 
 // Connect establishes a connection to MySQL
 func (a *MySQLAdapter) Connect(ctx context.Context) error {
-	// Build DSN (Data Source Name)
-	// format: user:password@tcp(host:port)/dbname?param=value
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?parseTime=true&timeout=%s",
-		a.user, a.password, a.host, a.port, a.dbName, a.connTimeout)
+	cfg, err := mysqlConfig(a.opts)
+	if err != nil {
+		return fmt.Errorf("invalid MySQL connection settings: %w", err)
+	}
+	a.dbName = cfg.DBName
+
+	return a.pool.open(ctx, a.opts, a.open)
+}
 
-	db, err := sql.Open("mysql", dsn)
+// RefreshCredentials reopens the pool if the password secret has rotated
+func (a *MySQLAdapter) RefreshCredentials(ctx context.Context) (bool, error) {
+	return a.pool.refresh(ctx, a.opts, a.open)
+}
+
+// open creates a verified connection pool using the given password
+func (a *MySQLAdapter) open(ctx context.Context, password string) (*sql.DB, error) {
+	opts := a.opts
+	opts.Password = password
+	cfg, err := mysqlConfig(opts)
 	if err != nil {
-		return fmt.Errorf("failed to open MySQL connection: %w", err)
+		return nil, fmt.Errorf("invalid MySQL connection settings: %w", err)
 	}
 
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open MySQL connection: %w", err)
+	}
+	db := sql.OpenDB(connector)
+
 	// Configure connection pool
-	db.SetMaxOpenConns(a.maxConns)
-	db.SetMaxIdleConns(a.maxIdleConns)
+	db.SetMaxOpenConns(a.opts.MaxConns)
+	db.SetMaxIdleConns(a.opts.MaxIdleConns)
 	db.SetConnMaxLifetime(time.Hour)
 
 	// Test connection
-	ctx, cancel := context.WithTimeout(ctx, a.connTimeout)
+	ctx, cancel := context.WithTimeout(ctx, a.opts.ConnTimeout)
 	defer cancel()
 
 	if err := db.PingContext(ctx); err != nil {
 		db.Close()
-		return fmt.Errorf("failed to ping MySQL: %w", err)
+		return nil, fmt.Errorf("failed to ping MySQL: %w", err)
 	}
 
-	a.db = db
-	return nil
+	return db, nil
 }
 
 // Close closes the MySQL connection
 func (a *MySQLAdapter) Close() error {
-	if a.db != nil {
-		return a.db.Close()
-	}
-	return nil
+	return a.pool.close()
 }
 
 // Ping checks if the database connection is alive
 func (a *MySQLAdapter) Ping(ctx context.Context) error {
-	if a.db == nil {
+	db := a.pool.get()
+	if db == nil {
 		return fmt.Errorf("database not connected")
 	}
-	return a.db.PingContext(ctx)
+	return db.PingContext(ctx)
 }
 
 // ListTables returns all tables in the MySQL database
@@ -94,7 +96,7 @@ func (a *MySQLAdapter) ListTables(ctx context.Context) ([]TableInfo, error) {
 		ORDER BY TABLE_NAME
 	`
 
-	rows, err := a.db.QueryContext(ctx, query, a.dbName)
+	rows, err := a.pool.get().QueryContext(ctx, query, a.dbName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tables: %w", err)
 	}
@@ -131,7 +133,7 @@ func (a *MySQLAdapter) DescribeTable(ctx context.Context, tableName string) ([]C
 		ORDER BY ORDINAL_POSITION
 	`
 
-	rows, err := a.db.QueryContext(ctx, query, a.dbName, tableName)
+	rows, err := a.pool.get().QueryContext(ctx, query, a.dbName, tableName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to describe table: %w", err)
 	}
@@ -157,9 +159,515 @@ func (a *MySQLAdapter) DescribeTable(ctx context.Context, tableName string) ([]C
 	return columns, nil
 }
 
+// ListColumns returns the columns of every table in the MySQL database
+func (a *MySQLAdapter) ListColumns(ctx context.Context) ([]ColumnInfo, error) {
+	query := `
+		SELECT
+			TABLE_SCHEMA as table_schema,
+			TABLE_NAME as table_name,
+			COLUMN_NAME as column_name,
+			DATA_TYPE as data_type,
+			IS_NULLABLE as is_nullable,
+			COALESCE(COLUMN_DEFAULT, '') as column_default,
+			COALESCE(COLUMN_KEY, '') as column_key
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ?
+		ORDER BY TABLE_NAME, ORDINAL_POSITION
+	`
+
+	rows, err := a.pool.get().QueryContext(ctx, query, a.dbName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []ColumnInfo
+	for rows.Next() {
+		var col ColumnInfo
+		if err := rows.Scan(&col.TableSchema, &col.TableName, &col.ColumnName, &col.DataType, &col.IsNullable, &col.ColumnDefault, &col.ColumnKey); err != nil {
+			return nil, fmt.Errorf("failed to scan column info: %w", err)
+		}
+		columns = append(columns, col)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating columns: %w", err)
+	}
+
+	return columns, nil
+}
+
+// SchemaFingerprint summarizes information_schema.TABLES and COLUMNS. ALTER
+// TABLE rebuilds bump CREATE_TIME; UPDATE_TIME is deliberately left out since
+// it also moves on every data change.
+func (a *MySQLAdapter) SchemaFingerprint(ctx context.Context) (string, error) {
+	query := `
+		SELECT CONCAT_WS(':',
+			(SELECT CONCAT(COUNT(*), '/', COALESCE(MAX(CREATE_TIME), ''))
+				FROM information_schema.TABLES WHERE TABLE_SCHEMA = ?),
+			(SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ?),
+			(SELECT COUNT(*) FROM information_schema.REFERENTIAL_CONSTRAINTS WHERE CONSTRAINT_SCHEMA = ?)
+		)
+	`
+
+	var fingerprint string
+	if err := a.pool.get().QueryRowContext(ctx, query, a.dbName, a.dbName, a.dbName).Scan(&fingerprint); err != nil {
+		return "", fmt.Errorf("failed to read schema fingerprint: %w", err)
+	}
+	return fingerprint, nil
+}
+
+// DescribeTableDetailed returns the full definition of a MySQL table
+func (a *MySQLAdapter) DescribeTableDetailed(ctx context.Context, tableName string) (*TableDetail, error) {
+	schema, table := SplitTableName(tableName)
+	if schema == "" {
+		schema = a.dbName
+	}
+
+	detail := &TableDetail{}
+	err := a.pool.get().QueryRowContext(ctx, `
+		SELECT TABLE_SCHEMA, TABLE_NAME, TABLE_TYPE, COALESCE(TABLE_COMMENT, '')
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+	`, schema, table).Scan(&detail.TableSchema, &detail.TableName, &detail.TableType, &detail.Comment)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("table not found: %s", tableName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe table: %w", err)
+	}
+
+	if detail.Columns, err = a.detailedColumns(ctx, schema, table); err != nil {
+		return nil, err
+	}
+	if detail.Indexes, err = a.indexes(ctx, schema, table); err != nil {
+		return nil, err
+	}
+	for _, idx := range detail.Indexes {
+		if idx.IsPrimary {
+			detail.PrimaryKey = idx.Columns
+		}
+	}
+	if detail.ForeignKeys, err = a.foreignKeys(ctx, schema, table); err != nil {
+		return nil, err
+	}
+
+	if detail.TableType == "VIEW" {
+		err := a.pool.get().QueryRowContext(ctx, `
+			SELECT COALESCE(VIEW_DEFINITION, '')
+			FROM information_schema.VIEWS
+			WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		`, schema, table).Scan(&detail.ViewDefinition)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, fmt.Errorf("failed to read view definition: %w", err)
+		}
+	}
+
+	return detail, nil
+}
+
+func (a *MySQLAdapter) detailedColumns(ctx context.Context, schema, table string) ([]ColumnInfo, error) {
+	query := `
+		SELECT
+			COLUMN_NAME,
+			DATA_TYPE,
+			IS_NULLABLE,
+			COALESCE(COLUMN_DEFAULT, ''),
+			COALESCE(COLUMN_KEY, ''),
+			COALESCE(EXTRA, ''),
+			COLUMN_TYPE,
+			COALESCE(COLUMN_COMMENT, '')
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		ORDER BY ORDINAL_POSITION
+	`
+
+	rows, err := a.pool.get().QueryContext(ctx, query, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []ColumnInfo
+	for rows.Next() {
+		var col ColumnInfo
+		if err := rows.Scan(&col.ColumnName, &col.DataType, &col.IsNullable, &col.ColumnDefault,
+			&col.ColumnKey, &col.Extra, &col.ColumnType, &col.Comment); err != nil {
+			return nil, fmt.Errorf("failed to scan column info: %w", err)
+		}
+		columns = append(columns, col)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating columns: %w", err)
+	}
+
+	return columns, nil
+}
+
+func (a *MySQLAdapter) indexes(ctx context.Context, schema, table string) ([]IndexInfo, error) {
+	query := `
+		SELECT INDEX_NAME, NON_UNIQUE, COALESCE(COLUMN_NAME, ''), INDEX_TYPE
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+		ORDER BY INDEX_NAME = 'PRIMARY' DESC, INDEX_NAME, SEQ_IN_INDEX
+	`
+
+	rows, err := a.pool.get().QueryContext(ctx, query, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+	defer rows.Close()
+
+	// STATISTICS has one row per indexed column; fold them into indexes
+	var indexes []IndexInfo
+	for rows.Next() {
+		var name, column, indexType string
+		var nonUnique int
+		if err := rows.Scan(&name, &nonUnique, &column, &indexType); err != nil {
+			return nil, fmt.Errorf("failed to scan index info: %w", err)
+		}
+		if n := len(indexes); n == 0 || indexes[n-1].IndexName != name {
+			indexes = append(indexes, IndexInfo{
+				IndexName: name,
+				IsUnique:  nonUnique == 0,
+				IsPrimary: name == "PRIMARY",
+				IndexType: indexType,
+			})
+		}
+		last := &indexes[len(indexes)-1]
+		last.Columns = append(last.Columns, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating indexes: %w", err)
+	}
+
+	return indexes, nil
+}
+
+// ListForeignKeys returns every foreign key in the configured database
+func (a *MySQLAdapter) ListForeignKeys(ctx context.Context) ([]ForeignKeyInfo, error) {
+	return a.queryForeignKeys(ctx, "k.TABLE_SCHEMA = ?", a.dbName)
+}
+
+func (a *MySQLAdapter) foreignKeys(ctx context.Context, schema, table string) ([]ForeignKeyInfo, error) {
+	fks, err := a.queryForeignKeys(ctx, "k.TABLE_SCHEMA = ? AND k.TABLE_NAME = ?", schema, table)
+	for i := range fks {
+		fks[i].TableSchema, fks[i].TableName = "", ""
+	}
+	return fks, err
+}
+
+// queryForeignKeys lists foreign key constraints matching the where clause
+func (a *MySQLAdapter) queryForeignKeys(ctx context.Context, where string, args ...interface{}) ([]ForeignKeyInfo, error) {
+	query := `
+		SELECT
+			k.TABLE_SCHEMA,
+			k.TABLE_NAME,
+			k.CONSTRAINT_NAME,
+			k.COLUMN_NAME,
+			k.REFERENCED_TABLE_SCHEMA,
+			k.REFERENCED_TABLE_NAME,
+			k.REFERENCED_COLUMN_NAME,
+			r.UPDATE_RULE,
+			r.DELETE_RULE
+		FROM information_schema.KEY_COLUMN_USAGE k
+		JOIN information_schema.REFERENTIAL_CONSTRAINTS r
+			ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA
+			AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME
+			AND r.TABLE_NAME = k.TABLE_NAME
+		WHERE ` + where + `
+			AND k.REFERENCED_TABLE_NAME IS NOT NULL
+		ORDER BY k.TABLE_SCHEMA, k.TABLE_NAME, k.CONSTRAINT_NAME, k.ORDINAL_POSITION
+	`
+
+	rows, err := a.pool.get().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	// KEY_COLUMN_USAGE has one row per column; fold them into constraints
+	var fks []ForeignKeyInfo
+	for rows.Next() {
+		var tableSchema, tableName, name, column, refSchema, refTable, refColumn, onUpdate, onDelete string
+		if err := rows.Scan(&tableSchema, &tableName, &name, &column, &refSchema, &refTable, &refColumn, &onUpdate, &onDelete); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key info: %w", err)
+		}
+		if n := len(fks); n == 0 || fks[n-1].TableName != tableName || fks[n-1].ConstraintName != name {
+			fks = append(fks, ForeignKeyInfo{
+				TableSchema:      tableSchema,
+				TableName:        tableName,
+				ConstraintName:   name,
+				ReferencedSchema: refSchema,
+				ReferencedTable:  refTable,
+				OnUpdate:         onUpdate,
+				OnDelete:         onDelete,
+			})
+		}
+		last := &fks[len(fks)-1]
+		last.Columns = append(last.Columns, column)
+		last.ReferencedColumns = append(last.ReferencedColumns, refColumn)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating foreign keys: %w", err)
+	}
+
+	return fks, nil
+}
+
+// mysqlProfileDialect returns the SQL flavour used when profiling MySQL tables.
+// The statement timeout is applied through an optimizer hint.
+func mysqlProfileDialect(timeout time.Duration) profileDialect {
+	d := profileDialect{
+		dbType:     "mysql",
+		lengthFunc: "CHAR_LENGTH",
+		castText:   func(expr string) string { return "CAST(" + expr + " AS CHAR)" },
+	}
+	if timeout > 0 {
+		d.selectHint = fmt.Sprintf("/*+ MAX_EXECUTION_TIME(%d) */ ", timeout.Milliseconds())
+	}
+	return d
+}
+
+// ProfileTable computes column statistics for a MySQL table. Tables with
+// more rows than opts.SampleRows are sampled with ORDER BY RAND().
+func (a *MySQLAdapter) ProfileTable(ctx context.Context, tableName string, opts ProfileOptions) (*TableProfile, error) {
+	detail, err := a.DescribeTableDetailed(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := selectColumns(detail.Columns, opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := a.pool.get().BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin read-only transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	profile := &TableProfile{TableName: detail.TableSchema + "." + detail.TableName}
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(TABLE_ROWS, 0)
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+	`, detail.TableSchema, detail.TableName).Scan(&profile.EstimatedRows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate row count: %w", err)
+	}
+
+	name := QuoteQualifiedName("mysql", detail.TableSchema, detail.TableName)
+	source := name
+	if opts.SampleRows > 0 {
+		if profile.EstimatedRows > int64(opts.SampleRows) {
+			source = fmt.Sprintf("(SELECT * FROM %s ORDER BY RAND(42) LIMIT %d) AS profile_sample", name, opts.SampleRows)
+			profile.Sampled = true
+			profile.SampleMethod = "ORDER BY RAND()"
+		} else {
+			source = fmt.Sprintf("(SELECT * FROM %s LIMIT %d) AS profile_sample", name, opts.SampleRows)
+		}
+	}
+
+	var estimates map[string]int64
+	if opts.ApproximateDistinct {
+		if estimates, err = a.distinctEstimates(ctx, tx, detail.TableSchema, detail.TableName); err != nil {
+			return nil, err
+		}
+	}
+
+	profile.RowsProfiled, profile.Columns, err = profileColumns(ctx, tx, mysqlProfileDialect(opts.Timeout), source, columns, opts, estimates)
+	if err != nil {
+		return nil, err
+	}
+	if !profile.Sampled && opts.SampleRows > 0 && profile.RowsProfiled >= int64(opts.SampleRows) {
+		profile.Sampled = true
+		profile.SampleMethod = "first rows"
+	}
+
+	return profile, nil
+}
+
+// distinctEstimates reads index cardinality for columns that lead an index
+func (a *MySQLAdapter) distinctEstimates(ctx context.Context, tx *sql.Tx, schema, table string) (map[string]int64, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT COLUMN_NAME, MAX(COALESCE(CARDINALITY, 0))
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND SEQ_IN_INDEX = 1 AND COLUMN_NAME IS NOT NULL
+		GROUP BY COLUMN_NAME
+	`, schema, table)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index statistics: %w", err)
+	}
+	defer rows.Close()
+
+	estimates := make(map[string]int64)
+	for rows.Next() {
+		var column string
+		var distinct int64
+		if err := rows.Scan(&column, &distinct); err != nil {
+			return nil, fmt.Errorf("failed to scan index statistics: %w", err)
+		}
+		estimates[column] = distinct
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating index statistics: %w", err)
+	}
+
+	return estimates, nil
+}
+
+// mysqlTableStatsQuery selects the statistics scanned by queryTableStats;
+// callers append further conditions and ordering
+const mysqlTableStatsQuery = `
+	SELECT TABLE_SCHEMA, TABLE_NAME, TABLE_TYPE,
+		COALESCE(TABLE_ROWS, 0), COALESCE(DATA_LENGTH, 0), COALESCE(INDEX_LENGTH, 0),
+		AUTO_INCREMENT, UPDATE_TIME
+	FROM information_schema.TABLES
+	WHERE TABLE_SCHEMA = ?
+`
+
+// ListTableStats returns row estimates and sizes for every MySQL table.
+// InnoDB row counts are estimates and MySQL 8 caches these values for
+// information_schema_stats_expiry seconds.
+func (a *MySQLAdapter) ListTableStats(ctx context.Context) ([]TableStats, error) {
+	return a.queryTableStats(ctx, mysqlTableStatsQuery+" ORDER BY TABLE_NAME", a.dbName)
+}
+
+// TableStats returns statistics for a MySQL table. The exact count runs in a
+// read-only transaction under opts.Timeout.
+func (a *MySQLAdapter) TableStats(ctx context.Context, tableName string, opts TableStatsOptions) (*TableStats, error) {
+	schema, table := SplitTableName(tableName)
+	if schema == "" {
+		schema = a.dbName
+	}
+	stats, err := a.queryTableStats(ctx, mysqlTableStatsQuery+" AND TABLE_NAME = ?", schema, table)
+	if err != nil {
+		return nil, err
+	}
+	if len(stats) == 0 {
+		return nil, fmt.Errorf("table not found: %s", tableName)
+	}
+	result := &stats[0]
+
+	if opts.ExactCount {
+		tx, err := a.pool.get().BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin read-only transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		var count int64
+		query := fmt.Sprintf("SELECT %sCOUNT(*) FROM %s", mysqlProfileDialect(opts.Timeout).selectHint,
+			QuoteQualifiedName("mysql", result.TableSchema, result.TableName))
+		if err := tx.QueryRowContext(ctx, query).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count rows: %w", err)
+		}
+		result.ExactRows = &count
+	}
+
+	return result, nil
+}
+
+func (a *MySQLAdapter) queryTableStats(ctx context.Context, query string, args ...interface{}) ([]TableStats, error) {
+	rows, err := a.pool.get().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table statistics: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []TableStats
+	for rows.Next() {
+		var (
+			t             TableStats
+			autoIncrement sql.NullInt64
+			updated       sql.NullTime
+		)
+		if err := rows.Scan(&t.TableSchema, &t.TableName, &t.TableType, &t.EstimatedRows,
+			&t.TableBytes, &t.IndexBytes, &autoIncrement, &updated); err != nil {
+			return nil, fmt.Errorf("failed to scan table statistics: %w", err)
+		}
+		t.TotalBytes = t.TableBytes + t.IndexBytes
+		t.TotalSize = FormatBytes(t.TotalBytes)
+		if autoIncrement.Valid {
+			t.AutoIncrement = &autoIncrement.Int64
+		}
+		t.LastUpdate = nullTimePtr(updated)
+		stats = append(stats, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating table statistics: %w", err)
+	}
+
+	return stats, nil
+}
+
+// SampleRows returns a random sample of a MySQL table. Small tables are
+// shuffled with a seeded RAND(); larger ones keep each row with a seeded
+// probability so the whole table is never sorted.
+func (a *MySQLAdapter) SampleRows(ctx context.Context, tableName string, opts SampleOptions) (*SampleResult, error) {
+	detail, err := a.DescribeTableDetailed(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := selectColumns(detail.Columns, opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := a.pool.get().BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin read-only transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	sample := &SampleResult{TableName: detail.TableSchema + "." + detail.TableName, Seed: opts.Seed}
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(TABLE_ROWS, 0)
+		FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+	`, detail.TableSchema, detail.TableName).Scan(&sample.EstimatedRows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate row count: %w", err)
+	}
+
+	d := mysqlProfileDialect(opts.Timeout)
+	name := QuoteQualifiedName("mysql", detail.TableSchema, detail.TableName)
+	list := selectList("mysql", columns)
+	var query string
+	if sample.EstimatedRows <= smallTableRows {
+		query = fmt.Sprintf("SELECT %s%s FROM %s ORDER BY RAND(%d) LIMIT %d", d.selectHint, list, name, opts.Seed, opts.N)
+		sample.SampleMethod = "ORDER BY RAND()"
+	} else {
+		query = fmt.Sprintf("SELECT %s%s FROM %s WHERE RAND(%d) < %.8f LIMIT %d",
+			d.selectHint, list, name, opts.Seed, sampleFraction(opts.N, sample.EstimatedRows), opts.N)
+		sample.SampleMethod = "WHERE RAND() <"
+	}
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample rows: %w", err)
+	}
+	defer rows.Close()
+
+	result, err := rowsToResult(rows, opts.N)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sample: %w", err)
+	}
+	sample.QueryResult = *result
+	sample.TruncatedCells = truncateCells(&sample.QueryResult, opts.MaxCellLength)
+
+	return sample, nil
+}
+
 // ExecuteQuery executes a read-only query on MySQL
 func (a *MySQLAdapter) ExecuteQuery(ctx context.Context, query string, maxRows int) (*QueryResult, error) {
-	rows, err := a.db.QueryContext(ctx, query)
+	rows, err := a.pool.get().QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -168,17 +676,91 @@ func (a *MySQLAdapter) ExecuteQuery(ctx context.Context, query string, maxRows i
 	return rowsToResult(rows, maxRows)
 }
 
-// ExplainQuery returns the execution plan for a MySQL query
-func (a *MySQLAdapter) ExplainQuery(ctx context.Context, query string) (*QueryResult, error) {
+// ExplainQuery returns the execution plan for a MySQL query. With
+// opts.Analyze the query is executed (MySQL 8.0.18+) inside a read-only
+// transaction that is rolled back afterwards.
+func (a *MySQLAdapter) ExplainQuery(ctx context.Context, query string, opts ExplainOptions) (*QueryResult, error) {
 	explainQuery := fmt.Sprintf("EXPLAIN %s", query)
+	if !opts.Analyze {
+		rows, err := a.pool.get().QueryContext(ctx, explainQuery)
+		if err != nil {
+			return nil, fmt.Errorf("failed to explain query: %w", err)
+		}
+		defer rows.Close()
+
+		return rowsToResult(rows, 1000) // EXPLAIN results are typically small
+	}
+
+	tx, restore, err := a.analyzeTx(ctx, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
 
-	rows, err := a.db.QueryContext(ctx, explainQuery)
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("EXPLAIN ANALYZE %s", query))
 	if err != nil {
 		return nil, fmt.Errorf("failed to explain query: %w", err)
 	}
 	defer rows.Close()
 
-	return rowsToResult(rows, 1000) // EXPLAIN results are typically small
+	return rowsToResult(rows, 1000)
+}
+
+// ExplainQueryJSON returns the execution plan for a MySQL query as JSON.
+// EXPLAIN ANALYZE only produces tree output, so opts.Analyze is rejected.
+func (a *MySQLAdapter) ExplainQueryJSON(ctx context.Context, query string, opts ExplainOptions) ([]byte, error) {
+	if opts.Analyze {
+		return nil, fmt.Errorf("EXPLAIN ANALYZE does not support JSON output on MySQL, use the text format")
+	}
+
+	var plan []byte
+	if err := a.pool.get().QueryRowContext(ctx, fmt.Sprintf("EXPLAIN FORMAT=JSON %s", query)).Scan(&plan); err != nil {
+		return nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+	return plan, nil
+}
+
+// EvaluateHypotheticalIndexes is not supported on MySQL
+func (a *MySQLAdapter) EvaluateHypotheticalIndexes(ctx context.Context, query string, statements []string, timeout time.Duration) ([]HypotheticalIndexResult, error) {
+	return nil, fmt.Errorf("hypothetical indexes are only supported on PostgreSQL with the hypopg extension")
+}
+
+// analyzeTx begins a read-only transaction limited by max_execution_time.
+// The session limit outlives the transaction, so the work is pinned to one
+// connection that is reset before it returns to the pool. The returned
+// function rolls back and releases the connection; it must always be called.
+func (a *MySQLAdapter) analyzeTx(ctx context.Context, timeout time.Duration) (*sql.Tx, func(), error) {
+	conn, err := a.pool.get().Conn(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	release := func() { conn.Close() }
+	if timeout > 0 {
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET SESSION max_execution_time = %d", timeout.Milliseconds())); err != nil {
+			conn.Close()
+			return nil, nil, fmt.Errorf("failed to set statement timeout: %w", err)
+		}
+		release = func() {
+			// Runs after the rollback, which a cancelled ctx may already have
+			// done; a connection that cannot be reset is discarded instead
+			if _, err := conn.ExecContext(context.Background(), "SET SESSION max_execution_time = DEFAULT"); err != nil {
+				conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+			}
+			conn.Close()
+		}
+	}
+
+	tx, err := conn.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		release()
+		return nil, nil, fmt.Errorf("failed to begin read-only transaction: %w", err)
+	}
+
+	return tx, func() {
+		tx.Rollback()
+		release()
+	}, nil
 }
 
 // GetDBType returns the database type
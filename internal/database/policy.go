@@ -0,0 +1,296 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/security"
+)
+
+// PolicyAdapter wraps an Adapter and hides the schemas, tables and columns
+// that a security policy denies: metadata is filtered, table tools refuse
+// denied tables, and queries are checked before they run.
+type PolicyAdapter struct {
+	Adapter
+	policy *security.Policy
+}
+
+// NewPolicyAdapter wraps inner with a security policy
+func NewPolicyAdapter(inner Adapter, policy *security.Policy) *PolicyAdapter {
+	return &PolicyAdapter{Adapter: inner, policy: policy}
+}
+
+// ResolveTable implements security.Catalog using the unfiltered metadata
+func (a *PolicyAdapter) ResolveTable(ctx context.Context, schema, table string) (string, []string, bool) {
+	return resolveTable(ctx, a.Adapter, schema, table)
+}
+
+// resolveTable looks a table up the way security.Catalog expects
+func resolveTable(ctx context.Context, adapter Adapter, schema, table string) (string, []string, bool) {
+	detail, err := adapter.DescribeTableDetailed(ctx, QuoteQualifiedName(adapter.GetDBType(), schema, table))
+	if err != nil {
+		return "", nil, false
+	}
+	columns := make([]string, len(detail.Columns))
+	for i, c := range detail.Columns {
+		columns[i] = c.ColumnName
+	}
+	return detail.TableSchema, columns, true
+}
+
+// checkQuery rejects queries that read denied objects
+func (a *PolicyAdapter) checkQuery(ctx context.Context, query string) error {
+	return a.policy.CheckQuery(ctx, a.GetDBType(), query, a)
+}
+
+// allowedTable resolves a table name and checks it against the policy
+func (a *PolicyAdapter) allowedTable(ctx context.Context, tableName string) (*TableDetail, error) {
+	detail, err := a.Adapter.DescribeTableDetailed(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.policy.CheckTable(detail.TableSchema, detail.TableName); err != nil {
+		return nil, err
+	}
+	return detail, nil
+}
+
+// allowedColumns checks requested columns, or lists the allowed columns of
+// the table when none were requested and some are denied
+func (a *PolicyAdapter) allowedColumns(detail *TableDetail, requested []string) ([]string, error) {
+	if len(requested) > 0 {
+		for _, c := range requested {
+			if err := a.policy.CheckColumn(detail.TableSchema, detail.TableName, c); err != nil {
+				return nil, err
+			}
+		}
+		return requested, nil
+	}
+
+	var allowed []string
+	for _, c := range detail.Columns {
+		if a.policy.CheckColumn(detail.TableSchema, detail.TableName, c.ColumnName) == nil {
+			allowed = append(allowed, c.ColumnName)
+		}
+	}
+	if len(allowed) == len(detail.Columns) {
+		return nil, nil
+	}
+	if len(allowed) == 0 {
+		return nil, &security.AccessError{Object: "every column of table " + detail.TableSchema + "." + detail.TableName}
+	}
+	return allowed, nil
+}
+
+// columnAllowed reports whether a column is visible
+func (a *PolicyAdapter) columnAllowed(schema, table, column string) bool {
+	return a.policy.CheckTable(schema, table) == nil && a.policy.CheckColumn(schema, table, column) == nil
+}
+
+// ListTables returns the allowed tables
+func (a *PolicyAdapter) ListTables(ctx context.Context) ([]TableInfo, error) {
+	tables, err := a.Adapter.ListTables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	allowed := make([]TableInfo, 0, len(tables))
+	for _, t := range tables {
+		if a.policy.CheckTable(t.TableSchema, t.TableName) == nil {
+			allowed = append(allowed, t)
+		}
+	}
+	return allowed, nil
+}
+
+// ListTableStats returns the statistics of the allowed tables
+func (a *PolicyAdapter) ListTableStats(ctx context.Context) ([]TableStats, error) {
+	stats, err := a.Adapter.ListTableStats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	allowed := make([]TableStats, 0, len(stats))
+	for _, t := range stats {
+		if a.policy.CheckTable(t.TableSchema, t.TableName) == nil {
+			allowed = append(allowed, t)
+		}
+	}
+	return allowed, nil
+}
+
+// DescribeTable returns the allowed columns of an allowed table
+func (a *PolicyAdapter) DescribeTable(ctx context.Context, tableName string) ([]ColumnInfo, error) {
+	detail, err := a.allowedTable(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := a.Adapter.DescribeTable(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	allowed := make([]ColumnInfo, 0, len(columns))
+	for _, c := range columns {
+		if a.policy.CheckColumn(detail.TableSchema, detail.TableName, c.ColumnName) == nil {
+			allowed = append(allowed, c)
+		}
+	}
+	return allowed, nil
+}
+
+// DescribeTableDetailed returns the definition of an allowed table without
+// denied columns, or keys and indexes that include them
+func (a *PolicyAdapter) DescribeTableDetailed(ctx context.Context, tableName string) (*TableDetail, error) {
+	detail, err := a.allowedTable(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	// The detail may be shared by a cache, so build a filtered copy
+	filtered := *detail
+	visible := func(column string) bool {
+		return a.policy.CheckColumn(detail.TableSchema, detail.TableName, column) == nil
+	}
+	filtered.Columns = nil
+	for _, c := range detail.Columns {
+		if visible(c.ColumnName) {
+			filtered.Columns = append(filtered.Columns, c)
+		}
+	}
+	filtered.PrimaryKey = nil
+	for _, c := range detail.PrimaryKey {
+		if visible(c) {
+			filtered.PrimaryKey = append(filtered.PrimaryKey, c)
+		}
+	}
+	filtered.ForeignKeys = nil
+	for _, fk := range detail.ForeignKeys {
+		if allVisible(fk.Columns, visible) && a.foreignKeyTargetAllowed(detail.TableSchema, fk) {
+			filtered.ForeignKeys = append(filtered.ForeignKeys, fk)
+		}
+	}
+	filtered.Indexes = nil
+	for _, idx := range detail.Indexes {
+		if allVisible(idx.Columns, visible) {
+			filtered.Indexes = append(filtered.Indexes, idx)
+		}
+	}
+	return &filtered, nil
+}
+
+// foreignKeyTargetAllowed reports whether the referenced table and columns
+// of a foreign key are visible
+func (a *PolicyAdapter) foreignKeyTargetAllowed(schema string, fk ForeignKeyInfo) bool {
+	if fk.ReferencedSchema != "" {
+		schema = fk.ReferencedSchema
+	}
+	for _, c := range fk.ReferencedColumns {
+		if !a.columnAllowed(schema, fk.ReferencedTable, c) {
+			return false
+		}
+	}
+	return a.policy.CheckTable(schema, fk.ReferencedTable) == nil
+}
+
+func allVisible(columns []string, visible func(string) bool) bool {
+	for _, c := range columns {
+		if !visible(c) {
+			return false
+		}
+	}
+	return true
+}
+
+// ListForeignKeys returns the foreign keys between allowed columns
+func (a *PolicyAdapter) ListForeignKeys(ctx context.Context) ([]ForeignKeyInfo, error) {
+	fks, err := a.Adapter.ListForeignKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	allowed := make([]ForeignKeyInfo, 0, len(fks))
+	for _, fk := range fks {
+		visible := func(column string) bool { return a.columnAllowed(fk.TableSchema, fk.TableName, column) }
+		if allVisible(fk.Columns, visible) && a.foreignKeyTargetAllowed(fk.TableSchema, fk) {
+			allowed = append(allowed, fk)
+		}
+	}
+	return allowed, nil
+}
+
+// ListColumns returns the allowed columns of the allowed tables
+func (a *PolicyAdapter) ListColumns(ctx context.Context) ([]ColumnInfo, error) {
+	columns, err := a.Adapter.ListColumns(ctx)
+	if err != nil {
+		return nil, err
+	}
+	allowed := make([]ColumnInfo, 0, len(columns))
+	for _, c := range columns {
+		if a.columnAllowed(c.TableSchema, c.TableName, c.ColumnName) {
+			allowed = append(allowed, c)
+		}
+	}
+	return allowed, nil
+}
+
+// TableStats returns statistics of an allowed table
+func (a *PolicyAdapter) TableStats(ctx context.Context, tableName string, opts TableStatsOptions) (*TableStats, error) {
+	if _, err := a.allowedTable(ctx, tableName); err != nil {
+		return nil, err
+	}
+	return a.Adapter.TableStats(ctx, tableName, opts)
+}
+
+// ProfileTable profiles the allowed columns of an allowed table
+func (a *PolicyAdapter) ProfileTable(ctx context.Context, tableName string, opts ProfileOptions) (*TableProfile, error) {
+	detail, err := a.allowedTable(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Columns, err = a.allowedColumns(detail, opts.Columns); err != nil {
+		return nil, err
+	}
+	return a.Adapter.ProfileTable(ctx, tableName, opts)
+}
+
+// SampleRows samples the allowed columns of an allowed table
+func (a *PolicyAdapter) SampleRows(ctx context.Context, tableName string, opts SampleOptions) (*SampleResult, error) {
+	detail, err := a.allowedTable(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Columns, err = a.allowedColumns(detail, opts.Columns); err != nil {
+		return nil, err
+	}
+	return a.Adapter.SampleRows(ctx, tableName, opts)
+}
+
+// ExecuteQuery runs a query that reads only allowed objects
+func (a *PolicyAdapter) ExecuteQuery(ctx context.Context, query string, maxRows int) (*QueryResult, error) {
+	if err := a.checkQuery(ctx, query); err != nil {
+		return nil, err
+	}
+	return a.Adapter.ExecuteQuery(ctx, query, maxRows)
+}
+
+// ExplainQuery explains a query that reads only allowed objects
+func (a *PolicyAdapter) ExplainQuery(ctx context.Context, query string, opts ExplainOptions) (*QueryResult, error) {
+	if err := a.checkQuery(ctx, query); err != nil {
+		return nil, err
+	}
+	return a.Adapter.ExplainQuery(ctx, query, opts)
+}
+
+// ExplainQueryJSON explains a query that reads only allowed objects
+func (a *PolicyAdapter) ExplainQueryJSON(ctx context.Context, query string, opts ExplainOptions) ([]byte, error) {
+	if err := a.checkQuery(ctx, query); err != nil {
+		return nil, err
+	}
+	return a.Adapter.ExplainQueryJSON(ctx, query, opts)
+}
+
+// EvaluateHypotheticalIndexes costs indexes for a query that reads only
+// allowed objects
+func (a *PolicyAdapter) EvaluateHypotheticalIndexes(ctx context.Context, query string, statements []string, timeout time.Duration) ([]HypotheticalIndexResult, error) {
+	if err := a.checkQuery(ctx, query); err != nil {
+		return nil, err
+	}
+	return a.Adapter.EvaluateHypotheticalIndexes(ctx, query, statements, timeout)
+}
@@ -0,0 +1,158 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hieubanhh/dbhubMCP/internal/security"
+)
+
+// catalogAdapter serves a fixed schema and records what reaches the database
+type catalogAdapter struct {
+	Adapter
+	tables  map[string][]string // "schema.table" -> columns
+	queries []string
+	sampled []string
+}
+
+func (a *catalogAdapter) GetDBType() string {
+	return "postgres"
+}
+
+func (a *catalogAdapter) ListTables(ctx context.Context) ([]TableInfo, error) {
+	var tables []TableInfo
+	for name := range a.tables {
+		schema, table := SplitTableName(name)
+		tables = append(tables, TableInfo{TableSchema: schema, TableName: table})
+	}
+	return tables, nil
+}
+
+func (a *catalogAdapter) DescribeTableDetailed(ctx context.Context, tableName string) (*TableDetail, error) {
+	schema, table := SplitTableName(tableName)
+	if schema == "" {
+		schema = "public"
+	}
+	columns, ok := a.tables[schema+"."+table]
+	if !ok {
+		return nil, fmt.Errorf("table %s not found", tableName)
+	}
+	detail := &TableDetail{TableInfo: TableInfo{TableSchema: schema, TableName: table}}
+	for _, c := range columns {
+		detail.Columns = append(detail.Columns, ColumnInfo{TableSchema: schema, TableName: table, ColumnName: c})
+	}
+	detail.PrimaryKey = []string{columns[0]}
+	return detail, nil
+}
+
+func (a *catalogAdapter) ExecuteQuery(ctx context.Context, query string, maxRows int) (*QueryResult, error) {
+	a.queries = append(a.queries, query)
+	return &QueryResult{}, nil
+}
+
+func (a *catalogAdapter) SampleRows(ctx context.Context, tableName string, opts SampleOptions) (*SampleResult, error) {
+	a.sampled = opts.Columns
+	return &SampleResult{}, nil
+}
+
+func newPolicyAdapter(t *testing.T) (*PolicyAdapter, *catalogAdapter) {
+	inner := &catalogAdapter{tables: map[string][]string{
+		"public.users":    {"id", "email", "password_hash"},
+		"public.orders":   {"id", "user_id", "total"},
+		"public.payments": {"id", "card_number"},
+		"hr.salaries":     {"id", "amount"},
+	}}
+	policy, err := security.NewPolicy(security.PolicyRules{}, security.PolicyRules{
+		Schemas: []string{"hr"},
+		Tables:  []string{"payments"},
+		Columns: []string{"users.password_hash"},
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+	return NewPolicyAdapter(inner, policy), inner
+}
+
+func TestPolicyAdapter_HidesMetadata(t *testing.T) {
+	adapter, _ := newPolicyAdapter(t)
+	ctx := context.Background()
+
+	tables, err := adapter.ListTables(ctx)
+	if err != nil {
+		t.Fatalf("ListTables failed: %v", err)
+	}
+	for _, tbl := range tables {
+		if tbl.TableName == "payments" || tbl.TableSchema == "hr" {
+			t.Errorf("Expected %s.%s to be hidden", tbl.TableSchema, tbl.TableName)
+		}
+	}
+	if len(tables) != 2 {
+		t.Errorf("Expected 2 visible tables, got %v", tables)
+	}
+
+	detail, err := adapter.DescribeTableDetailed(ctx, "users")
+	if err != nil {
+		t.Fatalf("DescribeTableDetailed failed: %v", err)
+	}
+	for _, c := range detail.Columns {
+		if c.ColumnName == "password_hash" {
+			t.Error("Expected password_hash to be hidden")
+		}
+	}
+
+	var accessErr *security.AccessError
+	if _, err := adapter.DescribeTableDetailed(ctx, "payments"); !errors.As(err, &accessErr) || accessErr.Object != "table public.payments" {
+		t.Errorf("Expected payments to be denied, got %v", err)
+	}
+}
+
+func TestPolicyAdapter_ChecksQueries(t *testing.T) {
+	adapter, inner := newPolicyAdapter(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		query string
+		want  string // expected error substring, empty if allowed
+	}{
+		{"SELECT id, email FROM users", ""},
+		{"SELECT o.total FROM orders o JOIN users u ON u.id = o.user_id", ""},
+		{"SELECT * FROM payments", "table public.payments"},
+		{"SELECT password_hash FROM users", "column public.users.password_hash"},
+		{"SELECT * FROM users", "column public.users.password_hash"},
+		{"SELECT amount FROM hr.salaries", "schema hr"},
+	}
+	for _, tt := range tests {
+		_, err := adapter.ExecuteQuery(ctx, tt.query, 10)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: expected success, got %v", tt.query, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error naming %q, got %v", tt.query, tt.want, err)
+		}
+	}
+	if len(inner.queries) != 2 {
+		t.Errorf("Expected only allowed queries to run, got %v", inner.queries)
+	}
+}
+
+func TestPolicyAdapter_SampleRowsColumns(t *testing.T) {
+	adapter, inner := newPolicyAdapter(t)
+	ctx := context.Background()
+
+	if _, err := adapter.SampleRows(ctx, "users", SampleOptions{}); err != nil {
+		t.Fatalf("SampleRows failed: %v", err)
+	}
+	if strings.Join(inner.sampled, ",") != "id,email" {
+		t.Errorf("Expected only the allowed columns to be sampled, got %v", inner.sampled)
+	}
+
+	if _, err := adapter.SampleRows(ctx, "users", SampleOptions{Columns: []string{"password_hash"}}); err == nil {
+		t.Error("Expected sampling a denied column to fail")
+	}
+}
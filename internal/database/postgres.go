@@ -3,83 +3,82 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 // PostgresAdapter implements the Adapter interface for PostgreSQL
 type PostgresAdapter struct {
-	db             *sql.DB
-	host           string
-	port           int
-	dbName         string
-	user           string
-	password       string
-	maxConns       int
-	maxIdleConns   int
-	connTimeout    time.Duration
+	pool rotatingPool
+	opts ConnectionOptions
 }
 
 // NewPostgresAdapter creates a new PostgreSQL adapter
-func NewPostgresAdapter(host string, port int, dbName, user, password string, maxConns, maxIdleConns int, connTimeout time.Duration) *PostgresAdapter {
-	return &PostgresAdapter{
-		host:         host,
-		port:         port,
-		dbName:       dbName,
-		user:         user,
-		password:     password,
-		maxConns:     maxConns,
-		maxIdleConns: maxIdleConns,
-		connTimeout:  connTimeout,
-	}
+func NewPostgresAdapter(opts ConnectionOptions) *PostgresAdapter {
+	return &PostgresAdapter{opts: opts}
 }
 
 // Connect establishes a connection to PostgreSQL
 func (a *PostgresAdapter) Connect(ctx context.Context) error {
-	// Build connection string
-	// format: host=localhost port=5432 user=myuser password=mypass dbname=mydb sslmode=disable
-	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=disable connect_timeout=%d",
-		a.host, a.port, a.user, a.password, a.dbName, int(a.connTimeout.Seconds()))
+	return a.pool.open(ctx, a.opts, a.open)
+}
 
-	db, err := sql.Open("postgres", connStr)
+// RefreshCredentials reopens the pool if the password secret has rotated
+func (a *PostgresAdapter) RefreshCredentials(ctx context.Context) (bool, error) {
+	return a.pool.refresh(ctx, a.opts, a.open)
+}
+
+// open creates a verified connection pool using the given password
+func (a *PostgresAdapter) open(ctx context.Context, password string) (*sql.DB, error) {
+	opts := a.opts
+	opts.Password = password
+	connStr, dialAddr, err := postgresConnString(opts)
 	if err != nil {
-		return fmt.Errorf("failed to open PostgreSQL connection: %w", err)
+		return nil, fmt.Errorf("invalid PostgreSQL connection settings: %w", err)
 	}
 
+	connector, err := pq.NewConnector(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open PostgreSQL connection: %w", err)
+	}
+	if dialAddr != "" {
+		connector.Dialer(fixedDialer{addr: dialAddr})
+	}
+	db := sql.OpenDB(connector)
+
 	// Configure connection pool
-	db.SetMaxOpenConns(a.maxConns)
-	db.SetMaxIdleConns(a.maxIdleConns)
+	db.SetMaxOpenConns(a.opts.MaxConns)
+	db.SetMaxIdleConns(a.opts.MaxIdleConns)
 	db.SetConnMaxLifetime(time.Hour)
 
 	// Test connection
-	ctx, cancel := context.WithTimeout(ctx, a.connTimeout)
+	ctx, cancel := context.WithTimeout(ctx, a.opts.ConnTimeout)
 	defer cancel()
 
 	if err := db.PingContext(ctx); err != nil {
 		db.Close()
-		return fmt.Errorf("failed to ping PostgreSQL: %w", err)
+		return nil, fmt.Errorf("failed to ping PostgreSQL: %w", err)
 	}
 
-	a.db = db
-	return nil
+	return db, nil
 }
 
 // Close closes the PostgreSQL connection
 func (a *PostgresAdapter) Close() error {
-	if a.db != nil {
-		return a.db.Close()
-	}
-	return nil
+	return a.pool.close()
 }
 
 // Ping checks if the database connection is alive
 func (a *PostgresAdapter) Ping(ctx context.Context) error {
-	if a.db == nil {
+	db := a.pool.get()
+	if db == nil {
 		return fmt.Errorf("database not connected")
 	}
-	return a.db.PingContext(ctx)
+	return db.PingContext(ctx)
 }
 
 // ListTables returns all tables in the PostgreSQL database
@@ -94,7 +93,7 @@ func (a *PostgresAdapter) ListTables(ctx context.Context) ([]TableInfo, error) {
 		ORDER BY table_name
 	`
 
-	rows, err := a.db.QueryContext(ctx, query)
+	rows, err := a.pool.get().QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list tables: %w", err)
 	}
@@ -132,7 +131,7 @@ func (a *PostgresAdapter) DescribeTable(ctx context.Context, tableName string) (
 		ORDER BY ordinal_position
 	`
 
-	rows, err := a.db.QueryContext(ctx, query, tableName)
+	rows, err := a.pool.get().QueryContext(ctx, query, tableName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to describe table: %w", err)
 	}
@@ -158,9 +157,588 @@ func (a *PostgresAdapter) DescribeTable(ctx context.Context, tableName string) (
 	return columns, nil
 }
 
+// ListColumns returns the columns of every table outside the system schemas
+func (a *PostgresAdapter) ListColumns(ctx context.Context) ([]ColumnInfo, error) {
+	query := `
+		SELECT
+			table_schema,
+			table_name,
+			column_name,
+			data_type,
+			is_nullable,
+			COALESCE(column_default, '') as column_default
+		FROM information_schema.columns
+		WHERE table_schema NOT IN ('pg_catalog', 'information_schema')
+		ORDER BY table_schema, table_name, ordinal_position
+	`
+
+	rows, err := a.pool.get().QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []ColumnInfo
+	for rows.Next() {
+		var col ColumnInfo
+		if err := rows.Scan(&col.TableSchema, &col.TableName, &col.ColumnName, &col.DataType, &col.IsNullable, &col.ColumnDefault); err != nil {
+			return nil, fmt.Errorf("failed to scan column info: %w", err)
+		}
+		columns = append(columns, col)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating columns: %w", err)
+	}
+
+	return columns, nil
+}
+
+// SchemaFingerprint summarizes the user catalog rows. Any DDL writes new
+// pg_class, pg_attribute or pg_constraint row versions, which changes the
+// count or the newest xmin.
+func (a *PostgresAdapter) SchemaFingerprint(ctx context.Context) (string, error) {
+	query := `
+		SELECT concat_ws(':',
+			(SELECT count(*) || '/' || COALESCE(max(c.xmin::text::bigint), 0)
+				FROM pg_catalog.pg_class c
+				JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+				WHERE n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg_toast%'),
+			(SELECT count(*) || '/' || COALESCE(max(a.xmin::text::bigint), 0)
+				FROM pg_catalog.pg_attribute a
+				JOIN pg_catalog.pg_class c ON c.oid = a.attrelid
+				JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+				WHERE n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg_toast%'
+					AND a.attnum > 0),
+			(SELECT count(*) || '/' || COALESCE(max(con.xmin::text::bigint), 0)
+				FROM pg_catalog.pg_constraint con)
+		)
+	`
+
+	var fingerprint string
+	if err := a.pool.get().QueryRowContext(ctx, query).Scan(&fingerprint); err != nil {
+		return "", fmt.Errorf("failed to read schema fingerprint: %w", err)
+	}
+	return fingerprint, nil
+}
+
+// DescribeTableDetailed returns the full definition of a PostgreSQL table
+func (a *PostgresAdapter) DescribeTableDetailed(ctx context.Context, tableName string) (*TableDetail, error) {
+	schema, table := SplitTableName(tableName)
+
+	// Resolve the schema when the name is unqualified, preferring the search path
+	var (
+		oid     int64
+		relKind string
+	)
+	query := `
+		SELECT n.nspname, c.oid, c.relkind, COALESCE(obj_description(c.oid, 'pg_class'), '')
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE c.relname = $1
+			AND c.relkind IN ('r', 'p', 'v', 'm', 'f')
+			AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+			AND ($2::text = '' OR n.nspname::text = $2::text)
+		ORDER BY (n.nspname = current_schema()) DESC, n.nspname
+		LIMIT 1
+	`
+	detail := &TableDetail{}
+	err := a.pool.get().QueryRowContext(ctx, query, table, schema).Scan(&detail.TableSchema, &oid, &relKind, &detail.Comment)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("table not found: %s", tableName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe table: %w", err)
+	}
+	detail.TableName = table
+	detail.TableType = postgresRelKinds[relKind]
+
+	if detail.Columns, err = a.detailedColumns(ctx, oid); err != nil {
+		return nil, err
+	}
+	if detail.Indexes, err = a.indexes(ctx, oid); err != nil {
+		return nil, err
+	}
+	for _, idx := range detail.Indexes {
+		if idx.IsPrimary {
+			detail.PrimaryKey = idx.Columns
+		}
+	}
+	if detail.ForeignKeys, err = a.foreignKeys(ctx, oid); err != nil {
+		return nil, err
+	}
+
+	if relKind == "v" || relKind == "m" {
+		if err := a.pool.get().QueryRowContext(ctx, "SELECT pg_get_viewdef($1::oid, true)", oid).Scan(&detail.ViewDefinition); err != nil {
+			return nil, fmt.Errorf("failed to read view definition: %w", err)
+		}
+	}
+
+	return detail, nil
+}
+
+// postgresRelKinds maps pg_class.relkind to information_schema style table types
+var postgresRelKinds = map[string]string{
+	"r": "BASE TABLE",
+	"p": "BASE TABLE",
+	"v": "VIEW",
+	"m": "MATERIALIZED VIEW",
+	"f": "FOREIGN",
+}
+
+// postgresFKActions maps pg_constraint action codes to SQL keywords
+var postgresFKActions = map[string]string{
+	"a": "NO ACTION",
+	"r": "RESTRICT",
+	"c": "CASCADE",
+	"n": "SET NULL",
+	"d": "SET DEFAULT",
+}
+
+func (a *PostgresAdapter) detailedColumns(ctx context.Context, oid int64) ([]ColumnInfo, error) {
+	query := `
+		SELECT
+			a.attname,
+			COALESCE(c.data_type, format_type(a.atttypid, NULL)),
+			CASE WHEN a.attnotnull THEN 'NO' ELSE 'YES' END,
+			COALESCE(pg_get_expr(d.adbin, d.adrelid), ''),
+			format_type(a.atttypid, a.atttypmod),
+			COALESCE(col_description(a.attrelid, a.attnum), '')
+		FROM pg_catalog.pg_attribute a
+		JOIN pg_catalog.pg_class cl ON cl.oid = a.attrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = cl.relnamespace
+		LEFT JOIN pg_catalog.pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		LEFT JOIN information_schema.columns c
+			ON c.table_schema = n.nspname AND c.table_name = cl.relname AND c.column_name = a.attname
+		WHERE a.attrelid = $1::oid AND a.attnum > 0 AND NOT a.attisdropped
+		ORDER BY a.attnum
+	`
+
+	rows, err := a.pool.get().QueryContext(ctx, query, oid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe columns: %w", err)
+	}
+	defer rows.Close()
+
+	var columns []ColumnInfo
+	for rows.Next() {
+		var col ColumnInfo
+		if err := rows.Scan(&col.ColumnName, &col.DataType, &col.IsNullable, &col.ColumnDefault, &col.ColumnType, &col.Comment); err != nil {
+			return nil, fmt.Errorf("failed to scan column info: %w", err)
+		}
+		columns = append(columns, col)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating columns: %w", err)
+	}
+
+	return columns, nil
+}
+
+func (a *PostgresAdapter) indexes(ctx context.Context, oid int64) ([]IndexInfo, error) {
+	query := `
+		SELECT
+			ic.relname,
+			i.indisunique,
+			i.indisprimary,
+			am.amname,
+			ARRAY(
+				SELECT pg_get_indexdef(i.indexrelid, k, true)
+				FROM generate_series(1, i.indnatts) AS k
+				ORDER BY k
+			)
+		FROM pg_catalog.pg_index i
+		JOIN pg_catalog.pg_class ic ON ic.oid = i.indexrelid
+		JOIN pg_catalog.pg_am am ON am.oid = ic.relam
+		WHERE i.indrelid = $1::oid
+		ORDER BY i.indisprimary DESC, ic.relname
+	`
+
+	rows, err := a.pool.get().QueryContext(ctx, query, oid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+	defer rows.Close()
+
+	var indexes []IndexInfo
+	for rows.Next() {
+		var idx IndexInfo
+		if err := rows.Scan(&idx.IndexName, &idx.IsUnique, &idx.IsPrimary, &idx.IndexType, pq.Array(&idx.Columns)); err != nil {
+			return nil, fmt.Errorf("failed to scan index info: %w", err)
+		}
+		indexes = append(indexes, idx)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating indexes: %w", err)
+	}
+
+	return indexes, nil
+}
+
+// ListForeignKeys returns every foreign key outside the system schemas
+func (a *PostgresAdapter) ListForeignKeys(ctx context.Context) ([]ForeignKeyInfo, error) {
+	return a.queryForeignKeys(ctx, "n.nspname NOT IN ('pg_catalog', 'information_schema')")
+}
+
+func (a *PostgresAdapter) foreignKeys(ctx context.Context, oid int64) ([]ForeignKeyInfo, error) {
+	fks, err := a.queryForeignKeys(ctx, "con.conrelid = $1::oid", oid)
+	for i := range fks {
+		fks[i].TableSchema, fks[i].TableName = "", ""
+	}
+	return fks, err
+}
+
+// queryForeignKeys lists foreign key constraints matching the where clause
+func (a *PostgresAdapter) queryForeignKeys(ctx context.Context, where string, args ...interface{}) ([]ForeignKeyInfo, error) {
+	query := `
+		SELECT
+			n.nspname,
+			c.relname,
+			con.conname,
+			nf.nspname,
+			cf.relname,
+			ARRAY(
+				SELECT att.attname
+				FROM unnest(con.conkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_catalog.pg_attribute att ON att.attrelid = con.conrelid AND att.attnum = k.attnum
+				ORDER BY k.ord
+			),
+			ARRAY(
+				SELECT att.attname
+				FROM unnest(con.confkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_catalog.pg_attribute att ON att.attrelid = con.confrelid AND att.attnum = k.attnum
+				ORDER BY k.ord
+			),
+			con.confupdtype,
+			con.confdeltype
+		FROM pg_catalog.pg_constraint con
+		JOIN pg_catalog.pg_class c ON c.oid = con.conrelid
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		JOIN pg_catalog.pg_class cf ON cf.oid = con.confrelid
+		JOIN pg_catalog.pg_namespace nf ON nf.oid = cf.relnamespace
+		WHERE con.contype = 'f' AND ` + where + `
+		ORDER BY n.nspname, c.relname, con.conname
+	`
+
+	rows, err := a.pool.get().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list foreign keys: %w", err)
+	}
+	defer rows.Close()
+
+	var fks []ForeignKeyInfo
+	for rows.Next() {
+		var fk ForeignKeyInfo
+		var onUpdate, onDelete string
+		if err := rows.Scan(&fk.TableSchema, &fk.TableName, &fk.ConstraintName, &fk.ReferencedSchema, &fk.ReferencedTable,
+			pq.Array(&fk.Columns), pq.Array(&fk.ReferencedColumns), &onUpdate, &onDelete); err != nil {
+			return nil, fmt.Errorf("failed to scan foreign key info: %w", err)
+		}
+		fk.OnUpdate = postgresFKActions[onUpdate]
+		fk.OnDelete = postgresFKActions[onDelete]
+		fks = append(fks, fk)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating foreign keys: %w", err)
+	}
+
+	return fks, nil
+}
+
+// postgresProfileDialect is the SQL flavour used when profiling PostgreSQL tables
+var postgresProfileDialect = profileDialect{
+	dbType:     "postgres",
+	lengthFunc: "length",
+	castText:   func(expr string) string { return "(" + expr + ")::text" },
+}
+
+// ProfileTable computes column statistics for a PostgreSQL table. Tables with
+// more rows than opts.SampleRows are sampled with TABLESAMPLE SYSTEM.
+func (a *PostgresAdapter) ProfileTable(ctx context.Context, tableName string, opts ProfileOptions) (*TableProfile, error) {
+	detail, err := a.DescribeTableDetailed(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := selectColumns(detail.Columns, opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := a.readOnlyTx(ctx, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	profile := &TableProfile{TableName: detail.TableSchema + "." + detail.TableName}
+	err = tx.QueryRowContext(ctx, `
+		SELECT GREATEST(c.reltuples, 0)::bigint
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relname = $2
+	`, detail.TableSchema, detail.TableName).Scan(&profile.EstimatedRows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate row count: %w", err)
+	}
+
+	name := QuoteQualifiedName("postgres", detail.TableSchema, detail.TableName)
+	source := name
+	if opts.SampleRows > 0 {
+		if profile.EstimatedRows > int64(opts.SampleRows) && detail.TableType == "BASE TABLE" {
+			// SYSTEM samples whole pages; ask for a little extra and trim with LIMIT
+			pct := float64(opts.SampleRows) / float64(profile.EstimatedRows) * 100 * 1.2
+			if pct > 100 {
+				pct = 100
+			}
+			source = fmt.Sprintf("(SELECT * FROM %s TABLESAMPLE SYSTEM (%.6f) REPEATABLE (42) LIMIT %d) AS profile_sample",
+				name, pct, opts.SampleRows)
+			profile.Sampled = true
+			profile.SampleMethod = "TABLESAMPLE SYSTEM"
+		} else {
+			source = fmt.Sprintf("(SELECT * FROM %s LIMIT %d) AS profile_sample", name, opts.SampleRows)
+		}
+	}
+
+	var estimates map[string]int64
+	if opts.ApproximateDistinct {
+		if estimates, err = a.distinctEstimates(ctx, tx, detail.TableSchema, detail.TableName, profile.EstimatedRows); err != nil {
+			return nil, err
+		}
+	}
+
+	profile.RowsProfiled, profile.Columns, err = profileColumns(ctx, tx, postgresProfileDialect, source, columns, opts, estimates)
+	if err != nil {
+		return nil, err
+	}
+	if !profile.Sampled && opts.SampleRows > 0 && profile.RowsProfiled >= int64(opts.SampleRows) {
+		profile.Sampled = true
+		profile.SampleMethod = "first rows"
+	}
+
+	return profile, nil
+}
+
+// distinctEstimates reads planner n_distinct statistics. Negative values are
+// a fraction of the row count.
+func (a *PostgresAdapter) distinctEstimates(ctx context.Context, tx *sql.Tx, schema, table string, rowCount int64) (map[string]int64, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT attname,
+			CASE WHEN n_distinct < 0 THEN (-n_distinct * $3)::bigint ELSE n_distinct::bigint END
+		FROM pg_catalog.pg_stats
+		WHERE schemaname = $1 AND tablename = $2
+	`, schema, table, rowCount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read column statistics: %w", err)
+	}
+	defer rows.Close()
+
+	estimates := make(map[string]int64)
+	for rows.Next() {
+		var column string
+		var distinct int64
+		if err := rows.Scan(&column, &distinct); err != nil {
+			return nil, fmt.Errorf("failed to scan column statistics: %w", err)
+		}
+		estimates[column] = distinct
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating column statistics: %w", err)
+	}
+
+	return estimates, nil
+}
+
+// postgresTableStatsQuery selects the statistics scanned by queryTableStats;
+// callers append further conditions and ordering
+const postgresTableStatsQuery = `
+	SELECT n.nspname, c.relname, c.relkind,
+		GREATEST(c.reltuples, 0)::bigint,
+		pg_table_size(c.oid), pg_indexes_size(c.oid), pg_total_relation_size(c.oid),
+		s.last_analyze, s.last_autoanalyze, s.last_vacuum, s.last_autovacuum
+	FROM pg_catalog.pg_class c
+	JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+	LEFT JOIN pg_catalog.pg_stat_all_tables s ON s.relid = c.oid
+	WHERE c.relkind IN ('r', 'p', 'v', 'm', 'f')
+		AND n.nspname NOT IN ('pg_catalog', 'information_schema')
+		AND n.nspname NOT LIKE 'pg_toast%'
+`
+
+// ListTableStats returns row estimates and sizes for every PostgreSQL table
+func (a *PostgresAdapter) ListTableStats(ctx context.Context) ([]TableStats, error) {
+	return a.queryTableStats(ctx, postgresTableStatsQuery+" ORDER BY c.relname")
+}
+
+// TableStats returns statistics for a PostgreSQL table. The exact count runs
+// in a read-only transaction under opts.Timeout.
+func (a *PostgresAdapter) TableStats(ctx context.Context, tableName string, opts TableStatsOptions) (*TableStats, error) {
+	schema, table := SplitTableName(tableName)
+	stats, err := a.queryTableStats(ctx, postgresTableStatsQuery+`
+		AND c.relname = $1
+		AND ($2::text = '' OR n.nspname::text = $2::text)
+		ORDER BY (n.nspname = current_schema()) DESC, n.nspname
+		LIMIT 1
+	`, table, schema)
+	if err != nil {
+		return nil, err
+	}
+	if len(stats) == 0 {
+		return nil, fmt.Errorf("table not found: %s", tableName)
+	}
+	result := &stats[0]
+
+	if opts.ExactCount {
+		tx, err := a.readOnlyTx(ctx, opts.Timeout)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+
+		var count int64
+		name := QuoteQualifiedName("postgres", result.TableSchema, result.TableName)
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+name).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to count rows: %w", err)
+		}
+		result.ExactRows = &count
+	}
+
+	return result, nil
+}
+
+func (a *PostgresAdapter) queryTableStats(ctx context.Context, query string, args ...interface{}) ([]TableStats, error) {
+	rows, err := a.pool.get().QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read table statistics: %w", err)
+	}
+	defer rows.Close()
+
+	var stats []TableStats
+	for rows.Next() {
+		var (
+			t                                        TableStats
+			relKind                                  string
+			analyze, autoAnalyze, vacuum, autoVacuum sql.NullTime
+		)
+		if err := rows.Scan(&t.TableSchema, &t.TableName, &relKind, &t.EstimatedRows,
+			&t.TableBytes, &t.IndexBytes, &t.TotalBytes,
+			&analyze, &autoAnalyze, &vacuum, &autoVacuum); err != nil {
+			return nil, fmt.Errorf("failed to scan table statistics: %w", err)
+		}
+		t.TableType = postgresRelKinds[relKind]
+		t.TotalSize = FormatBytes(t.TotalBytes)
+		t.LastAnalyze = nullTimePtr(analyze)
+		t.LastAutoAnalyze = nullTimePtr(autoAnalyze)
+		t.LastVacuum = nullTimePtr(vacuum)
+		t.LastAutoVacuum = nullTimePtr(autoVacuum)
+		stats = append(stats, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating table statistics: %w", err)
+	}
+
+	return stats, nil
+}
+
+// SampleRows returns a random sample of a PostgreSQL table. Small tables are
+// shuffled with a seeded random(); larger ones use TABLESAMPLE BERNOULLI, or
+// SYSTEM once a row-level scan would be too slow.
+func (a *PostgresAdapter) SampleRows(ctx context.Context, tableName string, opts SampleOptions) (*SampleResult, error) {
+	detail, err := a.DescribeTableDetailed(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+	columns, err := selectColumns(detail.Columns, opts.Columns)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := a.readOnlyTx(ctx, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	sample := &SampleResult{TableName: detail.TableSchema + "." + detail.TableName, Seed: opts.Seed}
+	err = tx.QueryRowContext(ctx, `
+		SELECT GREATEST(c.reltuples, 0)::bigint
+		FROM pg_catalog.pg_class c
+		JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = $1 AND c.relname = $2
+	`, detail.TableSchema, detail.TableName).Scan(&sample.EstimatedRows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate row count: %w", err)
+	}
+
+	name := QuoteQualifiedName("postgres", detail.TableSchema, detail.TableName)
+	list := selectList("postgres", columns)
+	var query string
+	if sample.EstimatedRows <= smallTableRows || detail.TableType != "BASE TABLE" {
+		// setseed takes a value in [-1, 1] and makes random() repeatable for the session
+		if _, err := tx.ExecContext(ctx, "SELECT setseed($1)", float64(opts.Seed%1000000)/1000000); err != nil {
+			return nil, fmt.Errorf("failed to seed random sample: %w", err)
+		}
+		query = fmt.Sprintf("SELECT %s FROM %s ORDER BY random() LIMIT %d", list, name, opts.N)
+		sample.SampleMethod = "ORDER BY random()"
+	} else {
+		method := "BERNOULLI"
+		if sample.EstimatedRows > 100*smallTableRows {
+			method = "SYSTEM"
+		}
+		query = fmt.Sprintf("SELECT %s FROM %s TABLESAMPLE %s (%.6f) REPEATABLE (%d) LIMIT %d",
+			list, name, method, sampleFraction(opts.N, sample.EstimatedRows)*100, opts.Seed, opts.N)
+		sample.SampleMethod = "TABLESAMPLE " + method
+	}
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample rows: %w", err)
+	}
+	defer rows.Close()
+
+	result, err := rowsToResult(rows, opts.N)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sample: %w", err)
+	}
+	sample.QueryResult = *result
+	sample.TruncatedCells = truncateCells(&sample.QueryResult, opts.MaxCellLength)
+
+	return sample, nil
+}
+
+// txBeginner is implemented by *sql.DB and *sql.Conn
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// readOnlyTx begins a read-only transaction whose statements are cancelled by
+// the server after timeout. Callers must roll it back.
+func (a *PostgresAdapter) readOnlyTx(ctx context.Context, timeout time.Duration) (*sql.Tx, error) {
+	return postgresReadOnlyTx(ctx, a.pool.get(), timeout)
+}
+
+func postgresReadOnlyTx(ctx context.Context, db txBeginner, timeout time.Duration) (*sql.Tx, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin read-only transaction: %w", err)
+	}
+
+	if timeout > 0 {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+			tx.Rollback()
+			return nil, fmt.Errorf("failed to set statement timeout: %w", err)
+		}
+	}
+
+	return tx, nil
+}
+
 // ExecuteQuery executes a read-only query on PostgreSQL
 func (a *PostgresAdapter) ExecuteQuery(ctx context.Context, query string, maxRows int) (*QueryResult, error) {
-	rows, err := a.db.QueryContext(ctx, query)
+	rows, err := a.pool.get().QueryContext(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -169,11 +747,22 @@ func (a *PostgresAdapter) ExecuteQuery(ctx context.Context, query string, maxRow
 	return rowsToResult(rows, maxRows)
 }
 
-// ExplainQuery returns the execution plan for a PostgreSQL query
-func (a *PostgresAdapter) ExplainQuery(ctx context.Context, query string) (*QueryResult, error) {
+// ExplainQuery returns the execution plan for a PostgreSQL query. With
+// opts.Analyze the query is executed inside a read-only transaction that is
+// rolled back afterwards.
+func (a *PostgresAdapter) ExplainQuery(ctx context.Context, query string, opts ExplainOptions) (*QueryResult, error) {
 	explainQuery := fmt.Sprintf("EXPLAIN %s", query)
+	if opts.Analyze {
+		explainQuery = fmt.Sprintf("EXPLAIN (ANALYZE, BUFFERS) %s", query)
+	}
+
+	tx, err := a.readOnlyTx(ctx, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
 
-	rows, err := a.db.QueryContext(ctx, explainQuery)
+	rows, err := tx.QueryContext(ctx, explainQuery)
 	if err != nil {
 		return nil, fmt.Errorf("failed to explain query: %w", err)
 	}
@@ -182,6 +771,107 @@ func (a *PostgresAdapter) ExplainQuery(ctx context.Context, query string) (*Quer
 	return rowsToResult(rows, 1000) // EXPLAIN results are typically small
 }
 
+// ExplainQueryJSON returns the execution plan for a PostgreSQL query as JSON
+func (a *PostgresAdapter) ExplainQueryJSON(ctx context.Context, query string, opts ExplainOptions) ([]byte, error) {
+	explainQuery := fmt.Sprintf("EXPLAIN (FORMAT JSON) %s", query)
+	if opts.Analyze {
+		explainQuery = fmt.Sprintf("EXPLAIN (ANALYZE, BUFFERS, FORMAT JSON) %s", query)
+	}
+
+	tx, err := a.readOnlyTx(ctx, opts.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var plan []byte
+	if err := tx.QueryRowContext(ctx, explainQuery).Scan(&plan); err != nil {
+		return nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+	return plan, nil
+}
+
+// EvaluateHypotheticalIndexes costs a query with each index created by the
+// hypopg extension. Hypothetical indexes live in the session rather than the
+// transaction, so the work is pinned to one connection that is reset before
+// it returns to the pool.
+func (a *PostgresAdapter) EvaluateHypotheticalIndexes(ctx context.Context, query string, statements []string, timeout time.Duration) ([]HypotheticalIndexResult, error) {
+	conn, err := a.pool.get().Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	// Deferred before the rollback so it runs after it; rolling back does not
+	// discard hypothetical indexes
+	var installed bool
+	defer func() {
+		if installed {
+			conn.ExecContext(context.Background(), "SELECT hypopg_reset()")
+		}
+	}()
+
+	tx, err := postgresReadOnlyTx(ctx, conn, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_catalog.pg_extension WHERE extname = 'hypopg')").Scan(&installed); err != nil {
+		return nil, fmt.Errorf("failed to check for hypopg: %w", err)
+	}
+	if !installed {
+		return nil, fmt.Errorf("the hypopg extension is not installed")
+	}
+
+	explainCost := func() (float64, string, error) {
+		var raw string
+		if err := tx.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query).Scan(&raw); err != nil {
+			return 0, "", fmt.Errorf("failed to explain query: %w", err)
+		}
+		var out []struct {
+			Plan struct {
+				TotalCost float64 `json:"Total Cost"`
+			} `json:"Plan"`
+		}
+		if err := json.Unmarshal([]byte(raw), &out); err != nil || len(out) == 0 {
+			return 0, "", fmt.Errorf("failed to parse query plan: %v", err)
+		}
+		return out[0].Plan.TotalCost, raw, nil
+	}
+
+	before, _, err := explainCost()
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]HypotheticalIndexResult, 0, len(statements))
+	for _, stmt := range statements {
+		var indexName string
+		if err := tx.QueryRowContext(ctx, "SELECT indexname FROM hypopg_create_index($1)", stmt).Scan(&indexName); err != nil {
+			return nil, fmt.Errorf("failed to create hypothetical index: %w", err)
+		}
+
+		after, raw, err := explainCost()
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, HypotheticalIndexResult{
+			Statement:  stmt,
+			CostBefore: before,
+			CostAfter:  after,
+			Used:       strings.Contains(raw, indexName),
+		})
+
+		// Evaluate each index on its own
+		if _, err := tx.ExecContext(ctx, "SELECT hypopg_reset()"); err != nil {
+			return nil, fmt.Errorf("failed to reset hypothetical indexes: %w", err)
+		}
+	}
+
+	return results, nil
+}
+
 // GetDBType returns the database type
 func (a *PostgresAdapter) GetDBType() string {
 	return "postgres"
@@ -0,0 +1,263 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/security"
+)
+
+// ProfileOptions controls how a table is profiled
+type ProfileOptions struct {
+	Columns             []string      // columns to profile; empty means all
+	SampleRows          int           // sample size for large tables; 0 profiles every row
+	TopN                int           // number of most frequent values per column
+	ApproximateDistinct bool          // use planner statistics instead of COUNT(DISTINCT)
+	Timeout             time.Duration // statement timeout enforced by the database
+}
+
+// ValueCount is a value and how often it occurs
+type ValueCount struct {
+	Value *string `json:"value"`
+	Count int64   `json:"count"`
+}
+
+// ColumnProfile summarizes the values of one column
+type ColumnProfile struct {
+	ColumnName     string       `json:"column_name"`
+	DataType       string       `json:"data_type"`
+	NullCount      int64        `json:"null_count"`
+	NullRatio      float64      `json:"null_ratio"`
+	DistinctCount  *int64       `json:"distinct_count,omitempty"`
+	DistinctMethod string       `json:"distinct_method,omitempty"`
+	Min            *string      `json:"min,omitempty"`
+	Max            *string      `json:"max,omitempty"`
+	MinLength      *int64       `json:"min_length,omitempty"`
+	MaxLength      *int64       `json:"max_length,omitempty"`
+	AvgLength      *float64     `json:"avg_length,omitempty"`
+	TopValues      []ValueCount `json:"top_values,omitempty"`
+}
+
+// TableProfile is the result of profiling a table
+type TableProfile struct {
+	TableName     string          `json:"table_name"`
+	EstimatedRows int64           `json:"estimated_rows"`
+	RowsProfiled  int64           `json:"rows_profiled"`
+	Sampled       bool            `json:"sampled"`
+	SampleMethod  string          `json:"sample_method,omitempty"`
+	Columns       []ColumnProfile `json:"columns"`
+
+	MaskedColumns []security.MaskedColumn `json:"masked_columns,omitempty"`
+}
+
+// columnKind groups data types by the statistics that make sense for them
+type columnKind int
+
+const (
+	kindOther   columnKind = iota // equality only: distinct and top values
+	kindString                    // ordered, with length statistics
+	kindOrdered                   // numeric and temporal: min/max
+	kindOpaque                    // blobs, JSON, arrays, geometry: nulls only
+)
+
+func classifyColumn(dataType string) columnKind {
+	t := strings.ToLower(dataType)
+	switch {
+	case strings.Contains(t, "blob"), strings.Contains(t, "bytea"), strings.Contains(t, "binary"),
+		strings.Contains(t, "json"), strings.Contains(t, "xml"), t == "array", t == "user-defined",
+		strings.Contains(t, "geometry"), strings.Contains(t, "point"), strings.Contains(t, "polygon"),
+		strings.HasSuffix(t, "[]"), strings.Contains(t, "tsvector"):
+		return kindOpaque
+	case strings.Contains(t, "char"), strings.Contains(t, "text"), t == "enum", t == "set", t == "name", t == "citext":
+		return kindString
+	case strings.Contains(t, "int"), strings.Contains(t, "numeric"), strings.Contains(t, "decimal"),
+		strings.Contains(t, "real"), strings.Contains(t, "double"), strings.Contains(t, "float"),
+		strings.Contains(t, "date"), strings.Contains(t, "time"), t == "year", t == "money", t == "interval":
+		return kindOrdered
+	default:
+		return kindOther
+	}
+}
+
+// profileDialect captures the SQL differences needed by profileColumns
+type profileDialect struct {
+	dbType     string
+	lengthFunc string
+	castText   func(expr string) string
+	selectHint string // inserted after SELECT, e.g. a MySQL optimizer hint
+}
+
+// selectColumns resolves the requested columns against the table definition
+func selectColumns(columns []ColumnInfo, requested []string) ([]ColumnInfo, error) {
+	if len(requested) == 0 {
+		return columns, nil
+	}
+
+	byName := make(map[string]ColumnInfo, len(columns))
+	for _, c := range columns {
+		byName[c.ColumnName] = c
+	}
+
+	selected := make([]ColumnInfo, 0, len(requested))
+	for _, name := range requested {
+		c, ok := byName[name]
+		if !ok {
+			return nil, fmt.Errorf("column not found: %s", name)
+		}
+		selected = append(selected, c)
+	}
+	return selected, nil
+}
+
+// profileColumns computes column statistics over source, which is a table
+// name or a parenthesized sample subquery with an alias
+func profileColumns(ctx context.Context, tx *sql.Tx, d profileDialect, source string, columns []ColumnInfo, opts ProfileOptions, estimates map[string]int64) (int64, []ColumnProfile, error) {
+	exprs := []string{"COUNT(*)"}
+	type target struct {
+		column int
+		dest   interface{}
+		assign func(p *ColumnProfile)
+	}
+	var targets []target
+
+	profiles := make([]ColumnProfile, len(columns))
+	nonNull := make([]int64, len(columns))
+	for i, c := range columns {
+		profiles[i] = ColumnProfile{ColumnName: c.ColumnName, DataType: c.DataType}
+		col := QuoteIdentifier(d.dbType, c.ColumnName)
+		kind := classifyColumn(c.DataType)
+
+		exprs = append(exprs, fmt.Sprintf("COUNT(%s)", col))
+		targets = append(targets, target{i, &nonNull[i], nil})
+
+		if kind == kindOpaque {
+			continue
+		}
+
+		if est, ok := estimates[c.ColumnName]; ok && opts.ApproximateDistinct {
+			v := est
+			profiles[i].DistinctCount = &v
+			profiles[i].DistinctMethod = "estimate"
+		} else {
+			distinct := new(int64)
+			exprs = append(exprs, fmt.Sprintf("COUNT(DISTINCT %s)", col))
+			targets = append(targets, target{i, distinct, func(p *ColumnProfile) {
+				p.DistinctCount = distinct
+				p.DistinctMethod = "exact"
+			}})
+		}
+
+		if kind == kindString || kind == kindOrdered {
+			minVal, maxVal := new(sql.NullString), new(sql.NullString)
+			exprs = append(exprs, d.castText("MIN("+col+")"), d.castText("MAX("+col+")"))
+			targets = append(targets,
+				target{i, minVal, func(p *ColumnProfile) { p.Min = nullStringPtr(*minVal) }},
+				target{i, maxVal, func(p *ColumnProfile) { p.Max = nullStringPtr(*maxVal) }})
+		}
+
+		if kind == kindString {
+			minLen, maxLen, avgLen := new(sql.NullInt64), new(sql.NullInt64), new(sql.NullFloat64)
+			length := fmt.Sprintf("%s(%s)", d.lengthFunc, col)
+			exprs = append(exprs, "MIN("+length+")", "MAX("+length+")", "AVG("+length+")")
+			targets = append(targets,
+				target{i, minLen, func(p *ColumnProfile) {
+					if minLen.Valid {
+						p.MinLength = &minLen.Int64
+					}
+				}},
+				target{i, maxLen, func(p *ColumnProfile) {
+					if maxLen.Valid {
+						p.MaxLength = &maxLen.Int64
+					}
+				}},
+				target{i, avgLen, func(p *ColumnProfile) {
+					if avgLen.Valid {
+						p.AvgLength = &avgLen.Float64
+					}
+				}})
+		}
+	}
+
+	var total int64
+	dests := []interface{}{&total}
+	for _, t := range targets {
+		dests = append(dests, t.dest)
+	}
+
+	query := fmt.Sprintf("SELECT %s%s FROM %s", d.selectHint, strings.Join(exprs, ", "), source)
+	if err := tx.QueryRowContext(ctx, query).Scan(dests...); err != nil {
+		return 0, nil, fmt.Errorf("failed to profile columns: %w", err)
+	}
+
+	for _, t := range targets {
+		if t.assign != nil {
+			t.assign(&profiles[t.column])
+		}
+	}
+	for i := range profiles {
+		profiles[i].NullCount = total - nonNull[i]
+		if total > 0 {
+			profiles[i].NullRatio = float64(profiles[i].NullCount) / float64(total)
+		}
+	}
+
+	if opts.TopN > 0 {
+		for i, c := range columns {
+			if classifyColumn(c.DataType) == kindOpaque {
+				continue
+			}
+			top, err := topValues(ctx, tx, d, source, c.ColumnName, opts.TopN)
+			if err != nil {
+				return 0, nil, err
+			}
+			profiles[i].TopValues = top
+		}
+	}
+
+	return total, profiles, nil
+}
+
+// maxProfileValueLength truncates long values in min/max and top value lists
+const maxProfileValueLength = 100
+
+func topValues(ctx context.Context, tx *sql.Tx, d profileDialect, source, column string, n int) ([]ValueCount, error) {
+	col := QuoteIdentifier(d.dbType, column)
+	query := fmt.Sprintf("SELECT %s%s, COUNT(*) AS cnt FROM %s GROUP BY %s ORDER BY cnt DESC LIMIT %d",
+		d.selectHint, d.castText(col), source, col, n)
+
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute top values for %s: %w", column, err)
+	}
+	defer rows.Close()
+
+	var values []ValueCount
+	for rows.Next() {
+		var v sql.NullString
+		var count int64
+		if err := rows.Scan(&v, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan top values: %w", err)
+		}
+		values = append(values, ValueCount{Value: nullStringPtr(v), Count: count})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating top values: %w", err)
+	}
+
+	return values, nil
+}
+
+func nullStringPtr(v sql.NullString) *string {
+	if !v.Valid {
+		return nil
+	}
+	s := v.String
+	if r := []rune(s); len(r) > maxProfileValueLength {
+		s = string(r[:maxProfileValueLength]) + "…"
+	}
+	return &s
+}
@@ -0,0 +1,48 @@
+package database
+
+import "testing"
+
+func TestClassifyColumn(t *testing.T) {
+	tests := map[string]columnKind{
+		"character varying":           kindString,
+		"varchar":                     kindString,
+		"text":                        kindString,
+		"enum":                        kindString,
+		"integer":                     kindOrdered,
+		"numeric":                     kindOrdered,
+		"timestamp without time zone": kindOrdered,
+		"date":                        kindOrdered,
+		"boolean":                     kindOther,
+		"uuid":                        kindOther,
+		"jsonb":                       kindOpaque,
+		"bytea":                       kindOpaque,
+		"longblob":                    kindOpaque,
+		"ARRAY":                       kindOpaque,
+	}
+	for dataType, want := range tests {
+		if got := classifyColumn(dataType); got != want {
+			t.Errorf("classifyColumn(%q) = %d, want %d", dataType, got, want)
+		}
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	columns := []ColumnInfo{{ColumnName: "id"}, {ColumnName: "email"}, {ColumnName: "name"}}
+
+	all, err := selectColumns(columns, nil)
+	if err != nil || len(all) != 3 {
+		t.Fatalf("Expected all columns, got %v, %v", all, err)
+	}
+
+	picked, err := selectColumns(columns, []string{"name", "id"})
+	if err != nil {
+		t.Fatalf("selectColumns failed: %v", err)
+	}
+	if len(picked) != 2 || picked[0].ColumnName != "name" || picked[1].ColumnName != "id" {
+		t.Errorf("Expected requested order, got %v", picked)
+	}
+
+	if _, err := selectColumns(columns, []string{"missing"}); err == nil {
+		t.Error("Expected error for unknown column")
+	}
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SampleOptions controls how rows are sampled from a table
+type SampleOptions struct {
+	Columns       []string      // columns to return; empty means all
+	N             int           // number of rows to return
+	Seed          int64         // seed for the random sample; the same seed returns the same rows
+	MaxCellLength int           // longer text values are truncated; 0 disables truncation
+	Timeout       time.Duration // statement timeout enforced by the database
+}
+
+// SampleResult is a sample of rows from a table
+type SampleResult struct {
+	TableName      string `json:"table_name"`
+	EstimatedRows  int64  `json:"estimated_rows"`
+	SampleMethod   string `json:"sample_method"`
+	Seed           int64  `json:"seed"`
+	TruncatedCells int    `json:"truncated_cells,omitempty"`
+	QueryResult
+}
+
+// smallTableRows is the estimated size below which a table is sampled by
+// shuffling every row rather than with a block or predicate sample
+const smallTableRows = 10000
+
+// sampleFraction returns the fraction of rows to keep so that about n rows
+// survive, with some headroom because the sample size varies
+func sampleFraction(n int, estimatedRows int64) float64 {
+	if estimatedRows <= 0 {
+		return 1
+	}
+	f := float64(n) / float64(estimatedRows) * 1.5
+	if f > 1 {
+		return 1
+	}
+	return f
+}
+
+// selectList quotes the given columns for a SELECT list
+func selectList(dbType string, columns []ColumnInfo) string {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = QuoteIdentifier(dbType, c.ColumnName)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// truncateCells shortens oversized text values and replaces binary values
+// with a placeholder, returning the number of cells changed
+func truncateCells(result *QueryResult, maxLength int) int {
+	changed := 0
+	for _, row := range result.Rows {
+		for col, val := range row {
+			s, ok := val.(string)
+			if !ok {
+				continue
+			}
+			if !utf8.ValidString(s) {
+				row[col] = fmt.Sprintf("<binary %d bytes>", len(s))
+				changed++
+				continue
+			}
+			if maxLength > 0 && utf8.RuneCountInString(s) > maxLength {
+				row[col] = string([]rune(s)[:maxLength]) + fmt.Sprintf("… (%d chars)", utf8.RuneCountInString(s))
+				changed++
+			}
+		}
+	}
+	return changed
+}
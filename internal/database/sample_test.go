@@ -0,0 +1,44 @@
+package database
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestTruncateCells(t *testing.T) {
+	result := &QueryResult{
+		Columns: []string{"id", "body", "payload", "short"},
+		Rows: []map[string]interface{}{
+			{"id": int64(1), "body": strings.Repeat("é", 30), "payload": string([]byte{0xff, 0xfe, 0x00}), "short": "ok"},
+		},
+	}
+
+	changed := truncateCells(result, 10)
+	if changed != 2 {
+		t.Errorf("Expected 2 changed cells, got %d", changed)
+	}
+
+	row := result.Rows[0]
+	if got := row["body"].(string); got != strings.Repeat("é", 10)+"… (30 chars)" {
+		t.Errorf("Unexpected truncated value: %q", got)
+	}
+	if got := row["payload"]; got != "<binary 3 bytes>" {
+		t.Errorf("Expected binary placeholder, got %q", got)
+	}
+	if row["short"] != "ok" || row["id"] != int64(1) {
+		t.Errorf("Expected other cells to be untouched, got %v", row)
+	}
+}
+
+func TestSampleFraction(t *testing.T) {
+	if f := sampleFraction(100, 1000000); math.Abs(f-0.00015) > 1e-12 {
+		t.Errorf("Expected 0.00015, got %v", f)
+	}
+	if f := sampleFraction(100, 50); f != 1 {
+		t.Errorf("Expected fraction to be capped at 1, got %v", f)
+	}
+	if f := sampleFraction(100, 0); f != 1 {
+		t.Errorf("Expected full sample without an estimate, got %v", f)
+	}
+}
@@ -0,0 +1,55 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TableStats holds size and row count statistics for a table. Row counts and
+// sizes come from catalog statistics and may lag behind the data unless
+// ExactRows is requested.
+type TableStats struct {
+	TableSchema     string     `json:"table_schema,omitempty"`
+	TableName       string     `json:"table_name"`
+	TableType       string     `json:"table_type,omitempty"`
+	EstimatedRows   int64      `json:"estimated_rows"`
+	ExactRows       *int64     `json:"exact_rows,omitempty"`
+	TableBytes      int64      `json:"table_bytes"`
+	IndexBytes      int64      `json:"index_bytes"`
+	TotalBytes      int64      `json:"total_bytes"`
+	TotalSize       string     `json:"total_size"`
+	AutoIncrement   *int64     `json:"auto_increment,omitempty"`
+	LastUpdate      *time.Time `json:"last_update,omitempty"`
+	LastAnalyze     *time.Time `json:"last_analyze,omitempty"`
+	LastAutoAnalyze *time.Time `json:"last_autoanalyze,omitempty"`
+	LastVacuum      *time.Time `json:"last_vacuum,omitempty"`
+	LastAutoVacuum  *time.Time `json:"last_autovacuum,omitempty"`
+}
+
+// TableStatsOptions controls how table statistics are collected
+type TableStatsOptions struct {
+	ExactCount bool          // run COUNT(*) in addition to the catalog estimate
+	Timeout    time.Duration // statement timeout for the exact count
+}
+
+// FormatBytes renders a byte count with a binary unit, e.g. "1.5 GiB"
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
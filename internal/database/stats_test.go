@@ -0,0 +1,19 @@
+package database
+
+import "testing"
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{
+		0:                "0 B",
+		1023:             "1023 B",
+		1024:             "1.0 KiB",
+		1536:             "1.5 KiB",
+		10 * 1024 * 1024: "10.0 MiB",
+		3 << 40:          "3.0 TiB",
+	}
+	for n, want := range tests {
+		if got := FormatBytes(n); got != want {
+			t.Errorf("FormatBytes(%d) = %s, want %s", n, got, want)
+		}
+	}
+}
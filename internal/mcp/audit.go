@@ -0,0 +1,179 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/audit"
+	"github.com/hieubanhh/dbhubMCP/internal/auth"
+	"github.com/hieubanhh/dbhubMCP/internal/security"
+)
+
+// maxClientInfos bounds how many clients' initialize info is remembered
+const maxClientInfos = 10000
+
+// SetAuditLogger records every tool call to l
+func (s *Server) SetAuditLogger(l *audit.Logger) {
+	s.audit = l
+}
+
+// clientInfos remembers the client info each caller sent in initialize, by
+// client key, for the audit log
+type clientInfos struct {
+	mu       sync.Mutex
+	byClient map[string]audit.ClientInfo
+}
+
+func (c *clientInfos) set(key string, info ClientInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.byClient == nil {
+		c.byClient = make(map[string]audit.ClientInfo)
+	}
+	if _, ok := c.byClient[key]; !ok && len(c.byClient) >= maxClientInfos {
+		for k := range c.byClient {
+			delete(c.byClient, k)
+			break
+		}
+	}
+	c.byClient[key] = audit.ClientInfo{Name: info.Name, Version: info.Version}
+}
+
+func (c *clientInfos) get(key string) *audit.ClientInfo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if info, ok := c.byClient[key]; ok {
+		return &info
+	}
+	return nil
+}
+
+// rememberClient records the client info of an initialize request
+func (s *Server) rememberClient(req *Request) {
+	paramsJSON, err := json.Marshal(req.Params)
+	if err != nil {
+		return
+	}
+	var params InitializeParams
+	if err := json.Unmarshal(paramsJSON, &params); err != nil {
+		return
+	}
+	s.clients.set(req.clientKey(), params.ClientInfo)
+}
+
+// validateQuery checks that a query is read-only, recording the query and
+// the outcome for the audit log
+func (s *Server) validateQuery(ctx context.Context, query string) error {
+	err := s.validator.ValidateReadOnlyQuery(query)
+	if u, ok := ctx.Value(usageKey{}).(*usage); ok {
+		u.queries = append(u.queries, query)
+		if err != nil {
+			u.validation = "rejected"
+		} else if u.validation == "" {
+			u.validation = "passed"
+		}
+	}
+	return err
+}
+
+// reject marks the validated queries of a call as rejected by a later
+// check: the security policy, the caller's role or the cost guard
+func (u *usage) reject() {
+	if len(u.queries) > 0 {
+		u.validation = "rejected"
+	}
+}
+
+// rejectQueries marks the queries of the call in ctx as rejected
+func rejectQueries(ctx context.Context) {
+	if u, ok := ctx.Value(usageKey{}).(*usage); ok {
+		u.reject()
+	}
+}
+
+// isDenial reports whether err is a denial by the security policy or the
+// caller's role rather than a failure
+func isDenial(err error) bool {
+	var policyErr *security.AccessError
+	var roleErr *auth.AccessError
+	return errors.As(err, &policyErr) || errors.As(err, &roleErr)
+}
+
+// auditCall records a finished tool call
+func (s *Server) auditCall(req *Request, params *CallToolParams, u *usage, duration time.Duration, resp *Response) {
+	e := audit.Event{
+		Client:     req.clientKey(),
+		ClientInfo: s.clients.get(req.clientKey()),
+		Tool:       params.Name,
+		Arguments:  params.Arguments,
+		Validation: u.validation,
+		Status:     "ok",
+		DurationMS: float64(duration) / float64(time.Millisecond),
+		Rows:       u.rows,
+	}
+	if req.identity != nil {
+		e.Identity = req.identity.Name
+		if req.identity.Role != nil {
+			e.Role = req.identity.Role.Name
+		}
+	}
+
+	// Tools taking a database argument run against the default without one
+	dbType := ""
+	if s.takesDatabase(params.Name) {
+		e.Database = stringArg(params.Arguments, "database")
+		if e.Database == "" {
+			e.Database = s.defaultConnection
+		}
+		if conn, ok := s.connections[e.Database]; ok {
+			dbType = conn.Adapter.GetDBType()
+		}
+	}
+	for _, query := range u.queries {
+		e.SQL = append(e.SQL, security.NormalizeQuery(dbType, query))
+	}
+
+	result, _ := resp.Result.(*CallToolResult)
+	switch {
+	case resp.retryAfter > 0:
+		e.Status = "rate_limited"
+		e.Error = resp.errorText()
+	case resp.Error != nil || (result != nil && result.IsError):
+		e.Status = "error"
+		e.Error = resp.errorText()
+	}
+
+	if err := s.audit.Log(e); err != nil {
+		log.Printf("[ERROR] %v", err)
+	}
+}
+
+// takesDatabase reports whether a tool has a database argument
+func (s *Server) takesDatabase(name string) bool {
+	for _, tool := range s.toolDefs {
+		if tool.Name == name {
+			_, ok := tool.InputSchema.Properties["database"]
+			return ok
+		}
+	}
+	return false
+}
+
+// errorText returns the message of an error response or the first line of
+// a tool error
+func (resp *Response) errorText() string {
+	if resp.Error != nil {
+		return resp.Error.Message
+	}
+	result, ok := resp.Result.(*CallToolResult)
+	if !ok || len(result.Content) == 0 {
+		return ""
+	}
+	text, _, _ := strings.Cut(result.Content[0].Text, "\n")
+	return strings.TrimPrefix(text, "Error: ")
+}
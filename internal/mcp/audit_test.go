@@ -0,0 +1,94 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hieubanhh/dbhubMCP/internal/audit"
+	"github.com/hieubanhh/dbhubMCP/internal/auth"
+	"github.com/hieubanhh/dbhubMCP/internal/ratelimit"
+	"github.com/hieubanhh/dbhubMCP/internal/security"
+)
+
+func TestServer_Audit(t *testing.T) {
+	s := newTestServer(t, map[string]*fakeAdapter{"app": {dbType: "postgres", table: "orders"}}, "app")
+	var buf bytes.Buffer
+	s.SetAuditLogger(audit.NewLogger(audit.NewWriterSink(&buf), true))
+
+	analyst := &auth.Identity{Name: "ci", Role: &auth.Role{Name: "analyst"}}
+	call := func(name string, args map[string]interface{}) {
+		s.handleRequest(context.Background(), &Request{JSONRPC: "2.0", ID: 1, Method: "tools/call", client: "client:ci", identity: analyst,
+			Params: map[string]interface{}{"name": name, "arguments": args}})
+	}
+	s.handleRequest(context.Background(), &Request{JSONRPC: "2.0", ID: 0, Method: "initialize", client: "client:ci",
+		Params: map[string]interface{}{"protocolVersion": ProtocolVersion, "clientInfo": map[string]interface{}{"name": "desktop-app", "version": "1.2"}}})
+
+	call("execute_readonly_query", map[string]interface{}{"query": "SELECT n\n  FROM   t"})
+	call("execute_readonly_query", map[string]interface{}{"query": "DELETE FROM t"})
+	call("list_connections", nil)
+	s.SetRateLimits(ratelimit.Limits{RequestsPerMinute: 1})
+	call("list_tables", nil)
+	call("list_tables", nil)
+
+	if _, n, err := audit.Verify(strings.NewReader(buf.String()), ""); err != nil || n != 5 {
+		t.Fatalf("Expected a chain of 5 entries, got %d, %v", n, err)
+	}
+	var events []audit.Event
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var e audit.Event
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			t.Fatalf("Invalid entry %q: %v", line, err)
+		}
+		events = append(events, e)
+	}
+
+	query := events[0]
+	if query.Identity != "ci" || query.Role != "analyst" || query.Client != "client:ci" || query.ClientInfo == nil || query.ClientInfo.Name != "desktop-app" {
+		t.Errorf("Expected the caller to be recorded, got %+v", query)
+	}
+	if query.Tool != "execute_readonly_query" || query.Database != "app" || query.Validation != "passed" || query.Status != "ok" || query.Rows != 3 {
+		t.Errorf("Unexpected query entry %+v", query)
+	}
+	if len(query.SQL) != 1 || query.SQL[0] != "SELECT n FROM t" {
+		t.Errorf("Expected the normalized query, got %q", query.SQL)
+	}
+
+	rejected := events[1]
+	if rejected.Validation != "rejected" || rejected.Status != "error" || !strings.Contains(rejected.Error, "query validation failed") || rejected.Rows != 0 {
+		t.Errorf("Expected the rejected query to be recorded, got %+v", rejected)
+	}
+	if events[2].Database != "" || events[2].SQL != nil || events[2].Status != "ok" {
+		t.Errorf("Expected no database for list_connections, got %+v", events[2])
+	}
+	if events[4].Status != "rate_limited" || !strings.Contains(events[4].Error, "rate limit exceeded") {
+		t.Errorf("Expected the rate limited call to be recorded, got %+v", events[4])
+	}
+}
+
+func TestServer_AuditPolicyDenial(t *testing.T) {
+	s := newTestServer(t, map[string]*fakeAdapter{"app": {dbType: "postgres", table: "orders"}}, "app")
+	var buf bytes.Buffer
+	s.SetAuditLogger(audit.NewLogger(audit.NewWriterSink(&buf), false))
+	policy, err := security.NewPolicy(security.PolicyRules{}, security.PolicyRules{})
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+	if err := s.SetLimits("app", Limits{MaxRows: 100, Policy: policy}); err != nil {
+		t.Fatalf("SetLimits failed: %v", err)
+	}
+
+	// Read-only, so it passes the validator, but pg_sleep is denied
+	s.handleRequest(context.Background(), &Request{JSONRPC: "2.0", ID: 1, Method: "tools/call",
+		Params: map[string]interface{}{"name": "execute_readonly_query", "arguments": map[string]interface{}{"query": "SELECT pg_sleep(10)"}}})
+
+	var e audit.Event
+	if err := json.Unmarshal(buf.Bytes(), &e); err != nil {
+		t.Fatalf("Invalid entry %q: %v", buf.String(), err)
+	}
+	if e.Validation != "rejected" || e.Status != "error" || !strings.Contains(e.Error, "pg_sleep") {
+		t.Errorf("Expected the policy denial to be recorded as rejected, got %+v", e)
+	}
+}
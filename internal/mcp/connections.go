@@ -0,0 +1,368 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/auth"
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+	"github.com/hieubanhh/dbhubMCP/internal/schema"
+	"github.com/hieubanhh/dbhubMCP/internal/security"
+)
+
+// Connection is a named database that tool calls can be routed to, together
+// with the limits applied to queries against it
+type Connection struct {
+	Name    string
+	Adapter database.Adapter
+	Limits
+}
+
+// Limits are the per-database query limits, access policy and masking
+// rules. They can be replaced with SetLimits while the server runs.
+type Limits struct {
+	MaxRows          int
+	QueryTimeout     time.Duration
+	ExplainAnalyze   bool             // allow EXPLAIN ANALYZE, which executes the query
+	MaxCost          float64          // reject queries the planner estimates to cost more; 0 disables
+	MaxEstimatedRows int              // reject queries the planner estimates to read more rows from one table; 0 disables
+	Policy           *security.Policy // hides denied schemas, tables and columns; nil allows all
+	Masker           *security.Masker // masks sensitive values in results; nil masks nothing
+}
+
+// connection is a registered Connection and its lazily opened state
+type connection struct {
+	Name       string
+	Adapter    database.Adapter
+	limits     atomic.Pointer[Limits]
+	joinGraphs *schema.GraphCache
+
+	mu        sync.Mutex
+	connected bool
+	lastErr   error
+
+	// Join graphs of roles with a policy, by role name
+	graphsMu   sync.Mutex
+	roleGraphs map[string]roleGraph
+}
+
+// roleGraph is the join graph cache of a role, built for one policy
+type roleGraph struct {
+	policy *security.Policy
+	cache  *schema.GraphCache
+}
+
+// target is the database a tool call runs against, with the limits in
+// effect when the call started; SetLimits does not affect calls in flight
+type target struct {
+	Connection
+	joinGraphs *schema.GraphCache
+}
+
+// snapshot captures the connection's current limits for one tool call made
+// with role. With a policy, the adapter enforces it on every metadata lookup
+// and query; with masking rules, it masks the rows it returns. The role's
+// policy narrows the connection's, and its row limit caps MaxRows.
+func (c *connection) snapshot(role *auth.Role) *target {
+	limits := *c.limits.Load()
+	limits.MaxRows = role.RowLimit(limits.MaxRows)
+	joinGraphs := c.joinGraphs
+
+	var adapter database.Adapter = c.Adapter
+	if limits.Policy != nil {
+		adapter = database.NewPolicyAdapter(adapter, limits.Policy)
+	}
+	if role != nil && role.Policy != nil {
+		adapter = database.NewPolicyAdapter(adapter, role.Policy)
+		// The shared join graph includes tables the role may not see
+		joinGraphs = c.roleJoinGraphs(role)
+	}
+	if limits.Masker != nil {
+		adapter = database.NewMaskingAdapter(adapter, limits.Masker)
+	}
+	return &target{
+		Connection: Connection{Name: c.Name, Adapter: adapter, Limits: limits},
+		joinGraphs: joinGraphs,
+	}
+}
+
+// roleJoinGraphs returns the join graph cache of a role with a policy. A
+// reloaded role brings a new policy, so its cache is then started afresh.
+func (c *connection) roleJoinGraphs(role *auth.Role) *schema.GraphCache {
+	c.graphsMu.Lock()
+	defer c.graphsMu.Unlock()
+
+	g, ok := c.roleGraphs[role.Name]
+	if !ok || g.policy != role.Policy {
+		if c.roleGraphs == nil {
+			c.roleGraphs = make(map[string]roleGraph)
+		}
+		g = roleGraph{policy: role.Policy, cache: schema.NewGraphCache(joinGraphTTL)}
+		c.roleGraphs[role.Name] = g
+	}
+	return g.cache
+}
+
+// invalidateJoinGraphs drops the shared join graph and those of every role
+func (c *connection) invalidateJoinGraphs() {
+	c.joinGraphs.Invalidate()
+
+	c.graphsMu.Lock()
+	defer c.graphsMu.Unlock()
+	for _, g := range c.roleGraphs {
+		g.cache.Invalidate()
+	}
+}
+
+// connect opens the database on first use. Failures are not cached, so an
+// unreachable database is retried on the next call.
+func (c *connection) connect(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.connected {
+		return nil
+	}
+	if err := c.Adapter.Connect(ctx); err != nil {
+		c.lastErr = err
+		return fmt.Errorf("failed to connect to database %s: %w", c.Name, err)
+	}
+
+	c.connected = true
+	c.lastErr = nil
+	log.Printf("[INFO] Connected to %s database %s", c.Adapter.GetDBType(), c.Name)
+	return nil
+}
+
+// status describes the connection state for list_connections
+func (c *connection) status() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch {
+	case c.connected:
+		return "connected"
+	case c.lastErr != nil:
+		return "error: " + c.lastErr.Error()
+	default:
+		return "not connected"
+	}
+}
+
+// isConnected reports whether the database has been opened
+func (c *connection) isConnected() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.connected
+}
+
+// close closes the database if it was opened
+func (c *connection) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.connected {
+		return
+	}
+	if err := c.Adapter.Close(); err != nil {
+		log.Printf("[WARN] Failed to close database %s: %v", c.Name, err)
+	}
+	c.connected = false
+}
+
+// AddConnection registers a named database. The first connection added is
+// the default until SetDefaultConnection is called.
+func (s *Server) AddConnection(conn Connection) error {
+	if conn.Name == "" {
+		return fmt.Errorf("connection name is required")
+	}
+	if _, ok := s.connections[conn.Name]; ok {
+		return fmt.Errorf("connection %s is already registered", conn.Name)
+	}
+
+	c := &connection{
+		Name:       conn.Name,
+		Adapter:    conn.Adapter,
+		joinGraphs: schema.NewGraphCache(joinGraphTTL),
+	}
+	c.setLimits(conn.Limits)
+	s.connections[conn.Name] = c
+	s.connectionNames = append(s.connectionNames, conn.Name)
+	if s.defaultConnection == "" {
+		s.defaultConnection = conn.Name
+	}
+	return nil
+}
+
+// SetLimits replaces the limits of a registered database. Tool calls that
+// already started keep the limits they started with.
+func (s *Server) SetLimits(name string, limits Limits) error {
+	conn, ok := s.connections[name]
+	if !ok {
+		return fmt.Errorf("connection %s is not registered", name)
+	}
+	conn.setLimits(limits)
+	return nil
+}
+
+// setLimits stores limits, defaulting the query timeout to 30 seconds. The
+// join graph is built from visible foreign keys, so it is dropped when the
+// policy changes.
+func (c *connection) setLimits(limits Limits) {
+	if limits.QueryTimeout <= 0 {
+		limits.QueryTimeout = 30 * time.Second
+	}
+	if old := c.limits.Swap(&limits); old != nil && old.Policy != limits.Policy {
+		c.invalidateJoinGraphs()
+	}
+}
+
+// SetDefaultConnection selects the database used when a tool call does not
+// name one
+func (s *Server) SetDefaultConnection(name string) error {
+	if _, ok := s.connections[name]; !ok {
+		return fmt.Errorf("connection %s is not registered", name)
+	}
+	s.defaultConnection = name
+	return nil
+}
+
+// lookupConnection resolves the database argument of a tool call, falling
+// back to the default, without connecting to it. The caller's role must
+// allow the database.
+func (s *Server) lookupConnection(ctx context.Context, args map[string]interface{}) (*connection, error) {
+	name := stringArg(args, "database")
+	if name == "" {
+		name = s.defaultConnection
+	}
+
+	conn, ok := s.connections[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown database %q; use list_connections to see the configured databases", name)
+	}
+	if role := auth.RoleFromContext(ctx); !role.AllowsDatabase(name) {
+		return nil, &auth.AccessError{Object: "database " + name, Role: role.Name}
+	}
+	return conn, nil
+}
+
+// connection resolves the database of a tool call, connects to it on first
+// use and captures its current limits
+func (s *Server) connection(ctx context.Context, args map[string]interface{}) (*target, error) {
+	conn, err := s.lookupConnection(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.connect(ctx); err != nil {
+		return nil, err
+	}
+	return conn.snapshot(auth.RoleFromContext(ctx)), nil
+}
+
+// EnableCredentialRefresh makes Run re-read the password secret of every
+// connected database at the given interval and replace the connection pool
+// when the password has rotated
+func (s *Server) EnableCredentialRefresh(interval time.Duration) {
+	s.credentialRefresh = interval
+}
+
+// refreshCredentials rotates connection pools whose password secret changed.
+// It returns when ctx is cancelled.
+func (s *Server) refreshCredentials(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		for _, name := range s.connectionNames {
+			conn := s.connections[name]
+			if !conn.isConnected() {
+				continue
+			}
+
+			refreshCtx, cancel := context.WithTimeout(ctx, time.Minute)
+			rotated, err := conn.Adapter.RefreshCredentials(refreshCtx)
+			cancel()
+
+			switch {
+			case err != nil:
+				log.Printf("[WARN] Credential refresh failed for %s, keeping the current connections: %v", name, err)
+			case rotated:
+				log.Printf("[INFO] Credentials rotated for %s, connection pool replaced", name)
+			}
+		}
+	}
+}
+
+// closeConnections closes every database that was opened
+func (s *Server) closeConnections() {
+	for _, name := range s.connectionNames {
+		s.connections[name].close()
+	}
+}
+
+// connectionInfo describes one database in the list_connections result
+type connectionInfo struct {
+	Name            string `json:"name"`
+	Type            string `json:"type"`
+	Default         bool   `json:"default"`
+	Status          string `json:"status"`
+	MaxRows         int    `json:"max_rows"`
+	QueryTimeoutSec int    `json:"query_timeout_sec"`
+	ExplainAnalyze  bool   `json:"explain_analyze"`
+
+	// Planner estimates above which queries are rejected
+	MaxCost          float64 `json:"max_cost,omitempty"`
+	MaxEstimatedRows int     `json:"max_estimated_rows,omitempty"`
+}
+
+// handleListConnections handles the list_connections tool, listing the
+// databases the caller's role may use
+func (s *Server) handleListConnections(ctx context.Context, args map[string]interface{}) (*CallToolResult, error) {
+	role := auth.RoleFromContext(ctx)
+	infos := make([]connectionInfo, 0, len(s.connectionNames))
+	for _, name := range s.connectionNames {
+		if !role.AllowsDatabase(name) {
+			continue
+		}
+		conn := s.connections[name]
+		limits := conn.limits.Load()
+		infos = append(infos, connectionInfo{
+			Name:            name,
+			Type:            conn.Adapter.GetDBType(),
+			Default:         name == s.defaultConnection,
+			Status:          conn.status(),
+			MaxRows:         role.RowLimit(limits.MaxRows),
+			QueryTimeoutSec: int(limits.QueryTimeout / time.Second),
+			ExplainAnalyze:  limits.ExplainAnalyze,
+
+			MaxCost:          limits.MaxCost,
+			MaxEstimatedRows: limits.MaxEstimatedRows,
+		})
+	}
+
+	resultJSON, err := json.MarshalIndent(infos, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to format result: %w", err)
+	}
+
+	return &CallToolResult{
+		Content: []Content{
+			{
+				Type: "text",
+				Text: fmt.Sprintf("Found %d databases. Pass the name as the database argument of other tools (default: %s):\n\n%s",
+					len(infos), s.defaultConnection, string(resultJSON)),
+			},
+		},
+	}, nil
+}
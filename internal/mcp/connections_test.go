@@ -0,0 +1,306 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/auth"
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+	"github.com/hieubanhh/dbhubMCP/internal/security"
+)
+
+// fakeAdapter records connection attempts and serves a fixed table list
+type fakeAdapter struct {
+	database.Adapter
+	dbType     string
+	table      string
+	connectErr error
+	connects   int
+	refreshes  atomic.Int32
+	closed     bool
+	plan       string // JSON plan returned by ExplainQueryJSON
+	queries    int
+
+	// Plan returned for queries with a LIMIT, and the last query explained
+	// and executed
+	limitedPlan string
+	explained   string
+	executed    string
+}
+
+func (a *fakeAdapter) Connect(ctx context.Context) error {
+	a.connects++
+	return a.connectErr
+}
+
+func (a *fakeAdapter) Close() error {
+	a.closed = true
+	return nil
+}
+
+func (a *fakeAdapter) RefreshCredentials(ctx context.Context) (bool, error) {
+	a.refreshes.Add(1)
+	return true, nil
+}
+
+func (a *fakeAdapter) GetDBType() string {
+	return a.dbType
+}
+
+func (a *fakeAdapter) ListTables(ctx context.Context) ([]database.TableInfo, error) {
+	return []database.TableInfo{{TableName: a.table}}, nil
+}
+
+func newTestServer(t *testing.T, adapters map[string]*fakeAdapter, names ...string) *Server {
+	s := NewServer(nil, security.NewValidator(10000))
+	for _, name := range names {
+		if err := s.AddConnection(Connection{Name: name, Adapter: adapters[name], Limits: Limits{MaxRows: 100}}); err != nil {
+			t.Fatalf("AddConnection failed: %v", err)
+		}
+	}
+	return s
+}
+
+func callText(t *testing.T, s *Server, tool string, args map[string]interface{}) (string, error) {
+	result, err := s.tools[tool](context.Background(), args)
+	if err != nil {
+		return "", err
+	}
+	return result.Content[0].Text, nil
+}
+
+func TestServer_RoutesByDatabaseArgument(t *testing.T) {
+	adapters := map[string]*fakeAdapter{
+		"oltp":      {dbType: "postgres", table: "orders"},
+		"reporting": {dbType: "mysql", table: "daily_sales"},
+	}
+	s := newTestServer(t, adapters, "oltp", "reporting")
+
+	text, err := callText(t, s, "list_tables", map[string]interface{}{})
+	if err != nil {
+		t.Fatalf("list_tables failed: %v", err)
+	}
+	if !strings.Contains(text, "orders") {
+		t.Errorf("Expected the default database to be used, got %s", text)
+	}
+	if adapters["reporting"].connects != 0 {
+		t.Errorf("Expected reporting to stay unconnected until used")
+	}
+
+	text, err = callText(t, s, "list_tables", map[string]interface{}{"database": "reporting"})
+	if err != nil {
+		t.Fatalf("list_tables failed: %v", err)
+	}
+	if !strings.Contains(text, "daily_sales") {
+		t.Errorf("Expected the reporting database to be used, got %s", text)
+	}
+
+	callText(t, s, "list_tables", map[string]interface{}{"database": "reporting"})
+	if adapters["reporting"].connects != 1 {
+		t.Errorf("Expected one connect, got %d", adapters["reporting"].connects)
+	}
+
+	s.closeConnections()
+	if !adapters["oltp"].closed || !adapters["reporting"].closed {
+		t.Errorf("Expected connected databases to be closed")
+	}
+}
+
+func TestServer_UnknownDatabase(t *testing.T) {
+	s := newTestServer(t, map[string]*fakeAdapter{"main": {dbType: "mysql"}}, "main")
+
+	_, err := callText(t, s, "list_tables", map[string]interface{}{"database": "missing"})
+	if err == nil || !strings.Contains(err.Error(), "list_connections") {
+		t.Errorf("Expected unknown database error, got %v", err)
+	}
+}
+
+func TestServer_RetriesFailedConnect(t *testing.T) {
+	adapter := &fakeAdapter{dbType: "mysql", table: "users", connectErr: errors.New("connection refused")}
+	s := newTestServer(t, map[string]*fakeAdapter{"main": adapter}, "main")
+
+	if _, err := callText(t, s, "list_tables", nil); err == nil {
+		t.Fatal("Expected connect error")
+	}
+	if status := s.connections["main"].status(); !strings.Contains(status, "connection refused") {
+		t.Errorf("Expected error status, got %s", status)
+	}
+
+	adapter.connectErr = nil
+	if _, err := callText(t, s, "list_tables", nil); err != nil {
+		t.Fatalf("Expected retry to succeed, got %v", err)
+	}
+	if adapter.connects != 2 {
+		t.Errorf("Expected 2 connect attempts, got %d", adapter.connects)
+	}
+}
+
+func TestServer_ListConnections(t *testing.T) {
+	adapters := map[string]*fakeAdapter{
+		"oltp":      {dbType: "postgres"},
+		"reporting": {dbType: "mysql"},
+	}
+	s := newTestServer(t, adapters, "oltp", "reporting")
+	if err := s.SetDefaultConnection("reporting"); err != nil {
+		t.Fatalf("SetDefaultConnection failed: %v", err)
+	}
+	if err := s.SetDefaultConnection("missing"); err == nil {
+		t.Error("Expected error for unregistered default")
+	}
+
+	text, err := callText(t, s, "list_connections", nil)
+	if err != nil {
+		t.Fatalf("list_connections failed: %v", err)
+	}
+	for _, want := range []string{`"name": "oltp"`, `"type": "mysql"`, `"status": "not connected"`, "(default: reporting)"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected %s in result:\n%s", want, text)
+		}
+	}
+	if adapters["oltp"].connects != 0 || adapters["reporting"].connects != 0 {
+		t.Errorf("Expected list_connections not to connect")
+	}
+}
+
+func TestServer_RefreshesConnectedCredentials(t *testing.T) {
+	adapters := map[string]*fakeAdapter{
+		"oltp":      {dbType: "postgres"},
+		"reporting": {dbType: "mysql"},
+	}
+	s := newTestServer(t, adapters, "oltp", "reporting")
+	if _, err := s.connection(context.Background(), nil); err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.refreshCredentials(ctx, 5*time.Millisecond)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for adapters["oltp"].refreshes.Load() < 2 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if adapters["oltp"].refreshes.Load() < 2 {
+		t.Errorf("Expected repeated refreshes of the connected database")
+	}
+	if adapters["reporting"].refreshes.Load() != 0 {
+		t.Errorf("Expected databases that were never used not to be refreshed")
+	}
+}
+
+func TestServer_SetLimits(t *testing.T) {
+	s := newTestServer(t, map[string]*fakeAdapter{"main": {dbType: "mysql"}}, "main")
+
+	inFlight, err := s.connection(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("connection failed: %v", err)
+	}
+
+	if err := s.SetLimits("main", Limits{MaxRows: 5, ExplainAnalyze: true}); err != nil {
+		t.Fatalf("SetLimits failed: %v", err)
+	}
+	if err := s.SetLimits("missing", Limits{}); err == nil {
+		t.Error("Expected error for an unknown connection")
+	}
+
+	if inFlight.MaxRows != 100 || inFlight.ExplainAnalyze {
+		t.Errorf("Expected the in-flight call to keep its limits, got %+v", inFlight.Limits)
+	}
+	next, _ := s.connection(context.Background(), nil)
+	if next.MaxRows != 5 || !next.ExplainAnalyze || next.QueryTimeout != 30*time.Second {
+		t.Errorf("Expected the new limits with the default timeout, got %+v", next.Limits)
+	}
+}
+
+func TestServer_RoleLimits(t *testing.T) {
+	adapters := map[string]*fakeAdapter{
+		"oltp":      {dbType: "postgres", table: "orders"},
+		"reporting": {dbType: "mysql", table: "daily_sales"},
+	}
+	s := newTestServer(t, adapters, "oltp", "reporting")
+	role := &auth.Role{Name: "analyst", Tools: []string{"list_*"}, Databases: []string{"reporting"}, MaxRows: 5}
+	identity := &auth.Identity{Name: "ci", Role: role}
+
+	call := func(method string, params interface{}) *Response {
+		return s.handleRequest(context.Background(), &Request{JSONRPC: "2.0", ID: 1, Method: method, Params: params, identity: identity})
+	}
+	callTool := func(name string, args map[string]interface{}) *CallToolResult {
+		return call("tools/call", map[string]interface{}{"name": name, "arguments": args}).Result.(*CallToolResult)
+	}
+
+	tools := call("tools/list", nil).Result.(ListToolsResult).Tools
+	for _, tool := range tools {
+		if !strings.HasPrefix(tool.Name, "list_") {
+			t.Errorf("Expected only list_ tools, got %s", tool.Name)
+		}
+	}
+	if len(tools) == 0 {
+		t.Error("Expected the list_ tools to be listed")
+	}
+
+	if result := callTool("execute_readonly_query", map[string]interface{}{"query": "SELECT 1"}); !result.IsError || !strings.Contains(result.Content[0].Text, "tool execute_readonly_query is denied for role analyst") {
+		t.Errorf("Expected execute_readonly_query to be denied, got %+v", result)
+	}
+	if result := callTool("list_tables", map[string]interface{}{"database": "oltp"}); !result.IsError || !strings.Contains(result.Content[0].Text, "database oltp is denied") {
+		t.Errorf("Expected oltp to be denied, got %+v", result)
+	}
+	if result := callTool("list_tables", map[string]interface{}{"database": "reporting"}); result.IsError || !strings.Contains(result.Content[0].Text, "daily_sales") {
+		t.Errorf("Expected reporting to be listed, got %+v", result)
+	}
+	if adapters["oltp"].connects != 0 {
+		t.Error("Expected the denied database not to be connected")
+	}
+
+	text := callTool("list_connections", nil).Content[0].Text
+	if strings.Contains(text, `"name": "oltp"`) || !strings.Contains(text, `"max_rows": 5`) {
+		t.Errorf("Expected only reporting with the role's row limit, got:\n%s", text)
+	}
+
+	if resp := call("resources/read", map[string]interface{}{"uri": tableResourcePrefix + "daily_sales"}); resp.Error == nil || !strings.Contains(fmt.Sprint(resp.Error.Data), "tool describe_table is denied") {
+		t.Errorf("Expected reading a resource to need describe_table, got %+v", resp)
+	}
+
+	ctx := auth.WithIdentity(context.Background(), identity)
+	conn, err := s.connection(ctx, map[string]interface{}{"database": "reporting"})
+	if err != nil || conn.MaxRows != 5 {
+		t.Errorf("Expected the role to cap max rows at 5, got %v, %v", conn, err)
+	}
+}
+
+func TestConnection_RoleJoinGraphs(t *testing.T) {
+	s := newTestServer(t, map[string]*fakeAdapter{"app": {dbType: "postgres", table: "orders"}}, "app")
+	conn := s.connections["app"]
+	newRole := func() *auth.Role {
+		policy, err := security.NewPolicy(security.PolicyRules{}, security.PolicyRules{Tables: []string{"payments"}})
+		if err != nil {
+			t.Fatalf("NewPolicy failed: %v", err)
+		}
+		return &auth.Role{Name: "analyst", Policy: policy}
+	}
+
+	role := newRole()
+	first := conn.snapshot(role).joinGraphs
+	if first == conn.joinGraphs {
+		t.Error("Expected a role with a policy not to share the join graph")
+	}
+	if again := conn.snapshot(role).joinGraphs; again != first {
+		t.Error("Expected the role's join graph to be reused")
+	}
+	if reloaded := conn.snapshot(newRole()).joinGraphs; reloaded == first {
+		t.Error("Expected a reloaded policy to get a new join graph")
+	}
+	if shared := conn.snapshot(&auth.Role{Name: "viewer"}).joinGraphs; shared != conn.joinGraphs {
+		t.Error("Expected a role without a policy to share the join graph")
+	}
+}
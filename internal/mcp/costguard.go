@@ -0,0 +1,130 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+	"github.com/hieubanhh/dbhubMCP/internal/plan"
+)
+
+// queryHook inspects a query before execute_readonly_query runs it; an
+// error rejects the query
+type queryHook func(ctx context.Context, conn *target, query string) error
+
+// costSummary describes a rejected query's plan so the caller can narrow it
+type costSummary struct {
+	Database         string         `json:"database"`
+	EstimatedCost    float64        `json:"estimated_cost"`
+	EstimatedRows    float64        `json:"estimated_rows"`
+	LargestScanRows  float64        `json:"largest_scan_rows"`
+	MaxCost          float64        `json:"max_cost,omitempty"`
+	MaxEstimatedRows int            `json:"max_estimated_rows,omitempty"`
+	Scans            []scanSummary  `json:"scans"`
+	Warnings         []plan.Warning `json:"warnings"`
+}
+
+// scanSummary is one table access of a plan
+type scanSummary struct {
+	Relation      string  `json:"relation"`
+	Operation     string  `json:"operation"`
+	AccessType    string  `json:"access_type,omitempty"`
+	Index         string  `json:"index,omitempty"`
+	EstimatedRows float64 `json:"estimated_rows"`
+	Filter        string  `json:"filter,omitempty"`
+}
+
+// costError rejects a query whose planner estimates exceed the limits of
+// its database
+type costError struct {
+	reasons []string
+	summary costSummary
+}
+
+func (e *costError) Error() string {
+	return fmt.Sprintf("query rejected before execution on database %s: %s", e.summary.Database, strings.Join(e.reasons, ", "))
+}
+
+// result returns the tool error with the plan summary
+func (e *costError) result() (*CallToolResult, error) {
+	summaryJSON, err := json.MarshalIndent(e.summary, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to format result: %w", err)
+	}
+	return &CallToolResult{
+		Content: []Content{
+			{
+				Type: "text",
+				Text: fmt.Sprintf("Error: %v. Add selective filters (ideally on indexed columns), a LIMIT or an aggregation, and try again.\n\nPlan summary:\n\n%s",
+					e, string(summaryJSON)),
+			},
+		},
+		StructuredContent: e.summary,
+		IsError:           true,
+	}, nil
+}
+
+// checkQueryCost explains a query and rejects it when the planner's
+// estimated cost, or the rows it expects a table access to read, exceed the
+// limits of the database. Nothing is explained when neither limit is set.
+//
+// Rows are checked per table access rather than at the root, whose estimate
+// a LIMIT caps; PostgreSQL keeps the full estimate on the scan below it.
+func (s *Server) checkQueryCost(ctx context.Context, conn *target, query string) error {
+	if conn.MaxCost <= 0 && conn.MaxEstimatedRows <= 0 {
+		return nil
+	}
+	raw, err := conn.Adapter.ExplainQueryJSON(ctx, query, database.ExplainOptions{Timeout: conn.QueryTimeout})
+	if err != nil {
+		return fmt.Errorf("failed to estimate query cost: %w", err)
+	}
+	p, err := plan.Parse(conn.Adapter.GetDBType(), raw)
+	if err != nil {
+		return fmt.Errorf("failed to estimate query cost: %w", err)
+	}
+
+	summary := costSummary{
+		Database:         conn.Name,
+		EstimatedCost:    p.TotalCost,
+		EstimatedRows:    p.EstimatedRows,
+		MaxCost:          conn.MaxCost,
+		MaxEstimatedRows: conn.MaxEstimatedRows,
+		Scans:            []scanSummary{},
+	}
+	var largest *plan.Node
+	p.Root.Walk(func(n *plan.Node) {
+		if n.Relation == "" {
+			return
+		}
+		summary.Scans = append(summary.Scans, scanSummary{
+			Relation:      n.Relation,
+			Operation:     n.Operation,
+			AccessType:    n.AccessType,
+			Index:         n.Index,
+			EstimatedRows: n.EstimatedRows,
+			Filter:        n.Filter,
+		})
+		if largest == nil || n.EstimatedRows > largest.EstimatedRows {
+			largest = n
+		}
+	})
+
+	var reasons []string
+	if conn.MaxCost > 0 && p.TotalCost > conn.MaxCost {
+		reasons = append(reasons, fmt.Sprintf("estimated cost %.0f exceeds the limit of %.0f", p.TotalCost, conn.MaxCost))
+	}
+	if largest != nil {
+		summary.LargestScanRows = largest.EstimatedRows
+		if conn.MaxEstimatedRows > 0 && largest.EstimatedRows > float64(conn.MaxEstimatedRows) {
+			reasons = append(reasons, fmt.Sprintf("estimated %.0f rows from %s exceed the limit of %d", largest.EstimatedRows, largest.Relation, conn.MaxEstimatedRows))
+		}
+	}
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	summary.Warnings = plan.Analyze(p, plan.Options{})
+	return &costError{reasons: reasons, summary: summary}
+}
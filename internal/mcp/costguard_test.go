@@ -0,0 +1,120 @@
+package mcp
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+)
+
+func (a *fakeAdapter) ExplainQueryJSON(ctx context.Context, query string, opts database.ExplainOptions) ([]byte, error) {
+	a.explained = query
+	if a.limitedPlan != "" && strings.Contains(query, "LIMIT") {
+		return []byte(a.limitedPlan), nil
+	}
+	return []byte(a.plan), nil
+}
+
+// seqScanPlan is a PostgreSQL plan scanning five million rows of orders
+const seqScanPlan = `[{"Plan": {"Node Type": "Aggregate", "Total Cost": 250000.5, "Plan Rows": 1,
+	"Plans": [{"Node Type": "Seq Scan", "Relation Name": "orders", "Schema": "public", "Alias": "orders",
+		"Total Cost": 200000, "Plan Rows": 5000000, "Filter": "(status = 'open'::text)"}]}}]`
+
+func TestServer_CostGuard(t *testing.T) {
+	adapter := &fakeAdapter{dbType: "postgres", table: "orders", plan: seqScanPlan}
+	s := newTestServer(t, map[string]*fakeAdapter{"app": adapter}, "app")
+	args := map[string]interface{}{"query": "SELECT count(*) FROM orders WHERE status = 'open'"}
+
+	// Without thresholds nothing is explained
+	if result, err := s.tools["execute_readonly_query"](context.Background(), args); err != nil || result.IsError || adapter.queries != 1 {
+		t.Fatalf("Expected the query to run, got %+v, %v", result, err)
+	}
+
+	if err := s.SetLimits("app", Limits{MaxRows: 100, MaxCost: 100000}); err != nil {
+		t.Fatal(err)
+	}
+	result, err := s.tools["execute_readonly_query"](context.Background(), args)
+	if err != nil {
+		t.Fatalf("Expected a tool error, got %v", err)
+	}
+	summary, ok := result.StructuredContent.(costSummary)
+	if !result.IsError || !ok || adapter.queries != 1 {
+		t.Fatalf("Expected the query to be rejected before execution, got %+v", result)
+	}
+	if !strings.Contains(result.Content[0].Text, "estimated cost 250000 exceeds the limit of 100000") {
+		t.Errorf("Unexpected message:\n%s", result.Content[0].Text)
+	}
+	if summary.EstimatedCost != 250000.5 || len(summary.Scans) != 1 || summary.Scans[0].Relation != "public.orders" ||
+		summary.Scans[0].EstimatedRows != 5000000 || len(summary.Warnings) == 0 {
+		t.Errorf("Unexpected plan summary %+v", summary)
+	}
+
+	// The aggregate returns one row, but its scan reads five million
+	if err := s.SetLimits("app", Limits{MaxRows: 100, MaxEstimatedRows: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	result, _ = s.tools["execute_readonly_query"](context.Background(), args)
+	if !result.IsError || adapter.queries != 1 || !strings.Contains(result.Content[0].Text, "estimated 5000000 rows from public.orders exceed the limit of 1000") {
+		t.Errorf("Expected the scan to exceed the row limit, got %+v", result)
+	}
+	if err := s.SetLimits("app", Limits{MaxRows: 100, MaxEstimatedRows: 10000000}); err != nil {
+		t.Fatal(err)
+	}
+	if result, err := s.tools["execute_readonly_query"](context.Background(), args); err != nil || result.IsError || adapter.queries != 2 {
+		t.Errorf("Expected the query to run, got %+v, %v", result, err)
+	}
+}
+
+// limitedScanPlan is seqScanPlan's scan stopped after 101 rows
+const limitedScanPlan = `[{"Plan": {"Node Type": "Limit", "Total Cost": 4.04, "Plan Rows": 101,
+	"Plans": [{"Node Type": "Seq Scan", "Relation Name": "orders", "Schema": "public", "Alias": "orders",
+		"Total Cost": 200000, "Plan Rows": 5000000}]}}]`
+
+func TestServer_CostGuardLimit(t *testing.T) {
+	adapter := &fakeAdapter{dbType: "postgres", table: "orders", plan: seqScanPlan, limitedPlan: limitedScanPlan}
+	s := newTestServer(t, map[string]*fakeAdapter{"app": adapter}, "app")
+	if err := s.SetLimits("app", Limits{MaxRows: 100, MaxCost: 1000}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unlimited, the scan would exceed the cost limit; the row limit keeps it cheap
+	result, err := s.tools["execute_readonly_query"](context.Background(), map[string]interface{}{"query": "SELECT * FROM orders"})
+	if err != nil || result.IsError || adapter.queries != 1 {
+		t.Fatalf("Expected the limited query to run, got %+v, %v", result, err)
+	}
+	if adapter.explained != "SELECT * FROM orders\nLIMIT 101" || adapter.executed != adapter.explained {
+		t.Errorf("Expected the limited query to be explained and executed, got %q and %q", adapter.explained, adapter.executed)
+	}
+
+	// The limit caps the root estimate, but not the rows the scan may read
+	if err := s.SetLimits("app", Limits{MaxRows: 100, MaxEstimatedRows: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	result, _ = s.tools["execute_readonly_query"](context.Background(), map[string]interface{}{"query": "SELECT * FROM orders"})
+	if summary, ok := result.StructuredContent.(costSummary); !result.IsError || !ok || summary.EstimatedRows != 101 || summary.LargestScanRows != 5000000 {
+		t.Errorf("Expected the scan to exceed the row limit, got %+v", result)
+	}
+}
+
+func TestServer_CostGuardMySQL(t *testing.T) {
+	adapter := &fakeAdapter{dbType: "mysql", table: "events", plan: `{"query_block": {"select_id": 1,
+		"cost_info": {"query_cost": "52000.10"},
+		"table": {"table_name": "events", "access_type": "ALL", "rows_examined_per_scan": 480000,
+			"attached_condition": "(events.kind = 'click')"}}}`}
+	s := newTestServer(t, map[string]*fakeAdapter{"app": adapter}, "app")
+	if err := s.SetLimits("app", Limits{MaxRows: 100, MaxEstimatedRows: 100000}); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := s.tools["execute_readonly_query"](context.Background(), map[string]interface{}{"query": "SELECT * FROM events WHERE kind = 'click'"})
+	if err != nil || !result.IsError || adapter.queries != 0 {
+		t.Fatalf("Expected the query to be rejected, got %+v, %v", result, err)
+	}
+	if !strings.Contains(result.Content[0].Text, "estimated 480000 rows from events exceed the limit of 100000") {
+		t.Errorf("Unexpected message:\n%s", result.Content[0].Text)
+	}
+	if summary := result.StructuredContent.(costSummary); summary.EstimatedCost != 52000.10 || summary.Scans[0].AccessType != "ALL" {
+		t.Errorf("Unexpected plan summary %+v", summary)
+	}
+}
@@ -3,21 +3,51 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+	"github.com/hieubanhh/dbhubMCP/internal/plan"
+	"github.com/hieubanhh/dbhubMCP/internal/schema"
 	"github.com/hieubanhh/dbhubMCP/internal/security"
 )
 
 // handleListTables handles the list_tables tool
 func (s *Server) handleListTables(ctx context.Context, args map[string]interface{}) (*CallToolResult, error) {
+	conn, err := s.connection(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
 	// Add timeout to context
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	tables, err := s.adapter.ListTables(ctx)
+	includeStats, err := boolArg(args, "include_stats", false)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list tables: %w", err)
+		return nil, err
+	}
+
+	var (
+		tables interface{}
+		count  int
+	)
+	if includeStats {
+		stats, err := conn.Adapter.ListTableStats(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list table statistics: %w", err)
+		}
+		tables, count = stats, len(stats)
+	} else {
+		list, err := conn.Adapter.ListTables(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tables: %w", err)
+		}
+		tables, count = list, len(list)
 	}
 
 	// Format result as JSON
@@ -30,7 +60,7 @@ func (s *Server) handleListTables(ctx context.Context, args map[string]interface
 		Content: []Content{
 			{
 				Type: "text",
-				Text: fmt.Sprintf("Found %d tables:\n\n%s", len(tables), string(resultJSON)),
+				Text: fmt.Sprintf("Found %d tables:\n\n%s", count, string(resultJSON)),
 			},
 		},
 	}, nil
@@ -49,11 +79,16 @@ func (s *Server) handleDescribeTable(ctx context.Context, args map[string]interf
 		return nil, fmt.Errorf("invalid table name: %w", err)
 	}
 
+	conn, err := s.connection(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
 	// Add timeout to context
 	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	columns, err := s.adapter.DescribeTable(ctx, tableName)
+	columns, err := conn.Adapter.DescribeTable(ctx, tableName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to describe table: %w", err)
 	}
@@ -83,20 +118,42 @@ func (s *Server) handleExecuteQuery(ctx context.Context, args map[string]interfa
 	}
 
 	// Validate query (read-only check)
-	if err := s.validator.ValidateReadOnlyQuery(query); err != nil {
+	if err := s.validateQuery(ctx, query); err != nil {
 		return nil, fmt.Errorf("query validation failed: %w", err)
 	}
 
+	conn, err := s.connection(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
 	// Add timeout to context
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, conn.QueryTimeout)
 	defer cancel()
 
+	// Ask for one row more than is returned, so the database stops early
+	// and truncation can be reported. The hooks see the query that runs.
+	query = security.LimitQuery(conn.Adapter.GetDBType(), query, conn.MaxRows+1)
+
+	for _, hook := range s.queryHooks {
+		if err := hook(ctx, conn, query); err != nil {
+			rejectQueries(ctx)
+			var costErr *costError
+			if errors.As(err, &costErr) {
+				return costErr.result()
+			}
+			return nil, err
+		}
+	}
+
 	// Execute query
-	result, err := s.adapter.ExecuteQuery(ctx, query, s.maxRows)
+	result, err := conn.Adapter.ExecuteQuery(ctx, query, conn.MaxRows)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 
+	recordRows(ctx, result.RowCount)
+
 	// Format result
 	var resultText string
 	if result.RowCount == 0 {
@@ -108,8 +165,8 @@ func (s *Server) handleExecuteQuery(ctx context.Context, args map[string]interfa
 		}
 
 		limitNote := ""
-		if result.RowCount >= s.maxRows {
-			limitNote = fmt.Sprintf("\n\n⚠️  Result limited to %d rows (MAX_ROWS setting)", s.maxRows)
+		if result.Truncated {
+			limitNote = fmt.Sprintf("\n\n⚠️  Result limited to %d rows (MAX_ROWS setting)", conn.MaxRows)
 		}
 
 		resultText = fmt.Sprintf("Query executed successfully. Returned %d rows:\n\n%s%s",
@@ -135,16 +192,39 @@ func (s *Server) handleExplainQuery(ctx context.Context, args map[string]interfa
 	}
 
 	// Validate query (read-only check)
-	if err := s.validator.ValidateReadOnlyQuery(query); err != nil {
+	if err := s.validateQuery(ctx, query); err != nil {
 		return nil, fmt.Errorf("query validation failed: %w", err)
 	}
 
+	format := stringArg(args, "format")
+	if format != "" && format != "text" && format != "json" {
+		return nil, fmt.Errorf("format must be 'text' or 'json'")
+	}
+
+	analyze, err := boolArg(args, "analyze", false)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := s.connection(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if analyze && !conn.ExplainAnalyze {
+		return nil, fmt.Errorf("analyze is disabled for database %s; enable explain_analyze for it (or set EXPLAIN_ANALYZE_ENABLED=true) to allow executing explained queries", conn.Name)
+	}
+	opts := database.ExplainOptions{Analyze: analyze, Timeout: conn.QueryTimeout}
+
 	// Add timeout to context
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, conn.QueryTimeout)
 	defer cancel()
 
+	if format == "json" {
+		return s.explainStructured(ctx, conn, query, opts)
+	}
+
 	// Get query execution plan
-	result, err := s.adapter.ExplainQuery(ctx, query)
+	result, err := conn.Adapter.ExplainQuery(ctx, query, opts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to explain query: %w", err)
 	}
@@ -164,3 +244,735 @@ func (s *Server) handleExplainQuery(ctx context.Context, args map[string]interfa
 		},
 	}, nil
 }
+
+// explainStructured returns the JSON plan of a query as a normalized tree
+// together with the problems found in it
+func (s *Server) explainStructured(ctx context.Context, conn *target, query string, opts database.ExplainOptions) (*CallToolResult, error) {
+	raw, err := conn.Adapter.ExplainQueryJSON(ctx, query, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to explain query: %w", err)
+	}
+
+	p, err := plan.Parse(conn.Adapter.GetDBType(), raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// Plan estimates only cover rows after filtering; table sizes make scan warnings accurate
+	analysis := plan.Options{TableRows: make(map[string]int64)}
+	if stats, err := conn.Adapter.ListTableStats(ctx); err != nil {
+		log.Printf("[WARN] Failed to read table statistics for plan analysis: %v", err)
+	} else {
+		for _, t := range stats {
+			analysis.TableRows[t.TableName] = t.EstimatedRows
+			analysis.TableRows[t.TableSchema+"."+t.TableName] = t.EstimatedRows
+		}
+	}
+	p.Warnings = plan.Analyze(p, analysis)
+
+	resultJSON, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to format result: %w", err)
+	}
+
+	return &CallToolResult{
+		Content: []Content{
+			{
+				Type: "text",
+				Text: fmt.Sprintf("Query execution plan (cost %.2f, ~%.0f rows, %d warnings):\n\n%s",
+					p.TotalCost, p.EstimatedRows, len(p.Warnings), string(resultJSON)),
+			},
+		},
+	}, nil
+}
+
+// maxIndexQueries caps the number of queries suggest_indexes explains at once
+const maxIndexQueries = 10
+
+// indexSuggestions is the result of suggest_indexes
+type indexSuggestions struct {
+	Candidates []plan.IndexCandidate `json:"candidates"`
+	Skipped    []string              `json:"skipped,omitempty"`
+}
+
+// handleSuggestIndexes handles the suggest_indexes tool
+func (s *Server) handleSuggestIndexes(ctx context.Context, args map[string]interface{}) (*CallToolResult, error) {
+	queries, err := stringsArg(args, "queries")
+	if err != nil {
+		return nil, err
+	}
+	if len(queries) == 0 || len(queries) > maxIndexQueries {
+		return nil, fmt.Errorf("queries must contain between 1 and %d queries", maxIndexQueries)
+	}
+	validate, err := boolArg(args, "validate", false)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, query := range queries {
+		if err := s.validateQuery(ctx, query); err != nil {
+			return nil, fmt.Errorf("query %d validation failed: %w", i+1, err)
+		}
+	}
+
+	conn, err := s.connection(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Explaining several queries and describing their tables takes a few rounds
+	ctx, cancel := context.WithTimeout(ctx, 2*conn.QueryTimeout)
+	defer cancel()
+
+	analysis := plan.Options{TableRows: make(map[string]int64)}
+	if stats, err := conn.Adapter.ListTableStats(ctx); err != nil {
+		log.Printf("[WARN] Failed to read table statistics for index suggestions: %v", err)
+	} else {
+		for _, t := range stats {
+			analysis.TableRows[t.TableName] = t.EstimatedRows
+			analysis.TableRows[t.TableSchema+"."+t.TableName] = t.EstimatedRows
+		}
+	}
+
+	// Collect candidates from every plan, resolving aliases to catalog tables
+	result := indexSuggestions{Candidates: []plan.IndexCandidate{}}
+	byKey := make(map[string]int)
+	for i, query := range queries {
+		raw, err := conn.Adapter.ExplainQueryJSON(ctx, query, database.ExplainOptions{Timeout: conn.QueryTimeout})
+		if err != nil {
+			return nil, fmt.Errorf("failed to explain query %d: %w", i+1, err)
+		}
+		p, err := plan.Parse(conn.Adapter.GetDBType(), raw)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range plan.CandidateIndexes(p, query, analysis) {
+			detail, err := conn.Adapter.DescribeTableDetailed(ctx, c.Table)
+			if err != nil {
+				result.Skipped = append(result.Skipped, fmt.Sprintf("%s: %v", c.Key(), err))
+				continue
+			}
+			c.Table = detail.TableSchema + "." + detail.TableName
+			if c.Columns = matchColumns(c.Columns, detail.Columns); len(c.Columns) == 0 {
+				continue
+			}
+			if idx := plan.CoveringIndex(c.Columns, detail.Indexes); idx != "" {
+				result.Skipped = append(result.Skipped, fmt.Sprintf("%s: already covered by index %s", c.Key(), idx))
+				continue
+			}
+
+			if j, ok := byKey[c.Key()]; ok {
+				existing := &result.Candidates[j]
+				existing.Reasons = append(existing.Reasons, c.Reasons...)
+				if last := existing.Queries[len(existing.Queries)-1]; last != i+1 {
+					existing.Queries = append(existing.Queries, i+1)
+				}
+				continue
+			}
+			c.Statement = plan.CreateIndexStatement(conn.Adapter.GetDBType(), detail.TableSchema, detail.TableName, c.Columns)
+			c.Queries = []int{i + 1}
+			byKey[c.Key()] = len(result.Candidates)
+			result.Candidates = append(result.Candidates, c)
+		}
+	}
+
+	// Each candidate is costed against the first query it was proposed for
+	if validate && len(result.Candidates) > 0 {
+		for i, query := range queries {
+			var statements []string
+			var targets []int
+			for j, c := range result.Candidates {
+				if c.Queries[0] == i+1 {
+					statements = append(statements, c.Statement)
+					targets = append(targets, j)
+				}
+			}
+			if len(statements) == 0 {
+				continue
+			}
+
+			evaluations, err := conn.Adapter.EvaluateHypotheticalIndexes(ctx, query, statements, conn.QueryTimeout)
+			if err != nil {
+				return nil, fmt.Errorf("failed to validate indexes: %w", err)
+			}
+			for k := range evaluations {
+				result.Candidates[targets[k]].Hypothetical = &evaluations[k]
+			}
+		}
+	}
+
+	resultJSON, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to format result: %w", err)
+	}
+
+	return &CallToolResult{
+		Content: []Content{
+			{
+				Type: "text",
+				Text: fmt.Sprintf("Suggested %d indexes for %d queries. The statements were not executed; review them before creating any index:\n\n%s",
+					len(result.Candidates), len(queries), string(resultJSON)),
+			},
+		},
+	}, nil
+}
+
+// matchColumns maps column names taken from a plan onto the table's columns,
+// dropping names that are not columns of the table (e.g. expressions)
+func matchColumns(names []string, columns []database.ColumnInfo) []string {
+	var matched []string
+	for _, name := range names {
+		for _, col := range columns {
+			if strings.EqualFold(col.ColumnName, name) {
+				matched = append(matched, col.ColumnName)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// handleExportSchema handles the export_schema tool
+func (s *Server) handleExportSchema(ctx context.Context, args map[string]interface{}) (*CallToolResult, error) {
+	format, err := schema.ParseFormat(stringArg(args, "format"))
+	if err != nil {
+		return nil, err
+	}
+
+	maxTokens, err := intArg(args, "max_tokens", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := schema.Filter{
+		SchemaPatterns: schema.ParsePatterns(stringArg(args, "schema_pattern")),
+		TablePatterns:  schema.ParsePatterns(stringArg(args, "table_pattern")),
+	}
+
+	conn, err := s.connection(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Describing every table takes one round of queries per table
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	snap, err := schema.BuildSnapshot(ctx, conn.Adapter, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export schema: %w", err)
+	}
+
+	out, level, err := schema.RenderWithBudget(snap, format, maxTokens)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format result: %w", err)
+	}
+
+	header := fmt.Sprintf("Exported %d tables as %s", len(snap.Tables), format)
+	if level != schema.DetailFull {
+		header += fmt.Sprintf(" (reduced to %s detail to fit %d tokens)", level, maxTokens)
+	}
+
+	return &CallToolResult{
+		Content: []Content{
+			{
+				Type: "text",
+				Text: header + ":\n\n" + out,
+			},
+		},
+	}, nil
+}
+
+// maxDiagramTables bounds the size of generated ER diagrams
+const maxDiagramTables = 100
+
+// handleGenerateERDiagram handles the generate_er_diagram tool
+func (s *Server) handleGenerateERDiagram(ctx context.Context, args map[string]interface{}) (*CallToolResult, error) {
+	format := schema.DiagramFormat(stringArg(args, "format"))
+	if format == "" {
+		format = schema.DiagramMermaid
+	}
+	if format != schema.DiagramMermaid && format != schema.DiagramDOT {
+		return nil, fmt.Errorf("format must be 'mermaid' or 'dot'")
+	}
+
+	columns := schema.ColumnMode(stringArg(args, "columns"))
+	if columns == "" {
+		columns = schema.ColumnsAll
+	}
+	if columns != schema.ColumnsAll && columns != schema.ColumnsKeys && columns != schema.ColumnsNone {
+		return nil, fmt.Errorf("columns must be 'all', 'keys' or 'none'")
+	}
+
+	hops, err := intArg(args, "hops", 1)
+	if err != nil {
+		return nil, err
+	}
+	if hops < 0 {
+		return nil, fmt.Errorf("hops must not be negative")
+	}
+
+	filter := schema.Filter{TablePatterns: schema.ParsePatterns(stringArg(args, "tables"))}
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	seedTable := stringArg(args, "seed_table")
+
+	conn, err := s.connection(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	tables, err := conn.Adapter.ListTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	fks, err := conn.Adapter.ListForeignKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list foreign keys: %w", err)
+	}
+	graph := schema.NewGraph(tables, fks)
+
+	// Collect the requested tables: explicit patterns plus the seed neighborhood
+	selected := make(map[schema.TableRef]bool)
+	if len(filter.TablePatterns) > 0 {
+		for _, t := range tables {
+			if filter.Match(t) {
+				selected[schema.TableRef{Schema: t.TableSchema, Name: t.TableName}] = true
+			}
+		}
+	}
+	if seedTable != "" {
+		seed, err := graph.Resolve(seedTable)
+		if err != nil {
+			return nil, err
+		}
+		for _, ref := range graph.Neighborhood([]schema.TableRef{seed}, hops) {
+			selected[ref] = true
+		}
+	}
+	if len(filter.TablePatterns) == 0 && seedTable == "" {
+		for _, ref := range graph.Tables() {
+			selected[ref] = true
+		}
+	}
+
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no tables matched")
+	}
+	if len(selected) > maxDiagramTables {
+		return nil, fmt.Errorf("diagram would include %d tables (limit %d); narrow it with tables or seed_table/hops", len(selected), maxDiagramTables)
+	}
+
+	var details []database.TableDetail
+	for _, ref := range graph.Tables() {
+		if !selected[ref] {
+			continue
+		}
+		detail, err := conn.Adapter.DescribeTableDetailed(ctx, ref.String())
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe %s: %w", ref, err)
+		}
+		details = append(details, *detail)
+	}
+
+	diagram, err := schema.RenderERDiagram(details, format, columns)
+	if err != nil {
+		return nil, err
+	}
+
+	fence := "mermaid"
+	if format == schema.DiagramDOT {
+		fence = "dot"
+	}
+
+	return &CallToolResult{
+		Content: []Content{
+			{
+				Type: "text",
+				Text: fmt.Sprintf("Entity-relationship diagram of %d tables:\n\n```%s\n%s```", len(details), fence, diagram),
+			},
+		},
+	}, nil
+}
+
+// handleSuggestJoin handles the suggest_join tool
+func (s *Server) handleSuggestJoin(ctx context.Context, args map[string]interface{}) (*CallToolResult, error) {
+	fromTable := stringArg(args, "from_table")
+	toTable := stringArg(args, "to_table")
+	if fromTable == "" || toTable == "" {
+		return nil, fmt.Errorf("from_table and to_table are required and must be strings")
+	}
+	for _, name := range []string{fromTable, toTable} {
+		if err := security.SanitizeTableName(name); err != nil {
+			return nil, fmt.Errorf("invalid table name: %w", err)
+		}
+	}
+
+	maxPaths, err := intArg(args, "max_paths", 3)
+	if err != nil {
+		return nil, err
+	}
+	maxHops, err := intArg(args, "max_hops", 4)
+	if err != nil {
+		return nil, err
+	}
+	if maxPaths < 1 || maxPaths > 10 {
+		return nil, fmt.Errorf("max_paths must be between 1 and 10")
+	}
+	if maxHops < 1 || maxHops > 8 {
+		return nil, fmt.Errorf("max_hops must be between 1 and 8")
+	}
+	includeInferred, err := boolArg(args, "include_inferred", true)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := s.connection(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add timeout to context
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	graph, err := conn.joinGraphs.Get(ctx, conn.Adapter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load relationships: %w", err)
+	}
+
+	from, err := graph.Resolve(fromTable)
+	if err != nil {
+		return nil, err
+	}
+	to, err := graph.Resolve(toTable)
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := graph.JoinPaths(from, to, schema.JoinOptions{
+		MaxPaths:        maxPaths,
+		MaxHops:         maxHops,
+		IncludeInferred: includeInferred,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		return &CallToolResult{
+			Content: []Content{
+				{
+					Type: "text",
+					Text: fmt.Sprintf("No join path found between %s and %s within %d hops.", from, to, maxHops),
+				},
+			},
+		}, nil
+	}
+
+	dbType := conn.Adapter.GetDBType()
+	var b strings.Builder
+	fmt.Fprintf(&b, "Found %d join path(s) from %s to %s:\n", len(paths), from, to)
+	for i, p := range paths {
+		fmt.Fprintf(&b, "\n%d. %d join(s)", i+1, len(p.Steps))
+		if p.Inferred() {
+			b.WriteString(", uses relationships inferred from column names (no foreign key); verify before relying on it")
+		}
+		fmt.Fprintf(&b, "\n\n```sql\n%s\n```\n", p.SQL(dbType))
+	}
+
+	return &CallToolResult{
+		Content: []Content{
+			{
+				Type: "text",
+				Text: b.String(),
+			},
+		},
+	}, nil
+}
+
+// handleRefreshSchema handles the refresh_schema tool
+func (s *Server) handleRefreshSchema(ctx context.Context, args map[string]interface{}) (*CallToolResult, error) {
+	conn, err := s.lookupConnection(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateSchema(conn)
+
+	return &CallToolResult{
+		Content: []Content{
+			{
+				Type: "text",
+				Text: fmt.Sprintf("Schema metadata cache of %s cleared. The next call will read tables and columns from the database.", conn.Name),
+			},
+		},
+	}, nil
+}
+
+// handleProfileTable handles the profile_table tool
+func (s *Server) handleProfileTable(ctx context.Context, args map[string]interface{}) (*CallToolResult, error) {
+	tableName, ok := args["table_name"].(string)
+	if !ok || tableName == "" {
+		return nil, fmt.Errorf("table_name is required and must be a string")
+	}
+	if err := security.SanitizeTableName(tableName); err != nil {
+		return nil, fmt.Errorf("invalid table name: %w", err)
+	}
+
+	sampleRows, err := intArg(args, "sample_rows", 100000)
+	if err != nil {
+		return nil, err
+	}
+	topN, err := intArg(args, "top_n", 5)
+	if err != nil {
+		return nil, err
+	}
+	if sampleRows < 0 {
+		return nil, fmt.Errorf("sample_rows must not be negative")
+	}
+	if topN < 0 || topN > 50 {
+		return nil, fmt.Errorf("top_n must be between 0 and 50")
+	}
+
+	distinct := stringArg(args, "distinct")
+	if distinct != "" && distinct != "exact" && distinct != "approximate" {
+		return nil, fmt.Errorf("distinct must be 'exact' or 'approximate'")
+	}
+
+	conn, err := s.connection(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := database.ProfileOptions{
+		Columns:             schema.ParsePatterns(stringArg(args, "columns")),
+		SampleRows:          sampleRows,
+		TopN:                topN,
+		ApproximateDistinct: distinct == "approximate",
+		Timeout:             conn.QueryTimeout,
+	}
+
+	// Add timeout to context
+	ctx, cancel := context.WithTimeout(ctx, conn.QueryTimeout)
+	defer cancel()
+
+	profile, err := conn.Adapter.ProfileTable(ctx, tableName, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to profile table: %w", err)
+	}
+
+	resultJSON, err := json.MarshalIndent(profile, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to format result: %w", err)
+	}
+
+	summary := fmt.Sprintf("Profiled %d of ~%d rows in %s", profile.RowsProfiled, profile.EstimatedRows, profile.TableName)
+	if profile.Sampled {
+		summary += fmt.Sprintf(" (sampled with %s; counts describe the sample)", profile.SampleMethod)
+	}
+
+	return &CallToolResult{
+		Content: []Content{
+			{
+				Type: "text",
+				Text: fmt.Sprintf("%s:\n\n%s", summary, string(resultJSON)),
+			},
+		},
+	}, nil
+}
+
+// handleTableStats handles the table_stats tool
+func (s *Server) handleTableStats(ctx context.Context, args map[string]interface{}) (*CallToolResult, error) {
+	tableName, ok := args["table_name"].(string)
+	if !ok || tableName == "" {
+		return nil, fmt.Errorf("table_name is required and must be a string")
+	}
+	if err := security.SanitizeTableName(tableName); err != nil {
+		return nil, fmt.Errorf("invalid table name: %w", err)
+	}
+
+	exact, err := boolArg(args, "exact_count", false)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := s.connection(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+
+	// Add timeout to context
+	ctx, cancel := context.WithTimeout(ctx, conn.QueryTimeout)
+	defer cancel()
+
+	stats, err := conn.Adapter.TableStats(ctx, tableName, database.TableStatsOptions{
+		ExactCount: exact,
+		Timeout:    conn.QueryTimeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get table statistics: %w", err)
+	}
+
+	resultJSON, err := json.MarshalIndent(stats, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to format result: %w", err)
+	}
+
+	rows := fmt.Sprintf("~%d rows", stats.EstimatedRows)
+	if stats.ExactRows != nil {
+		rows = fmt.Sprintf("%d rows", *stats.ExactRows)
+	}
+
+	return &CallToolResult{
+		Content: []Content{
+			{
+				Type: "text",
+				Text: fmt.Sprintf("Table %s.%s has %s, %s on disk:\n\n%s",
+					stats.TableSchema, stats.TableName, rows, stats.TotalSize, string(resultJSON)),
+			},
+		},
+	}, nil
+}
+
+// maxSampleRows caps sample_rows so a sample stays small enough to read
+const maxSampleRows = 100
+
+// maxSampleCellLength is the number of characters kept from each text value
+const maxSampleCellLength = 200
+
+// handleSampleRows handles the sample_rows tool
+func (s *Server) handleSampleRows(ctx context.Context, args map[string]interface{}) (*CallToolResult, error) {
+	tableName, ok := args["table_name"].(string)
+	if !ok || tableName == "" {
+		return nil, fmt.Errorf("table_name is required and must be a string")
+	}
+	if err := security.SanitizeTableName(tableName); err != nil {
+		return nil, fmt.Errorf("invalid table name: %w", err)
+	}
+
+	n, err := intArg(args, "n", 10)
+	if err != nil {
+		return nil, err
+	}
+	if n < 1 || n > maxSampleRows {
+		return nil, fmt.Errorf("n must be between 1 and %d", maxSampleRows)
+	}
+	conn, err := s.connection(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if n > conn.MaxRows {
+		n = conn.MaxRows
+	}
+	seed, err := intArg(args, "seed", 42)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := database.SampleOptions{
+		Columns:       schema.ParsePatterns(stringArg(args, "columns")),
+		N:             n,
+		Seed:          int64(seed),
+		MaxCellLength: maxSampleCellLength,
+		Timeout:       conn.QueryTimeout,
+	}
+
+	// Add timeout to context
+	ctx, cancel := context.WithTimeout(ctx, conn.QueryTimeout)
+	defer cancel()
+
+	sample, err := conn.Adapter.SampleRows(ctx, tableName, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sample rows: %w", err)
+	}
+	recordRows(ctx, sample.RowCount)
+
+	resultJSON, err := json.MarshalIndent(sample, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to format result: %w", err)
+	}
+
+	return &CallToolResult{
+		Content: []Content{
+			{
+				Type: "text",
+				Text: fmt.Sprintf("Sampled %d of ~%d rows from %s (%s, seed %d):\n\n%s",
+					sample.RowCount, sample.EstimatedRows, sample.TableName, sample.SampleMethod, sample.Seed, string(resultJSON)),
+			},
+		},
+	}, nil
+}
+
+// stringArg returns an optional string argument, or "" when absent
+func stringArg(args map[string]interface{}, key string) string {
+	value, _ := args[key].(string)
+	return value
+}
+
+// intArg returns an optional integer argument. JSON numbers arrive as float64,
+// but numeric strings are accepted too since some clients send them that way.
+func intArg(args map[string]interface{}, key string, defaultValue int) (int, error) {
+	switch v := args[key].(type) {
+	case nil:
+		return defaultValue, nil
+	case float64:
+		if v != float64(int(v)) {
+			return 0, fmt.Errorf("%s must be an integer", key)
+		}
+		return int(v), nil
+	case string:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("%s must be an integer", key)
+		}
+		return n, nil
+	default:
+		return 0, fmt.Errorf("%s must be an integer", key)
+	}
+}
+
+// boolArg returns an optional boolean argument
+func boolArg(args map[string]interface{}, key string, defaultValue bool) (bool, error) {
+	switch v := args[key].(type) {
+	case nil:
+		return defaultValue, nil
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return false, fmt.Errorf("%s must be a boolean", key)
+		}
+		return b, nil
+	default:
+		return false, fmt.Errorf("%s must be a boolean", key)
+	}
+}
+
+// stringsArg returns an optional list of strings. A single string is treated
+// as a list of one.
+func stringsArg(args map[string]interface{}, key string) ([]string, error) {
+	switch v := args[key].(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a list of strings", key)
+			}
+			values = append(values, str)
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("%s must be a list of strings", key)
+	}
+}
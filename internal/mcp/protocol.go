@@ -1,5 +1,11 @@
 package mcp
 
+import (
+	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/auth"
+)
+
 // JSON-RPC 2.0 protocol structures
 
 // Request represents a JSON-RPC 2.0 request
@@ -8,6 +14,13 @@ type Request struct {
 	ID      interface{} `json:"id,omitempty"`
 	Method  string      `json:"method"`
 	Params  interface{} `json:"params,omitempty"`
+
+	// identity is the client the transport authenticated, if any
+	identity *auth.Identity
+
+	// client identifies the caller for rate limits, e.g. "ip:10.0.0.1";
+	// empty for the stdio client
+	client string
 }
 
 // Response represents a JSON-RPC 2.0 response
@@ -16,6 +29,16 @@ type Response struct {
 	ID      interface{} `json:"id,omitempty"`
 	Result  interface{} `json:"result,omitempty"`
 	Error   *ErrorObj   `json:"error,omitempty"`
+
+	// retryAfter is set when the request exceeded a rate limit
+	retryAfter time.Duration
+}
+
+// Notification represents a JSON-RPC 2.0 notification sent by the server
+type Notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
 }
 
 // ErrorObj represents a JSON-RPC 2.0 error object
@@ -63,7 +86,8 @@ type InitializeResult struct {
 
 // ServerCapabilities represents server capabilities
 type ServerCapabilities struct {
-	Tools *ToolsCapability `json:"tools,omitempty"`
+	Tools     *ToolsCapability     `json:"tools,omitempty"`
+	Resources *ResourcesCapability `json:"resources,omitempty"`
 }
 
 // ToolsCapability represents tools capability
@@ -71,6 +95,11 @@ type ToolsCapability struct {
 	ListChanged bool `json:"listChanged,omitempty"`
 }
 
+// ResourcesCapability represents resources capability
+type ResourcesCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
 // ServerInfo represents server information
 type ServerInfo struct {
 	Name    string `json:"name"`
@@ -98,9 +127,10 @@ type InputSchema struct {
 
 // Property represents a property in the input schema
 type Property struct {
-	Type        string   `json:"type"`
-	Description string   `json:"description,omitempty"`
-	Enum        []string `json:"enum,omitempty"`
+	Type        string    `json:"type"`
+	Description string    `json:"description,omitempty"`
+	Enum        []string  `json:"enum,omitempty"`
+	Items       *Property `json:"items,omitempty"`
 }
 
 // CallToolParams represents the parameters for tools/call
@@ -111,8 +141,9 @@ type CallToolParams struct {
 
 // CallToolResult represents the result of tools/call
 type CallToolResult struct {
-	Content []Content `json:"content"`
-	IsError bool      `json:"isError,omitempty"`
+	Content           []Content   `json:"content"`
+	StructuredContent interface{} `json:"structuredContent,omitempty"`
+	IsError           bool        `json:"isError,omitempty"`
 }
 
 // Content represents content in a tool result
@@ -120,3 +151,33 @@ type Content struct {
 	Type string `json:"type"`
 	Text string `json:"text"`
 }
+
+// Resource represents an MCP resource definition
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+// ListResourcesResult represents the result of resources/list
+type ListResourcesResult struct {
+	Resources []Resource `json:"resources"`
+}
+
+// ReadResourceParams represents the parameters for resources/read
+type ReadResourceParams struct {
+	URI string `json:"uri"`
+}
+
+// ReadResourceResult represents the result of resources/read
+type ReadResourceResult struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
+// ResourceContents represents the text contents of a resource
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text"`
+}
@@ -0,0 +1,102 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/ratelimit"
+)
+
+// rateLimitedCode is the JSON-RPC error code of requests other than tool
+// calls that exceeded a rate limit
+const rateLimitedCode = -32029
+
+// SetRateLimits limits the request rate, expensive tool calls and daily
+// usage of each client. Usage counted so far is kept when limits change.
+func (s *Server) SetRateLimits(limits ratelimit.Limits) {
+	s.limiter.SetLimits(limits)
+}
+
+// clientKey identifies the caller of req for rate limits
+func (req *Request) clientKey() string {
+	if req.client != "" {
+		return req.client
+	}
+	if req.identity != nil {
+		return "client:" + req.identity.Name
+	}
+	return "stdio"
+}
+
+// expensiveCall reports whether a tool call runs queries whose cost the
+// caller controls: arbitrary queries, EXPLAIN ANALYZE and table profiles
+func expensiveCall(name string, args map[string]interface{}) bool {
+	switch name {
+	case "execute_readonly_query", "profile_table":
+		return true
+	case "explain_query":
+		analyze, _ := boolArg(args, "analyze", false)
+		return analyze
+	}
+	return false
+}
+
+// allow checks the rate limits of a request
+func (s *Server) allow(req *Request, expensive bool) *ratelimit.Error {
+	err := s.limiter.Allow(req.clientKey(), expensive)
+	if err == nil {
+		return nil
+	}
+	log.Printf("[WARN] Request %s from %s rejected: %v", req.Method, req.clientKey(), err)
+	return err.(*ratelimit.Error)
+}
+
+// rateLimited answers a request that exceeded a limit. Tool calls get a
+// tool error the model can read; the HTTP transport answers 429.
+func rateLimited(req *Request, err *ratelimit.Error) *Response {
+	details := map[string]interface{}{
+		"error":         "rate_limited",
+		"limit":         err.Limit,
+		"retryAfterSec": int(math.Ceil(err.RetryAfter.Seconds())),
+	}
+	resp := &Response{JSONRPC: "2.0", ID: req.ID, retryAfter: err.RetryAfter}
+	if req.Method == "tools/call" {
+		resp.Result = &CallToolResult{
+			Content:           []Content{{Type: "text", Text: fmt.Sprintf("Error: %v", err)}},
+			StructuredContent: details,
+			IsError:           true,
+		}
+	} else {
+		resp.Error = &ErrorObj{Code: rateLimitedCode, Message: err.Error(), Data: details}
+	}
+	return resp
+}
+
+// usage is what a tool call consumed, counted against daily quotas, and
+// the queries it validated, for the audit log
+type usage struct {
+	rows       int
+	queries    []string
+	validation string // "passed" or "rejected"; empty without queries
+}
+
+type usageKey struct{}
+
+// recordRows counts rows a tool call returns to the client
+func recordRows(ctx context.Context, rows int) {
+	if u, ok := ctx.Value(usageKey{}).(*usage); ok {
+		u.rows += rows
+	}
+}
+
+// callTool runs a tool handler, collecting its usage in u, and adds the
+// rows it returned and its duration to the caller's daily usage
+func (s *Server) callTool(ctx context.Context, req *Request, handler ToolHandler, args map[string]interface{}, u *usage) (*CallToolResult, error) {
+	start := time.Now()
+	result, err := handler(context.WithValue(ctx, usageKey{}, u), args)
+	s.limiter.Record(req.clientKey(), u.rows, time.Since(start))
+	return result, err
+}
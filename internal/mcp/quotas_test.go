@@ -0,0 +1,118 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+	"github.com/hieubanhh/dbhubMCP/internal/ratelimit"
+)
+
+func (a *fakeAdapter) ExecuteQuery(ctx context.Context, query string, maxRows int) (*database.QueryResult, error) {
+	a.queries++
+	a.executed = query
+	return &database.QueryResult{
+		Columns:  []string{"n"},
+		Rows:     []map[string]interface{}{{"n": 1}, {"n": 2}, {"n": 3}},
+		RowCount: 3,
+	}, nil
+}
+
+func TestServer_RateLimits(t *testing.T) {
+	s := newTestServer(t, map[string]*fakeAdapter{"app": {dbType: "postgres", table: "orders"}}, "app")
+	s.SetRateLimits(ratelimit.Limits{RequestsPerMinute: 100, ExpensivePerMinute: 2, DailyRows: 5})
+
+	query := func(client string) *Response {
+		return s.handleRequest(context.Background(), &Request{JSONRPC: "2.0", ID: 1, Method: "tools/call", client: client,
+			Params: map[string]interface{}{"name": "execute_readonly_query", "arguments": map[string]interface{}{"query": "SELECT n FROM t"}}})
+	}
+
+	// The second query returns rows past the daily quota, so the third is rejected
+	for i := 0; i < 2; i++ {
+		if resp := query("ip:10.0.0.1"); resp.Result.(*CallToolResult).IsError {
+			t.Fatalf("query %d: unexpected error %+v", i+1, resp.Result)
+		}
+	}
+	resp := query("ip:10.0.0.1")
+	result := resp.Result.(*CallToolResult)
+	details, _ := result.StructuredContent.(map[string]interface{})
+	if !result.IsError || details["limit"] != "daily_rows" || resp.retryAfter <= 0 {
+		t.Errorf("Expected the row quota to be exceeded, got %+v", result)
+	}
+
+	// Another client is limited separately, to two expensive calls a minute
+	s.SetRateLimits(ratelimit.Limits{RequestsPerMinute: 100, ExpensivePerMinute: 2})
+	query("ip:10.0.0.2")
+	query("ip:10.0.0.2")
+	result = query("ip:10.0.0.2").Result.(*CallToolResult)
+	if details, _ := result.StructuredContent.(map[string]interface{}); !result.IsError || details["limit"] != "expensive_calls" {
+		t.Errorf("Expected the expensive call limit, got %+v", result)
+	}
+	if resp := s.handleRequest(context.Background(), &Request{JSONRPC: "2.0", ID: 2, Method: "tools/call", client: "ip:10.0.0.2",
+		Params: map[string]interface{}{"name": "list_tables"}}); resp.Result.(*CallToolResult).IsError {
+		t.Errorf("Expected cheap tools to stay available, got %+v", resp.Result)
+	}
+
+	s.SetRateLimits(ratelimit.Limits{RequestsPerMinute: 1})
+	s.handleRequest(context.Background(), &Request{JSONRPC: "2.0", ID: 3, Method: "tools/list"})
+	resp = s.handleRequest(context.Background(), &Request{JSONRPC: "2.0", ID: 4, Method: "tools/list"})
+	if resp.Error == nil || resp.Error.Code != rateLimitedCode || !strings.Contains(resp.Error.Message, "requests") {
+		t.Errorf("Expected a rate limit error for the stdio client, got %+v", resp)
+	}
+}
+
+func TestExpensiveCall(t *testing.T) {
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want bool
+	}{
+		{"execute_readonly_query", nil, true},
+		{"profile_table", nil, true},
+		{"explain_query", map[string]interface{}{"analyze": true}, true},
+		{"explain_query", nil, false},
+		{"list_tables", nil, false},
+	}
+	for _, tt := range tests {
+		if got := expensiveCall(tt.name, tt.args); got != tt.want {
+			t.Errorf("%s %v: expected %v, got %v", tt.name, tt.args, tt.want, got)
+		}
+	}
+}
+
+func TestHTTPTransport_TooManyRequests(t *testing.T) {
+	transport := NewHTTPTransport(HTTPTransportConfig{Addr: ":8080", CORSOrigins: []string{"*"}})
+	body, _ := json.Marshal(Request{JSONRPC: "2.0", ID: 1, Method: "tools/list"})
+	req := httptest.NewRequest("POST", "/mcp", bytes.NewBuffer(body))
+	req.RemoteAddr = "10.0.0.1:5000"
+	w := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		transport.handleMCPRequest(w, req)
+		close(done)
+	}()
+	queued, err := transport.ReadRequest()
+	if err != nil {
+		t.Fatalf("ReadRequest failed: %v", err)
+	}
+	if queued.client != "ip:10.0.0.1" {
+		t.Errorf("Expected anonymous clients to be keyed by IP, got %q", queued.client)
+	}
+	transport.WriteResponse(rateLimited(queued, &ratelimit.Error{Limit: "requests", RetryAfter: 1500 * time.Millisecond}))
+	<-done
+
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "2" {
+		t.Errorf("Expected 429 with Retry-After 2, got %d %q", w.Code, w.Header().Get("Retry-After"))
+	}
+	var resp Response
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || resp.Error == nil || resp.Error.Code != rateLimitedCode {
+		t.Errorf("Expected a JSON-RPC rate limit error, got %+v, %v", resp, err)
+	}
+}
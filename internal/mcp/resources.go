@@ -0,0 +1,196 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/auth"
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+	"github.com/hieubanhh/dbhubMCP/internal/security"
+)
+
+// tableResourcePrefix is the URI prefix of the per-table schema resources
+const tableResourcePrefix = "dbhub://tables/"
+
+// handleResourcesList handles the resources/list request. Every table of the
+// default database is exposed as a resource holding its full definition,
+// when the caller's role allows list_tables.
+func (s *Server) handleResourcesList(ctx context.Context, req *Request) *Response {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var tables []database.TableInfo
+	var conn *target
+	var err error
+	if role := auth.RoleFromContext(ctx); !role.AllowsTool("list_tables") {
+		err = &auth.AccessError{Object: "tool list_tables", Role: role.Name}
+	} else if conn, err = s.connection(ctx, nil); err == nil {
+		tables, err = conn.Adapter.ListTables(ctx)
+	}
+	if err != nil {
+		return &Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &ErrorObj{
+				Code:    -32603,
+				Message: "Failed to list resources",
+				Data:    err.Error(),
+			},
+		}
+	}
+
+	resources := make([]Resource, 0, len(tables))
+	for _, t := range tables {
+		name := t.TableName
+		if t.TableSchema != "" {
+			name = t.TableSchema + "." + t.TableName
+		}
+		resources = append(resources, Resource{
+			URI:         tableResourcePrefix + name,
+			Name:        name,
+			Description: fmt.Sprintf("Definition of %s %s", strings.ToLower(t.TableType), name),
+			MimeType:    "application/json",
+		})
+	}
+
+	return &Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result:  ListResourcesResult{Resources: resources},
+	}
+}
+
+// handleResourcesRead handles the resources/read request. Reading a table
+// definition needs the same permission as describe_table.
+func (s *Server) handleResourcesRead(ctx context.Context, req *Request) *Response {
+	invalid := func(message string, data interface{}) *Response {
+		return &Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &ErrorObj{
+				Code:    -32602,
+				Message: message,
+				Data:    data,
+			},
+		}
+	}
+
+	paramsJSON, err := json.Marshal(req.Params)
+	if err != nil {
+		return invalid("Invalid params", err.Error())
+	}
+	var params ReadResourceParams
+	if err := json.Unmarshal(paramsJSON, &params); err != nil {
+		return invalid("Invalid params", err.Error())
+	}
+
+	tableName := strings.TrimPrefix(params.URI, tableResourcePrefix)
+	if tableName == params.URI {
+		return invalid(fmt.Sprintf("Unknown resource: %s", params.URI), nil)
+	}
+	if err := security.SanitizeTableName(tableName); err != nil {
+		return invalid("Invalid table name", err.Error())
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	var detail *database.TableDetail
+	var conn *target
+	if role := auth.RoleFromContext(ctx); !role.AllowsTool("describe_table") {
+		err = &auth.AccessError{Object: "tool describe_table", Role: role.Name}
+	} else if conn, err = s.connection(ctx, nil); err == nil {
+		detail, err = conn.Adapter.DescribeTableDetailed(ctx, tableName)
+	}
+	if err != nil {
+		return invalid(fmt.Sprintf("Unknown resource: %s", params.URI), err.Error())
+	}
+
+	data, err := json.MarshalIndent(detail, "", "  ")
+	if err != nil {
+		return &Response{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Error: &ErrorObj{
+				Code:    -32603,
+				Message: "Failed to format resource",
+				Data:    err.Error(),
+			},
+		}
+	}
+
+	return &Response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+		Result: ReadResourceResult{
+			Contents: []ResourceContents{
+				{
+					URI:      params.URI,
+					MimeType: "application/json",
+					Text:     string(data),
+				},
+			},
+		},
+	}
+}
+
+// invalidateSchema drops every cached view of a database's schema and tells
+// the client that the resource list may have changed
+func (s *Server) invalidateSchema(conn *connection) {
+	if cache, ok := conn.Adapter.(database.Invalidator); ok {
+		cache.Invalidate()
+	}
+	conn.invalidateJoinGraphs()
+
+	if writer, ok := s.transport.(NotificationWriter); ok {
+		err := writer.WriteNotification(&Notification{
+			JSONRPC: "2.0",
+			Method:  "notifications/resources/list_changed",
+		})
+		if err != nil {
+			log.Printf("[ERROR] Failed to send resources/list_changed: %v", err)
+		}
+	}
+}
+
+// watchSchema polls the schema fingerprint of every connected database and
+// invalidates its caches when it changes. It returns when ctx is cancelled.
+func (s *Server) watchSchema(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := make(map[string]string)
+	for {
+		for _, name := range s.connectionNames {
+			conn := s.connections[name]
+			if !conn.isConnected() {
+				continue
+			}
+
+			checkCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			fingerprint, err := conn.Adapter.SchemaFingerprint(checkCtx)
+			cancel()
+
+			switch {
+			case err != nil:
+				log.Printf("[WARN] Schema change detection failed for %s: %v", name, err)
+			case last[name] != "" && fingerprint != last[name]:
+				log.Printf("[INFO] Schema change detected in %s, refreshing metadata", name)
+				s.invalidateSchema(conn)
+			}
+			if err == nil {
+				last[name] = fingerprint
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"time"
 
-	"github.com/hieubanhh/dbhubMCP/internal/database"
+	"github.com/hieubanhh/dbhubMCP/internal/audit"
+	"github.com/hieubanhh/dbhubMCP/internal/auth"
+	"github.com/hieubanhh/dbhubMCP/internal/ratelimit"
 	"github.com/hieubanhh/dbhubMCP/internal/security"
 )
 
@@ -22,42 +25,94 @@ type ToolHandler func(ctx context.Context, args map[string]interface{}) (*CallTo
 
 // Server represents the MCP server
 type Server struct {
-	transport MessageTransport
-	adapter   database.Adapter
-	validator *security.Validator
-	tools     map[string]ToolHandler
-	toolDefs  []Tool
-	maxRows   int
-	queryTimeout context.Context
+	transport  MessageTransport
+	validator  *security.Validator
+	tools      map[string]ToolHandler
+	toolDefs   []Tool
+	schemaPoll time.Duration
+
+	// Interval at which rotated database credentials are picked up
+	credentialRefresh time.Duration
+
+	// Per-client request rates and daily quotas; unlimited by default
+	limiter *ratelimit.Limiter
+
+	// Checks run before execute_readonly_query executes a query
+	queryHooks []queryHook
+
+	// Log of every tool call and the client info it is annotated with;
+	// nil disables auditing
+	audit   *audit.Logger
+	clients clientInfos
+
+	// Databases by name; connectionNames keeps registration order
+	connections       map[string]*connection
+	connectionNames   []string
+	defaultConnection string
 }
 
-// NewServer creates a new MCP server
-func NewServer(transport MessageTransport, adapter database.Adapter, validator *security.Validator, maxRows int) *Server {
+// joinGraphTTL is how long the relationship graph used by suggest_join is reused
+const joinGraphTTL = 10 * time.Minute
+
+// NewServer creates a new MCP server. Databases are registered with
+// AddConnection before Run.
+func NewServer(transport MessageTransport, validator *security.Validator) *Server {
 	s := &Server{
-		transport: transport,
-		adapter:   adapter,
-		validator: validator,
-		tools:     make(map[string]ToolHandler),
-		maxRows:   maxRows,
+		transport:   transport,
+		validator:   validator,
+		tools:       make(map[string]ToolHandler),
+		connections: make(map[string]*connection),
+		limiter:     ratelimit.New(ratelimit.Limits{}),
 	}
 
 	// Register tools
 	s.registerTools()
+	s.queryHooks = []queryHook{s.checkQueryCost}
 
 	return s
 }
 
+// EnableSchemaChangeDetection makes Run poll the database for schema changes
+// at the given interval and refresh cached metadata when one is seen
+func (s *Server) EnableSchemaChangeDetection(interval time.Duration) {
+	s.schemaPoll = interval
+}
+
+// databaseProperty is the argument every database tool takes to select a
+// connection
+var databaseProperty = Property{
+	Type:        "string",
+	Description: "Name of the database to use, as returned by list_connections (default: the configured default database)",
+}
+
 // registerTools registers all available tools
 func (s *Server) registerTools() {
-	// list_tables tool
+	// list_connections tool
 	s.RegisterTool(Tool{
-		Name:        "list_tables",
-		Description: "Lists all tables in the connected database. Returns table names, schemas, and types.",
+		Name:        "list_connections",
+		Description: "Lists the databases this server can query, with their type, connection status and limits. Pass a name as the database argument of other tools.",
 		InputSchema: InputSchema{
 			Type:       "object",
 			Properties: map[string]Property{},
 			Required:   []string{},
 		},
+	}, s.handleListConnections)
+
+	// list_tables tool
+	s.RegisterTool(Tool{
+		Name:        "list_tables",
+		Description: "Lists all tables in the connected database. Returns table names, schemas, and types.",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]Property{
+				"database": databaseProperty,
+				"include_stats": {
+					Type:        "boolean",
+					Description: "Include estimated row counts and on-disk sizes (default: false)",
+				},
+			},
+			Required: []string{},
+		},
 	}, s.handleListTables)
 
 	// describe_table tool
@@ -67,6 +122,7 @@ func (s *Server) registerTools() {
 		InputSchema: InputSchema{
 			Type: "object",
 			Properties: map[string]Property{
+				"database": databaseProperty,
 				"table_name": {
 					Type:        "string",
 					Description: "The name of the table to describe",
@@ -83,6 +139,7 @@ func (s *Server) registerTools() {
 		InputSchema: InputSchema{
 			Type: "object",
 			Properties: map[string]Property{
+				"database": databaseProperty,
 				"query": {
 					Type:        "string",
 					Description: "The SQL SELECT query to execute",
@@ -99,14 +156,241 @@ func (s *Server) registerTools() {
 		InputSchema: InputSchema{
 			Type: "object",
 			Properties: map[string]Property{
+				"database": databaseProperty,
 				"query": {
 					Type:        "string",
 					Description: "The SQL query to explain",
 				},
+				"format": {
+					Type:        "string",
+					Description: "text returns the raw EXPLAIN rows; json returns a parsed plan tree with warnings about full scans, nested loops, sorts and unused indexes (default: text)",
+					Enum:        []string{"text", "json"},
+				},
+				"analyze": {
+					Type:        "boolean",
+					Description: "Execute the query in a rolled-back read-only transaction to report actual rows and timings (EXPLAIN ANALYZE). Only available when enabled in the server configuration (default: false)",
+				},
 			},
 			Required: []string{"query"},
 		},
 	}, s.handleExplainQuery)
+
+	// export_schema tool
+	s.RegisterTool(Tool{
+		Name:        "export_schema",
+		Description: "Exports the schema of many tables at once as reconstructed DDL, a stable JSON document, or a Markdown data dictionary. Supports schema/table filters and a token budget that drops low-value details to fit.",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]Property{
+				"database": databaseProperty,
+				"format": {
+					Type:        "string",
+					Description: "Output format (default: markdown)",
+					Enum:        []string{"ddl", "json", "markdown"},
+				},
+				"schema_pattern": {
+					Type:        "string",
+					Description: "Comma-separated glob patterns of schemas to include (e.g. 'public,sales_*')",
+				},
+				"table_pattern": {
+					Type:        "string",
+					Description: "Comma-separated glob patterns of tables to include; patterns with a dot match schema.table",
+				},
+				"max_tokens": {
+					Type:        "integer",
+					Description: "Approximate token budget for the output; details are dropped until it fits (0 = unlimited)",
+				},
+			},
+			Required: []string{},
+		},
+	}, s.handleExportSchema)
+
+	// generate_er_diagram tool
+	s.RegisterTool(Tool{
+		Name:        "generate_er_diagram",
+		Description: "Generates an entity-relationship diagram from foreign key metadata as Mermaid erDiagram or Graphviz DOT text. Select tables explicitly, or a seed table plus every table within N relationship hops.",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]Property{
+				"database": databaseProperty,
+				"tables": {
+					Type:        "string",
+					Description: "Comma-separated table names or glob patterns to include",
+				},
+				"seed_table": {
+					Type:        "string",
+					Description: "Table to start from; tables within 'hops' foreign key steps are included",
+				},
+				"hops": {
+					Type:        "integer",
+					Description: "Number of relationship hops from seed_table (default: 1)",
+				},
+				"format": {
+					Type:        "string",
+					Description: "Diagram format (default: mermaid)",
+					Enum:        []string{"mermaid", "dot"},
+				},
+				"columns": {
+					Type:        "string",
+					Description: "Columns drawn in each entity (default: all)",
+					Enum:        []string{"all", "keys", "none"},
+				},
+			},
+			Required: []string{},
+		},
+	}, s.handleGenerateERDiagram)
+
+	// suggest_join tool
+	s.RegisterTool(Tool{
+		Name:        "suggest_join",
+		Description: "Finds the shortest join paths between two tables using foreign keys (plus <table>_id naming when foreign keys are missing) and returns ready-to-use JOIN ... ON clauses.",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]Property{
+				"database": databaseProperty,
+				"from_table": {
+					Type:        "string",
+					Description: "Table to start from (optionally schema-qualified)",
+				},
+				"to_table": {
+					Type:        "string",
+					Description: "Table to reach (optionally schema-qualified)",
+				},
+				"max_paths": {
+					Type:        "integer",
+					Description: "Number of alternative paths to return (default: 3)",
+				},
+				"max_hops": {
+					Type:        "integer",
+					Description: "Longest join chain to consider (default: 4)",
+				},
+				"include_inferred": {
+					Type:        "boolean",
+					Description: "Use <table>_id columns without a foreign key as relationships (default: true)",
+				},
+			},
+			Required: []string{"from_table", "to_table"},
+		},
+	}, s.handleSuggestJoin)
+
+	// refresh_schema tool
+	s.RegisterTool(Tool{
+		Name:        "refresh_schema",
+		Description: "Drops cached schema metadata (tables, columns, keys, join graph) so that the next call reads it fresh from the database. Use after DDL changes.",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]Property{
+				"database": databaseProperty,
+			},
+			Required: []string{},
+		},
+	}, s.handleRefreshSchema)
+
+	// profile_table tool
+	s.RegisterTool(Tool{
+		Name:        "profile_table",
+		Description: "Profiles the values of a table: per column null ratio, distinct count, min/max, most frequent values and string length statistics. Large tables are sampled; runs read-only within the query timeout.",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]Property{
+				"database": databaseProperty,
+				"table_name": {
+					Type:        "string",
+					Description: "The name of the table to profile",
+				},
+				"columns": {
+					Type:        "string",
+					Description: "Comma-separated columns to profile (default: all)",
+				},
+				"sample_rows": {
+					Type:        "integer",
+					Description: "Sample size for tables larger than this (default: 100000, 0 = scan every row)",
+				},
+				"top_n": {
+					Type:        "integer",
+					Description: "Number of most frequent values per column (default: 5, max: 50)",
+				},
+				"distinct": {
+					Type:        "string",
+					Description: "exact counts distinct values in the profiled rows; approximate uses planner statistics where available (default: exact)",
+					Enum:        []string{"exact", "approximate"},
+				},
+			},
+			Required: []string{"table_name"},
+		},
+	}, s.handleProfileTable)
+
+	// sample_rows tool
+	s.RegisterTool(Tool{
+		Name:        "sample_rows",
+		Description: "Returns a random, repeatable sample of rows from a table. Prefer this over SELECT * to look at data; oversized text and binary values are truncated.",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]Property{
+				"database": databaseProperty,
+				"table_name": {
+					Type:        "string",
+					Description: "The name of the table to sample",
+				},
+				"columns": {
+					Type:        "string",
+					Description: "Comma-separated columns to return (default: all)",
+				},
+				"n": {
+					Type:        "integer",
+					Description: "Number of rows to return (default: 10, max: 100)",
+				},
+				"seed": {
+					Type:        "integer",
+					Description: "Random seed; the same seed returns the same sample while the data is unchanged (default: 42)",
+				},
+			},
+			Required: []string{"table_name"},
+		},
+	}, s.handleSampleRows)
+
+	// table_stats tool
+	s.RegisterTool(Tool{
+		Name:        "table_stats",
+		Description: "Returns the estimated row count, table and index size, last analyze/vacuum times (PostgreSQL) and auto-increment value (MySQL) of a table. Check this before querying an unfamiliar table.",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]Property{
+				"database": databaseProperty,
+				"table_name": {
+					Type:        "string",
+					Description: "The name of the table",
+				},
+				"exact_count": {
+					Type:        "boolean",
+					Description: "Also run COUNT(*) within the query timeout; slow on large tables (default: false)",
+				},
+			},
+			Required: []string{"table_name"},
+		},
+	}, s.handleTableStats)
+
+	// suggest_indexes tool
+	s.RegisterTool(Tool{
+		Name:        "suggest_indexes",
+		Description: "Explains one or more read-only queries and proposes CREATE INDEX statements, with rationale, for full scans, nested loops and sorts that an index could avoid. Existing indexes are taken into account. Nothing is ever executed; on PostgreSQL the candidates can be costed with the hypopg extension.",
+		InputSchema: InputSchema{
+			Type: "object",
+			Properties: map[string]Property{
+				"database": databaseProperty,
+				"queries": {
+					Type:        "array",
+					Description: "The SELECT queries to optimize (max: 10)",
+					Items:       &Property{Type: "string"},
+				},
+				"validate": {
+					Type:        "boolean",
+					Description: "Cost each candidate with a hypothetical index (PostgreSQL with hypopg installed only, default: false)",
+				},
+			},
+			Required: []string{"queries"},
+		},
+	}, s.handleSuggestIndexes)
 }
 
 // RegisterTool registers a tool with the server
@@ -119,15 +403,26 @@ func (s *Server) RegisterTool(tool Tool, handler ToolHandler) {
 func (s *Server) Run(ctx context.Context) error {
 	log.Printf("[INFO] MCP Server starting with %s transport...", s.transport.GetType())
 
-	// Connect to database
-	if err := s.adapter.Connect(ctx); err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+	if len(s.connections) == 0 {
+		return fmt.Errorf("no database connections registered")
 	}
-	defer s.adapter.Close()
 
-	log.Printf("[INFO] Connected to %s database", s.adapter.GetDBType())
+	// Databases are connected on first use so that one unreachable database
+	// does not keep the others from being served
+	defer s.closeConnections()
+
+	log.Printf("[INFO] Serving %d databases (default: %s)", len(s.connections), s.defaultConnection)
 	log.Printf("[INFO] Registered %d tools", len(s.toolDefs))
 
+	if s.schemaPoll > 0 {
+		log.Printf("[INFO] Polling for schema changes every %v", s.schemaPoll)
+		go s.watchSchema(ctx, s.schemaPoll)
+	}
+	if s.credentialRefresh > 0 {
+		log.Printf("[INFO] Refreshing database credentials every %v", s.credentialRefresh)
+		go s.refreshCredentials(ctx, s.credentialRefresh)
+	}
+
 	// Start transport
 	if err := s.transport.Start(ctx); err != nil {
 		return fmt.Errorf("failed to start transport: %w", err)
@@ -158,15 +453,34 @@ func (s *Server) Run(ctx context.Context) error {
 
 // handleRequest processes an incoming request
 func (s *Server) handleRequest(ctx context.Context, req *Request) *Response {
+	// Handlers find the authenticated client, if any, in the context
+	if req.identity != nil {
+		ctx = auth.WithIdentity(ctx, req.identity)
+	}
+
+	// Tool calls are checked once their arguments are parsed
+	if req.Method != "initialized" && req.Method != "tools/call" {
+		if err := s.allow(req, false); err != nil {
+			return rateLimited(req, err)
+		}
+	}
+
 	switch req.Method {
 	case "initialize":
+		if s.audit != nil {
+			s.rememberClient(req)
+		}
 		return s.handleInitialize(req)
 	case "initialized":
 		return s.handleInitialized(req)
 	case "tools/list":
-		return s.handleToolsList(req)
+		return s.handleToolsList(ctx, req)
 	case "tools/call":
 		return s.handleToolsCall(ctx, req)
+	case "resources/list":
+		return s.handleResourcesList(ctx, req)
+	case "resources/read":
+		return s.handleResourcesRead(ctx, req)
 	case "ping":
 		return s.handlePing(req)
 	default:
@@ -189,6 +503,9 @@ func (s *Server) handleInitialize(req *Request) *Response {
 			Tools: &ToolsCapability{
 				ListChanged: false,
 			},
+			Resources: &ResourcesCapability{
+				ListChanged: true,
+			},
 		},
 		ServerInfo: ServerInfo{
 			Name:    ServerName,
@@ -210,10 +527,18 @@ func (s *Server) handleInitialized(req *Request) *Response {
 	return nil
 }
 
-// handleToolsList handles the tools/list request
-func (s *Server) handleToolsList(req *Request) *Response {
+// handleToolsList handles the tools/list request, listing the tools the
+// caller's role may use
+func (s *Server) handleToolsList(ctx context.Context, req *Request) *Response {
+	role := auth.RoleFromContext(ctx)
+	tools := make([]Tool, 0, len(s.toolDefs))
+	for _, tool := range s.toolDefs {
+		if role.AllowsTool(tool.Name) {
+			tools = append(tools, tool)
+		}
+	}
 	result := ListToolsResult{
-		Tools: s.toolDefs,
+		Tools: tools,
 	}
 
 	return &Response{
@@ -223,8 +548,17 @@ func (s *Server) handleToolsList(req *Request) *Response {
 	}
 }
 
-// handleToolsCall handles the tools/call request
-func (s *Server) handleToolsCall(ctx context.Context, req *Request) *Response {
+// handleToolsCall handles the tools/call request and records it to the
+// audit log, if any
+func (s *Server) handleToolsCall(ctx context.Context, req *Request) (resp *Response) {
+	var params CallToolParams
+	u := &usage{}
+	if s.audit != nil {
+		defer func(start time.Time) {
+			s.auditCall(req, &params, u, time.Since(start), resp)
+		}(time.Now())
+	}
+
 	// Parse params
 	paramsJSON, err := json.Marshal(req.Params)
 	if err != nil {
@@ -239,7 +573,6 @@ func (s *Server) handleToolsCall(ctx context.Context, req *Request) *Response {
 		}
 	}
 
-	var params CallToolParams
 	if err := json.Unmarshal(paramsJSON, &params); err != nil {
 		return &Response{
 			JSONRPC: "2.0",
@@ -265,9 +598,21 @@ func (s *Server) handleToolsCall(ctx context.Context, req *Request) *Response {
 		}
 	}
 
-	// Execute tool
-	result, err := handler(ctx, params.Arguments)
+	if limitErr := s.allow(req, expensiveCall(params.Name, params.Arguments)); limitErr != nil {
+		return rateLimited(req, limitErr)
+	}
+
+	// Execute tool, if the caller's role allows it
+	var result *CallToolResult
+	if role := auth.RoleFromContext(ctx); !role.AllowsTool(params.Name) {
+		err = &auth.AccessError{Object: "tool " + params.Name, Role: role.Name}
+	} else {
+		result, err = s.callTool(ctx, req, handler, params.Arguments, u)
+	}
 	if err != nil {
+		if isDenial(err) {
+			u.reject()
+		}
 		log.Printf("[ERROR] Tool execution failed: %v", err)
 		return &Response{
 			JSONRPC: "2.0",
@@ -293,7 +638,12 @@ func (s *Server) handleToolsCall(ctx context.Context, req *Request) *Response {
 
 // handlePing handles the ping request
 func (s *Server) handlePing(req *Request) *Response {
-	if err := s.adapter.Ping(context.Background()); err != nil {
+	ctx := context.Background()
+	conn, err := s.connection(ctx, nil)
+	if err == nil {
+		err = conn.Adapter.Ping(ctx)
+	}
+	if err != nil {
 		return &Response{
 			JSONRPC: "2.0",
 			ID:      req.ID,
@@ -3,26 +3,38 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/auth"
 )
 
 // HTTPTransportConfig holds configuration for HTTP transport
 type HTTPTransportConfig struct {
-	Addr        string   // Server address (e.g., ":8080")
-	CORSOrigins []string // Allowed CORS origins (e.g., ["*"] or ["https://example.com"])
-	APIKey      string   // Optional API key for authentication
+	Addr        string               // Server address (e.g., ":8080")
+	CORSOrigins []string             // Allowed CORS origins (e.g., ["*"] or ["https://example.com"])
+	APIKey      string               // Optional shared API key with unrestricted access
+	APIKeys     []auth.APIKey        // Optional named API keys mapped to roles
+	OAuth       *auth.TokenValidator // Optional OAuth bearer token validation
+	TLS         *HTTPTLSConfig       // Optional HTTPS and client certificate settings
+	ClientCerts *auth.ClientCerts    // Identities of verified client certificates
 }
 
 // HTTPTransport handles HTTP-based communication
 type HTTPTransport struct {
 	server       *http.Server
 	addr         string
-	corsOrigins  []string
-	apiKey       string
+	tls          *HTTPTLSConfig
+	access       atomic.Pointer[httpAccess]
 	requestChan  chan *httpRequest
 	responseChan map[string]chan *Response
 	mu           sync.RWMutex
@@ -30,6 +42,14 @@ type HTTPTransport struct {
 	cancel       context.CancelFunc
 }
 
+// httpAccess holds the settings that UpdateAccess can change while serving
+type httpAccess struct {
+	corsOrigins []string
+	keys        *auth.Keys
+	oauth       *auth.TokenValidator
+	clientCerts *auth.ClientCerts
+}
+
 // httpRequest wraps a request with its response channel
 type httpRequest struct {
 	req      *Request
@@ -42,18 +62,20 @@ func NewHTTPTransport(config HTTPTransportConfig) *HTTPTransport {
 
 	t := &HTTPTransport{
 		addr:         config.Addr,
-		corsOrigins:  config.CORSOrigins,
-		apiKey:       config.APIKey,
+		tls:          config.TLS,
 		requestChan:  make(chan *httpRequest, 10), // Buffered channel for concurrent requests
 		responseChan: make(map[string]chan *Response),
 		ctx:          ctx,
 		cancel:       cancel,
 	}
+	t.UpdateAccess(config)
 
 	// Create HTTP server
 	mux := http.NewServeMux()
 	mux.HandleFunc("/mcp", t.handleMCPRequest)
 	mux.HandleFunc("/health", t.handleHealthCheck)
+	mux.HandleFunc(protectedResourcePath, t.handleProtectedResource)
+	mux.HandleFunc(protectedResourcePath+"/", t.handleProtectedResource)
 
 	t.server = &http.Server{
 		Addr:         config.Addr,
@@ -65,17 +87,50 @@ func NewHTTPTransport(config HTTPTransportConfig) *HTTPTransport {
 	return t
 }
 
+// UpdateAccess replaces the API keys, OAuth settings, client certificate
+// identities and allowed CORS origins with those of config; Addr and TLS
+// are ignored. The shared APIKey, if set,
+// is accepted alongside the named keys and is not limited by a role.
+// Requests that already passed the checks are not affected.
+func (t *HTTPTransport) UpdateAccess(config HTTPTransportConfig) {
+	keys := append([]auth.APIKey(nil), config.APIKeys...)
+	if config.APIKey != "" {
+		keys = append(keys, auth.APIKey{Name: "api_key", Hash: auth.HashKey(config.APIKey)})
+	}
+	t.access.Store(&httpAccess{
+		corsOrigins: config.CORSOrigins,
+		keys:        auth.NewKeys(keys...),
+		oauth:       config.OAuth,
+		clientCerts: config.ClientCerts,
+	})
+}
+
 // GetType returns the transport type
 func (t *HTTPTransport) GetType() TransportType {
 	return TransportHTTP
 }
 
-// Start initializes the HTTP server
+// Start initializes the HTTP server, serving HTTPS when TLS is configured
 func (t *HTTPTransport) Start(ctx context.Context) error {
+	if t.tls != nil {
+		certs, err := newCertReloader(*t.tls)
+		if err != nil {
+			return err
+		}
+		t.server.TLSConfig = certs.tlsConfig()
+	}
+
 	// Start HTTP server in background
 	go func() {
-		log.Printf("[INFO] HTTP server listening on %s", t.addr)
-		if err := t.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		var err error
+		if t.tls != nil {
+			log.Printf("[INFO] HTTPS server listening on %s", t.addr)
+			err = t.server.ListenAndServeTLS("", "")
+		} else {
+			log.Printf("[INFO] HTTP server listening on %s", t.addr)
+			err = t.server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
 			log.Printf("[ERROR] HTTP server error: %v", err)
 		}
 	}()
@@ -159,13 +214,12 @@ func (t *HTTPTransport) handleMCPRequest(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Check API key if configured
-	if t.apiKey != "" {
-		providedKey := r.Header.Get("X-API-Key")
-		if providedKey != t.apiKey {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
-			return
-		}
+	// Check the API key or bearer token if configured
+	access := t.access.Load()
+	identity, err := access.authenticate(r)
+	if err != nil {
+		access.challenge(w, r, err)
+		return
 	}
 
 	// Parse request body
@@ -175,7 +229,10 @@ func (t *HTTPTransport) handleMCPRequest(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	log.Printf("[DEBUG] HTTP request: method=%s id=%v", req.Method, req.ID)
+	req.identity = identity
+	req.client = httpClientKey(r, identity)
+
+	log.Printf("[DEBUG] HTTP request: method=%s id=%v client=%s", req.Method, req.ID, identity)
 
 	// Create response channel for this request
 	respChan := make(chan *Response, 1)
@@ -205,6 +262,10 @@ func (t *HTTPTransport) handleMCPRequest(w http.ResponseWriter, r *http.Request)
 
 		// Send response
 		w.Header().Set("Content-Type", "application/json")
+		if resp.retryAfter > 0 {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(resp.retryAfter.Seconds()))))
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
 		if err := json.NewEncoder(w).Encode(resp); err != nil {
 			log.Printf("[ERROR] Failed to encode response: %v", err)
 		}
@@ -221,6 +282,108 @@ func (t *HTTPTransport) handleMCPRequest(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+// httpClientKey identifies the caller for rate limits: authenticated
+// clients by identity, others by IP address
+func httpClientKey(r *http.Request, identity *auth.Identity) string {
+	if identity != nil {
+		return "client:" + identity.Name
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// errNoCredentials reports a request without an API key or bearer token
+var errNoCredentials = errors.New("authentication required")
+
+// errInvalidAPIKey reports an API key that matches no configured key
+var errInvalidAPIKey = errors.New("invalid API key")
+
+// errUnknownClientCert reports a verified client certificate that no
+// client certificate rule maps to an identity
+var errUnknownClientCert = errors.New("client certificate is not mapped to an identity")
+
+// authenticate returns the identity of the request's bearer token, API key
+// or verified client certificate, in that order. Without API keys and OAuth,
+// requests without credentials are accepted anonymously.
+func (a *httpAccess) authenticate(r *http.Request) (*auth.Identity, error) {
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") && a.oauth != nil {
+		return a.oauth.Validate(r.Context(), strings.TrimSpace(token))
+	}
+	if key := r.Header.Get("X-API-Key"); key != "" && a.keys.Len() > 0 {
+		identity, ok := a.keys.Authenticate(key)
+		if !ok {
+			return nil, errInvalidAPIKey
+		}
+		return identity, nil
+	}
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		identity, ok := a.clientCerts.Identify(r.TLS.VerifiedChains[0][0])
+		if !ok {
+			return nil, errUnknownClientCert
+		}
+		return identity, nil
+	}
+	if a.keys.Len() == 0 && a.oauth == nil {
+		return nil, nil
+	}
+	return nil, errNoCredentials
+}
+
+// challenge rejects a request that failed authentication. With OAuth, the
+// WWW-Authenticate header points the client to the protected resource
+// metadata, which names the authorization server to get a token from.
+func (a *httpAccess) challenge(w http.ResponseWriter, r *http.Request, err error) {
+	status := http.StatusUnauthorized
+	if a.oauth != nil {
+		params := []string{fmt.Sprintf("resource_metadata=%q", requestOrigin(r)+protectedResourcePath)}
+		var tokenErr *auth.TokenError
+		if errors.As(err, &tokenErr) {
+			code := "invalid_token"
+			if tokenErr.Scope {
+				code, status = "insufficient_scope", http.StatusForbidden
+				params = append(params, fmt.Sprintf("scope=%q", strings.Join(a.oauth.Scopes(), " ")))
+			}
+			params = append(params, fmt.Sprintf("error=%q", code), fmt.Sprintf("error_description=%q", strings.ReplaceAll(tokenErr.Reason, `"`, "'")))
+		}
+		w.Header().Set("WWW-Authenticate", "Bearer "+strings.Join(params, ", "))
+	}
+	log.Printf("[WARN] HTTP request rejected from %s: %v", r.RemoteAddr, err)
+	http.Error(w, http.StatusText(status), status)
+}
+
+// protectedResourcePath serves the OAuth protected resource metadata
+const protectedResourcePath = "/.well-known/oauth-protected-resource"
+
+// handleProtectedResource serves the OAuth protected resource metadata
+// (RFC 9728) when OAuth is configured
+func (t *HTTPTransport) handleProtectedResource(w http.ResponseWriter, r *http.Request) {
+	t.setCORSHeaders(w, r)
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	oauth := t.access.Load().oauth
+	if oauth == nil {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(oauth.Metadata())
+}
+
+// requestOrigin returns the scheme and host the client used
+func requestOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
 // handleHealthCheck handles health check requests
 func (t *HTTPTransport) handleHealthCheck(w http.ResponseWriter, r *http.Request) {
 	// Set CORS headers
@@ -243,7 +406,7 @@ func (t *HTTPTransport) setCORSHeaders(w http.ResponseWriter, r *http.Request) {
 
 	// Check if origin is allowed
 	allowed := false
-	for _, allowedOrigin := range t.corsOrigins {
+	for _, allowedOrigin := range t.access.Load().corsOrigins {
 		if allowedOrigin == "*" || allowedOrigin == origin {
 			allowed = true
 			if allowedOrigin == "*" {
@@ -261,7 +424,8 @@ func (t *HTTPTransport) setCORSHeaders(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-API-Key")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-API-Key, Authorization")
+	w.Header().Set("Access-Control-Expose-Headers", "WWW-Authenticate, Retry-After")
 	w.Header().Set("Access-Control-Max-Age", "3600")
 }
 
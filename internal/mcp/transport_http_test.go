@@ -3,11 +3,22 @@ package mcp
 import (
 	"bytes"
 	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
+	"math/big"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/auth"
 )
 
 func TestHTTPTransport_GetType(t *testing.T) {
@@ -251,3 +262,191 @@ func TestHTTPTransport_ConcurrentRequests(t *testing.T) {
 		}
 	}
 }
+
+func TestHTTPTransport_UpdateAccess(t *testing.T) {
+	transport := NewHTTPTransport(HTTPTransportConfig{
+		Addr:        ":8080",
+		CORSOrigins: []string{"http://old.example"},
+		APIKey:      "old-key",
+	})
+	transport.UpdateAccess(HTTPTransportConfig{APIKey: "new-key", CORSOrigins: []string{"http://new.example"}})
+
+	req := httptest.NewRequest("POST", "/mcp", bytes.NewBufferString("{}"))
+	req.Header.Set("X-API-Key", "old-key")
+	req.Header.Set("Origin", "http://new.example")
+	w := httptest.NewRecorder()
+
+	transport.handleMCPRequest(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the old API key to be rejected, got %d", w.Code)
+	}
+	if origin := w.Header().Get("Access-Control-Allow-Origin"); origin != "http://new.example" {
+		t.Errorf("Expected the new origin to be allowed, got '%s'", origin)
+	}
+}
+
+func TestHTTPTransport_NamedKeys(t *testing.T) {
+	analyst := &auth.Role{Name: "analyst"}
+	transport := NewHTTPTransport(HTTPTransportConfig{
+		Addr:        ":8080",
+		CORSOrigins: []string{"*"},
+		APIKey:      "shared-key",
+		APIKeys:     []auth.APIKey{{Name: "ci", Hash: auth.HashKey("ci-key"), Role: analyst}},
+	})
+
+	tests := []struct {
+		key      string
+		identity string
+	}{
+		{"ci-key", "ci"},
+		{"shared-key", "api_key"},
+	}
+	for i, tt := range tests {
+		body, _ := json.Marshal(Request{JSONRPC: "2.0", ID: i, Method: "ping"})
+		req := httptest.NewRequest("POST", "/mcp", bytes.NewBuffer(body))
+		req.Header.Set("X-API-Key", tt.key)
+		w := httptest.NewRecorder()
+
+		done := make(chan struct{})
+		go func() {
+			transport.handleMCPRequest(w, req)
+			close(done)
+		}()
+
+		queued, err := transport.ReadRequest()
+		if err != nil {
+			t.Fatalf("ReadRequest failed: %v", err)
+		}
+		if queued.identity == nil || queued.identity.Name != tt.identity {
+			t.Errorf("%s: expected identity %s, got %v", tt.key, tt.identity, queued.identity)
+		}
+		transport.WriteResponse(&Response{JSONRPC: "2.0", ID: queued.ID})
+		<-done
+	}
+}
+
+// newTestOAuth returns a validator trusting a locally generated RSA key and
+// a function signing tokens with it
+func newTestOAuth(t *testing.T) (*auth.TokenValidator, func(claims map[string]interface{}) string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	b64 := base64.RawURLEncoding
+	jwks, _ := json.Marshal(map[string]interface{}{"keys": []map[string]string{{
+		"kty": "RSA", "kid": "k1", "n": b64.EncodeToString(key.N.Bytes()), "e": b64.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	validator, err := auth.NewTokenValidator(auth.OAuthConfig{
+		Issuer:   "https://auth.example",
+		Audience: "https://mcp.example/mcp",
+		JWKS:     path,
+		Scopes:   map[string][]string{"db:read": {"list_*"}},
+	})
+	if err != nil {
+		t.Fatalf("NewTokenValidator failed: %v", err)
+	}
+
+	sign := func(claims map[string]interface{}) string {
+		header := b64.EncodeToString([]byte(`{"alg":"RS256","kid":"k1"}`))
+		payload, _ := json.Marshal(claims)
+		signed := header + "." + b64.EncodeToString(payload)
+		digest := sha256.Sum256([]byte(signed))
+		sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		if err != nil {
+			t.Fatalf("failed to sign token: %v", err)
+		}
+		return signed + "." + b64.EncodeToString(sig)
+	}
+	return validator, sign
+}
+
+func TestHTTPTransport_OAuth(t *testing.T) {
+	validator, sign := newTestOAuth(t)
+	transport := NewHTTPTransport(HTTPTransportConfig{
+		Addr:        ":8080",
+		CORSOrigins: []string{"*"},
+		OAuth:       validator,
+	})
+	claims := func(scope string, exp time.Duration) map[string]interface{} {
+		return map[string]interface{}{
+			"iss": "https://auth.example", "aud": "https://mcp.example/mcp", "sub": "alice",
+			"scope": scope, "exp": time.Now().Add(exp).Unix(),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		token     string
+		status    int
+		challenge string
+	}{
+		{"missing token", "", http.StatusUnauthorized, `Bearer resource_metadata="http://mcp.example/.well-known/oauth-protected-resource"`},
+		{"expired token", sign(claims("db:read", -time.Hour)), http.StatusUnauthorized, `error="invalid_token", error_description="token has expired"`},
+		{"insufficient scope", sign(claims("profile", time.Hour)), http.StatusForbidden, `scope="db:read", error="insufficient_scope"`},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "http://mcp.example/mcp", bytes.NewBufferString(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+		if tt.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+		}
+		w := httptest.NewRecorder()
+		transport.handleMCPRequest(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.name, tt.status, w.Code)
+		}
+		if challenge := w.Header().Get("WWW-Authenticate"); !strings.Contains(challenge, tt.challenge) {
+			t.Errorf("%s: expected challenge containing %s, got %s", tt.name, tt.challenge, challenge)
+		}
+	}
+
+	req := httptest.NewRequest("POST", "/mcp", bytes.NewBufferString(`{"jsonrpc":"2.0","id":2,"method":"ping"}`))
+	req.Header.Set("Authorization", "Bearer "+sign(claims("db:read", time.Hour)))
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		transport.handleMCPRequest(w, req)
+		close(done)
+	}()
+	queued, err := transport.ReadRequest()
+	if err != nil {
+		t.Fatalf("ReadRequest failed: %v", err)
+	}
+	if queued.identity == nil || queued.identity.Name != "alice" || !queued.identity.Role.AllowsTool("list_tables") || queued.identity.Role.AllowsTool("sample_rows") {
+		t.Errorf("Expected alice limited to the db:read tools, got %v", queued.identity)
+	}
+	transport.WriteResponse(&Response{JSONRPC: "2.0", ID: queued.ID})
+	<-done
+}
+
+func TestHTTPTransport_ProtectedResourceMetadata(t *testing.T) {
+	transport := NewHTTPTransport(HTTPTransportConfig{Addr: ":8080", CORSOrigins: []string{"*"}})
+
+	w := httptest.NewRecorder()
+	transport.handleProtectedResource(w, httptest.NewRequest("GET", "/.well-known/oauth-protected-resource", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 without OAuth, got %d", w.Code)
+	}
+
+	validator, _ := newTestOAuth(t)
+	transport.UpdateAccess(HTTPTransportConfig{CORSOrigins: []string{"*"}, OAuth: validator})
+	w = httptest.NewRecorder()
+	transport.handleProtectedResource(w, httptest.NewRequest("GET", "/.well-known/oauth-protected-resource/mcp", nil))
+
+	var meta auth.ProtectedResourceMetadata
+	if err := json.NewDecoder(w.Body).Decode(&meta); err != nil {
+		t.Fatalf("Failed to decode metadata: %v", err)
+	}
+	if meta.Resource != "https://mcp.example/mcp" || len(meta.AuthorizationServers) != 1 || meta.AuthorizationServers[0] != "https://auth.example" {
+		t.Errorf("Unexpected metadata %+v", meta)
+	}
+	if len(meta.ScopesSupported) != 1 || meta.ScopesSupported[0] != "db:read" {
+		t.Errorf("Expected the configured scopes, got %v", meta.ScopesSupported)
+	}
+}
@@ -27,3 +27,10 @@ type MessageTransport interface {
 	// Close cleans up transport resources
 	Close() error
 }
+
+// NotificationWriter is implemented by transports that can push
+// server-initiated notifications to the client
+type NotificationWriter interface {
+	// WriteNotification writes a notification to the transport
+	WriteNotification(n *Notification) error
+}
@@ -77,6 +77,24 @@ func (t *StdioTransport) WriteResponse(resp *Response) error {
 	return nil
 }
 
+// WriteNotification writes a JSON-RPC notification to stdout
+func (t *StdioTransport) WriteNotification(n *Notification) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	data, err := json.Marshal(n)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification: %w", err)
+	}
+
+	if _, err := t.writer.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write notification: %w", err)
+	}
+
+	log.Printf("[DEBUG] Sent notification: method=%s", n.Method)
+	return nil
+}
+
 // Close cleans up transport resources (no-op for STDIO)
 func (t *StdioTransport) Close() error {
 	return nil
@@ -0,0 +1,141 @@
+package mcp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// HTTPTLSConfig enables HTTPS and, with ClientCAFile, verification of
+// client certificates (mutual TLS)
+type HTTPTLSConfig struct {
+	CertFile     string // PEM certificate chain
+	KeyFile      string // PEM private key
+	ClientCAFile string // PEM CA certificates that sign client certificates
+	ClientAuth   string // "require" (default) or "optional"
+}
+
+// certCheckInterval limits how often the certificate files are checked for
+// changes
+const certCheckInterval = 2 * time.Second
+
+// certReloader serves the server certificate and client CAs from files and
+// loads them again when the files change, so renewed certificates are used
+// without a restart. A change that fails to load keeps the previous files.
+type certReloader struct {
+	config HTTPTLSConfig
+
+	mu        sync.Mutex
+	checked   time.Time
+	stamp     string // modification times and sizes of the loaded files
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+}
+
+// newCertReloader loads the certificate and client CAs
+func newCertReloader(config HTTPTLSConfig) (*certReloader, error) {
+	switch config.ClientAuth {
+	case "", "require", "optional":
+	default:
+		return nil, fmt.Errorf("client auth must be require or optional, got: %s", config.ClientAuth)
+	}
+	r := &certReloader{config: config}
+	stamp, err := r.fileStamp()
+	if err != nil {
+		return nil, err
+	}
+	if err := r.load(stamp); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// tlsConfig returns a server TLS configuration that reads the current
+// certificate on every handshake
+func (r *certReloader) tlsConfig() *tls.Config {
+	base := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+	config := base.Clone()
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		cert, clientCAs := r.current()
+		config := base.Clone()
+		config.Certificates = []tls.Certificate{*cert}
+		if clientCAs != nil {
+			config.ClientCAs = clientCAs
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+			if r.config.ClientAuth == "optional" {
+				config.ClientAuth = tls.VerifyClientCertIfGiven
+			}
+		}
+		return config, nil
+	}
+	return config
+}
+
+// current returns the certificate and client CAs, loading them again if
+// the files changed since the last check
+func (r *certReloader) current() (*tls.Certificate, *x509.CertPool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if time.Since(r.checked) >= certCheckInterval {
+		r.checked = time.Now()
+		stamp, err := r.fileStamp()
+		switch {
+		case err != nil:
+			// Files may be replaced non-atomically; try again on the next check
+		case stamp != r.stamp:
+			if err := r.load(stamp); err != nil {
+				log.Printf("[WARN] Failed to reload TLS certificate, keeping the current one: %v", err)
+			} else {
+				log.Printf("[INFO] TLS certificate reloaded")
+			}
+		}
+	}
+	return r.cert, r.clientCAs
+}
+
+// load reads the files; the caller holds mu or owns r
+func (r *certReloader) load(stamp string) error {
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.config.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in client CA file %s", r.config.ClientCAFile)
+		}
+	}
+
+	r.cert, r.clientCAs, r.stamp = &cert, clientCAs, stamp
+	return nil
+}
+
+// fileStamp describes the modification times and sizes of the files
+func (r *certReloader) fileStamp() (string, error) {
+	var stamp string
+	for _, path := range []string{r.config.CertFile, r.config.KeyFile, r.config.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read TLS file: %w", err)
+		}
+		stamp += fmt.Sprintf("%s:%d:%d;", path, info.ModTime().UnixNano(), info.Size())
+	}
+	return stamp, nil
+}
@@ -0,0 +1,245 @@
+package mcp
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/auth"
+)
+
+// testCA issues certificates signed by a locally generated CA
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	ca := &testCA{}
+	ca.cert, ca.key, ca.pem, _ = issueCert(t, nil, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test CA"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+	return ca
+}
+
+// issue returns the PEM certificate and key of a leaf certificate
+func (ca *testCA) issue(t *testing.T, name string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	t.Helper()
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: name},
+		ExtKeyUsage: []x509.ExtKeyUsage{usage},
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+	}
+	if usage == x509.ExtKeyUsageServerAuth {
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	_, _, certPEM, keyPEM = issueCert(t, ca, template)
+	return certPEM, keyPEM
+}
+
+// issueCert signs template with ca, or self-signs it when ca is nil
+func issueCert(t *testing.T, ca *testCA, template *x509.Certificate) (*x509.Certificate, *ecdsa.PrivateKey, []byte, []byte) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template.SerialNumber = serial
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	parent, signer := template, key
+	if ca != nil {
+		parent, signer = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return cert, key,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeTestFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHTTPTransport_ClientCertificates(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	config := HTTPTLSConfig{
+		CertFile:     filepath.Join(dir, "server.crt"),
+		KeyFile:      filepath.Join(dir, "server.key"),
+		ClientCAFile: filepath.Join(dir, "ca.crt"),
+	}
+	certPEM, keyPEM := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	writeTestFile(t, config.CertFile, certPEM)
+	writeTestFile(t, config.KeyFile, keyPEM)
+	writeTestFile(t, config.ClientCAFile, ca.pem)
+
+	reporting := &auth.Role{Name: "reporting"}
+	certs, err := auth.NewClientCerts(auth.CertRule{Pattern: "reporting-*", Role: reporting})
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := NewHTTPTransport(HTTPTransportConfig{Addr: ":8080", CORSOrigins: []string{"*"}, ClientCerts: certs})
+	reloader, err := newCertReloader(config)
+	if err != nil {
+		t.Fatalf("newCertReloader failed: %v", err)
+	}
+	server := httptest.NewUnstartedServer(http.HandlerFunc(transport.handleMCPRequest))
+	server.TLS = reloader.tlsConfig()
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	client := func(name string) *http.Client {
+		config := &tls.Config{RootCAs: roots}
+		if name != "" {
+			certPEM, keyPEM := ca.issue(t, name, x509.ExtKeyUsageClientAuth)
+			cert, err := tls.X509KeyPair(certPEM, keyPEM)
+			if err != nil {
+				t.Fatal(err)
+			}
+			config.Certificates = []tls.Certificate{cert}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: config}, Timeout: 5 * time.Second}
+	}
+	body, _ := json.Marshal(Request{JSONRPC: "2.0", ID: 1, Method: "ping"})
+
+	done := make(chan *http.Response)
+	go func() {
+		resp, err := client("reporting-eu").Post(server.URL+"/mcp", "application/json", bytes.NewReader(body))
+		if err != nil {
+			t.Errorf("request failed: %v", err)
+		}
+		done <- resp
+	}()
+	queued, err := transport.ReadRequest()
+	if err != nil {
+		t.Fatalf("ReadRequest failed: %v", err)
+	}
+	if queued.identity == nil || queued.identity.Name != "reporting-eu" || queued.identity.Role != reporting {
+		t.Errorf("Expected the certificate subject as identity, got %v", queued.identity)
+	}
+	transport.WriteResponse(&Response{JSONRPC: "2.0", ID: queued.ID})
+	if resp := <-done; resp != nil {
+		resp.Body.Close()
+	}
+
+	resp, err := client("intruder").Post(server.URL+"/mcp", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected an unmapped certificate to be rejected, got %d", resp.StatusCode)
+	}
+
+	if _, err := client("").Get(server.URL + "/health"); err == nil {
+		t.Error("Expected the handshake to fail without a client certificate")
+	}
+}
+
+func TestCertReloader_Reload(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	config := HTTPTLSConfig{CertFile: filepath.Join(dir, "server.crt"), KeyFile: filepath.Join(dir, "server.key")}
+	certPEM, keyPEM := ca.issue(t, "first", x509.ExtKeyUsageServerAuth)
+	writeTestFile(t, config.CertFile, certPEM)
+	writeTestFile(t, config.KeyFile, keyPEM)
+
+	reloader, err := newCertReloader(config)
+	if err != nil {
+		t.Fatalf("newCertReloader failed: %v", err)
+	}
+	subject := func() string {
+		reloader.mu.Lock()
+		reloader.checked = time.Time{}
+		reloader.mu.Unlock()
+		cert, _ := reloader.current()
+		leaf, _ := x509.ParseCertificate(cert.Certificate[0])
+		return leaf.Subject.CommonName
+	}
+
+	certPEM, keyPEM = ca.issue(t, "renewed", x509.ExtKeyUsageServerAuth)
+	writeTestFile(t, config.CertFile, certPEM)
+	writeTestFile(t, config.KeyFile, keyPEM)
+	if got := subject(); got != "renewed" {
+		t.Errorf("Expected the renewed certificate, got %s", got)
+	}
+
+	writeTestFile(t, config.KeyFile, []byte("not a key"))
+	if got := subject(); got != "renewed" {
+		t.Errorf("Expected a broken key to keep the current certificate, got %s", got)
+	}
+
+	if _, err := newCertReloader(HTTPTLSConfig{CertFile: config.CertFile, KeyFile: config.KeyFile, ClientAuth: "maybe"}); err == nil {
+		t.Error("Expected an invalid client auth mode to be rejected")
+	}
+}
+
+func TestCertReloader_NegotiatesHTTP2(t *testing.T) {
+	ca := newTestCA(t)
+	dir := t.TempDir()
+	config := HTTPTLSConfig{CertFile: filepath.Join(dir, "server.crt"), KeyFile: filepath.Join(dir, "server.key")}
+	certPEM, keyPEM := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	writeTestFile(t, config.CertFile, certPEM)
+	writeTestFile(t, config.KeyFile, keyPEM)
+
+	reloader, err := newCertReloader(config)
+	if err != nil {
+		t.Fatalf("newCertReloader failed: %v", err)
+	}
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", reloader.tlsConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.(*tls.Conn).Handshake()
+	}()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{RootCAs: roots, NextProtos: []string{"h2", "http/1.1"}})
+	if err != nil {
+		t.Fatalf("handshake failed: %v", err)
+	}
+	defer conn.Close()
+	if got := conn.ConnectionState().NegotiatedProtocol; got != "h2" {
+		t.Errorf("Expected h2 to be negotiated, got %q", got)
+	}
+}
@@ -0,0 +1,154 @@
+package plan
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Flags set on nodes by the parsers and checked by Analyze
+const (
+	FlagFilesort   = "using filesort"
+	FlagTemporary  = "using temporary table"
+	FlagSortOnDisk = "sort spilled to disk"
+)
+
+// Severity levels of plan warnings, most severe first
+const (
+	SeverityHigh   = "high"
+	SeverityMedium = "medium"
+	SeverityLow    = "low"
+)
+
+var severityOrder = map[string]int{SeverityHigh: 0, SeverityMedium: 1, SeverityLow: 2}
+
+// Warning is a potential performance problem found in a plan
+type Warning struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Relation string `json:"relation,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Options tunes the thresholds used by Analyze
+type Options struct {
+	LargeTableRows float64          // full scans of tables at least this big are flagged (default 10000)
+	NestedLoopRows float64          // nested loops driven by at least this many rows are flagged (default 1000)
+	TableRows      map[string]int64 // row estimates by table name, preferred over plan estimates for scans
+}
+
+func (o Options) withDefaults() Options {
+	if o.LargeTableRows <= 0 {
+		o.LargeTableRows = 10000
+	}
+	if o.NestedLoopRows <= 0 {
+		o.NestedLoopRows = 1000
+	}
+	return o
+}
+
+// tableRows returns the best known size of the relation scanned by n
+func (o Options) tableRows(n *Node) float64 {
+	if rows, ok := o.TableRows[n.Relation]; ok {
+		return float64(rows)
+	}
+	if i := strings.LastIndex(n.Relation, "."); i >= 0 {
+		if rows, ok := o.TableRows[n.Relation[i+1:]]; ok {
+			return float64(rows)
+		}
+	}
+	return n.EstimatedRows
+}
+
+// rows returns the actual row count of n when known, else the estimate
+func (n *Node) rows() float64 {
+	if n.ActualRows != nil {
+		return *n.ActualRows
+	}
+	return n.EstimatedRows
+}
+
+// isFullScan reports whether n reads every row of a table
+func (n *Node) isFullScan() bool {
+	return n.Operation == "Seq Scan" || n.AccessType == "ALL"
+}
+
+// Analyze flags sequential scans of large tables, nested loops over many
+// rows, sorts and temporary tables, and unused indexes. Warnings are ordered
+// by severity.
+func Analyze(p *Plan, opts Options) []Warning {
+	opts = opts.withDefaults()
+	warnings := []Warning{}
+	add := func(severity, code, relation, format string, args ...interface{}) {
+		warnings = append(warnings, Warning{severity, code, relation, fmt.Sprintf(format, args...)})
+	}
+
+	p.Root.Walk(func(n *Node) {
+		switch {
+		case n.isFullScan():
+			if rows := opts.tableRows(n); rows >= opts.LargeTableRows {
+				msg := fmt.Sprintf("Full scan of %s (~%.0f rows)", n.Relation, rows)
+				if n.Filter != "" {
+					msg += "; no index is used for the filter " + n.Filter
+				}
+				add(SeverityHigh, "full_scan", n.Relation, "%s", msg)
+			}
+			if len(n.PossibleKeys) > 0 && n.Index == "" {
+				add(SeverityMedium, "index_not_used", n.Relation, "Indexes %s on %s were considered but not used",
+					strings.Join(n.PossibleKeys, ", "), n.Relation)
+			}
+		case n.AccessType == "index":
+			if rows := opts.tableRows(n); rows >= opts.LargeTableRows {
+				add(SeverityMedium, "full_index_scan", n.Relation, "Full scan of index %s on %s (~%.0f rows)", n.Index, n.Relation, rows)
+			}
+		case n.Operation == "Nested Loop" && len(n.Children) > 1:
+			outer := n.Children[0].rows()
+			for _, inner := range n.Children[1:] {
+				if outer >= opts.NestedLoopRows {
+					severity := SeverityMedium
+					if inner.isFullScan() {
+						severity = SeverityHigh
+					}
+					add(severity, "nested_loop", inner.Relation, "Nested loop repeats %s about %.0f times", describe(inner), outer)
+				}
+				if r := inner.rows(); r > 0 {
+					outer *= r
+				}
+			}
+		case n.Operation == "Sort" && n.rows() >= opts.LargeTableRows:
+			add(SeverityLow, "large_sort", "", "Sort of ~%.0f rows", n.rows())
+		}
+
+		for _, flag := range n.Flags {
+			switch flag {
+			case FlagFilesort:
+				add(SeverityMedium, "filesort", n.Relation, "%s needs a filesort; an index matching the ORDER BY could avoid it", describe(n))
+			case FlagTemporary:
+				add(SeverityMedium, "temporary_table", n.Relation, "%s uses a temporary table", describe(n))
+			case FlagSortOnDisk:
+				add(SeverityHigh, "sort_on_disk", n.Relation, "Sort spilled to disk; consider raising work_mem or adding an index on the sort key")
+			}
+		}
+
+		if n.ActualRows != nil && *n.ActualRows >= 1000 && n.EstimatedRows > 0 {
+			ratio := *n.ActualRows / n.EstimatedRows
+			if ratio >= 10 || ratio <= 0.1 {
+				add(SeverityLow, "misestimate", n.Relation, "%s returned %.0f rows but %.0f were estimated; statistics may be stale",
+					describe(n), *n.ActualRows, n.EstimatedRows)
+			}
+		}
+	})
+
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return severityOrder[warnings[i].Severity] < severityOrder[warnings[j].Severity]
+	})
+	return warnings
+}
+
+// describe names a node for warning messages
+func describe(n *Node) string {
+	if n.Relation != "" {
+		return n.Operation + " on " + n.Relation
+	}
+	return n.Operation
+}
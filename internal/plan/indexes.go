@@ -0,0 +1,261 @@
+package plan
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+)
+
+// maxIndexColumns caps the width of a suggested index
+const maxIndexColumns = 4
+
+// IndexCandidate is an index that could help one or more queries. Table is
+// the name as written in the query; Statement is filled in once the table
+// has been resolved against the catalog.
+type IndexCandidate struct {
+	Table        string                            `json:"table"`
+	Columns      []string                          `json:"columns"`
+	Statement    string                            `json:"statement,omitempty"`
+	Reasons      []string                          `json:"reasons"`
+	Queries      []int                             `json:"queries"`
+	Hypothetical *database.HypotheticalIndexResult `json:"hypothetical,omitempty"`
+}
+
+// Key identifies candidates on the same table and columns
+func (c IndexCandidate) Key() string {
+	return strings.ToLower(c.Table + "(" + strings.Join(c.Columns, ",") + ")")
+}
+
+// columnRef is a column compared in a plan condition
+type columnRef struct {
+	qualifier string // table or alias the column was qualified with, if any
+	column    string
+	equality  bool
+}
+
+// conditionColumn matches a possibly qualified and quoted column followed by
+// a comparison operator, e.g. (o.status = 'open') or `shop`.`o`.`total` > 10
+var conditionColumn = regexp.MustCompile("((?:[`\"]?\\w+[`\"]?\\.){0,2})[`\"]?(\\w+)[`\"]?\\s*(=|<>|!=|<=|>=|<|>|~~\\*?|(?i:like|in|is|between)\\b)")
+
+// joinedColumn matches a qualified column on the right of an equality, the
+// inner side of a join condition such as (o.user_id = u.id)
+var joinedColumn = regexp.MustCompile("=\\s*((?:[`\"]?[A-Za-z_]\\w*[`\"]?\\.){1,2})[`\"]?(\\w+)[`\"]?")
+
+// columnRefs extracts compared columns from a condition in order of appearance
+func columnRefs(condition string) []columnRef {
+	type match struct {
+		pos int
+		ref columnRef
+	}
+	var matches []match
+
+	for _, m := range conditionColumn.FindAllStringSubmatchIndex(condition, -1) {
+		op := strings.ToUpper(condition[m[6]:m[7]])
+		matches = append(matches, match{m[0], columnRef{
+			qualifier: lastQualifier(condition[m[2]:m[3]]),
+			column:    condition[m[4]:m[5]],
+			equality:  op == "=" || op == "IN" || op == "IS",
+		}})
+	}
+	for _, m := range joinedColumn.FindAllStringSubmatchIndex(condition, -1) {
+		matches = append(matches, match{m[2], columnRef{
+			qualifier: lastQualifier(condition[m[2]:m[3]]),
+			column:    condition[m[4]:m[5]],
+			equality:  true,
+		}})
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].pos < matches[j].pos })
+	refs := make([]columnRef, len(matches))
+	for i, m := range matches {
+		refs[i] = m.ref
+	}
+	return refs
+}
+
+// lastQualifier returns the innermost qualifier of "schema.table." or "t."
+func lastQualifier(prefix string) string {
+	parts := strings.Split(strings.TrimSuffix(prefix, "."), ".")
+	return strings.Trim(parts[len(parts)-1], "`\"")
+}
+
+// sortColumn extracts the column of a sort key such as "o.created_at DESC"
+func sortColumn(key string) string {
+	key = strings.TrimSpace(key)
+	if i := strings.IndexByte(key, ' '); i >= 0 {
+		key = key[:i]
+	}
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		key = key[i+1:]
+	}
+	return strings.Trim(key, "`\"")
+}
+
+// tableRef matches a table in a FROM or JOIN clause with its optional alias
+var tableRef = regexp.MustCompile("(?i)\\b(?:from|join)\\s+((?:[`\"]?\\w+[`\"]?\\.)?[`\"]?\\w+[`\"]?)(?:\\s+(?:as\\s+)?([`\"]?\\w+[`\"]?))?")
+
+// notAliases are keywords that can follow a table name in place of an alias
+var notAliases = map[string]bool{
+	"where": true, "join": true, "inner": true, "left": true, "right": true, "full": true,
+	"cross": true, "natural": true, "on": true, "using": true, "group": true, "order": true,
+	"limit": true, "having": true, "union": true, "window": true, "for": true, "straight_join": true,
+	"offset": true, "fetch": true, "lateral": true, "tablesample": true,
+}
+
+// QueryAliases maps the aliases used in a query's FROM and JOIN clauses to
+// the table names they stand for
+func QueryAliases(query string) map[string]string {
+	aliases := make(map[string]string)
+	for _, m := range tableRef.FindAllStringSubmatch(query, -1) {
+		table := strings.ReplaceAll(strings.ReplaceAll(m[1], "`", ""), `"`, "")
+		alias := strings.Trim(m[2], "`\"")
+		if alias != "" && !notAliases[strings.ToLower(alias)] {
+			aliases[alias] = table
+		}
+	}
+	return aliases
+}
+
+// scanTarget describes a node that reads a table, for attributing columns
+type scanTarget struct {
+	node  *Node
+	names map[string]bool // relation and alias, as they may appear in conditions
+}
+
+func newScanTarget(n *Node) scanTarget {
+	t := scanTarget{node: n, names: map[string]bool{n.Relation: true}}
+	if i := strings.LastIndex(n.Relation, "."); i >= 0 {
+		t.names[n.Relation[i+1:]] = true
+	}
+	if n.Alias != "" {
+		t.names[n.Alias] = true
+	}
+	return t
+}
+
+// owns reports whether a column reference belongs to the scanned table.
+// Unqualified columns are attributed to the table being scanned.
+func (t scanTarget) owns(ref columnRef) bool {
+	return ref.qualifier == "" || t.names[ref.qualifier]
+}
+
+// CandidateIndexes proposes indexes for the full scans, nested loop inner
+// scans and sorts in a plan. Columns are ordered equality first, then a single
+// range column, then sort columns. The caller resolves tables and drops
+// candidates already covered by existing indexes.
+func CandidateIndexes(p *Plan, query string, opts Options) []IndexCandidate {
+	opts = opts.withDefaults()
+	aliases := QueryAliases(query)
+	var candidates []IndexCandidate
+
+	build := func(target scanTarget, refs []columnRef, sortKeys []string, reason string) {
+		var equality, ranged []string
+		seen := make(map[string]bool)
+		for _, ref := range refs {
+			if !target.owns(ref) || seen[ref.column] {
+				continue
+			}
+			seen[ref.column] = true
+			if ref.equality {
+				equality = append(equality, ref.column)
+			} else {
+				ranged = append(ranged, ref.column)
+			}
+		}
+
+		columns := equality
+		if len(ranged) > 0 {
+			columns = append(columns, ranged[0])
+		} else {
+			for _, key := range sortKeys {
+				if col := sortColumn(key); col != "" && !seen[col] {
+					seen[col] = true
+					columns = append(columns, col)
+				}
+			}
+		}
+		if len(columns) == 0 {
+			return
+		}
+		if len(columns) > maxIndexColumns {
+			columns = columns[:maxIndexColumns]
+		}
+
+		table := target.node.Relation
+		if real, ok := aliases[table]; ok {
+			table = real
+		}
+		candidates = append(candidates, IndexCandidate{Table: table, Columns: columns, Reasons: []string{reason}})
+	}
+
+	p.Root.Walk(func(n *Node) {
+		switch {
+		case n.isFullScan() && n.Filter != "":
+			if rows := opts.tableRows(n); rows >= opts.LargeTableRows {
+				build(newScanTarget(n), columnRefs(n.Filter), nil,
+					fmt.Sprintf("Full scan of %s (~%.0f rows) filtering on %s", n.Relation, rows, n.Filter))
+			}
+		case n.Operation == "Nested Loop" && len(n.Children) > 1:
+			for _, inner := range n.Children[1:] {
+				if !inner.isFullScan() {
+					continue
+				}
+				refs := append(columnRefs(n.Condition), columnRefs(inner.Filter)...)
+				build(newScanTarget(inner), refs, nil,
+					fmt.Sprintf("Nested loop scans %s in full for each of ~%.0f outer rows", inner.Relation, n.Children[0].rows()))
+			}
+		case n.Operation == "Sort" && len(n.SortKey) > 0 && n.rows() >= opts.LargeTableRows:
+			scan := n
+			for len(scan.Children) == 1 && scan.Relation == "" {
+				scan = scan.Children[0]
+			}
+			if scan.Relation != "" {
+				build(newScanTarget(scan), columnRefs(scan.Filter), n.SortKey,
+					fmt.Sprintf("Sort of ~%.0f rows by %s", n.rows(), strings.Join(n.SortKey, ", ")))
+			}
+		}
+	})
+
+	return candidates
+}
+
+// CoveringIndex returns the name of an existing index whose leading columns
+// are the candidate's columns, if any
+func CoveringIndex(columns []string, indexes []database.IndexInfo) string {
+	for _, idx := range indexes {
+		if len(idx.Columns) < len(columns) {
+			continue
+		}
+		match := true
+		for i, col := range columns {
+			if !strings.EqualFold(idx.Columns[i], col) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return idx.IndexName
+		}
+	}
+	return ""
+}
+
+// CreateIndexStatement builds the DDL for a candidate index. The index name
+// is derived from the table and columns and kept within identifier limits.
+func CreateIndexStatement(dbType, schema, table string, columns []string) string {
+	name := "idx_" + table + "_" + strings.Join(columns, "_")
+	if len(name) > 63 {
+		name = name[:63]
+	}
+
+	quoted := make([]string, len(columns))
+	for i, col := range columns {
+		quoted[i] = database.QuoteIdentifier(dbType, col)
+	}
+
+	return fmt.Sprintf("CREATE INDEX %s ON %s (%s)", database.QuoteIdentifier(dbType, name),
+		database.QuoteQualifiedName(dbType, schema, table), strings.Join(quoted, ", "))
+}
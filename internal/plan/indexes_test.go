@@ -0,0 +1,112 @@
+package plan
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+)
+
+func TestColumnRefs(t *testing.T) {
+	refs := columnRefs("((o.status = 'open'::text) AND (o.total > 100) AND (o.user_id = u.id) AND (lower(email) = 'x'))")
+
+	want := []columnRef{
+		{qualifier: "o", column: "status", equality: true},
+		{qualifier: "o", column: "total", equality: false},
+		{qualifier: "o", column: "user_id", equality: true},
+		{qualifier: "u", column: "id", equality: true},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Errorf("columnRefs = %+v, want %+v", refs, want)
+	}
+
+	mysql := columnRefs("((`shop`.`o`.`status` = 'open') and (`shop`.`o`.`created_at` >= DATE'2024-01-01'))")
+	if len(mysql) != 2 || mysql[0].qualifier != "o" || mysql[1].column != "created_at" || mysql[1].equality {
+		t.Errorf("Unexpected MySQL refs: %+v", mysql)
+	}
+}
+
+func TestQueryAliases(t *testing.T) {
+	aliases := QueryAliases(`SELECT * FROM shop.orders o JOIN users AS u ON u.id = o.user_id LEFT JOIN items WHERE o.id = 1`)
+
+	want := map[string]string{"o": "shop.orders", "u": "users"}
+	if !reflect.DeepEqual(aliases, want) {
+		t.Errorf("QueryAliases = %v, want %v", aliases, want)
+	}
+}
+
+func TestCandidateIndexes_Postgres(t *testing.T) {
+	p, _ := Parse("postgres", []byte(postgresPlan))
+	query := "SELECT * FROM orders o JOIN users ON users.id = o.user_id WHERE o.status = 'open' ORDER BY o.created_at DESC"
+
+	candidates := CandidateIndexes(p, query, Options{TableRows: map[string]int64{"orders": 500000}})
+	if len(candidates) != 1 {
+		t.Fatalf("Expected one candidate, got %+v", candidates)
+	}
+	c := candidates[0]
+	if c.Table != "orders" || !reflect.DeepEqual(c.Columns, []string{"status"}) {
+		t.Errorf("Unexpected candidate: %+v", c)
+	}
+
+	if got := CandidateIndexes(p, query, Options{}); len(got) != 0 {
+		t.Errorf("Expected no candidates for a small table, got %+v", got)
+	}
+}
+
+func TestCandidateIndexes_MySQL(t *testing.T) {
+	p, _ := Parse("mysql", []byte(mysqlPlan))
+	query := "SELECT * FROM orders o JOIN users u ON u.id = o.user_id WHERE o.status = 'open' ORDER BY o.created_at"
+
+	candidates := CandidateIndexes(p, query, Options{})
+	if len(candidates) != 1 || candidates[0].Table != "orders" || candidates[0].Columns[0] != "status" {
+		t.Errorf("Expected index on orders(status) resolved from alias, got %+v", candidates)
+	}
+}
+
+func TestCandidateIndexes_SortAndNestedLoop(t *testing.T) {
+	raw := `[{"Plan": {"Node Type": "Sort", "Plan Rows": 50000, "Sort Key": ["e.created_at DESC"],
+		"Plans": [{"Node Type": "Nested Loop", "Plan Rows": 50000,
+			"Join Filter": "(e.account_id = a.id)",
+			"Plans": [
+				{"Node Type": "Index Scan", "Relation Name": "events", "Alias": "e", "Index Name": "events_pkey", "Plan Rows": 50000},
+				{"Node Type": "Seq Scan", "Relation Name": "accounts", "Alias": "a", "Plan Rows": 40}
+			]}]}}]`
+	p, err := Parse("postgres", []byte(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	candidates := CandidateIndexes(p, "SELECT 1", Options{})
+	if len(candidates) != 1 {
+		t.Fatalf("Expected nested loop candidate, got %+v", candidates)
+	}
+	if c := candidates[0]; c.Table != "accounts" || !reflect.DeepEqual(c.Columns, []string{"id"}) {
+		t.Errorf("Expected index on the inner join column, got %+v", c)
+	}
+}
+
+func TestCoveringIndex(t *testing.T) {
+	indexes := []database.IndexInfo{
+		{IndexName: "orders_pkey", Columns: []string{"id"}},
+		{IndexName: "orders_status_created", Columns: []string{"status", "created_at"}},
+	}
+
+	if got := CoveringIndex([]string{"STATUS"}, indexes); got != "orders_status_created" {
+		t.Errorf("Expected leading column match, got %q", got)
+	}
+	if got := CoveringIndex([]string{"created_at"}, indexes); got != "" {
+		t.Errorf("Expected non-leading column not to be covered, got %q", got)
+	}
+}
+
+func TestCreateIndexStatement(t *testing.T) {
+	got := CreateIndexStatement("postgres", "shop", "orders", []string{"status", "created_at"})
+	want := `CREATE INDEX "idx_orders_status_created_at" ON "shop"."orders" ("status", "created_at")`
+	if got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+
+	if got := CreateIndexStatement("mysql", "", "orders", []string{"status"}); got != "CREATE INDEX `idx_orders_status` ON `orders` (`status`)" {
+		t.Errorf("Unexpected MySQL statement: %s", got)
+	}
+}
@@ -0,0 +1,130 @@
+package plan
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// mysqlOperations names the MySQL EXPLAIN FORMAT=JSON blocks that become nodes
+var mysqlOperations = map[string]string{
+	"query_block":                "Query Block",
+	"table":                      "Table Access",
+	"nested_loop":                "Nested Loop",
+	"ordering_operation":         "Sort",
+	"grouping_operation":         "Group",
+	"duplicates_removal":         "Distinct",
+	"windowing":                  "Window",
+	"union_result":               "Union",
+	"materialized_from_subquery": "Materialize",
+	"buffer_result":              "Buffer",
+}
+
+// mysqlChildKeys lists the keys that hold nested plan blocks, in display order
+var mysqlChildKeys = []string{
+	"union_result", "query_specifications", "ordering_operation", "grouping_operation",
+	"duplicates_removal", "windowing", "buffer_result", "nested_loop", "table",
+	"materialized_from_subquery", "query_block", "attached_subqueries",
+	"optimized_away_subqueries", "order_by_subqueries", "having_subqueries", "select_list_subqueries",
+}
+
+func parseMySQL(raw []byte) (*Plan, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var out map[string]interface{}
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("failed to parse MySQL plan: %w", err)
+	}
+	block, ok := out["query_block"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("failed to parse MySQL plan: missing query_block")
+	}
+
+	root := convertMySQL("query_block", block)
+	p := &Plan{DBType: "mysql", Root: root, TotalCost: root.EstimatedCost}
+	root.Walk(func(n *Node) {
+		if n.Relation != "" && p.EstimatedRows == 0 {
+			p.EstimatedRows = n.EstimatedRows
+		}
+	})
+	return p, nil
+}
+
+func convertMySQL(kind string, m map[string]interface{}) *Node {
+	n := &Node{Operation: mysqlOperations[kind]}
+
+	if v, ok := m["table_name"].(string); ok {
+		n.Relation = v
+	}
+	if v, ok := m["access_type"].(string); ok {
+		n.AccessType = v
+		n.Operation = "Table Access (" + v + ")"
+	}
+	if v, ok := m["key"].(string); ok {
+		n.Index = v
+	}
+	if keys, ok := m["possible_keys"].([]interface{}); ok {
+		for _, k := range keys {
+			if s, ok := k.(string); ok {
+				n.PossibleKeys = append(n.PossibleKeys, s)
+			}
+		}
+	}
+	if v, ok := m["rows_examined_per_scan"]; ok {
+		n.EstimatedRows = number(v)
+	}
+	if v, ok := m["attached_condition"].(string); ok {
+		n.Filter = v
+	}
+	if v, ok := m["ref"].([]interface{}); ok && len(v) > 0 {
+		n.Condition = fmt.Sprint(v...)
+	}
+	if cost, ok := m["cost_info"].(map[string]interface{}); ok {
+		for _, key := range []string{"query_cost", "prefix_cost", "sort_cost"} {
+			if v, ok := cost[key]; ok {
+				n.EstimatedCost = number(v)
+				break
+			}
+		}
+	}
+	if v, ok := m["message"].(string); ok {
+		n.Flags = append(n.Flags, v)
+	}
+	if m["using_filesort"] == true {
+		n.Flags = append(n.Flags, FlagFilesort)
+	}
+	if m["using_temporary_table"] == true {
+		n.Flags = append(n.Flags, FlagTemporary)
+	}
+	if m["using_index"] == true {
+		n.Flags = append(n.Flags, "covering index")
+	}
+
+	n.Children = mysqlChildren(m)
+	return n
+}
+
+// mysqlChildren converts the nested blocks of m. Arrays other than
+// nested_loop are transparent wrappers around further blocks.
+func mysqlChildren(m map[string]interface{}) []*Node {
+	var children []*Node
+	for _, key := range mysqlChildKeys {
+		switch v := m[key].(type) {
+		case map[string]interface{}:
+			children = append(children, convertMySQL(key, v))
+		case []interface{}:
+			var nested []*Node
+			for _, elem := range v {
+				if em, ok := elem.(map[string]interface{}); ok {
+					nested = append(nested, mysqlChildren(em)...)
+				}
+			}
+			if key == "nested_loop" {
+				children = append(children, &Node{Operation: mysqlOperations[key], Children: nested})
+			} else {
+				children = append(children, nested...)
+			}
+		}
+	}
+	return children
+}
@@ -0,0 +1,80 @@
+// Package plan parses database EXPLAIN output into an engine-neutral tree and
+// flags common performance problems.
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Node is one operation in a query plan
+type Node struct {
+	Operation     string   `json:"operation"`
+	JoinType      string   `json:"join_type,omitempty"`
+	Relation      string   `json:"relation,omitempty"`
+	Alias         string   `json:"alias,omitempty"`
+	Index         string   `json:"index,omitempty"`
+	PossibleKeys  []string `json:"possible_keys,omitempty"`
+	AccessType    string   `json:"access_type,omitempty"`
+	EstimatedRows float64  `json:"estimated_rows"`
+	EstimatedCost float64  `json:"estimated_cost,omitempty"`
+	Condition     string   `json:"condition,omitempty"`
+	Filter        string   `json:"filter,omitempty"`
+	SortKey       []string `json:"sort_key,omitempty"`
+	Flags         []string `json:"flags,omitempty"`
+	ActualRows    *float64 `json:"actual_rows,omitempty"`
+	ActualTimeMs  *float64 `json:"actual_time_ms,omitempty"`
+	Loops         *float64 `json:"loops,omitempty"`
+	Children      []*Node  `json:"children,omitempty"`
+}
+
+// Plan is a parsed query plan with the problems found in it
+type Plan struct {
+	DBType          string    `json:"db_type"`
+	TotalCost       float64   `json:"total_cost"`
+	EstimatedRows   float64   `json:"estimated_rows"`
+	PlanningTimeMs  *float64  `json:"planning_time_ms,omitempty"`
+	ExecutionTimeMs *float64  `json:"execution_time_ms,omitempty"`
+	Warnings        []Warning `json:"warnings"`
+	Root            *Node     `json:"plan"`
+}
+
+// Walk calls fn for every node, parents before children
+func (n *Node) Walk(fn func(*Node)) {
+	if n == nil {
+		return
+	}
+	fn(n)
+	for _, c := range n.Children {
+		c.Walk(fn)
+	}
+}
+
+// Parse parses the JSON EXPLAIN output of the given database type
+func Parse(dbType string, raw []byte) (*Plan, error) {
+	switch dbType {
+	case "postgres":
+		return parsePostgres(raw)
+	case "mysql":
+		return parseMySQL(raw)
+	default:
+		return nil, fmt.Errorf("unsupported database type: %s", dbType)
+	}
+}
+
+// number reads a JSON value that MySQL may encode either as a number or a string
+func number(v interface{}) float64 {
+	switch n := v.(type) {
+	case float64:
+		return n
+	case json.Number:
+		f, _ := n.Float64()
+		return f
+	case string:
+		f, _ := strconv.ParseFloat(n, 64)
+		return f
+	default:
+		return 0
+	}
+}
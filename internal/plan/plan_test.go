@@ -0,0 +1,207 @@
+package plan
+
+import (
+	"testing"
+)
+
+const postgresPlan = `[
+  {
+    "Plan": {
+      "Node Type": "Sort",
+      "Startup Cost": 1200.5,
+      "Total Cost": 1210.75,
+      "Plan Rows": 4100,
+      "Sort Key": ["o.created_at DESC"],
+      "Plans": [
+        {
+          "Node Type": "Nested Loop",
+          "Join Type": "Inner",
+          "Total Cost": 1100.0,
+          "Plan Rows": 4100,
+          "Plans": [
+            {
+              "Node Type": "Seq Scan",
+              "Relation Name": "orders",
+              "Alias": "o",
+              "Total Cost": 900.0,
+              "Plan Rows": 2000,
+              "Filter": "(status = 'open'::text)"
+            },
+            {
+              "Node Type": "Index Scan",
+              "Relation Name": "users",
+              "Alias": "users",
+              "Index Name": "users_pkey",
+              "Total Cost": 0.3,
+              "Plan Rows": 1,
+              "Index Cond": "(id = o.user_id)"
+            }
+          ]
+        }
+      ]
+    },
+    "Planning Time": 0.25
+  }
+]`
+
+const mysqlPlan = `{
+  "query_block": {
+    "select_id": 1,
+    "cost_info": {"query_cost": "5120.40"},
+    "ordering_operation": {
+      "using_temporary_table": true,
+      "using_filesort": true,
+      "nested_loop": [
+        {
+          "table": {
+            "table_name": "o",
+            "access_type": "ALL",
+            "possible_keys": ["idx_status"],
+            "rows_examined_per_scan": 48000,
+            "filtered": "10.00",
+            "cost_info": {"prefix_cost": "4800.00"},
+            "attached_condition": "(o.status = 'open')"
+          }
+        },
+        {
+          "table": {
+            "table_name": "u",
+            "access_type": "eq_ref",
+            "key": "PRIMARY",
+            "ref": ["shop.o.user_id"],
+            "rows_examined_per_scan": 1,
+            "cost_info": {"prefix_cost": "5120.40"}
+          }
+        }
+      ]
+    }
+  }
+}`
+
+func codes(warnings []Warning) map[string]Warning {
+	m := make(map[string]Warning)
+	for _, w := range warnings {
+		m[w.Code] = w
+	}
+	return m
+}
+
+func TestParsePostgres(t *testing.T) {
+	p, err := Parse("postgres", []byte(postgresPlan))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if p.TotalCost != 1210.75 || p.EstimatedRows != 4100 {
+		t.Errorf("Unexpected totals: cost %v rows %v", p.TotalCost, p.EstimatedRows)
+	}
+	if p.PlanningTimeMs == nil || *p.PlanningTimeMs != 0.25 {
+		t.Errorf("Expected planning time, got %v", p.PlanningTimeMs)
+	}
+
+	loop := p.Root.Children[0]
+	if loop.Operation != "Nested Loop" || len(loop.Children) != 2 {
+		t.Fatalf("Expected nested loop with two children, got %+v", loop)
+	}
+	scan := loop.Children[0]
+	if scan.Relation != "orders" || scan.Alias != "o" || scan.Filter == "" {
+		t.Errorf("Unexpected scan node: %+v", scan)
+	}
+	if idx := loop.Children[1]; idx.Index != "users_pkey" || idx.Condition != "(id = o.user_id)" || idx.Alias != "" {
+		t.Errorf("Unexpected index node: %+v", idx)
+	}
+}
+
+func TestAnalyzePostgres(t *testing.T) {
+	p, _ := Parse("postgres", []byte(postgresPlan))
+
+	// The plan only estimates 2000 rows after the filter; the table itself is large
+	warnings := Analyze(p, Options{TableRows: map[string]int64{"orders": 500000}})
+	got := codes(warnings)
+
+	if w, ok := got["full_scan"]; !ok || w.Severity != SeverityHigh || w.Relation != "orders" {
+		t.Errorf("Expected high severity full scan on orders, got %+v", warnings)
+	}
+	if w, ok := got["nested_loop"]; !ok || w.Severity != SeverityMedium {
+		t.Errorf("Expected medium nested loop warning driving an index scan, got %+v", warnings)
+	}
+	if warnings[0].Severity != SeverityHigh {
+		t.Errorf("Expected warnings ordered by severity, got %+v", warnings)
+	}
+
+	if warnings := Analyze(p, Options{}); len(codes(warnings)) != 1 {
+		t.Errorf("Expected only the nested loop warning without table sizes, got %+v", warnings)
+	}
+}
+
+func TestParseMySQL(t *testing.T) {
+	p, err := Parse("mysql", []byte(mysqlPlan))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if p.TotalCost != 5120.40 {
+		t.Errorf("Expected query cost 5120.40, got %v", p.TotalCost)
+	}
+	if p.EstimatedRows != 48000 {
+		t.Errorf("Expected rows of the driving table, got %v", p.EstimatedRows)
+	}
+
+	sortNode := p.Root.Children[0]
+	if sortNode.Operation != "Sort" || len(sortNode.Flags) != 2 {
+		t.Fatalf("Expected sort node with filesort and temporary flags, got %+v", sortNode)
+	}
+	loop := sortNode.Children[0]
+	if loop.Operation != "Nested Loop" || len(loop.Children) != 2 {
+		t.Fatalf("Expected nested loop of two tables, got %+v", loop)
+	}
+	if u := loop.Children[1]; u.Index != "PRIMARY" || u.AccessType != "eq_ref" || u.Condition != "shop.o.user_id" {
+		t.Errorf("Unexpected inner table node: %+v", u)
+	}
+}
+
+func TestAnalyzeMySQL(t *testing.T) {
+	p, _ := Parse("mysql", []byte(mysqlPlan))
+	got := codes(Analyze(p, Options{}))
+
+	for _, code := range []string{"full_scan", "index_not_used", "nested_loop", "filesort", "temporary_table"} {
+		if _, ok := got[code]; !ok {
+			t.Errorf("Expected %s warning, got %+v", code, got)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	if _, err := Parse("sqlite", []byte("{}")); err == nil {
+		t.Error("Expected error for unsupported database type")
+	}
+	if _, err := Parse("postgres", []byte("[]")); err == nil {
+		t.Error("Expected error for empty plan")
+	}
+	if _, err := Parse("mysql", []byte(`{"foo": 1}`)); err == nil {
+		t.Error("Expected error for missing query_block")
+	}
+}
+
+func TestAnalyze_ActualRows(t *testing.T) {
+	raw := `[{"Plan": {"Node Type": "Index Scan", "Relation Name": "events", "Index Name": "events_type_idx",
+		"Total Cost": 8.4, "Plan Rows": 10, "Actual Rows": 25000, "Actual Total Time": 41.5, "Actual Loops": 1,
+		"Shared Hit Blocks": 120, "Shared Read Blocks": 3},
+		"Planning Time": 0.1, "Execution Time": 42.0}]`
+
+	p, err := Parse("postgres", []byte(raw))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if p.ExecutionTimeMs == nil || *p.ExecutionTimeMs != 42.0 {
+		t.Errorf("Expected execution time, got %v", p.ExecutionTimeMs)
+	}
+	if len(p.Root.Flags) != 1 || p.Root.Flags[0] != "buffers: shared hit=120 read=3" {
+		t.Errorf("Expected buffer usage flag, got %v", p.Root.Flags)
+	}
+
+	warnings := Analyze(p, Options{})
+	if len(warnings) != 1 || warnings[0].Code != "misestimate" {
+		t.Errorf("Expected misestimate warning, got %+v", warnings)
+	}
+}
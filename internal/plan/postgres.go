@@ -0,0 +1,110 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// pgNode mirrors a node of EXPLAIN (FORMAT JSON) output
+type pgNode struct {
+	NodeType            string   `json:"Node Type"`
+	JoinType            string   `json:"Join Type"`
+	RelationName        string   `json:"Relation Name"`
+	Schema              string   `json:"Schema"`
+	Alias               string   `json:"Alias"`
+	IndexName           string   `json:"Index Name"`
+	TotalCost           float64  `json:"Total Cost"`
+	PlanRows            float64  `json:"Plan Rows"`
+	Filter              string   `json:"Filter"`
+	IndexCond           string   `json:"Index Cond"`
+	RecheckCond         string   `json:"Recheck Cond"`
+	HashCond            string   `json:"Hash Cond"`
+	MergeCond           string   `json:"Merge Cond"`
+	JoinFilter          string   `json:"Join Filter"`
+	SortKey             []string `json:"Sort Key"`
+	SortMethod          string   `json:"Sort Method"`
+	SortSpaceType       string   `json:"Sort Space Type"`
+	ActualRows          *float64 `json:"Actual Rows"`
+	ActualTotalTime     *float64 `json:"Actual Total Time"`
+	ActualLoops         *float64 `json:"Actual Loops"`
+	RowsRemovedByFilter *float64 `json:"Rows Removed by Filter"`
+	SharedHitBlocks     *float64 `json:"Shared Hit Blocks"`
+	SharedReadBlocks    *float64 `json:"Shared Read Blocks"`
+	Plans               []pgNode `json:"Plans"`
+}
+
+type pgExplain struct {
+	Plan          pgNode   `json:"Plan"`
+	PlanningTime  *float64 `json:"Planning Time"`
+	ExecutionTime *float64 `json:"Execution Time"`
+}
+
+func parsePostgres(raw []byte) (*Plan, error) {
+	var out []pgExplain
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse PostgreSQL plan: %w", err)
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("failed to parse PostgreSQL plan: empty result")
+	}
+
+	root := convertPostgres(out[0].Plan)
+	return &Plan{
+		DBType:          "postgres",
+		TotalCost:       root.EstimatedCost,
+		EstimatedRows:   root.EstimatedRows,
+		PlanningTimeMs:  out[0].PlanningTime,
+		ExecutionTimeMs: out[0].ExecutionTime,
+		Root:            root,
+	}, nil
+}
+
+func convertPostgres(p pgNode) *Node {
+	n := &Node{
+		Operation:     p.NodeType,
+		JoinType:      p.JoinType,
+		Relation:      p.RelationName,
+		Index:         p.IndexName,
+		EstimatedRows: p.PlanRows,
+		EstimatedCost: p.TotalCost,
+		Filter:        p.Filter,
+		SortKey:       p.SortKey,
+		ActualRows:    p.ActualRows,
+		ActualTimeMs:  p.ActualTotalTime,
+		Loops:         p.ActualLoops,
+	}
+	if p.Schema != "" && p.RelationName != "" {
+		n.Relation = p.Schema + "." + p.RelationName
+	}
+	if p.Alias != p.RelationName {
+		n.Alias = p.Alias
+	}
+
+	var conds []string
+	for _, c := range []string{p.IndexCond, p.RecheckCond, p.HashCond, p.MergeCond, p.JoinFilter} {
+		if c != "" {
+			conds = append(conds, c)
+		}
+	}
+	n.Condition = strings.Join(conds, " AND ")
+
+	if p.SortMethod != "" {
+		n.Flags = append(n.Flags, "sort method: "+p.SortMethod)
+	}
+	if p.SortSpaceType == "Disk" {
+		n.Flags = append(n.Flags, FlagSortOnDisk)
+	}
+	if p.RowsRemovedByFilter != nil {
+		n.Flags = append(n.Flags, fmt.Sprintf("rows removed by filter: %.0f", *p.RowsRemovedByFilter))
+	}
+
+	if p.SharedHitBlocks != nil && p.SharedReadBlocks != nil {
+		n.Flags = append(n.Flags, fmt.Sprintf("buffers: shared hit=%.0f read=%.0f", *p.SharedHitBlocks, *p.SharedReadBlocks))
+	}
+
+	for _, child := range p.Plans {
+		n.Children = append(n.Children, convertPostgres(child))
+	}
+	return n
+}
@@ -0,0 +1,184 @@
+// Package ratelimit limits how fast and how much each client may query,
+// with token buckets for request rates and daily quotas on rows returned
+// and query time
+package ratelimit
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Limits are applied to each client separately; zero disables a limit
+type Limits struct {
+	RequestsPerMinute  int // all requests
+	Burst              int // requests allowed at once; defaults to RequestsPerMinute
+	ExpensivePerMinute int // expensive tool calls, on top of the request limit
+
+	// Daily quotas, reset at midnight UTC
+	DailyRows      int
+	DailyQueryTime time.Duration
+}
+
+// Enabled reports whether any limit is set
+func (l Limits) Enabled() bool {
+	return l.RequestsPerMinute > 0 || l.ExpensivePerMinute > 0 || l.DailyRows > 0 || l.DailyQueryTime > 0
+}
+
+func (l Limits) String() string {
+	if !l.Enabled() {
+		return "(none)"
+	}
+	return fmt.Sprintf("requests_per_minute=%d burst=%d expensive_per_minute=%d daily_rows=%d daily_query_time=%v",
+		l.RequestsPerMinute, l.burst(), l.ExpensivePerMinute, l.DailyRows, l.DailyQueryTime)
+}
+
+func (l Limits) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.RequestsPerMinute
+}
+
+// Error reports a request that exceeded a limit
+type Error struct {
+	Limit      string // "requests", "expensive_calls", "daily_rows" or "daily_query_time"
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("rate limit exceeded: %s; retry after %s", e.Limit, e.RetryAfter.Round(time.Second))
+}
+
+// idleExpiry is how long a client's state is kept after its last request;
+// by then its buckets are full and its quotas reset
+const idleExpiry = 24 * time.Hour
+
+// Limiter tracks the rates and daily usage of each client
+type Limiter struct {
+	mu      sync.Mutex
+	limits  Limits
+	clients map[string]*client
+	swept   time.Time
+	now     func() time.Time
+}
+
+type client struct {
+	requests  bucket
+	expensive bucket
+	seen      time.Time
+
+	// Usage of the current day
+	day       time.Time
+	rows      int
+	queryTime time.Duration
+}
+
+// New returns a limiter applying limits
+func New(limits Limits) *Limiter {
+	return &Limiter{limits: limits, clients: make(map[string]*client), now: time.Now}
+}
+
+// SetLimits replaces the limits; usage counted so far is kept
+func (l *Limiter) SetLimits(limits Limits) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limits = limits
+}
+
+// Allow takes a request of the client from its buckets, or returns an
+// *Error when a rate or daily quota is exhausted. Expensive requests also
+// count against ExpensivePerMinute.
+func (l *Limiter) Allow(key string, expensive bool) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	c := l.client(key, now)
+	if l.limits.DailyRows > 0 && c.rows >= l.limits.DailyRows {
+		return &Error{Limit: "daily_rows", RetryAfter: c.day.AddDate(0, 0, 1).Sub(now)}
+	}
+	if l.limits.DailyQueryTime > 0 && c.queryTime >= l.limits.DailyQueryTime {
+		return &Error{Limit: "daily_query_time", RetryAfter: c.day.AddDate(0, 0, 1).Sub(now)}
+	}
+
+	// Check both buckets before taking from either, so a rejected expensive
+	// call does not use up a request
+	c.requests.refill(now, l.limits.RequestsPerMinute, l.limits.burst())
+	c.expensive.refill(now, l.limits.ExpensivePerMinute, l.limits.ExpensivePerMinute)
+	if l.limits.RequestsPerMinute > 0 && c.requests.tokens < 1 {
+		return &Error{Limit: "requests", RetryAfter: c.requests.wait(l.limits.RequestsPerMinute)}
+	}
+	if expensive && l.limits.ExpensivePerMinute > 0 && c.expensive.tokens < 1 {
+		return &Error{Limit: "expensive_calls", RetryAfter: c.expensive.wait(l.limits.ExpensivePerMinute)}
+	}
+	if l.limits.RequestsPerMinute > 0 {
+		c.requests.tokens--
+	}
+	if expensive && l.limits.ExpensivePerMinute > 0 {
+		c.expensive.tokens--
+	}
+	return nil
+}
+
+// Record adds rows returned and query time to the client's daily usage
+func (l *Limiter) Record(key string, rows int, queryTime time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	c := l.client(key, l.now())
+	c.rows += rows
+	c.queryTime += queryTime
+}
+
+// client returns the state of key, starting a new day of usage at midnight
+// UTC. Clients idle for a day are dropped now and then.
+func (l *Limiter) client(key string, now time.Time) *client {
+	if now.Sub(l.swept) > time.Hour {
+		for k, c := range l.clients {
+			if now.Sub(c.seen) > idleExpiry {
+				delete(l.clients, k)
+			}
+		}
+		l.swept = now
+	}
+
+	day := now.UTC().Truncate(24 * time.Hour)
+	c, ok := l.clients[key]
+	if !ok {
+		c = &client{requests: bucket{tokens: -1}, expensive: bucket{tokens: -1}, day: day}
+		l.clients[key] = c
+	}
+	if !c.day.Equal(day) {
+		c.day, c.rows, c.queryTime = day, 0, 0
+	}
+	c.seen = now
+	return c
+}
+
+// bucket is a token bucket refilled at a rate per minute; a negative
+// token count marks a new bucket, which starts full
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func (b *bucket) refill(now time.Time, perMinute, burst int) {
+	if perMinute <= 0 {
+		return
+	}
+	if b.tokens < 0 {
+		b.tokens = float64(burst)
+	} else {
+		b.tokens += now.Sub(b.updated).Minutes() * float64(perMinute)
+		if b.tokens > float64(burst) {
+			b.tokens = float64(burst)
+		}
+	}
+	b.updated = now
+}
+
+// wait returns how long until the bucket holds a token
+func (b *bucket) wait(perMinute int) time.Duration {
+	return time.Duration((1 - b.tokens) / float64(perMinute) * float64(time.Minute))
+}
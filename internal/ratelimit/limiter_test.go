@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestLimiter returns a limiter with a clock the test advances
+func newTestLimiter(limits Limits) (*Limiter, *time.Time) {
+	now := time.Date(2026, 3, 14, 12, 0, 0, 0, time.UTC)
+	l := New(limits)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func limitOf(err error) string {
+	var limitErr *Error
+	if errors.As(err, &limitErr) {
+		return limitErr.Limit
+	}
+	return ""
+}
+
+func TestLimiter_Requests(t *testing.T) {
+	l, now := newTestLimiter(Limits{RequestsPerMinute: 60, Burst: 3})
+
+	for i := 0; i < 3; i++ {
+		if err := l.Allow("ci", false); err != nil {
+			t.Fatalf("request %d: unexpected error %v", i+1, err)
+		}
+	}
+	err := l.Allow("ci", false)
+	var limitErr *Error
+	if !errors.As(err, &limitErr) || limitErr.Limit != "requests" || limitErr.RetryAfter != time.Second {
+		t.Fatalf("Expected the burst to be used up, got %v", err)
+	}
+	if err := l.Allow("other", false); err != nil {
+		t.Errorf("Expected clients to be limited separately, got %v", err)
+	}
+
+	*now = now.Add(time.Second)
+	if err := l.Allow("ci", false); err != nil {
+		t.Errorf("Expected a token after one second, got %v", err)
+	}
+}
+
+func TestLimiter_Expensive(t *testing.T) {
+	l, now := newTestLimiter(Limits{RequestsPerMinute: 100, ExpensivePerMinute: 2})
+
+	for i := 0; i < 2; i++ {
+		if err := l.Allow("ci", true); err != nil {
+			t.Fatalf("call %d: unexpected error %v", i+1, err)
+		}
+	}
+	if got := limitOf(l.Allow("ci", true)); got != "expensive_calls" {
+		t.Errorf("Expected the expensive limit, got %q", got)
+	}
+	if err := l.Allow("ci", false); err != nil {
+		t.Errorf("Expected cheap calls to pass, got %v", err)
+	}
+	*now = now.Add(30 * time.Second)
+	if err := l.Allow("ci", true); err != nil {
+		t.Errorf("Expected a token after 30 seconds, got %v", err)
+	}
+}
+
+func TestLimiter_DailyQuotas(t *testing.T) {
+	l, now := newTestLimiter(Limits{DailyRows: 100, DailyQueryTime: time.Minute})
+
+	l.Record("ci", 60, time.Second)
+	if err := l.Allow("ci", false); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	l.Record("ci", 60, time.Second)
+	err := l.Allow("ci", false)
+	var limitErr *Error
+	if !errors.As(err, &limitErr) || limitErr.Limit != "daily_rows" || limitErr.RetryAfter != 12*time.Hour {
+		t.Fatalf("Expected the row quota until midnight, got %v", err)
+	}
+
+	l.Record("ops", 0, 2*time.Minute)
+	if got := limitOf(l.Allow("ops", false)); got != "daily_query_time" {
+		t.Errorf("Expected the query time quota, got %q", got)
+	}
+
+	*now = now.Add(12 * time.Hour)
+	if err := l.Allow("ci", false); err != nil {
+		t.Errorf("Expected quotas to reset at midnight, got %v", err)
+	}
+
+	l.SetLimits(Limits{})
+	l.Record("ci", 1000, time.Hour)
+	if err := l.Allow("ci", false); err != nil {
+		t.Errorf("Expected no limits after SetLimits, got %v", err)
+	}
+}
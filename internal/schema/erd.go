@@ -0,0 +1,265 @@
+package schema
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+)
+
+// DiagramFormat is an output format for entity-relationship diagrams
+type DiagramFormat string
+
+const (
+	DiagramMermaid DiagramFormat = "mermaid"
+	DiagramDOT     DiagramFormat = "dot"
+)
+
+// ColumnMode controls which columns are drawn inside each entity
+type ColumnMode string
+
+const (
+	ColumnsAll  ColumnMode = "all"
+	ColumnsKeys ColumnMode = "keys"
+	ColumnsNone ColumnMode = "none"
+)
+
+var (
+	// Mermaid entity names and attribute types are restricted to word-like tokens
+	mermaidNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+	mermaidTypeUnsafe = regexp.MustCompile(`[^A-Za-z0-9_()\[\]-]+`)
+)
+
+// RenderERDiagram draws the tables and the foreign keys between them. Foreign
+// keys pointing outside the given tables are omitted.
+func RenderERDiagram(tables []database.TableDetail, format DiagramFormat, columns ColumnMode) (string, error) {
+	sorted := make([]database.TableDetail, len(tables))
+	copy(sorted, tables)
+	sort.Slice(sorted, func(i, j int) bool { return refOf(&sorted[i]).String() < refOf(&sorted[j]).String() })
+
+	switch format {
+	case DiagramMermaid, "":
+		return renderMermaid(sorted, columns), nil
+	case DiagramDOT:
+		return renderDOT(sorted, columns), nil
+	default:
+		return "", fmt.Errorf("unsupported diagram format %q (expected mermaid or dot)", format)
+	}
+}
+
+func refOf(t *database.TableDetail) TableRef {
+	return TableRef{Schema: t.TableSchema, Name: t.TableName}
+}
+
+// relationship is a foreign key resolved against the set of drawn tables
+type relationship struct {
+	from, to database.TableDetail
+	fk       database.ForeignKeyInfo
+	optional bool // some referencing column is nullable
+	unique   bool // referencing columns are unique, so at most one row per parent
+}
+
+func relationships(tables []database.TableDetail) []relationship {
+	byRef := make(map[TableRef]int, len(tables))
+	for i := range tables {
+		byRef[refOf(&tables[i])] = i
+	}
+
+	var rels []relationship
+	for _, t := range tables {
+		for _, fk := range t.ForeignKeys {
+			schema := fk.ReferencedSchema
+			if schema == "" {
+				schema = t.TableSchema
+			}
+			idx, ok := byRef[TableRef{Schema: schema, Name: fk.ReferencedTable}]
+			if !ok {
+				continue
+			}
+			rels = append(rels, relationship{
+				from:     t,
+				to:       tables[idx],
+				fk:       fk,
+				optional: anyNullable(t.Columns, fk.Columns),
+				unique:   coversUniqueKey(t, fk.Columns),
+			})
+		}
+	}
+	return rels
+}
+
+func anyNullable(columns []database.ColumnInfo, names []string) bool {
+	for _, c := range columns {
+		if c.IsNullable == "YES" && containsString(names, c.ColumnName) {
+			return true
+		}
+	}
+	return false
+}
+
+// coversUniqueKey reports whether the columns are exactly a unique key of the table
+func coversUniqueKey(t database.TableDetail, columns []string) bool {
+	if sameColumnSet(t.PrimaryKey, columns) {
+		return true
+	}
+	for _, idx := range t.Indexes {
+		if idx.IsUnique && sameColumnSet(idx.Columns, columns) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameColumnSet(a, b []string) bool {
+	if len(a) == 0 || len(a) != len(b) {
+		return false
+	}
+	for _, x := range a {
+		if !containsString(b, x) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// keyMarkers returns PK/FK/UK markers for each column of the table
+func keyMarkers(t database.TableDetail) map[string][]string {
+	markers := make(map[string][]string)
+	for _, c := range t.PrimaryKey {
+		markers[c] = append(markers[c], "PK")
+	}
+	for _, fk := range t.ForeignKeys {
+		for _, c := range fk.Columns {
+			if !containsString(markers[c], "FK") {
+				markers[c] = append(markers[c], "FK")
+			}
+		}
+	}
+	for _, idx := range t.Indexes {
+		if idx.IsUnique && !idx.IsPrimary && len(idx.Columns) == 1 {
+			c := idx.Columns[0]
+			if !containsString(markers[c], "PK") && !containsString(markers[c], "UK") {
+				markers[c] = append(markers[c], "UK")
+			}
+		}
+	}
+	return markers
+}
+
+// visibleColumns filters the columns of a table according to the mode
+func visibleColumns(t database.TableDetail, mode ColumnMode, markers map[string][]string) []database.ColumnInfo {
+	switch mode {
+	case ColumnsNone:
+		return nil
+	case ColumnsKeys:
+		var cols []database.ColumnInfo
+		for _, c := range t.Columns {
+			if len(markers[c.ColumnName]) > 0 {
+				cols = append(cols, c)
+			}
+		}
+		return cols
+	default:
+		return t.Columns
+	}
+}
+
+func mermaidName(ref TableRef) string {
+	return strings.Trim(mermaidNameUnsafe.ReplaceAllString(ref.String(), "_"), "_")
+}
+
+func renderMermaid(tables []database.TableDetail, mode ColumnMode) string {
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+
+	for _, t := range tables {
+		name := mermaidName(refOf(&t))
+		markers := keyMarkers(t)
+		cols := visibleColumns(t, mode, markers)
+		if len(cols) == 0 {
+			fmt.Fprintf(&b, "    %s {\n    }\n", name)
+			continue
+		}
+
+		fmt.Fprintf(&b, "    %s {\n", name)
+		for _, c := range cols {
+			colType := mermaidTypeUnsafe.ReplaceAllString(c.DataType, "_")
+			if colType == "" {
+				colType = "unknown"
+			}
+			line := fmt.Sprintf("        %s %s", colType, mermaidNameUnsafe.ReplaceAllString(c.ColumnName, "_"))
+			if keys := markers[c.ColumnName]; len(keys) > 0 {
+				line += " " + strings.Join(keys, ",")
+			}
+			b.WriteString(line + "\n")
+		}
+		b.WriteString("    }\n")
+	}
+
+	for _, r := range relationships(tables) {
+		// Parent side: exactly one, or zero-or-one when the FK is nullable.
+		// Child side: zero-or-many, or zero-or-one when the FK is unique.
+		parent := "||"
+		if r.optional {
+			parent = "o|"
+		}
+		child := "}o"
+		if r.unique {
+			child = "|o"
+		}
+		fmt.Fprintf(&b, "    %s %s--%s %s : %q\n", mermaidName(refOf(&r.from)), child, parent,
+			mermaidName(refOf(&r.to)), strings.Join(r.fk.Columns, ", "))
+	}
+
+	return b.String()
+}
+
+func renderDOT(tables []database.TableDetail, mode ColumnMode) string {
+	var b strings.Builder
+	b.WriteString("digraph er {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=plaintext, fontname=\"Helvetica\"];\n")
+	b.WriteString("  edge [fontname=\"Helvetica\", fontsize=10];\n")
+
+	for _, t := range tables {
+		ref := refOf(&t)
+		markers := keyMarkers(t)
+
+		fmt.Fprintf(&b, "  %q [label=<\n", ref.String())
+		b.WriteString("    <table border=\"0\" cellborder=\"1\" cellspacing=\"0\" cellpadding=\"4\">\n")
+		fmt.Fprintf(&b, "      <tr><td colspan=\"2\" bgcolor=\"lightgrey\"><b>%s</b></td></tr>\n", html.EscapeString(ref.String()))
+		for _, c := range visibleColumns(t, mode, markers) {
+			name := html.EscapeString(c.ColumnName)
+			if keys := markers[c.ColumnName]; len(keys) > 0 {
+				name += " <i>(" + strings.Join(keys, ",") + ")</i>"
+			}
+			fmt.Fprintf(&b, "      <tr><td align=\"left\" port=%q>%s</td><td align=\"left\">%s</td></tr>\n",
+				c.ColumnName, name, html.EscapeString(c.DataType))
+		}
+		b.WriteString("    </table>\n  >];\n")
+	}
+
+	for _, r := range relationships(tables) {
+		label := strings.Join(r.fk.Columns, ", ") + " → " + strings.Join(r.fk.ReferencedColumns, ", ")
+		style := ""
+		if r.optional {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  %q -> %q [label=%q%s];\n", refOf(&r.from).String(), refOf(&r.to).String(), label, style)
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,121 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+)
+
+func TestRenderERDiagram_Mermaid(t *testing.T) {
+	snap := testSnapshot("postgres")
+
+	out, err := RenderERDiagram(snap.Tables, DiagramMermaid, ColumnsAll)
+	if err != nil {
+		t.Fatalf("RenderERDiagram failed: %v", err)
+	}
+
+	expected := []string{
+		"erDiagram",
+		"    shop_orders {",
+		"        integer id PK",
+		"        integer user_id FK",
+		"        character_varying email UK",
+		`    shop_orders }o--|| shop_users : "user_id"`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected Mermaid output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderERDiagram_Cardinality(t *testing.T) {
+	snap := testSnapshot("postgres")
+	orders := &snap.Tables[0]
+	orders.Columns[1].IsNullable = "YES"
+	orders.Indexes = append(orders.Indexes, database.IndexInfo{IndexName: "orders_user_key", Columns: []string{"user_id"}, IsUnique: true})
+
+	out, _ := RenderERDiagram(snap.Tables, DiagramMermaid, ColumnsKeys)
+	if !strings.Contains(out, `shop_orders |o--o| shop_users`) {
+		t.Errorf("Expected optional one-to-one relationship, got:\n%s", out)
+	}
+	if strings.Contains(out, " note") {
+		t.Errorf("Expected non-key columns to be hidden, got:\n%s", out)
+	}
+}
+
+func TestRenderERDiagram_DOT(t *testing.T) {
+	snap := testSnapshot("mysql")
+
+	out, err := RenderERDiagram(snap.Tables, DiagramDOT, ColumnsNone)
+	if err != nil {
+		t.Fatalf("RenderERDiagram failed: %v", err)
+	}
+
+	if !strings.HasPrefix(out, "digraph er {") {
+		t.Errorf("Expected DOT digraph, got:\n%s", out)
+	}
+	if !strings.Contains(out, `"shop.orders" -> "shop.users" [label="user_id → id"];`) {
+		t.Errorf("Expected relationship edge, got:\n%s", out)
+	}
+}
+
+func TestRenderERDiagram_SkipsExternalReferences(t *testing.T) {
+	snap := testSnapshot("postgres")
+
+	out, _ := RenderERDiagram(snap.Tables[:1], DiagramMermaid, ColumnsNone)
+	if strings.Contains(out, "shop_users") {
+		t.Errorf("Expected relationship to undrawn table to be omitted, got:\n%s", out)
+	}
+}
+
+func TestGraph_Neighborhood(t *testing.T) {
+	tables := []database.TableInfo{
+		{TableSchema: "s", TableName: "a"},
+		{TableSchema: "s", TableName: "b"},
+		{TableSchema: "s", TableName: "c"},
+		{TableSchema: "s", TableName: "d"},
+		{TableSchema: "t", TableName: "a"},
+	}
+	fks := []database.ForeignKeyInfo{
+		{TableSchema: "s", TableName: "b", Columns: []string{"a_id"}, ReferencedSchema: "s", ReferencedTable: "a", ReferencedColumns: []string{"id"}},
+		{TableSchema: "s", TableName: "c", Columns: []string{"b_id"}, ReferencedSchema: "s", ReferencedTable: "b", ReferencedColumns: []string{"id"}},
+		{TableSchema: "s", TableName: "d", Columns: []string{"x_id"}, ReferencedSchema: "s", ReferencedTable: "missing", ReferencedColumns: []string{"id"}},
+	}
+	g := NewGraph(tables, fks)
+
+	if len(g.Edges()) != 2 {
+		t.Errorf("Expected dangling foreign key to be dropped, got %d edges", len(g.Edges()))
+	}
+
+	seed, err := g.Resolve("s.a")
+	if err != nil {
+		t.Fatalf("Resolve failed: %v", err)
+	}
+
+	names := func(refs []TableRef) string {
+		var out []string
+		for _, r := range refs {
+			out = append(out, r.String())
+		}
+		return strings.Join(out, ",")
+	}
+
+	if got := names(g.Neighborhood([]TableRef{seed}, 0)); got != "s.a" {
+		t.Errorf("0 hops: got %s", got)
+	}
+	if got := names(g.Neighborhood([]TableRef{seed}, 1)); got != "s.a,s.b" {
+		t.Errorf("1 hop: got %s", got)
+	}
+	if got := names(g.Neighborhood([]TableRef{seed}, 5)); got != "s.a,s.b,s.c" {
+		t.Errorf("5 hops: got %s", got)
+	}
+
+	if _, err := g.Resolve("a"); err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Expected ambiguous name error, got %v", err)
+	}
+	if ref, err := g.Resolve("c"); err != nil || ref.Schema != "s" {
+		t.Errorf("Expected unqualified unique name to resolve, got %v, %v", ref, err)
+	}
+}
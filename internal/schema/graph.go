@@ -0,0 +1,165 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+)
+
+// TableRef identifies a table by schema and name
+type TableRef struct {
+	Schema string `json:"schema,omitempty"`
+	Name   string `json:"name"`
+}
+
+// String returns the schema-qualified name
+func (r TableRef) String() string {
+	if r.Schema == "" {
+		return r.Name
+	}
+	return r.Schema + "." + r.Name
+}
+
+// Edge is a relationship from one table's columns to another's, either a
+// declared foreign key or one inferred from column naming
+type Edge struct {
+	From        TableRef `json:"from"`
+	FromColumns []string `json:"from_columns"`
+	To          TableRef `json:"to"`
+	ToColumns   []string `json:"to_columns"`
+	Constraint  string   `json:"constraint,omitempty"`
+	Inferred    bool     `json:"inferred,omitempty"`
+}
+
+// Graph is an undirected view of the relationships between tables
+type Graph struct {
+	tables []TableRef
+	edges  []Edge
+	adj    map[TableRef][]int
+}
+
+// NewGraph builds a graph from table metadata and foreign keys. Foreign keys
+// pointing at tables that are not listed are ignored.
+func NewGraph(tables []database.TableInfo, fks []database.ForeignKeyInfo) *Graph {
+	g := &Graph{adj: make(map[TableRef][]int)}
+
+	for _, t := range tables {
+		ref := TableRef{Schema: t.TableSchema, Name: t.TableName}
+		if _, ok := g.adj[ref]; ok {
+			continue
+		}
+		g.tables = append(g.tables, ref)
+		g.adj[ref] = nil
+	}
+	sort.Slice(g.tables, func(i, j int) bool { return g.tables[i].String() < g.tables[j].String() })
+
+	for _, fk := range fks {
+		g.AddEdge(Edge{
+			From:        TableRef{Schema: fk.TableSchema, Name: fk.TableName},
+			FromColumns: fk.Columns,
+			To:          TableRef{Schema: fk.ReferencedSchema, Name: fk.ReferencedTable},
+			ToColumns:   fk.ReferencedColumns,
+			Constraint:  fk.ConstraintName,
+		})
+	}
+
+	return g
+}
+
+// AddEdge adds a relationship between two known tables
+func (g *Graph) AddEdge(e Edge) bool {
+	if _, ok := g.adj[e.From]; !ok {
+		return false
+	}
+	if _, ok := g.adj[e.To]; !ok {
+		return false
+	}
+	idx := len(g.edges)
+	g.edges = append(g.edges, e)
+	g.adj[e.From] = append(g.adj[e.From], idx)
+	if e.To != e.From {
+		g.adj[e.To] = append(g.adj[e.To], idx)
+	}
+	return true
+}
+
+// Tables returns every table in the graph, sorted by qualified name
+func (g *Graph) Tables() []TableRef {
+	return g.tables
+}
+
+// Edges returns every relationship in the graph
+func (g *Graph) Edges() []Edge {
+	return g.edges
+}
+
+// Resolve finds a table by name. Unqualified names must be unambiguous.
+func (g *Graph) Resolve(name string) (TableRef, error) {
+	schema, table := database.SplitTableName(name)
+	if schema != "" {
+		ref := TableRef{Schema: schema, Name: table}
+		if _, ok := g.adj[ref]; ok {
+			return ref, nil
+		}
+		return TableRef{}, fmt.Errorf("table not found: %s", name)
+	}
+
+	var matches []TableRef
+	for _, ref := range g.tables {
+		if ref.Name == table {
+			matches = append(matches, ref)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return TableRef{}, fmt.Errorf("table not found: %s", name)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, len(matches))
+		for i, m := range matches {
+			names[i] = m.String()
+		}
+		return TableRef{}, fmt.Errorf("table name %s is ambiguous, qualify it with a schema: %s", name, strings.Join(names, ", "))
+	}
+}
+
+// Neighborhood returns the seeds plus every table reachable within the given
+// number of relationship hops, in either direction
+func (g *Graph) Neighborhood(seeds []TableRef, hops int) []TableRef {
+	seen := make(map[TableRef]bool)
+	frontier := make([]TableRef, 0, len(seeds))
+	for _, s := range seeds {
+		if !seen[s] {
+			seen[s] = true
+			frontier = append(frontier, s)
+		}
+	}
+
+	for depth := 0; depth < hops && len(frontier) > 0; depth++ {
+		var next []TableRef
+		for _, ref := range frontier {
+			for _, idx := range g.adj[ref] {
+				other := g.edges[idx].To
+				if other == ref {
+					other = g.edges[idx].From
+				}
+				if !seen[other] {
+					seen[other] = true
+					next = append(next, other)
+				}
+			}
+		}
+		frontier = next
+	}
+
+	result := make([]TableRef, 0, len(seen))
+	for _, ref := range g.tables {
+		if seen[ref] {
+			result = append(result, ref)
+		}
+	}
+	return result
+}
@@ -0,0 +1,447 @@
+package schema
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+)
+
+// inferredEdgeCost makes paths through guessed relationships rank below
+// equally long paths through declared foreign keys
+const inferredEdgeCost = 1.5
+
+// AddInferredEdges adds relationships for <table>_id columns that are not
+// covered by a foreign key, pointing at the id column of the matching table.
+// It returns the number of edges added.
+func (g *Graph) AddInferredEdges(columns []database.ColumnInfo) int {
+	// Index which tables have an id column and which columns already have FKs
+	idColumns := make(map[TableRef]string)
+	byName := make(map[string][]TableRef)
+	for _, ref := range g.tables {
+		byName[strings.ToLower(ref.Name)] = append(byName[strings.ToLower(ref.Name)], ref)
+	}
+	for _, c := range columns {
+		if strings.EqualFold(c.ColumnName, "id") {
+			idColumns[TableRef{Schema: c.TableSchema, Name: c.TableName}] = c.ColumnName
+		}
+	}
+	declared := make(map[string]bool)
+	for _, e := range g.edges {
+		for _, c := range e.FromColumns {
+			declared[e.From.String()+"."+strings.ToLower(c)] = true
+		}
+	}
+
+	added := 0
+	for _, c := range columns {
+		from := TableRef{Schema: c.TableSchema, Name: c.TableName}
+		lower := strings.ToLower(c.ColumnName)
+		if !strings.HasSuffix(lower, "_id") || len(lower) <= len("_id") || declared[from.String()+"."+lower] {
+			continue
+		}
+
+		target, ok := inferTarget(strings.TrimSuffix(lower, "_id"), from, byName, idColumns)
+		if !ok {
+			continue
+		}
+
+		if g.AddEdge(Edge{
+			From:        from,
+			FromColumns: []string{c.ColumnName},
+			To:          target,
+			ToColumns:   []string{idColumns[target]},
+			Inferred:    true,
+		}) {
+			added++
+		}
+	}
+	return added
+}
+
+// inferTarget finds the table a <base>_id column most likely refers to,
+// trying simple plural forms and preferring the referencing table's schema
+func inferTarget(base string, from TableRef, byName map[string][]TableRef, idColumns map[TableRef]string) (TableRef, bool) {
+	names := []string{base, base + "s", base + "es"}
+	if strings.HasSuffix(base, "y") {
+		names = append(names, strings.TrimSuffix(base, "y")+"ies")
+	}
+
+	var candidates []TableRef
+	for _, name := range names {
+		for _, ref := range byName[name] {
+			if _, ok := idColumns[ref]; ok && ref != from {
+				candidates = append(candidates, ref)
+			}
+		}
+	}
+
+	for _, ref := range candidates {
+		if ref.Schema == from.Schema {
+			return ref, true
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], true
+	}
+	return TableRef{}, false
+}
+
+// JoinStep is one hop of a join path. Forward is true when the step walks
+// the edge from the referencing table to the referenced one.
+type JoinStep struct {
+	Edge    Edge `json:"edge"`
+	Forward bool `json:"forward"`
+}
+
+// JoinPath is a sequence of joins connecting two tables
+type JoinPath struct {
+	Tables []TableRef `json:"tables"`
+	Steps  []JoinStep `json:"steps"`
+	Cost   float64    `json:"cost"`
+}
+
+// Inferred reports whether any step relies on a guessed relationship
+func (p JoinPath) Inferred() bool {
+	for _, s := range p.Steps {
+		if s.Edge.Inferred {
+			return true
+		}
+	}
+	return false
+}
+
+// JoinOptions bounds the search for join paths
+type JoinOptions struct {
+	MaxPaths        int  // number of alternative paths to return
+	MaxHops         int  // longest path considered
+	IncludeInferred bool // whether to use inferred <table>_id relationships
+}
+
+// rawPath is a path as node and edge indices during the search
+type rawPath struct {
+	nodes []TableRef
+	edges []int
+	cost  float64
+}
+
+func (p rawPath) key() string {
+	parts := make([]string, len(p.edges))
+	for i, e := range p.edges {
+		parts[i] = fmt.Sprint(e)
+	}
+	return strings.Join(parts, ",")
+}
+
+// JoinPaths returns up to MaxPaths loop-free paths from one table to another,
+// cheapest first, using Yen's k-shortest-paths algorithm
+func (g *Graph) JoinPaths(from, to TableRef, opts JoinOptions) ([]JoinPath, error) {
+	if from == to {
+		return nil, fmt.Errorf("source and target are the same table: %s", from)
+	}
+	if opts.MaxPaths <= 0 {
+		opts.MaxPaths = 1
+	}
+
+	first, ok := g.shortestPath(from, to, nil, nil, opts.IncludeInferred)
+	if !ok || (opts.MaxHops > 0 && len(first.edges) > opts.MaxHops) {
+		return nil, nil
+	}
+
+	accepted := []rawPath{first}
+	seen := map[string]bool{first.key(): true}
+	var candidates []rawPath
+
+	for len(accepted) < opts.MaxPaths {
+		prev := accepted[len(accepted)-1]
+
+		for i := 0; i < len(prev.edges); i++ {
+			spur := prev.nodes[i]
+			rootNodes := prev.nodes[:i+1]
+			rootEdges := prev.edges[:i]
+
+			// Ban the next edge of every accepted path sharing this root,
+			// and every root node except the spur to keep paths loop-free
+			bannedEdges := make(map[int]bool)
+			for _, p := range accepted {
+				if len(p.edges) > i && sameRoot(p, rootNodes, rootEdges) {
+					bannedEdges[p.edges[i]] = true
+				}
+			}
+			bannedNodes := make(map[TableRef]bool)
+			for _, n := range rootNodes[:i] {
+				bannedNodes[n] = true
+			}
+
+			spurPath, ok := g.shortestPath(spur, to, bannedEdges, bannedNodes, opts.IncludeInferred)
+			if !ok {
+				continue
+			}
+
+			total := rawPath{
+				nodes: append(append([]TableRef{}, rootNodes[:i]...), spurPath.nodes...),
+				edges: append(append([]int{}, rootEdges...), spurPath.edges...),
+			}
+			for _, e := range total.edges {
+				total.cost += g.edgeCost(e)
+			}
+			if opts.MaxHops > 0 && len(total.edges) > opts.MaxHops {
+				continue
+			}
+			if key := total.key(); !seen[key] {
+				seen[key] = true
+				candidates = append(candidates, total)
+			}
+		}
+
+		if len(candidates) == 0 {
+			break
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			if candidates[i].cost != candidates[j].cost {
+				return candidates[i].cost < candidates[j].cost
+			}
+			return len(candidates[i].edges) < len(candidates[j].edges)
+		})
+		accepted = append(accepted, candidates[0])
+		candidates = candidates[1:]
+	}
+
+	paths := make([]JoinPath, len(accepted))
+	for i, p := range accepted {
+		paths[i] = g.toJoinPath(p)
+	}
+	return paths, nil
+}
+
+func sameRoot(p rawPath, nodes []TableRef, edges []int) bool {
+	if len(p.nodes) < len(nodes) || len(p.edges) < len(edges) {
+		return false
+	}
+	for i := range nodes {
+		if p.nodes[i] != nodes[i] {
+			return false
+		}
+	}
+	for i := range edges {
+		if p.edges[i] != edges[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *Graph) edgeCost(idx int) float64 {
+	if g.edges[idx].Inferred {
+		return inferredEdgeCost
+	}
+	return 1
+}
+
+func (g *Graph) toJoinPath(p rawPath) JoinPath {
+	path := JoinPath{Tables: p.nodes, Cost: p.cost}
+	for i, idx := range p.edges {
+		e := g.edges[idx]
+		path.Steps = append(path.Steps, JoinStep{Edge: e, Forward: e.From == p.nodes[i]})
+	}
+	return path
+}
+
+// shortestPath runs Dijkstra from src to dst, skipping banned edges and nodes
+func (g *Graph) shortestPath(src, dst TableRef, bannedEdges map[int]bool, bannedNodes map[TableRef]bool, includeInferred bool) (rawPath, bool) {
+	dist := map[TableRef]float64{src: 0}
+	prevEdge := make(map[TableRef]int)
+	prevNode := make(map[TableRef]TableRef)
+	done := make(map[TableRef]bool)
+
+	pq := &refQueue{{ref: src}}
+	for pq.Len() > 0 {
+		cur := heap.Pop(pq).(refItem)
+		if done[cur.ref] {
+			continue
+		}
+		done[cur.ref] = true
+		if cur.ref == dst {
+			break
+		}
+
+		for _, idx := range g.adj[cur.ref] {
+			e := g.edges[idx]
+			if bannedEdges[idx] || (e.Inferred && !includeInferred) {
+				continue
+			}
+			next := e.To
+			if next == cur.ref {
+				next = e.From
+			}
+			if next == cur.ref || bannedNodes[next] || done[next] {
+				continue
+			}
+			cost := cur.cost + g.edgeCost(idx)
+			if d, ok := dist[next]; !ok || cost < d {
+				dist[next] = cost
+				prevEdge[next] = idx
+				prevNode[next] = cur.ref
+				heap.Push(pq, refItem{ref: next, cost: cost})
+			}
+		}
+	}
+
+	if !done[dst] {
+		return rawPath{}, false
+	}
+
+	path := rawPath{cost: dist[dst]}
+	for n := dst; n != src; n = prevNode[n] {
+		path.nodes = append([]TableRef{n}, path.nodes...)
+		path.edges = append([]int{prevEdge[n]}, path.edges...)
+	}
+	path.nodes = append([]TableRef{src}, path.nodes...)
+	return path, true
+}
+
+type refItem struct {
+	ref  TableRef
+	cost float64
+}
+
+// refQueue is a min-heap of tables by path cost, tie-broken by name so that
+// results are deterministic
+type refQueue []refItem
+
+func (q refQueue) Len() int { return len(q) }
+func (q refQueue) Less(i, j int) bool {
+	if q[i].cost != q[j].cost {
+		return q[i].cost < q[j].cost
+	}
+	return q[i].ref.String() < q[j].ref.String()
+}
+func (q refQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *refQueue) Push(x interface{}) { *q = append(*q, x.(refItem)) }
+func (q *refQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// OnClauses returns the ON condition for each step, using the given aliases
+func (p JoinPath) OnClauses(dbType string, aliases []string) []string {
+	clauses := make([]string, len(p.Steps))
+	for i, step := range p.Steps {
+		prev, next := aliases[i], aliases[i+1]
+		prevCols, nextCols := step.Edge.FromColumns, step.Edge.ToColumns
+		if !step.Forward {
+			prevCols, nextCols = nextCols, prevCols
+		}
+
+		conds := make([]string, len(prevCols))
+		for j := range prevCols {
+			conds[j] = fmt.Sprintf("%s.%s = %s.%s",
+				next, database.QuoteIdentifier(dbType, nextCols[j]),
+				prev, database.QuoteIdentifier(dbType, prevCols[j]))
+		}
+		clauses[i] = strings.Join(conds, " AND ")
+	}
+	return clauses
+}
+
+// Aliases returns short, unique aliases for the tables of the path built
+// from the initials of their names (order_items -> oi)
+func (p JoinPath) Aliases() []string {
+	used := make(map[string]int)
+	aliases := make([]string, len(p.Tables))
+	for i, t := range p.Tables {
+		var alias strings.Builder
+		for _, part := range strings.Split(strings.ToLower(t.Name), "_") {
+			if part != "" {
+				alias.WriteByte(part[0])
+			}
+		}
+		a := alias.String()
+		if a == "" || !isIdentStart(a[0]) {
+			a = "t" + a
+		}
+		used[a]++
+		if used[a] > 1 {
+			a = fmt.Sprintf("%s%d", a, used[a])
+		}
+		aliases[i] = a
+	}
+	return aliases
+}
+
+func isIdentStart(c byte) bool {
+	return (c >= 'a' && c <= 'z') || c == '_'
+}
+
+// SQL renders the path as a FROM ... JOIN ... ON ... fragment
+func (p JoinPath) SQL(dbType string) string {
+	aliases := p.Aliases()
+	on := p.OnClauses(dbType, aliases)
+
+	var b strings.Builder
+	first := p.Tables[0]
+	fmt.Fprintf(&b, "FROM %s %s", database.QuoteQualifiedName(dbType, first.Schema, first.Name), aliases[0])
+	for i, clause := range on {
+		t := p.Tables[i+1]
+		fmt.Fprintf(&b, "\nJOIN %s %s ON %s", database.QuoteQualifiedName(dbType, t.Schema, t.Name), aliases[i+1], clause)
+	}
+	return b.String()
+}
+
+// GraphCache holds a join graph built from database metadata and rebuilds it
+// after the TTL expires or when invalidated
+type GraphCache struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	graph   *Graph
+	builtAt time.Time
+}
+
+// NewGraphCache creates a cache whose graph expires after ttl
+func NewGraphCache(ttl time.Duration) *GraphCache {
+	return &GraphCache{ttl: ttl}
+}
+
+// Get returns the cached graph, building it from the adapter when missing or stale
+func (c *GraphCache) Get(ctx context.Context, adapter database.Adapter) (*Graph, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.graph != nil && time.Since(c.builtAt) < c.ttl {
+		return c.graph, nil
+	}
+
+	tables, err := adapter.ListTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	fks, err := adapter.ListForeignKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list foreign keys: %w", err)
+	}
+	columns, err := adapter.ListColumns(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list columns: %w", err)
+	}
+
+	graph := NewGraph(tables, fks)
+	graph.AddInferredEdges(columns)
+
+	c.graph = graph
+	c.builtAt = time.Now()
+	return graph, nil
+}
+
+// Invalidate drops the cached graph so the next Get rebuilds it
+func (c *GraphCache) Invalidate() {
+	c.mu.Lock()
+	c.graph = nil
+	c.mu.Unlock()
+}
@@ -0,0 +1,123 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+)
+
+// joinTestGraph models customers <- orders <- order_items -> products, with
+// order_items.product_id lacking a declared foreign key
+func joinTestGraph() *Graph {
+	tables := []database.TableInfo{
+		{TableSchema: "public", TableName: "customers"},
+		{TableSchema: "public", TableName: "orders"},
+		{TableSchema: "public", TableName: "order_items"},
+		{TableSchema: "public", TableName: "products"},
+		{TableSchema: "public", TableName: "categories"},
+	}
+	fks := []database.ForeignKeyInfo{
+		{TableSchema: "public", TableName: "orders", ConstraintName: "orders_customer_fk", Columns: []string{"customer_id"},
+			ReferencedSchema: "public", ReferencedTable: "customers", ReferencedColumns: []string{"id"}},
+		{TableSchema: "public", TableName: "order_items", ConstraintName: "items_order_fk", Columns: []string{"order_id"},
+			ReferencedSchema: "public", ReferencedTable: "orders", ReferencedColumns: []string{"id"}},
+	}
+	columns := []database.ColumnInfo{
+		{TableSchema: "public", TableName: "customers", ColumnName: "id"},
+		{TableSchema: "public", TableName: "orders", ColumnName: "id"},
+		{TableSchema: "public", TableName: "orders", ColumnName: "customer_id"},
+		{TableSchema: "public", TableName: "order_items", ColumnName: "order_id"},
+		{TableSchema: "public", TableName: "order_items", ColumnName: "product_id"},
+		{TableSchema: "public", TableName: "products", ColumnName: "id"},
+		{TableSchema: "public", TableName: "products", ColumnName: "category_id"},
+		{TableSchema: "public", TableName: "categories", ColumnName: "id"},
+	}
+
+	g := NewGraph(tables, fks)
+	g.AddInferredEdges(columns)
+	return g
+}
+
+func TestGraph_AddInferredEdges(t *testing.T) {
+	g := joinTestGraph()
+
+	var inferred []string
+	for _, e := range g.Edges() {
+		if e.Inferred {
+			inferred = append(inferred, e.From.Name+"."+e.FromColumns[0]+"->"+e.To.Name)
+		}
+	}
+
+	got := strings.Join(inferred, ",")
+	if got != "order_items.product_id->products,products.category_id->categories" {
+		t.Errorf("Unexpected inferred edges: %s", got)
+	}
+}
+
+func TestGraph_JoinPaths(t *testing.T) {
+	g := joinTestGraph()
+	from, _ := g.Resolve("customers")
+	to, _ := g.Resolve("products")
+
+	paths, err := g.JoinPaths(from, to, JoinOptions{MaxPaths: 3, MaxHops: 4, IncludeInferred: true})
+	if err != nil {
+		t.Fatalf("JoinPaths failed: %v", err)
+	}
+	if len(paths) != 1 {
+		t.Fatalf("Expected exactly one path, got %d", len(paths))
+	}
+
+	path := paths[0]
+	if len(path.Steps) != 3 || !path.Inferred() {
+		t.Errorf("Expected a 3-step inferred path, got %+v", path)
+	}
+
+	sql := path.SQL("postgres")
+	expected := `FROM "public"."customers" c
+JOIN "public"."orders" o ON o."customer_id" = c."id"
+JOIN "public"."order_items" oi ON oi."order_id" = o."id"
+JOIN "public"."products" p ON p."id" = oi."product_id"`
+	if sql != expected {
+		t.Errorf("Unexpected SQL:\n%s\nwant:\n%s", sql, expected)
+	}
+
+	paths, _ = g.JoinPaths(from, to, JoinOptions{MaxPaths: 3, MaxHops: 4})
+	if len(paths) != 0 {
+		t.Errorf("Expected no path without inferred edges, got %d", len(paths))
+	}
+
+	paths, _ = g.JoinPaths(from, to, JoinOptions{MaxPaths: 3, MaxHops: 2, IncludeInferred: true})
+	if len(paths) != 0 {
+		t.Errorf("Expected no path within 2 hops, got %d", len(paths))
+	}
+}
+
+func TestGraph_JoinPathsAlternatives(t *testing.T) {
+	tables := []database.TableInfo{
+		{TableName: "users"},
+		{TableName: "tickets"},
+	}
+	fks := []database.ForeignKeyInfo{
+		{TableName: "tickets", ConstraintName: "tickets_author_fk", Columns: []string{"author_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+		{TableName: "tickets", ConstraintName: "tickets_assignee_fk", Columns: []string{"assignee_id"}, ReferencedTable: "users", ReferencedColumns: []string{"id"}},
+	}
+	g := NewGraph(tables, fks)
+
+	paths, err := g.JoinPaths(TableRef{Name: "users"}, TableRef{Name: "tickets"}, JoinOptions{MaxPaths: 5})
+	if err != nil {
+		t.Fatalf("JoinPaths failed: %v", err)
+	}
+	if len(paths) != 2 {
+		t.Fatalf("Expected one path per foreign key, got %d", len(paths))
+	}
+
+	on := paths[0].OnClauses("mysql", paths[0].Aliases())
+	if on[0] != "t.`author_id` = u.`id`" {
+		t.Errorf("Unexpected ON clause: %s", on[0])
+	}
+
+	if _, err := g.JoinPaths(TableRef{Name: "users"}, TableRef{Name: "users"}, JoinOptions{}); err == nil {
+		t.Error("Expected error joining a table to itself")
+	}
+}
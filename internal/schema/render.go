@@ -0,0 +1,328 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+)
+
+// Format is an output format for a schema snapshot
+type Format string
+
+const (
+	FormatDDL      Format = "ddl"
+	FormatJSON     Format = "json"
+	FormatMarkdown Format = "markdown"
+)
+
+// ParseFormat validates a format name, defaulting to Markdown when empty
+func ParseFormat(value string) (Format, error) {
+	switch Format(strings.ToLower(value)) {
+	case "":
+		return FormatMarkdown, nil
+	case FormatDDL, "sql":
+		return FormatDDL, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	case FormatMarkdown, "md":
+		return FormatMarkdown, nil
+	default:
+		return "", fmt.Errorf("unsupported format %q (expected ddl, json or markdown)", value)
+	}
+}
+
+// Render renders the snapshot in the requested format
+func Render(snap *Snapshot, format Format) (string, error) {
+	switch format {
+	case FormatDDL:
+		return renderDDL(snap), nil
+	case FormatJSON:
+		data, err := json.MarshalIndent(snap, "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("failed to encode snapshot: %w", err)
+		}
+		return string(data), nil
+	case FormatMarkdown:
+		return renderMarkdown(snap), nil
+	default:
+		return "", fmt.Errorf("unsupported format %q", format)
+	}
+}
+
+// EstimateTokens approximates the number of LLM tokens in text
+// (roughly four characters per token for SQL and English)
+func EstimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// RenderWithBudget renders the snapshot at the highest detail level that fits
+// within maxTokens. If even table names do not fit, trailing tables are
+// dropped and counted in OmittedTables. A maxTokens of zero disables the budget.
+func RenderWithBudget(snap *Snapshot, format Format, maxTokens int) (string, DetailLevel, error) {
+	if maxTokens <= 0 {
+		out, err := Render(snap, format)
+		return out, DetailFull, err
+	}
+
+	var reduced *Snapshot
+	for level := DetailFull; level <= DetailNames; level++ {
+		reduced = snap.Reduce(level)
+		out, err := Render(reduced, format)
+		if err != nil {
+			return "", level, err
+		}
+		if EstimateTokens(out) <= maxTokens {
+			return out, level, nil
+		}
+	}
+
+	// Binary search for the largest prefix of tables that fits
+	lo, hi := 0, len(reduced.Tables)
+	for lo < hi {
+		mid := (lo + hi + 1) / 2
+		out, err := Render(reduced.Truncate(mid), format)
+		if err != nil {
+			return "", DetailNames, err
+		}
+		if EstimateTokens(out) <= maxTokens {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	out, err := Render(reduced.Truncate(lo), format)
+	return out, DetailNames, err
+}
+
+func qualifiedName(dbType string, t *database.TableDetail) string {
+	return database.QuoteQualifiedName(dbType, t.TableSchema, t.TableName)
+}
+
+func quoteList(dbType string, names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = database.QuoteIdentifier(dbType, n)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+func sqlString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// renderDDL reconstructs CREATE statements from the snapshot. The output is
+// meant for reading, not for replaying: engine options, partitions, triggers
+// and grants are not captured.
+func renderDDL(snap *Snapshot) string {
+	var b strings.Builder
+	dbType := snap.DBType
+
+	fmt.Fprintf(&b, "-- Schema snapshot (%s), %d tables\n", dbType, len(snap.Tables))
+
+	for i := range snap.Tables {
+		t := &snap.Tables[i]
+		name := qualifiedName(dbType, t)
+		b.WriteString("\n")
+
+		if strings.Contains(t.TableType, "VIEW") {
+			if t.ViewDefinition != "" {
+				fmt.Fprintf(&b, "CREATE %s %s AS\n%s;\n", t.TableType, name,
+					strings.TrimRight(strings.TrimSpace(t.ViewDefinition), ";"))
+				continue
+			}
+			var cols []string
+			for _, c := range t.Columns {
+				cols = append(cols, c.ColumnName+" "+c.DataType)
+			}
+			fmt.Fprintf(&b, "-- %s %s (%s)\n", strings.ToLower(t.TableType), name, strings.Join(cols, ", "))
+			continue
+		}
+
+		if len(t.Columns) == 0 {
+			fmt.Fprintf(&b, "-- table %s\n", name)
+			continue
+		}
+
+		var lines []string
+		for _, c := range t.Columns {
+			colType := c.ColumnType
+			if colType == "" {
+				colType = c.DataType
+			}
+			line := fmt.Sprintf("  %s %s", database.QuoteIdentifier(dbType, c.ColumnName), colType)
+			if c.IsNullable == "NO" {
+				line += " NOT NULL"
+			}
+			if c.ColumnDefault != "" {
+				line += " DEFAULT " + c.ColumnDefault
+			}
+			if c.Extra != "" && dbType == "mysql" {
+				line += " " + strings.ToUpper(c.Extra)
+			}
+			if c.Comment != "" && dbType == "mysql" {
+				line += " COMMENT " + sqlString(c.Comment)
+			}
+			lines = append(lines, line)
+		}
+		if len(t.PrimaryKey) > 0 {
+			lines = append(lines, fmt.Sprintf("  PRIMARY KEY (%s)", quoteList(dbType, t.PrimaryKey)))
+		}
+		for _, fk := range t.ForeignKeys {
+			ref := database.QuoteQualifiedName(dbType, fk.ReferencedSchema, fk.ReferencedTable)
+			line := fmt.Sprintf("  CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+				database.QuoteIdentifier(dbType, fk.ConstraintName), quoteList(dbType, fk.Columns),
+				ref, quoteList(dbType, fk.ReferencedColumns))
+			if fk.OnUpdate != "" && fk.OnUpdate != "NO ACTION" {
+				line += " ON UPDATE " + fk.OnUpdate
+			}
+			if fk.OnDelete != "" && fk.OnDelete != "NO ACTION" {
+				line += " ON DELETE " + fk.OnDelete
+			}
+			lines = append(lines, line)
+		}
+
+		fmt.Fprintf(&b, "CREATE TABLE %s (\n%s\n)", name, strings.Join(lines, ",\n"))
+		if t.Comment != "" && dbType == "mysql" {
+			b.WriteString(" COMMENT=" + sqlString(t.Comment))
+		}
+		b.WriteString(";\n")
+
+		for _, idx := range t.Indexes {
+			if idx.IsPrimary {
+				continue
+			}
+			unique := ""
+			if idx.IsUnique {
+				unique = "UNIQUE "
+			}
+			// Postgres index columns may be expressions and are kept verbatim
+			cols := strings.Join(idx.Columns, ", ")
+			if dbType == "mysql" {
+				cols = quoteList(dbType, idx.Columns)
+			}
+			fmt.Fprintf(&b, "CREATE %sINDEX %s ON %s (%s);\n", unique,
+				database.QuoteIdentifier(dbType, idx.IndexName), name, cols)
+		}
+
+		if dbType != "mysql" {
+			if t.Comment != "" {
+				fmt.Fprintf(&b, "COMMENT ON TABLE %s IS %s;\n", name, sqlString(t.Comment))
+			}
+			for _, c := range t.Columns {
+				if c.Comment != "" {
+					fmt.Fprintf(&b, "COMMENT ON COLUMN %s.%s IS %s;\n", name,
+						database.QuoteIdentifier(dbType, c.ColumnName), sqlString(c.Comment))
+				}
+			}
+		}
+	}
+
+	if snap.OmittedTables > 0 {
+		fmt.Fprintf(&b, "\n-- %d more tables omitted to fit the size limit\n", snap.OmittedTables)
+	}
+
+	return b.String()
+}
+
+// renderMarkdown produces a compact data dictionary
+func renderMarkdown(snap *Snapshot) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Data dictionary (%s, %d tables)\n", snap.DBType, len(snap.Tables))
+
+	listing := false
+	for i := range snap.Tables {
+		t := &snap.Tables[i]
+		name := t.TableName
+		if t.TableSchema != "" {
+			name = t.TableSchema + "." + t.TableName
+		}
+
+		if len(t.Columns) == 0 {
+			if !listing {
+				b.WriteString("\n")
+				listing = true
+			}
+			fmt.Fprintf(&b, "- %s\n", name)
+			continue
+		}
+		listing = false
+
+		fmt.Fprintf(&b, "\n## %s", name)
+		if t.TableType != "" && t.TableType != "BASE TABLE" {
+			fmt.Fprintf(&b, " (%s)", strings.ToLower(t.TableType))
+		}
+		b.WriteString("\n\n")
+		if t.Comment != "" {
+			fmt.Fprintf(&b, "%s\n\n", t.Comment)
+		}
+
+		b.WriteString("| Column | Type | Null | Default | Notes |\n")
+		b.WriteString("|---|---|---|---|---|\n")
+		for _, c := range t.Columns {
+			colType := c.ColumnType
+			if colType == "" {
+				colType = c.DataType
+			}
+			var notes []string
+			if c.ColumnKey != "" {
+				notes = append(notes, c.ColumnKey)
+			}
+			if c.Extra != "" {
+				notes = append(notes, c.Extra)
+			}
+			if c.Comment != "" {
+				notes = append(notes, c.Comment)
+			}
+			fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", markdownCell(c.ColumnName), markdownCell(colType),
+				markdownCell(c.IsNullable), markdownCell(c.ColumnDefault), markdownCell(strings.Join(notes, "; ")))
+		}
+
+		if len(t.PrimaryKey) > 0 {
+			fmt.Fprintf(&b, "\n**Primary key:** %s\n", strings.Join(t.PrimaryKey, ", "))
+		}
+		if len(t.ForeignKeys) > 0 {
+			b.WriteString("\n**References:**\n")
+			for _, fk := range t.ForeignKeys {
+				ref := fk.ReferencedTable
+				if fk.ReferencedSchema != "" {
+					ref = fk.ReferencedSchema + "." + ref
+				}
+				fmt.Fprintf(&b, "- (%s) → %s(%s)\n", strings.Join(fk.Columns, ", "), ref, strings.Join(fk.ReferencedColumns, ", "))
+			}
+		}
+		if len(t.Indexes) > 0 {
+			var idxs []string
+			for _, idx := range t.Indexes {
+				if idx.IsPrimary {
+					continue
+				}
+				desc := fmt.Sprintf("%s (%s)", idx.IndexName, strings.Join(idx.Columns, ", "))
+				if idx.IsUnique {
+					desc += " unique"
+				}
+				idxs = append(idxs, desc)
+			}
+			if len(idxs) > 0 {
+				fmt.Fprintf(&b, "\n**Indexes:** %s\n", strings.Join(idxs, "; "))
+			}
+		}
+		if t.ViewDefinition != "" {
+			fmt.Fprintf(&b, "\n```sql\n%s\n```\n", strings.TrimSpace(t.ViewDefinition))
+		}
+	}
+
+	if snap.OmittedTables > 0 {
+		fmt.Fprintf(&b, "\n_%d more tables omitted to fit the size limit._\n", snap.OmittedTables)
+	}
+
+	return b.String()
+}
+
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.ReplaceAll(s, "\n", " ")
+}
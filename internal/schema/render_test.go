@@ -0,0 +1,217 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+)
+
+func testSnapshot(dbType string) *Snapshot {
+	return &Snapshot{
+		Version: SnapshotVersion,
+		DBType:  dbType,
+		Tables: []database.TableDetail{
+			{
+				TableInfo: database.TableInfo{TableName: "orders", TableSchema: "shop", TableType: "BASE TABLE"},
+				Comment:   "Customer orders",
+				Columns: []database.ColumnInfo{
+					{ColumnName: "id", DataType: "integer", ColumnType: "integer", IsNullable: "NO"},
+					{ColumnName: "user_id", DataType: "integer", ColumnType: "integer", IsNullable: "NO"},
+					{ColumnName: "note", DataType: "text", ColumnType: "text", IsNullable: "YES", Comment: "free-form note"},
+				},
+				PrimaryKey: []string{"id"},
+				ForeignKeys: []database.ForeignKeyInfo{
+					{
+						ConstraintName:    "orders_user_fk",
+						Columns:           []string{"user_id"},
+						ReferencedSchema:  "shop",
+						ReferencedTable:   "users",
+						ReferencedColumns: []string{"id"},
+						OnDelete:          "CASCADE",
+					},
+				},
+				Indexes: []database.IndexInfo{
+					{IndexName: "orders_pkey", Columns: []string{"id"}, IsUnique: true, IsPrimary: true},
+					{IndexName: "orders_user_idx", Columns: []string{"user_id"}},
+				},
+			},
+			{
+				TableInfo: database.TableInfo{TableName: "users", TableSchema: "shop", TableType: "BASE TABLE"},
+				Columns: []database.ColumnInfo{
+					{ColumnName: "id", DataType: "integer", ColumnType: "integer", IsNullable: "NO"},
+					{ColumnName: "email", DataType: "character varying", ColumnType: "character varying(255)", IsNullable: "NO"},
+				},
+				PrimaryKey: []string{"id"},
+				Indexes: []database.IndexInfo{
+					{IndexName: "users_email_key", Columns: []string{"email"}, IsUnique: true},
+				},
+			},
+		},
+	}
+}
+
+func TestRender_DDLPostgres(t *testing.T) {
+	out, err := Render(testSnapshot("postgres"), FormatDDL)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	expected := []string{
+		`CREATE TABLE "shop"."orders" (`,
+		`  "id" integer NOT NULL,`,
+		`  PRIMARY KEY ("id"),`,
+		`CONSTRAINT "orders_user_fk" FOREIGN KEY ("user_id") REFERENCES "shop"."users" ("id") ON DELETE CASCADE`,
+		`CREATE INDEX "orders_user_idx" ON "shop"."orders" (user_id);`,
+		`CREATE UNIQUE INDEX "users_email_key" ON "shop"."users" (email);`,
+		`COMMENT ON TABLE "shop"."orders" IS 'Customer orders';`,
+		`COMMENT ON COLUMN "shop"."orders"."note" IS 'free-form note';`,
+	}
+	for _, want := range expected {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected DDL to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "orders_pkey") {
+		t.Errorf("Primary key index should not be emitted as CREATE INDEX:\n%s", out)
+	}
+}
+
+func TestRender_DDLMySQL(t *testing.T) {
+	out, err := Render(testSnapshot("mysql"), FormatDDL)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	expected := []string{
+		"CREATE TABLE `shop`.`orders` (",
+		"  `note` text COMMENT 'free-form note'",
+		") COMMENT='Customer orders';",
+		"CREATE INDEX `orders_user_idx` ON `shop`.`orders` (`user_id`);",
+	}
+	for _, want := range expected {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected DDL to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "COMMENT ON") {
+		t.Errorf("MySQL DDL should use inline comments:\n%s", out)
+	}
+}
+
+func TestRender_JSONIsStable(t *testing.T) {
+	first, err := Render(testSnapshot("postgres"), FormatJSON)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+	second, _ := Render(testSnapshot("postgres"), FormatJSON)
+	if first != second {
+		t.Error("Expected identical JSON for identical snapshots")
+	}
+
+	var decoded Snapshot
+	if err := json.Unmarshal([]byte(first), &decoded); err != nil {
+		t.Fatalf("Failed to decode JSON: %v", err)
+	}
+	if len(decoded.Tables) != 2 || decoded.Tables[0].TableName != "orders" {
+		t.Errorf("Unexpected decoded snapshot: %+v", decoded.Tables)
+	}
+}
+
+func TestRender_Markdown(t *testing.T) {
+	out, err := Render(testSnapshot("postgres"), FormatMarkdown)
+	if err != nil {
+		t.Fatalf("Render failed: %v", err)
+	}
+
+	expected := []string{
+		"## shop.orders",
+		"| note | text | YES |  | free-form note |",
+		"**Primary key:** id",
+		"- (user_id) → shop.users(id)",
+		"**Indexes:** users_email_key (email) unique",
+	}
+	for _, want := range expected {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected Markdown to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderWithBudget(t *testing.T) {
+	snap := testSnapshot("postgres")
+	for i := 0; i < 50; i++ {
+		snap.Tables = append(snap.Tables, database.TableDetail{
+			TableInfo: database.TableInfo{TableName: fmt.Sprintf("extra_%02d", i), TableSchema: "shop"},
+			Columns:   []database.ColumnInfo{{ColumnName: "id", DataType: "integer", Comment: strings.Repeat("x", 100)}},
+		})
+	}
+
+	full, _ := Render(snap, FormatMarkdown)
+	fullTokens := EstimateTokens(full)
+
+	tests := []struct {
+		name      string
+		maxTokens int
+		level     DetailLevel
+	}{
+		{"no budget", 0, DetailFull},
+		{"generous budget", fullTokens, DetailFull},
+		{"drops comments", fullTokens - 1, DetailCompact},
+		{"names only", 250, DetailNames},
+		{"truncated", 40, DetailNames},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, level, err := RenderWithBudget(snap, FormatMarkdown, tt.maxTokens)
+			if err != nil {
+				t.Fatalf("RenderWithBudget failed: %v", err)
+			}
+			if level != tt.level {
+				t.Errorf("Expected level %s, got %s", tt.level, level)
+			}
+			if tt.maxTokens > 0 && EstimateTokens(out) > tt.maxTokens {
+				t.Errorf("Output of %d tokens exceeds budget %d", EstimateTokens(out), tt.maxTokens)
+			}
+		})
+	}
+
+	out, _, _ := RenderWithBudget(snap, FormatMarkdown, 40)
+	if !strings.Contains(out, "more tables omitted") {
+		t.Errorf("Expected truncation note, got:\n%s", out)
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	users := database.TableInfo{TableName: "users", TableSchema: "public"}
+	payments := database.TableInfo{TableName: "payments", TableSchema: "billing"}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		table  database.TableInfo
+		want   bool
+	}{
+		{"empty filter", Filter{}, users, true},
+		{"schema match", Filter{SchemaPatterns: []string{"pub*"}}, users, true},
+		{"schema mismatch", Filter{SchemaPatterns: []string{"pub*"}}, payments, false},
+		{"table glob", Filter{TablePatterns: []string{"us?rs"}}, users, true},
+		{"qualified table", Filter{TablePatterns: []string{"billing.*"}}, payments, true},
+		{"qualified mismatch", Filter{TablePatterns: []string{"billing.*"}}, users, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.table); got != tt.want {
+				t.Errorf("Match() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := (Filter{TablePatterns: []string{"["}}).Validate(); err == nil {
+		t.Error("Expected invalid pattern to fail validation")
+	}
+}
@@ -0,0 +1,211 @@
+package schema
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hieubanhh/dbhubMCP/internal/database"
+)
+
+// SnapshotVersion is bumped whenever the JSON layout of Snapshot changes
+const SnapshotVersion = 1
+
+// Snapshot is a point-in-time description of every table selected by a Filter.
+// Tables are sorted by schema and name so that repeated exports are stable.
+type Snapshot struct {
+	Version       int                    `json:"version"`
+	DBType        string                 `json:"db_type"`
+	Tables        []database.TableDetail `json:"tables"`
+	OmittedTables int                    `json:"omitted_tables,omitempty"`
+}
+
+// Filter selects tables by glob patterns (path.Match syntax). Empty pattern
+// lists match everything. Table patterns containing a dot are matched against
+// the qualified schema.table name.
+type Filter struct {
+	SchemaPatterns []string
+	TablePatterns  []string
+}
+
+// ParsePatterns splits a comma-separated pattern list, dropping empty entries
+func ParsePatterns(value string) []string {
+	var patterns []string
+	for _, p := range strings.Split(value, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// Validate checks that every pattern is well formed
+func (f Filter) Validate() error {
+	for _, p := range append(append([]string{}, f.SchemaPatterns...), f.TablePatterns...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+// Match reports whether a table passes the filter
+func (f Filter) Match(table database.TableInfo) bool {
+	if len(f.SchemaPatterns) > 0 && !matchAny(f.SchemaPatterns, table.TableSchema) {
+		return false
+	}
+	if len(f.TablePatterns) == 0 {
+		return true
+	}
+	qualified := table.TableSchema + "." + table.TableName
+	for _, p := range f.TablePatterns {
+		name := table.TableName
+		if strings.Contains(p, ".") {
+			name = qualified
+		}
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// BuildSnapshot lists the tables in the database and describes each one that
+// matches the filter
+func BuildSnapshot(ctx context.Context, adapter database.Adapter, filter Filter) (*Snapshot, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+
+	tables, err := adapter.ListTables(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	snap := &Snapshot{
+		Version: SnapshotVersion,
+		DBType:  adapter.GetDBType(),
+		Tables:  make([]database.TableDetail, 0, len(tables)),
+	}
+
+	for _, table := range tables {
+		if !filter.Match(table) {
+			continue
+		}
+
+		name := table.TableName
+		if table.TableSchema != "" {
+			name = table.TableSchema + "." + table.TableName
+		}
+
+		detail, err := adapter.DescribeTableDetailed(ctx, name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe %s: %w", name, err)
+		}
+		snap.Tables = append(snap.Tables, *detail)
+	}
+
+	sort.SliceStable(snap.Tables, func(i, j int) bool {
+		if snap.Tables[i].TableSchema != snap.Tables[j].TableSchema {
+			return snap.Tables[i].TableSchema < snap.Tables[j].TableSchema
+		}
+		return snap.Tables[i].TableName < snap.Tables[j].TableName
+	})
+
+	return snap, nil
+}
+
+// DetailLevel controls how much of a snapshot is rendered
+type DetailLevel int
+
+const (
+	// DetailFull renders every column attribute, key, index and view definition
+	DetailFull DetailLevel = iota
+	// DetailCompact drops comments, defaults, view bodies and non-unique indexes
+	DetailCompact
+	// DetailColumns keeps only column names, types, primary and foreign keys
+	DetailColumns
+	// DetailNames keeps only the table names
+	DetailNames
+)
+
+// String returns the name used for the level in tool output
+func (l DetailLevel) String() string {
+	switch l {
+	case DetailFull:
+		return "full"
+	case DetailCompact:
+		return "compact"
+	case DetailColumns:
+		return "columns"
+	case DetailNames:
+		return "names"
+	default:
+		return fmt.Sprintf("DetailLevel(%d)", int(l))
+	}
+}
+
+// Reduce returns a copy of the snapshot with details below the level removed
+func (s *Snapshot) Reduce(level DetailLevel) *Snapshot {
+	out := *s
+	out.Tables = make([]database.TableDetail, len(s.Tables))
+
+	for i, t := range s.Tables {
+		if level >= DetailCompact {
+			t.Comment = ""
+			t.ViewDefinition = ""
+
+			var indexes []database.IndexInfo
+			for _, idx := range t.Indexes {
+				if idx.IsUnique && !idx.IsPrimary && level < DetailColumns {
+					indexes = append(indexes, idx)
+				}
+			}
+			t.Indexes = indexes
+
+			columns := make([]database.ColumnInfo, len(t.Columns))
+			for j, c := range t.Columns {
+				c.Comment = ""
+				c.ColumnDefault = ""
+				c.Extra = ""
+				if level >= DetailColumns {
+					c.ColumnKey = ""
+					c.IsNullable = ""
+				}
+				columns[j] = c
+			}
+			t.Columns = columns
+		}
+		if level >= DetailNames {
+			t.Columns = nil
+			t.PrimaryKey = nil
+			t.ForeignKeys = nil
+			t.Indexes = nil
+		}
+		out.Tables[i] = t
+	}
+
+	return &out
+}
+
+// Truncate returns a copy of the snapshot keeping only the first n tables
+func (s *Snapshot) Truncate(n int) *Snapshot {
+	if n >= len(s.Tables) {
+		return s
+	}
+	out := *s
+	out.Tables = s.Tables[:n]
+	out.OmittedTables = s.OmittedTables + len(s.Tables) - n
+	return &out
+}
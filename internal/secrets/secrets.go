@@ -0,0 +1,95 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// SecretProvider supplies a secret such as a database password. Providers are
+// asked again on every credential refresh, so rotated secrets are picked up.
+type SecretProvider interface {
+	// Secret returns the current value of the secret
+	Secret(ctx context.Context) (string, error)
+
+	// String describes the source of the secret, without its value
+	String() string
+}
+
+// FileProvider reads a secret from a file, such as a Docker or Kubernetes
+// secret mount. Trailing newlines are removed.
+type FileProvider struct {
+	Path string
+}
+
+// Secret reads the file
+func (p FileProvider) Secret(ctx context.Context) (string, error) {
+	data, err := os.ReadFile(p.Path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	secret := strings.TrimRight(string(data), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", p.Path)
+	}
+	return secret, nil
+}
+
+func (p FileProvider) String() string {
+	return "file " + p.Path
+}
+
+// defaultCommandTimeout bounds how long a secret command may run
+const defaultCommandTimeout = 30 * time.Second
+
+// CommandProvider runs a command, such as `pass show db/reporting` or
+// `vault kv get -field=password secret/db`, and uses its output as the
+// secret. The command is run directly, not through a shell.
+type CommandProvider struct {
+	Args    []string
+	Timeout time.Duration // 0 uses 30s
+}
+
+// NewCommandProvider splits a command line on whitespace
+func NewCommandProvider(command string) (*CommandProvider, error) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("secret command is empty")
+	}
+	return &CommandProvider{Args: args}, nil
+}
+
+// Secret runs the command and returns its standard output
+func (p *CommandProvider) Secret(ctx context.Context) (string, error) {
+	timeout := p.Timeout
+	if timeout <= 0 {
+		timeout = defaultCommandTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Args[0], p.Args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("secret command %s failed: %w: %s", p.Args[0], err, msg)
+		}
+		return "", fmt.Errorf("secret command %s failed: %w", p.Args[0], err)
+	}
+
+	secret := strings.TrimRight(stdout.String(), "\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("secret command %s printed nothing", p.Args[0])
+	}
+	return secret, nil
+}
+
+func (p *CommandProvider) String() string {
+	return "command " + p.Args[0]
+}
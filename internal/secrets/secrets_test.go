@@ -0,0 +1,63 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db_password")
+	if err := os.WriteFile(path, []byte("s3cret with spaces \n"), 0o600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+
+	secret, err := FileProvider{Path: path}.Secret(context.Background())
+	if err != nil {
+		t.Fatalf("Secret failed: %v", err)
+	}
+	if secret != "s3cret with spaces " {
+		t.Errorf("Expected trailing newline to be trimmed only, got %q", secret)
+	}
+
+	os.WriteFile(path, []byte("\n"), 0o600)
+	if _, err := (FileProvider{Path: path}).Secret(context.Background()); err == nil {
+		t.Error("Expected error for an empty secret")
+	}
+	if _, err := (FileProvider{Path: path + ".missing"}).Secret(context.Background()); err == nil {
+		t.Error("Expected error for a missing file")
+	}
+}
+
+func TestCommandProvider(t *testing.T) {
+	p, err := NewCommandProvider("echo rotated-password")
+	if err != nil {
+		t.Fatalf("NewCommandProvider failed: %v", err)
+	}
+	secret, err := p.Secret(context.Background())
+	if err != nil {
+		t.Fatalf("Secret failed: %v", err)
+	}
+	if secret != "rotated-password" {
+		t.Errorf("Expected command output, got %q", secret)
+	}
+	if p.String() != "command echo" {
+		t.Errorf("Expected the description to omit arguments, got %s", p)
+	}
+
+	p, _ = NewCommandProvider("false")
+	if _, err := p.Secret(context.Background()); err == nil {
+		t.Error("Expected error for a failing command")
+	}
+
+	p, _ = NewCommandProvider("true")
+	if _, err := p.Secret(context.Background()); err == nil || !strings.Contains(err.Error(), "printed nothing") {
+		t.Errorf("Expected error for empty output, got %v", err)
+	}
+
+	if _, err := NewCommandProvider("  "); err == nil {
+		t.Error("Expected error for an empty command")
+	}
+}
@@ -0,0 +1,155 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+)
+
+// functionGroup is a set of function name patterns denied for one reason
+type functionGroup struct {
+	reason   string
+	patterns string
+}
+
+// deniedFunctions are the functions each dialect denies unless the policy
+// allows them: they read server files, stall connections, reach other
+// servers, change server state or run SQL that the policy cannot see
+var deniedFunctions = map[string][]functionGroup{
+	"postgres": {
+		{"reads server files", `pg_read_file pg_read_binary_file pg_ls_* pg_stat_file pg_logdir_ls
+			lo_import lo_export lo_get lo_open lo_from_bytea loread pg_file_*`},
+		{"sleeps", `pg_sleep pg_sleep_for pg_sleep_until`},
+		{"connects to other servers", `dblink* postgres_fdw_* pg_replication_origin_*`},
+		{"changes server state", `set_config pg_terminate_backend pg_cancel_backend pg_reload_conf
+			pg_rotate_logfile pg_promote pg_switch_wal pg_create_restore_point pg_start_backup pg_stop_backup
+			pg_backup_start pg_backup_stop pg_create_*_replication_slot pg_drop_replication_slot
+			pg_copy_*_replication_slot pg_logical_emit_message pg_notify nextval setval lo_create lo_creat
+			lo_unlink lo_put lo_truncate lowrite pg_wal_replay_pause pg_wal_replay_resume`},
+		{"takes locks", `pg_advisory_lock* pg_advisory_xact_lock* pg_try_advisory_lock* pg_try_advisory_xact_lock*`},
+		{"runs SQL the policy cannot check", `query_to_xml* cursor_to_xml* table_to_xml* schema_to_xml*
+			database_to_xml*`},
+	},
+	"mysql": {
+		{"reads server files", `load_file`},
+		{"sleeps", `sleep benchmark master_pos_wait source_pos_wait wait_for_executed_gtid_set
+			wait_until_sql_thread_after_gtids`},
+		{"takes locks", `get_lock release_lock release_all_locks`},
+		{"runs server commands", `sys_exec sys_eval`},
+	},
+}
+
+// deniedClauses names the clauses denied unless the policy allows them
+var deniedClauses = map[string]string{
+	"into":    "writes results to a file, variable or table",
+	"locking": "locks rows",
+}
+
+// deniedFunction is a compiled function pattern and why it is denied
+type deniedFunction struct {
+	pattern pattern
+	reason  string
+}
+
+// builtinFunctions holds the compiled deniedFunctions by dialect
+var builtinFunctions = compileDeniedFunctions()
+
+func compileDeniedFunctions() map[string][]deniedFunction {
+	compiled := make(map[string][]deniedFunction)
+	for dbType, groups := range deniedFunctions {
+		for _, g := range groups {
+			patterns, err := compilePatterns(strings.Fields(g.patterns), 1)
+			if err != nil {
+				panic(fmt.Sprintf("invalid built-in function pattern: %v", err))
+			}
+			for _, p := range patterns {
+				compiled[dbType] = append(compiled[dbType], deniedFunction{pattern: p, reason: g.reason})
+			}
+		}
+	}
+	return compiled
+}
+
+// compileClauses checks clause names
+func compileClauses(names []string) (map[string]bool, error) {
+	clauses := make(map[string]bool)
+	for _, name := range names {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, ok := deniedClauses[name]; !ok {
+			return nil, fmt.Errorf("unknown clause %q, use into or locking", name)
+		}
+		clauses[name] = true
+	}
+	return clauses, nil
+}
+
+// checkFunctions returns an *AccessError for the first denied function
+// call or clause in the tokens of a query
+func (p *Policy) checkFunctions(dbType string, toks []Token) error {
+	for i, t := range toks {
+		switch {
+		case t.isName() && i+1 < len(toks) && toks[i+1].IsSymbol("("):
+			schema := ""
+			if i >= 2 && toks[i-1].IsSymbol(".") && toks[i-2].isName() {
+				schema = toks[i-2].Value
+			}
+			if reason, denied := p.functionDenied(dbType, schema, t.Value); denied {
+				return &AccessError{Object: "function " + qualify(schema, t.Value), Reason: reason}
+			}
+		case t.Is("INTO") && !p.allowClauses["into"]:
+			return &AccessError{Object: "clause " + clauseText(toks, i, 2), Reason: deniedClauses["into"]}
+		case t.Is("FOR") && !p.allowClauses["locking"] && lockingStrength(toks, i+1) > 0:
+			return &AccessError{Object: "clause " + clauseText(toks, i, 1+lockingStrength(toks, i+1)), Reason: deniedClauses["locking"]}
+		case t.Is("LOCK") && !p.allowClauses["locking"] && i+3 < len(toks) && toks[i+1].Is("IN") && toks[i+2].Is("SHARE") && toks[i+3].Is("MODE"):
+			return &AccessError{Object: "clause LOCK IN SHARE MODE", Reason: deniedClauses["locking"]}
+		}
+	}
+	return nil
+}
+
+// functionDenied reports whether a function is denied by the policy or by
+// the dialect's built-in list, and why
+func (p *Policy) functionDenied(dbType, schema, name string) (string, bool) {
+	if matchAny(p.deny.functions, schema, name) {
+		return "denied by configuration", true
+	}
+	if matchAny(p.allow.functions, schema, name) {
+		return "", false
+	}
+	for _, f := range builtinFunctions[dbType] {
+		if f.pattern.matches(name) {
+			return f.reason, true
+		}
+	}
+	return "", false
+}
+
+// lockingStrength returns the number of words of the row locking strength
+// starting at i: UPDATE, SHARE, NO KEY UPDATE or KEY SHARE; 0 if none
+func lockingStrength(toks []Token, i int) int {
+	word := func(k int, kw string) bool { return k < len(toks) && toks[k].Is(kw) }
+	switch {
+	case word(i, "UPDATE"), word(i, "SHARE"):
+		return 1
+	case word(i, "NO") && word(i+1, "KEY") && word(i+2, "UPDATE"):
+		return 3
+	case word(i, "KEY") && word(i+1, "SHARE"):
+		return 2
+	}
+	return 0
+}
+
+// clauseWords are written in upper case in error messages
+var clauseWords = wordSet(`INTO OUTFILE DUMPFILE FOR UPDATE SHARE NO KEY`)
+
+// clauseText joins n tokens starting at i for error messages
+func clauseText(toks []Token, i, n int) string {
+	var words []string
+	for k := i; k < i+n && k < len(toks); k++ {
+		if isWord(toks[k], clauseWords) {
+			words = append(words, strings.ToUpper(toks[k].Value))
+		} else {
+			words = append(words, toks[k].Value)
+		}
+	}
+	return strings.Join(words, " ")
+}
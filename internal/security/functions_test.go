@@ -0,0 +1,121 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCheckQuery_Functions(t *testing.T) {
+	policy, err := NewPolicy(PolicyRules{}, PolicyRules{})
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+
+	tests := []struct {
+		dbType string
+		query  string
+		want   string // denied object, empty if allowed
+	}{
+		// PostgreSQL
+		{"postgres", "SELECT pg_read_file('/etc/passwd')", "function pg_read_file"},
+		{"postgres", "SELECT pg_catalog.pg_sleep(3600)", "function pg_catalog.pg_sleep"},
+		{"postgres", `SELECT "pg_sleep"(1)`, "function pg_sleep"},
+		{"postgres", "SELECT * FROM dblink('host=evil', 'SELECT 1') AS t(x int)", "function dblink"},
+		{"postgres", "SELECT set_config('statement_timeout', '0', false)", "function set_config"},
+		{"postgres", "SELECT pg_ls_dir('.')", "function pg_ls_dir"},
+		{"postgres", "SELECT query_to_xml('SELECT * FROM payments', true, false, '')", "function query_to_xml"},
+		{"postgres", "SELECT pg_advisory_lock(1)", "function pg_advisory_lock"},
+		{"postgres", "SELECT id FROM users FOR UPDATE", "clause FOR UPDATE"},
+		{"postgres", "SELECT id FROM users FOR NO KEY UPDATE", "clause FOR NO KEY UPDATE"},
+		{"postgres", "SELECT id FROM users FOR KEY SHARE", "clause FOR KEY SHARE"},
+		{"postgres", "SELECT id INTO copy_of_users FROM users", "clause INTO copy_of_users"},
+		{"postgres", "SELECT 'pg_sleep(10)', lower(name), substring(name FROM 1 FOR 2) FROM users", ""},
+		{"postgres", "SELECT $$ FOR UPDATE $$", ""},
+		// MySQL
+		{"mysql", "SELECT LOAD_FILE('/etc/passwd')", "function LOAD_FILE"},
+		{"mysql", "SELECT SLEEP(3600)", "function SLEEP"},
+		{"mysql", "SELECT BENCHMARK(1000000000, MD5('x'))", "function BENCHMARK"},
+		{"mysql", "SELECT /*!50000 sleep(10) */ 1", "function sleep"},
+		{"mysql", "SELECT GET_LOCK('a', 10)", "function GET_LOCK"},
+		{"mysql", "SELECT * FROM users INTO OUTFILE '/tmp/users.csv'", "clause INTO OUTFILE"},
+		{"mysql", "SELECT id INTO @id FROM users LIMIT 1", "clause INTO @id"},
+		{"mysql", "SELECT id FROM users FOR SHARE", "clause FOR SHARE"},
+		{"mysql", "SELECT id FROM users LOCK IN SHARE MODE", "clause LOCK IN SHARE MODE"},
+		{"mysql", "SELECT `sleep` FROM timings", ""},
+		{"mysql", "SELECT pg_sleep(1)", ""},
+		{"postgres", "SELECT sleep(1)", ""},
+	}
+	for _, tt := range tests {
+		err := policy.CheckQuery(context.Background(), tt.dbType, tt.query, testCatalog)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s %q: expected success, got %v", tt.dbType, tt.query, err)
+			}
+			continue
+		}
+		var accessErr *AccessError
+		if !errors.As(err, &accessErr) || accessErr.Object != tt.want {
+			t.Errorf("%s %q: expected %s to be denied, got %v", tt.dbType, tt.query, tt.want, err)
+		}
+	}
+}
+
+func TestCheckQuery_ConfiguredFunctions(t *testing.T) {
+	policy, err := NewPolicy(
+		PolicyRules{Functions: []string{"pg_sleep"}, Clauses: []string{"locking"}},
+		PolicyRules{Functions: []string{"export_*", "audit.read_log"}},
+	)
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT pg_sleep(1)", ""},
+		{"SELECT id FROM users FOR UPDATE", ""},
+		{"SELECT pg_read_file('x')", "reads server files"},
+		{"SELECT export_users()", "denied by configuration"},
+		{"SELECT audit.read_log()", "denied by configuration"},
+		{"SELECT read_log()", ""},
+		{"SELECT id INTO t FROM users", "writes results"},
+	}
+	for _, tt := range tests {
+		err := policy.CheckQuery(context.Background(), "postgres", tt.query, testCatalog)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%q: expected success, got %v", tt.query, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: expected error containing %q, got %v", tt.query, tt.want, err)
+		}
+	}
+
+	if _, err := NewPolicy(PolicyRules{Clauses: []string{"window"}}, PolicyRules{}); err == nil {
+		t.Error("Expected an unknown clause to be rejected")
+	}
+	if _, err := NewPolicy(PolicyRules{}, PolicyRules{Clauses: []string{"into"}}); err == nil {
+		t.Error("Expected deny clauses to be rejected")
+	}
+}
+
+func TestNewObjectPolicy(t *testing.T) {
+	policy, err := NewObjectPolicy(PolicyRules{}, PolicyRules{Tables: []string{"payments"}})
+	if err != nil {
+		t.Fatalf("NewObjectPolicy failed: %v", err)
+	}
+	if err := policy.CheckQuery(context.Background(), "postgres", "SELECT pg_sleep(1)", testCatalog); err != nil {
+		t.Errorf("Expected functions to be left to the connection policy, got %v", err)
+	}
+	if err := policy.CheckQuery(context.Background(), "postgres", "SELECT * FROM payments", testCatalog); err == nil {
+		t.Error("Expected payments to be denied")
+	}
+	if _, err := NewObjectPolicy(PolicyRules{Clauses: []string{"into"}}, PolicyRules{}); err == nil {
+		t.Error("Expected clauses to be rejected")
+	}
+}
@@ -0,0 +1,149 @@
+package security
+
+import "strconv"
+
+// limitAlias names the derived table of queries wrapped by LimitQuery
+const limitAlias = "dbhub_limited"
+
+// LimitQuery rewrites a top-level SELECT (including WITH queries and
+// unions) so the database returns at most limit rows. An existing LIMIT or
+// FETCH FIRST is tightened when it allows more rows, keeping OFFSET; a
+// query without one gets a LIMIT before any trailing locking clause or
+// semicolon. PostgreSQL queries whose limit is not a plain number are
+// wrapped in a subquery. Other statements, and queries that cannot be
+// tokenized, are returned unchanged.
+func LimitQuery(dbType, query string, limit int) string {
+	if limit <= 0 {
+		return query
+	}
+	toks, err := Tokenize(dbType, query)
+	if err != nil || len(toks) == 0 {
+		return query
+	}
+	if !toks[0].Is("SELECT") && !toks[0].Is("WITH") && !toks[0].IsSymbol("(") {
+		return query
+	}
+
+	// Find the clauses of the outermost query expression. A LIMIT of a
+	// union branch must be parenthesized, so only clauses after the last
+	// set operator count.
+	end := len(toks)
+	limitAt, fetchAt, offsetAt, lockAt := -1, -1, -1, -1
+	hasSelect := toks[0].Is("SELECT")
+	depth := 0
+	for i, tok := range toks {
+		switch {
+		case tok.IsSymbol("("):
+			depth++
+		case tok.IsSymbol(")"):
+			depth--
+		case depth != 0:
+		case tok.IsSymbol(";"):
+			if i != len(toks)-1 {
+				return query // several statements
+			}
+			end = i
+		case tok.Is("SELECT"):
+			hasSelect = true
+		case tok.Is("UNION") || tok.Is("INTERSECT") || tok.Is("EXCEPT"):
+			limitAt, fetchAt, offsetAt, lockAt = -1, -1, -1, -1
+		case tok.Is("LIMIT"):
+			limitAt = i
+		case tok.Is("FETCH"):
+			fetchAt = i
+		case tok.Is("OFFSET"):
+			offsetAt = i
+		case (tok.Is("FOR") || tok.Is("LOCK")) && lockAt < 0:
+			lockAt = i
+		}
+	}
+	if depth != 0 || (toks[0].Is("WITH") && !hasSelect) {
+		return query
+	}
+	limitText := strconv.Itoa(limit)
+
+	switch {
+	case limitAt >= 0:
+		// LIMIT count, LIMIT offset, count (MySQL), LIMIT ALL or LIMIT NULL
+		count := limitAt + 1
+		if dbType == "mysql" && count+2 < end && toks[count+1].IsSymbol(",") {
+			count += 2
+		}
+		if next := count + 1; count < end && (next == end || next == offsetAt || next == lockAt) {
+			tok := toks[count]
+			if n, ok := limitCount(tok); ok {
+				if n <= limit {
+					return query
+				}
+				return replaceToken(query, tok, limitText)
+			}
+			if tok.Is("ALL") || tok.Is("NULL") {
+				return replaceToken(query, tok, limitText)
+			}
+		}
+	case fetchAt >= 0:
+		// FETCH { FIRST | NEXT } [ count ] { ROW | ROWS } ONLY
+		i := fetchAt + 1
+		if i < end && (toks[i].Is("FIRST") || toks[i].Is("NEXT")) {
+			i++
+		}
+		if i+1 < end && isRows(toks[i]) && toks[i+1].Is("ONLY") {
+			return query // one row
+		}
+		if i+2 >= end || !isRows(toks[i+1]) || !toks[i+2].Is("ONLY") {
+			break
+		}
+		if n, ok := limitCount(toks[i]); ok {
+			if n <= limit {
+				return query
+			}
+			return replaceToken(query, toks[i], limitText)
+		}
+	default:
+		insertAt := len(query)
+		if lockAt >= 0 {
+			insertAt = toks[lockAt].Pos
+		} else if end < len(toks) {
+			insertAt = toks[end].Pos
+		}
+		if insertAt == len(query) {
+			// A newline ends a trailing -- comment
+			return query + "\nLIMIT " + limitText
+		}
+		clause := "LIMIT " + limitText + " "
+		if c := query[insertAt-1]; c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+			clause = " " + clause
+		}
+		return query[:insertAt] + clause + query[insertAt:]
+	}
+
+	// MySQL only accepts plain numbers and placeholders as limits, so only
+	// PostgreSQL limits can be expressions worth wrapping
+	if dbType != "postgres" {
+		return query
+	}
+	body := query
+	if end < len(toks) {
+		body = query[:toks[end].Pos]
+	}
+	return "SELECT * FROM (\n" + body + "\n) AS " + limitAlias + " LIMIT " + limitText
+}
+
+// limitCount parses a non-negative integer literal
+func limitCount(tok Token) (int, bool) {
+	if tok.Kind != TokenNumber {
+		return 0, false
+	}
+	n, err := strconv.Atoi(tok.Value)
+	return n, err == nil && n >= 0
+}
+
+// isRows reports whether the token is ROW or ROWS
+func isRows(tok Token) bool {
+	return tok.Is("ROW") || tok.Is("ROWS")
+}
+
+// replaceToken replaces the source text of a number or unquoted word
+func replaceToken(query string, tok Token, text string) string {
+	return query[:tok.Pos] + text + query[tok.Pos+len(tok.Value):]
+}
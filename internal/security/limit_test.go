@@ -0,0 +1,54 @@
+package security
+
+import "testing"
+
+func TestLimitQuery(t *testing.T) {
+	tests := []struct {
+		dbType string
+		query  string
+		want   string
+	}{
+		// Appended
+		{"postgres", "SELECT * FROM events", "SELECT * FROM events\nLIMIT 101"},
+		{"mysql", "SELECT * FROM events -- all of them", "SELECT * FROM events -- all of them\nLIMIT 101"},
+		{"postgres", "SELECT * FROM events;", "SELECT * FROM events LIMIT 101 ;"},
+		{"postgres", "SELECT * FROM events ORDER BY id OFFSET 20", "SELECT * FROM events ORDER BY id OFFSET 20\nLIMIT 101"},
+		{"mysql", "SELECT * FROM events FOR UPDATE", "SELECT * FROM events LIMIT 101 FOR UPDATE"},
+		{"mysql", "SELECT id FROM a UNION SELECT id FROM b", "SELECT id FROM a UNION SELECT id FROM b\nLIMIT 101"},
+		{"mysql", "(SELECT id FROM a LIMIT 5) UNION (SELECT id FROM b LIMIT 5)", "(SELECT id FROM a LIMIT 5) UNION (SELECT id FROM b LIMIT 5)\nLIMIT 101"},
+		{"postgres", "SELECT id FROM a LIMIT 5 UNION ALL SELECT id FROM b", "SELECT id FROM a LIMIT 5 UNION ALL SELECT id FROM b\nLIMIT 101"},
+		{"postgres", "WITH recent AS (SELECT * FROM events LIMIT 500) SELECT * FROM recent", "WITH recent AS (SELECT * FROM events LIMIT 500) SELECT * FROM recent\nLIMIT 101"},
+		{"postgres", "SELECT substring(name FROM 1 FOR 3) FROM users", "SELECT substring(name FROM 1 FOR 3) FROM users\nLIMIT 101"},
+
+		// Tightened or kept
+		{"postgres", "SELECT * FROM events LIMIT 10", "SELECT * FROM events LIMIT 10"},
+		{"postgres", "SELECT * FROM events LIMIT 5000 OFFSET 10", "SELECT * FROM events LIMIT 101 OFFSET 10"},
+		{"postgres", "SELECT * FROM events OFFSET 10 LIMIT 5000;", "SELECT * FROM events OFFSET 10 LIMIT 101;"},
+		{"postgres", "SELECT * FROM events LIMIT ALL", "SELECT * FROM events LIMIT 101"},
+		{"mysql", "SELECT * FROM events LIMIT 20, 5000", "SELECT * FROM events LIMIT 20, 101"},
+		{"mysql", "SELECT * FROM events LIMIT 5000 FOR UPDATE", "SELECT * FROM events LIMIT 101 FOR UPDATE"},
+		{"postgres", "WITH x AS (SELECT 1) SELECT * FROM x LIMIT 9999", "WITH x AS (SELECT 1) SELECT * FROM x LIMIT 101"},
+		{"postgres", "SELECT * FROM events FETCH FIRST 5000 ROWS ONLY", "SELECT * FROM events FETCH FIRST 101 ROWS ONLY"},
+		{"postgres", "SELECT * FROM events OFFSET 5 ROWS FETCH NEXT 50 ROWS ONLY", "SELECT * FROM events OFFSET 5 ROWS FETCH NEXT 50 ROWS ONLY"},
+		{"postgres", "SELECT * FROM events FETCH FIRST ROW ONLY", "SELECT * FROM events FETCH FIRST ROW ONLY"},
+
+		// Wrapped
+		{"postgres", "SELECT * FROM events LIMIT (SELECT n FROM settings);", "SELECT * FROM (\nSELECT * FROM events LIMIT (SELECT n FROM settings)\n) AS dbhub_limited LIMIT 101"},
+		{"postgres", "SELECT * FROM events ORDER BY ts FETCH FIRST 5000 ROWS WITH TIES", "SELECT * FROM (\nSELECT * FROM events ORDER BY ts FETCH FIRST 5000 ROWS WITH TIES\n) AS dbhub_limited LIMIT 101"},
+
+		// Unchanged
+		{"mysql", "SELECT * FROM events LIMIT ?", "SELECT * FROM events LIMIT ?"},
+		{"mysql", "SHOW TABLES", "SHOW TABLES"},
+		{"postgres", "EXPLAIN SELECT * FROM events", "EXPLAIN SELECT * FROM events"},
+		{"postgres", "SELECT 1; SELECT 2", "SELECT 1; SELECT 2"},
+		{"postgres", "SELECT 'unterminated", "SELECT 'unterminated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := LimitQuery(tt.dbType, tt.query, 101); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaskStrategy is how a masked value is replaced
+type MaskStrategy string
+
+const (
+	MaskRedact  MaskStrategy = "redact"  // replaced by ****
+	MaskPartial MaskStrategy = "partial" // keeps a hint: j***@x.com, ****1234
+	MaskHash    MaskStrategy = "hash"    // keyed HMAC, stable so equal values still join
+	MaskNull    MaskStrategy = "null"    // replaced by NULL
+)
+
+// MaskRule masks the columns matching Columns, or the values that a Detect
+// detector finds. With both set, only the detected values of the matching
+// columns are masked.
+type MaskRule struct {
+	Columns  []string // column patterns, as in PolicyRules.Columns
+	Detect   []string // "email", "credit_card", "iban"
+	Strategy MaskStrategy
+}
+
+func (r MaskRule) String() string {
+	return fmt.Sprintf("columns=%v detect=%v strategy=%s", r.Columns, r.Detect, r.Strategy)
+}
+
+// MaskedColumn reports a result column whose values were masked
+type MaskedColumn struct {
+	Column   string       `json:"column"`
+	Strategy MaskStrategy `json:"strategy"`
+	Reason   string       `json:"reason"` // e.g. "matches users.email", "detected credit_card"
+}
+
+// detector finds sensitive values in text
+type detector struct {
+	re    *regexp.Regexp
+	valid func(string) bool // checksum of a match; nil accepts every match
+}
+
+var detectors = map[string]detector{
+	"email":       {re: regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9\-]+(?:\.[A-Za-z0-9\-]+)*\.[A-Za-z]{2,}`)},
+	"credit_card": {re: regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`), valid: luhnValid},
+	"iban":        {re: regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]){11,30}\b`), valid: ibanValid},
+}
+
+// maskRule is a compiled MaskRule
+type maskRule struct {
+	raw       MaskRule
+	columns   []pattern
+	detectors []string
+}
+
+// Masker replaces sensitive values in query results
+type Masker struct {
+	rules []maskRule
+	key   []byte
+}
+
+// NewMasker compiles masking rules. hashKey keys the hash strategy and is
+// required when a rule uses it, so hashes are stable across restarts.
+func NewMasker(rules []MaskRule, hashKey string) (*Masker, error) {
+	m := &Masker{key: []byte(hashKey)}
+	for i, r := range rules {
+		switch r.Strategy {
+		case MaskRedact, MaskPartial, MaskNull:
+		case MaskHash:
+			if hashKey == "" {
+				return nil, fmt.Errorf("masking rule %d: the hash strategy requires a hash key", i+1)
+			}
+		default:
+			return nil, fmt.Errorf("masking rule %d: strategy must be redact, partial, hash or null, got: %q", i+1, r.Strategy)
+		}
+		if len(r.Columns) == 0 && len(r.Detect) == 0 {
+			return nil, fmt.Errorf("masking rule %d: set columns, detect or both", i+1)
+		}
+		columns, err := compilePatterns(r.Columns, 3)
+		if err != nil {
+			return nil, fmt.Errorf("masking rule %d: invalid column pattern: %w", i+1, err)
+		}
+		for _, name := range r.Detect {
+			if _, ok := detectors[name]; !ok {
+				return nil, fmt.Errorf("masking rule %d: detect must be email, credit_card or iban, got: %q", i+1, name)
+			}
+		}
+		m.rules = append(m.rules, maskRule{raw: r, columns: columns, detectors: r.Detect})
+	}
+	return m, nil
+}
+
+// String describes the rules for configuration diffs, without the key
+func (m *Masker) String() string {
+	if m == nil {
+		return "(none)"
+	}
+	rules := make([]string, len(m.rules))
+	for i, r := range m.rules {
+		rules[i] = r.raw.String()
+	}
+	return "[" + strings.Join(rules, "; ") + "]"
+}
+
+// TableName is a table a result was read from
+type TableName struct {
+	Schema, Name string
+}
+
+// matches reports whether a column rule applies to a column of one of the
+// tables. Without tables only unqualified patterns can match.
+func (r *maskRule) matches(tables []TableName, names []string) (string, bool) {
+	if len(tables) == 0 {
+		tables = []TableName{{}}
+	}
+	for _, p := range r.columns {
+		for _, name := range names {
+			for _, t := range tables {
+				if p.matches(t.Schema, t.Name, name) {
+					return qualify(t.Schema, t.Name, name), true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// wholeRowRead describes a result column that holds whole table rows, as
+// PostgreSQL returns for a table alias used as a value
+type wholeRowRead struct {
+	read    bool
+	unknown string // a table whose columns the catalog does not know
+}
+
+// maskRows masks rows in place. sources lists, per column, the table column
+// names the value may come from, in addition to the column's own name, and
+// wholeRows which columns hold whole rows; either may be nil.
+func (m *Masker) maskRows(tables []TableName, columns []string, sources [][]string, wholeRows []wholeRowRead, rows []map[string]interface{}) []MaskedColumn {
+	var masked []MaskedColumn
+	for i, column := range columns {
+		names := []string{column}
+		if sources != nil {
+			names = append(names, sources[i]...)
+		}
+		var whole wholeRowRead
+		if wholeRows != nil {
+			whole = wholeRows[i]
+		}
+		if whole.unknown != "" && m.hasColumnRules() {
+			// Any of the row's columns may be masked, so none can be shown
+			for _, row := range rows {
+				row[column] = nil
+			}
+			masked = append(masked, MaskedColumn{Column: column, Strategy: MaskNull, Reason: "whole rows of " + whole.unknown + ", whose columns are unknown"})
+			continue
+		}
+		if report, ok := m.maskColumn(tables, names, column, rows, whole.read); ok {
+			masked = append(masked, report)
+		}
+	}
+	return masked
+}
+
+// hasColumnRules reports whether a rule masks columns by name
+func (m *Masker) hasColumnRules() bool {
+	for _, r := range m.rules {
+		if len(r.columns) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// maskColumn masks one column of rows with the first whole-column rule that
+// matches it, or else with the detector rules that apply to it. A column of
+// whole rows is masked as one value; a partial mask would show the other
+// fields, so it is redacted instead.
+func (m *Masker) maskColumn(tables []TableName, names []string, column string, rows []map[string]interface{}, wholeRow bool) (MaskedColumn, bool) {
+	var detect []*maskRule
+	for i := range m.rules {
+		r := &m.rules[i]
+		if len(r.columns) > 0 {
+			match, ok := r.matches(tables, names)
+			if !ok {
+				continue
+			}
+			if len(r.detectors) == 0 {
+				strategy := r.raw.Strategy
+				if wholeRow {
+					match += " (whole row)"
+					if strategy == MaskPartial {
+						strategy = MaskRedact
+					}
+				}
+				for _, row := range rows {
+					if v := row[column]; v != nil {
+						row[column] = m.maskValue(strategy, valueString(v))
+					}
+				}
+				return MaskedColumn{Column: column, Strategy: strategy, Reason: "matches " + match}, true
+			}
+		}
+		detect = append(detect, r)
+	}
+
+	var report MaskedColumn
+	found := false
+	for _, row := range rows {
+		s, ok := row[column].(string)
+		if !ok {
+			continue
+		}
+		for _, r := range detect {
+			value, name, hit := m.maskDetected(r, s)
+			if !hit {
+				continue
+			}
+			if !found {
+				report, found = MaskedColumn{Column: column, Strategy: r.raw.Strategy, Reason: "detected " + name}, true
+			}
+			if value == nil {
+				row[column] = nil
+				break
+			}
+			s = *value
+			row[column] = s
+		}
+	}
+	return report, found
+}
+
+// maskDetected masks the values that the rule's detectors find in s. The
+// null strategy replaces the whole value, returned as nil.
+func (m *Masker) maskDetected(r *maskRule, s string) (*string, string, bool) {
+	hit := ""
+	for _, name := range r.detectors {
+		d := detectors[name]
+		s = d.re.ReplaceAllStringFunc(s, func(match string) string {
+			if d.valid != nil && !d.valid(match) {
+				return match
+			}
+			if hit == "" {
+				hit = name
+			}
+			if masked, ok := m.maskValue(r.raw.Strategy, match).(string); ok {
+				return masked
+			}
+			return match
+		})
+	}
+	if hit == "" {
+		return &s, "", false
+	}
+	if r.raw.Strategy == MaskNull {
+		return nil, hit, true
+	}
+	return &s, hit, true
+}
+
+// maskValue applies a strategy to one value
+func (m *Masker) maskValue(strategy MaskStrategy, s string) interface{} {
+	switch strategy {
+	case MaskNull:
+		return nil
+	case MaskHash:
+		mac := hmac.New(sha256.New, m.key)
+		mac.Write([]byte(s))
+		return hex.EncodeToString(mac.Sum(nil))[:32]
+	case MaskPartial:
+		return partial(s)
+	default:
+		return "****"
+	}
+}
+
+// partial keeps the first character and domain of an email address, the
+// last four characters of a long value, or the first character otherwise
+func partial(s string) string {
+	if at := strings.LastIndexByte(s, '@'); at > 0 {
+		first, _ := utf8.DecodeRuneInString(s)
+		return string(first) + "***" + s[at:]
+	}
+	runes := []rune(s)
+	switch {
+	case len(runes) >= 8:
+		return "****" + string(runes[len(runes)-4:])
+	case len(runes) >= 2:
+		return string(runes[0]) + "***"
+	default:
+		return "****"
+	}
+}
+
+// valueString formats a result value for masking
+func valueString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// luhnValid reports whether the digits of s pass the Luhn check
+func luhnValid(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		c := s[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ibanValid reports whether s passes the ISO 13616 mod-97 check
+func ibanValid(s string) bool {
+	s = strings.ReplaceAll(s, " ", "")
+	s = s[4:] + s[:4]
+	var digits strings.Builder
+	for _, c := range s {
+		if c >= 'A' && c <= 'Z' {
+			fmt.Fprintf(&digits, "%d", c-'A'+10)
+		} else {
+			digits.WriteRune(c)
+		}
+	}
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
@@ -0,0 +1,281 @@
+package security
+
+import (
+	"context"
+	"strings"
+)
+
+// MaskQueryResult masks the rows of a query's result in place and reports
+// the masked columns. Column rules match the result column names, and the
+// table columns that the outer select list reads, following aliases,
+// expressions, unions and the select lists of subqueries and CTEs. On
+// PostgreSQL a table alias or name read as a value is a whole row and is
+// masked as one value when a rule matches any column of the table. Queries
+// that cannot be parsed are masked by result column names only.
+func (m *Masker) MaskQueryResult(ctx context.Context, dbType, query string, catalog Catalog, columns []string, rows []map[string]interface{}) []MaskedColumn {
+	if m == nil {
+		return nil
+	}
+
+	var tables []TableName
+	rowTables := make(map[string]rowTable) // by the name a whole row is read by
+	if refs, err := parseReferences(dbType, query); err == nil {
+		seen := make(map[string]bool)
+		for _, ref := range refs.tables {
+			visible := ref.alias
+			if visible == "" {
+				visible = ref.name
+			}
+			if ref.derived {
+				rowTables[strings.ToLower(visible)] = rowTable{name: visible}
+				continue
+			}
+			t := TableName{Schema: ref.schema, Name: ref.name}
+			rt := rowTable{name: qualify(ref.schema, ref.name)}
+			if catalog != nil {
+				if schema, columns, ok := catalog.ResolveTable(ctx, ref.schema, ref.name); ok {
+					t.Schema = schema
+					rt.columns, rt.known = columns, true
+				}
+			}
+			rowTables[strings.ToLower(visible)] = rt
+			key := strings.ToLower(t.Schema + "." + t.Name)
+			if !seen[key] {
+				seen[key] = true
+				tables = append(tables, t)
+			}
+		}
+	}
+
+	sources := make([][]string, len(columns))
+	if toks, err := Tokenize(dbType, query); err == nil {
+		outer, aliases := selectSources(toks)
+		for i, column := range columns {
+			if len(outer) == len(columns) {
+				sources[i] = outer[i]
+			}
+			sources[i] = expandAliases(append([]string{column}, sources[i]...), aliases)
+		}
+	}
+
+	var wholeRows []wholeRowRead
+	if dbType == "postgres" {
+		wholeRows = make([]wholeRowRead, len(columns))
+		for i := range columns {
+			for _, name := range sources[i] {
+				rt, ok := rowTables[strings.ToLower(name)]
+				if !ok {
+					continue
+				}
+				wholeRows[i].read = true
+				if !rt.known {
+					wholeRows[i].unknown = rt.name
+				}
+				sources[i] = append(sources[i], rt.columns...)
+			}
+		}
+	}
+	return m.maskRows(tables, columns, sources, wholeRows, rows)
+}
+
+// rowTable is a table of a query with the columns the catalog lists for it
+type rowTable struct {
+	name    string
+	columns []string
+	known   bool
+}
+
+// MaskTableRows masks rows read from one table in place and reports the
+// masked columns
+func (m *Masker) MaskTableRows(schema, table string, columns []string, rows []map[string]interface{}) []MaskedColumn {
+	if m == nil {
+		return nil
+	}
+	return m.maskRows([]TableName{{Schema: schema, Name: table}}, columns, nil, nil, rows)
+}
+
+// MaskTableValues masks values of one table column in place, such as the
+// minimum, maximum and frequent values of a profile. The null strategy sets
+// the elements to nil.
+func (m *Masker) MaskTableValues(schema, table, column string, values []*string) (MaskedColumn, bool) {
+	if m == nil {
+		return MaskedColumn{}, false
+	}
+	rows := make([]map[string]interface{}, len(values))
+	for i, v := range values {
+		rows[i] = map[string]interface{}{column: nil}
+		if v != nil {
+			rows[i][column] = *v
+		}
+	}
+	report, ok := m.maskColumn([]TableName{{Schema: schema, Name: table}}, []string{column}, column, rows, false)
+	for i, row := range rows {
+		if s, isString := row[column].(string); isString {
+			values[i] = &s
+		} else {
+			values[i] = nil
+		}
+	}
+	return report, ok
+}
+
+// selectEnd are the words that end a select list
+var selectEnd = wordSet(`FROM INTO WHERE GROUP HAVING ORDER LIMIT OFFSET FETCH UNION EXCEPT INTERSECT MINUS WINDOW FOR LOCK`)
+
+// selectModifiers may precede the first item of a select list
+var selectModifiers = wordSet(`ALL DISTINCT DISTINCTROW HIGH_PRIORITY STRAIGHT_JOIN SQL_SMALL_RESULT SQL_BIG_RESULT
+	SQL_BUFFER_RESULT SQL_NO_CACHE SQL_CACHE SQL_CALC_FOUND_ROWS`)
+
+// selectSources lists, for each item of the outer select list, the column
+// names it reads. Items of every SELECT joined by a set operation are merged
+// by position; outer is nil when the branches differ in width or an item is
+// a star. aliases maps the output name of every select item in the query,
+// including subqueries, to the names it reads.
+func selectSources(toks []Token) (outer [][]string, aliases map[string][]string) {
+	aliases = make(map[string][]string)
+	depth := make([]int, len(toks))
+	d := 0
+	for i, t := range toks {
+		if t.IsSymbol(")") {
+			d--
+		}
+		depth[i] = d
+		if t.IsSymbol("(") {
+			d++
+		}
+	}
+
+	outerOK := true
+	for i, t := range toks {
+		if !t.Is("SELECT") {
+			continue
+		}
+		items, star := selectItems(toks, depth, i+1)
+		for _, item := range items {
+			if name := outputName(item); name != "" {
+				key := strings.ToLower(name)
+				aliases[key] = append(aliases[key], itemSources(item)...)
+			}
+		}
+		if depth[i] != 0 || !outerOK {
+			continue
+		}
+		switch {
+		case star:
+			outerOK = false
+		case outer == nil:
+			for _, item := range items {
+				outer = append(outer, itemSources(item))
+			}
+		case len(outer) == len(items):
+			for k, item := range items {
+				outer[k] = append(outer[k], itemSources(item)...)
+			}
+		default:
+			outerOK = false
+		}
+	}
+	if !outerOK {
+		outer = nil
+	}
+	return outer, aliases
+}
+
+// selectItems splits the select list starting at i into items, and reports
+// whether one of them is * or t.*
+func selectItems(toks []Token, depth []int, i int) ([][]Token, bool) {
+	level := 0
+	if i < len(toks) {
+		level = depth[i]
+	}
+	for i < len(toks) && isWord(toks[i], selectModifiers) {
+		i++
+		if i+1 < len(toks) && toks[i].Is("ON") && toks[i+1].IsSymbol("(") {
+			// DISTINCT ON (...)
+			for i++; i < len(toks) && !(toks[i].IsSymbol(")") && depth[i] == level); i++ {
+			}
+			i++
+		}
+	}
+
+	var items [][]Token
+	var item []Token
+	star := false
+	for ; i < len(toks); i++ {
+		t := toks[i]
+		if depth[i] < level || (depth[i] == level && (isWord(t, selectEnd) || t.IsSymbol(";"))) {
+			break
+		}
+		if depth[i] == level && t.IsSymbol(",") {
+			items = append(items, item)
+			item = nil
+			continue
+		}
+		if depth[i] == level && t.IsSymbol("*") && (len(item) == 0 || item[len(item)-1].IsSymbol(".")) {
+			star = true
+		}
+		item = append(item, t)
+	}
+	if len(item) > 0 {
+		items = append(items, item)
+	}
+	return items, star
+}
+
+// itemSources returns the column names a select item reads: names that are
+// not keywords, function names, type names or qualifiers
+func itemSources(item []Token) []string {
+	var names []string
+	for k, t := range item {
+		if !t.isName() || (t.Kind == TokenWord && isWord(t, sqlKeywords)) {
+			continue
+		}
+		if k+1 < len(item) && (item[k+1].IsSymbol("(") || item[k+1].IsSymbol(".")) {
+			continue
+		}
+		if k > 0 && item[k-1].IsSymbol("::") {
+			continue
+		}
+		names = append(names, t.Value)
+	}
+	return names
+}
+
+// outputName returns the column name a select item produces, if it is an
+// alias or a plain column
+func outputName(item []Token) string {
+	n := len(item)
+	if n == 0 {
+		return ""
+	}
+	last := item[n-1]
+	if !last.isName() || (last.Kind == TokenWord && isWord(last, sqlKeywords)) {
+		return ""
+	}
+	if n == 1 {
+		return last.Value
+	}
+	prev := item[n-2]
+	if prev.Is("AS") || prev.IsSymbol(".") || prev.IsSymbol(")") || prev.isName() {
+		return last.Value
+	}
+	return ""
+}
+
+// expandAliases adds the names that aliases among names read, transitively
+func expandAliases(names []string, aliases map[string][]string) []string {
+	seen := make(map[string]bool)
+	var out []string
+	for len(names) > 0 {
+		name := names[0]
+		names = names[1:]
+		key := strings.ToLower(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, name)
+		names = append(names, aliases[key]...)
+	}
+	return out
+}
@@ -0,0 +1,197 @@
+package security
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func newTestMasker(t *testing.T, rules ...MaskRule) *Masker {
+	t.Helper()
+	m, err := NewMasker(rules, "test-key")
+	if err != nil {
+		t.Fatalf("NewMasker failed: %v", err)
+	}
+	return m
+}
+
+func TestMasker_Strategies(t *testing.T) {
+	tests := []struct {
+		strategy MaskStrategy
+		value    string
+		want     interface{}
+	}{
+		{MaskRedact, "jane@example.com", "****"},
+		{MaskPartial, "jane@example.com", "j***@example.com"},
+		{MaskPartial, "4111111111111111", "****1111"},
+		{MaskPartial, "Jane", "J***"},
+		{MaskNull, "jane@example.com", nil},
+	}
+	for _, tt := range tests {
+		m := newTestMasker(t, MaskRule{Columns: []string{"email"}, Strategy: tt.strategy})
+		rows := []map[string]interface{}{{"email": tt.value}}
+		masked := m.MaskTableRows("public", "users", []string{"email"}, rows)
+		if rows[0]["email"] != tt.want {
+			t.Errorf("%s(%q): expected %v, got %v", tt.strategy, tt.value, tt.want, rows[0]["email"])
+		}
+		if len(masked) != 1 || masked[0].Column != "email" || masked[0].Strategy != tt.strategy {
+			t.Errorf("%s: expected email to be reported, got %v", tt.strategy, masked)
+		}
+	}
+}
+
+func TestMasker_HashIsStable(t *testing.T) {
+	m := newTestMasker(t, MaskRule{Columns: []string{"*.email"}, Strategy: MaskHash})
+	rows := []map[string]interface{}{{"email": "a@x.com"}, {"email": "a@x.com"}, {"email": "b@x.com"}}
+	m.MaskTableRows("public", "users", []string{"email"}, rows)
+
+	if rows[0]["email"] != rows[1]["email"] || rows[0]["email"] == rows[2]["email"] {
+		t.Errorf("Expected equal values to hash equally and distinct values to differ, got %v", rows)
+	}
+	if strings.Contains(rows[0]["email"].(string), "@") {
+		t.Errorf("Expected a hash, got %v", rows[0]["email"])
+	}
+
+	other, _ := NewMasker([]MaskRule{{Columns: []string{"email"}, Strategy: MaskHash}}, "other-key")
+	again := []map[string]interface{}{{"email": "a@x.com"}}
+	other.MaskTableRows("public", "users", []string{"email"}, again)
+	if again[0]["email"] == rows[0]["email"] {
+		t.Error("Expected the hash to depend on the key")
+	}
+}
+
+func TestMasker_Detectors(t *testing.T) {
+	m := newTestMasker(t, MaskRule{Detect: []string{"email", "credit_card", "iban"}, Strategy: MaskRedact})
+	rows := []map[string]interface{}{
+		{"note": "contact jane@example.com today", "n": 42},
+		{"note": "card 4111 1111 1111 1111 on file"},
+		{"note": "order 4111111111111112"}, // fails the Luhn check
+		{"note": "pay to DE89 3704 0044 0532 0130 00"},
+		{"note": "ref GB00WEST12345698765432"}, // fails the IBAN checksum
+	}
+	masked := m.MaskTableRows("", "tickets", []string{"note", "n"}, rows)
+
+	want := []string{
+		"contact **** today",
+		"card **** on file",
+		"order 4111111111111112",
+		"pay to ****",
+		"ref GB00WEST12345698765432",
+	}
+	for i, w := range want {
+		if rows[i]["note"] != w {
+			t.Errorf("row %d: expected %q, got %q", i, w, rows[i]["note"])
+		}
+	}
+	if rows[0]["n"] != 42 {
+		t.Errorf("Expected non-text values to be left alone, got %v", rows[0]["n"])
+	}
+	if len(masked) != 1 || masked[0].Column != "note" || masked[0].Reason != "detected email" {
+		t.Errorf("Expected note to be reported once, got %v", masked)
+	}
+}
+
+func TestMasker_DetectorsLimitedToColumns(t *testing.T) {
+	m := newTestMasker(t, MaskRule{Columns: []string{"comments"}, Detect: []string{"email"}, Strategy: MaskPartial})
+	rows := []map[string]interface{}{{"comments": "ask bob@example.org", "owner": "ann@example.org"}}
+	m.MaskTableRows("", "tickets", []string{"comments", "owner"}, rows)
+
+	if rows[0]["comments"] != "ask b***@example.org" || rows[0]["owner"] != "ann@example.org" {
+		t.Errorf("Expected only comments to be masked, got %v", rows[0])
+	}
+}
+
+func TestMasker_MaskQueryResult(t *testing.T) {
+	m := newTestMasker(t,
+		MaskRule{Columns: []string{"users.email"}, Strategy: MaskRedact},
+		MaskRule{Columns: []string{"ssn"}, Strategy: MaskNull},
+	)
+	catalog := fakeCatalog{"public.users": {"id", "email", "ssn"}, "public.orders": {"id", "email"}}
+
+	tests := []struct {
+		name    string
+		dbType  string
+		query   string
+		columns []string
+		masked  []string
+	}{
+		{"plain column", "postgres", "SELECT id, email FROM users", []string{"id", "email"}, []string{"email"}},
+		{"alias", "postgres", "SELECT email AS contact FROM users", []string{"contact"}, []string{"contact"}},
+		{"expression", "mysql", "SELECT LOWER(u.email) FROM users u", []string{"LOWER(u.email)"}, []string{"LOWER(u.email)"}},
+		{"union", "postgres", "SELECT id, 'x' AS label FROM orders UNION ALL SELECT id, email FROM users", []string{"id", "label"}, []string{"label"}},
+		{"cte", "postgres", "WITH c AS (SELECT email AS e FROM users) SELECT e FROM c", []string{"e"}, []string{"e"}},
+		{"star", "postgres", "SELECT * FROM (SELECT ssn AS tax_id FROM users) t", []string{"tax_id"}, []string{"tax_id"}},
+		{"other table", "postgres", "SELECT email FROM orders", []string{"email"}, nil},
+		{"unqualified anywhere", "mysql", "SELECT ssn FROM people", []string{"ssn"}, []string{"ssn"}},
+		{"whole row", "postgres", "SELECT u FROM users u", []string{"u"}, []string{"u"}},
+		{"whole row function", "postgres", "SELECT row_to_json(u) AS j FROM users u", []string{"j"}, []string{"j"}},
+		{"whole row by table name", "postgres", "SELECT id, to_jsonb(users) FROM users", []string{"id", "to_jsonb"}, []string{"to_jsonb"}},
+		{"whole row cast", "postgres", "SELECT u::text AS r FROM users u", []string{"r"}, []string{"r"}},
+		{"whole row without masked columns", "postgres", "SELECT o FROM orders o", []string{"o"}, nil},
+		{"whole row of unknown table", "postgres", "SELECT p FROM products p", []string{"p"}, []string{"p"}},
+		{"whole row of subquery", "postgres", "SELECT t FROM (SELECT id FROM orders) t", []string{"t"}, []string{"t"}},
+		{"column named like an alias", "mysql", "SELECT u FROM users u", []string{"u"}, nil},
+	}
+	for _, tt := range tests {
+		row := make(map[string]interface{})
+		for _, c := range tt.columns {
+			row[c] = "value"
+		}
+		masked := m.MaskQueryResult(context.Background(), tt.dbType, tt.query, catalog, tt.columns, []map[string]interface{}{row})
+
+		var got []string
+		for _, c := range masked {
+			got = append(got, c.Column)
+			if row[c.Column] == "value" {
+				t.Errorf("%s: %s reported but not masked", tt.name, c.Column)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(tt.masked, ",") {
+			t.Errorf("%s: expected %v to be masked, got %v", tt.name, tt.masked, got)
+		}
+	}
+}
+
+func TestMasker_MaskTableValues(t *testing.T) {
+	m := newTestMasker(t, MaskRule{Columns: []string{"email"}, Strategy: MaskNull})
+	min, max := "a@x.com", "z@x.com"
+	values := []*string{&min, &max, nil}
+	if _, ok := m.MaskTableValues("public", "users", "email", values); !ok {
+		t.Fatal("Expected email to be masked")
+	}
+	for i, v := range values {
+		if v != nil {
+			t.Errorf("value %d: expected nil, got %q", i, *v)
+		}
+	}
+	if _, ok := m.MaskTableValues("public", "users", "id", []*string{&min}); ok {
+		t.Error("Expected id to be left alone")
+	}
+}
+
+func TestNewMasker_Errors(t *testing.T) {
+	tests := map[string]struct {
+		rule MaskRule
+		key  string
+		want string
+	}{
+		"strategy":      {MaskRule{Columns: []string{"a"}, Strategy: "scramble"}, "", "strategy must be"},
+		"hash key":      {MaskRule{Columns: []string{"a"}, Strategy: MaskHash}, "", "requires a hash key"},
+		"empty rule":    {MaskRule{Strategy: MaskRedact}, "", "set columns, detect or both"},
+		"detector":      {MaskRule{Detect: []string{"phone"}, Strategy: MaskRedact}, "", "detect must be"},
+		"column parts":  {MaskRule{Columns: []string{"a.b.c.d"}, Strategy: MaskRedact}, "", "dotted parts"},
+		"valid hashing": {MaskRule{Columns: []string{"a"}, Strategy: MaskHash}, "k", ""},
+	}
+	for name, tt := range tests {
+		_, err := NewMasker([]MaskRule{tt.rule}, tt.key)
+		if tt.want == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected error containing %q, got %v", name, tt.want, err)
+		}
+	}
+}
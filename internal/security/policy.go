@@ -0,0 +1,241 @@
+package security
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// PolicyRules lists glob patterns (* and ?) for schemas, tables and columns.
+// Table patterns may be qualified with a schema, and column patterns with a
+// table or schema.table. Patterns are matched case-insensitively.
+//
+// Functions and clauses work differently: each dialect denies a built-in
+// list of dangerous functions and the into and locking clauses. Deny
+// function patterns extend that list; allow patterns and clauses exempt
+// entries from it rather than restricting queries to them.
+type PolicyRules struct {
+	Schemas   []string // e.g. "hr", "tmp_*"
+	Tables    []string // e.g. "payments", "public.audit_*"
+	Columns   []string // e.g. "ssn", "users.password_hash", "public.users.*_token"
+	Functions []string // e.g. "pg_sleep", "myschema.export_*"
+	Clauses   []string // "into" or "locking"; allow only
+}
+
+// Merge returns the patterns of both rule sets
+func (r PolicyRules) Merge(other PolicyRules) PolicyRules {
+	return PolicyRules{
+		Schemas:   append(append([]string(nil), r.Schemas...), other.Schemas...),
+		Tables:    append(append([]string(nil), r.Tables...), other.Tables...),
+		Columns:   append(append([]string(nil), r.Columns...), other.Columns...),
+		Functions: append(append([]string(nil), r.Functions...), other.Functions...),
+		Clauses:   append(append([]string(nil), r.Clauses...), other.Clauses...),
+	}
+}
+
+func (r PolicyRules) String() string {
+	return fmt.Sprintf("schemas=%v tables=%v columns=%v functions=%v clauses=%v",
+		r.Schemas, r.Tables, r.Columns, r.Functions, r.Clauses)
+}
+
+// pattern is a compiled pattern: lowercased parts, innermost last
+type pattern []string
+
+// compiledRules holds the patterns of one rule set
+type compiledRules struct {
+	schemas, tables, columns, functions []pattern
+}
+
+// Policy decides which schemas, tables and columns may be read, and which
+// functions and clauses queries may use. An object is denied when it
+// matches a deny pattern, or when allow patterns exist for its kind and it
+// matches none of them.
+type Policy struct {
+	allowRules, denyRules PolicyRules
+	allow, deny           compiledRules
+	allowClauses          map[string]bool
+	objectsOnly           bool // functions and clauses are left to another policy
+}
+
+// NewPolicy compiles allow and deny patterns. Empty rules still deny the
+// built-in functions and clauses.
+func NewPolicy(allow, deny PolicyRules) (*Policy, error) {
+	p := &Policy{allowRules: allow, denyRules: deny}
+	var err error
+	if p.allow, err = compileRules(allow); err != nil {
+		return nil, fmt.Errorf("invalid allow pattern: %w", err)
+	}
+	if p.deny, err = compileRules(deny); err != nil {
+		return nil, fmt.Errorf("invalid deny pattern: %w", err)
+	}
+	if len(deny.Clauses) > 0 {
+		return nil, fmt.Errorf("clauses are denied by default; list them under allow to permit them")
+	}
+	if p.allowClauses, err = compileClauses(allow.Clauses); err != nil {
+		return nil, fmt.Errorf("invalid allow pattern: %w", err)
+	}
+	return p, nil
+}
+
+// NewObjectPolicy compiles schema, table and column patterns only, for
+// narrowing another policy: it does not check functions or clauses.
+func NewObjectPolicy(allow, deny PolicyRules) (*Policy, error) {
+	for _, r := range []PolicyRules{allow, deny} {
+		if len(r.Functions) > 0 || len(r.Clauses) > 0 {
+			return nil, fmt.Errorf("only schemas, tables and columns can be set")
+		}
+	}
+	p, err := NewPolicy(allow, deny)
+	if err != nil {
+		return nil, err
+	}
+	p.objectsOnly = true
+	return p, nil
+}
+
+func compileRules(r PolicyRules) (compiledRules, error) {
+	var c compiledRules
+	var err error
+	if c.schemas, err = compilePatterns(r.Schemas, 1); err != nil {
+		return c, err
+	}
+	if c.tables, err = compilePatterns(r.Tables, 2); err != nil {
+		return c, err
+	}
+	if c.columns, err = compilePatterns(r.Columns, 3); err != nil {
+		return c, err
+	}
+	c.functions, err = compilePatterns(r.Functions, 2)
+	return c, err
+}
+
+// compilePatterns splits dotted patterns into at most maxParts parts
+func compilePatterns(patterns []string, maxParts int) ([]pattern, error) {
+	compiled := make([]pattern, 0, len(patterns))
+	for _, raw := range patterns {
+		parts := strings.Split(strings.ToLower(strings.TrimSpace(raw)), ".")
+		if len(parts) > maxParts {
+			return nil, fmt.Errorf("%q has more than %d dotted parts", raw, maxParts)
+		}
+		for _, part := range parts {
+			if part == "" {
+				return nil, fmt.Errorf("%q has an empty part", raw)
+			}
+			if _, err := path.Match(part, ""); err != nil {
+				return nil, fmt.Errorf("%q: %w", raw, err)
+			}
+		}
+		compiled = append(compiled, parts)
+	}
+	return compiled, nil
+}
+
+// matches reports whether the pattern matches the innermost parts of the
+// object name. An unknown (empty) schema only matches a wildcard.
+func (p pattern) matches(object ...string) bool {
+	if len(p) > len(object) {
+		return false
+	}
+	object = object[len(object)-len(p):]
+	for i, part := range p {
+		if ok, _ := path.Match(part, strings.ToLower(object[i])); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func matchAny(patterns []pattern, object ...string) bool {
+	for _, p := range patterns {
+		if p.matches(object...) {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessError reports an object that the security policy does not allow
+type AccessError struct {
+	Object string // e.g. "table public.payments", "function pg_sleep"
+	Reason string // optional, e.g. "sleeps"
+}
+
+func (e *AccessError) Error() string {
+	if e.Reason != "" {
+		return fmt.Sprintf("access to %s is denied by the security policy: %s", e.Object, e.Reason)
+	}
+	return fmt.Sprintf("access to %s is denied by the security policy", e.Object)
+}
+
+// qualify joins the known parts of a dotted name
+func qualify(parts ...string) string {
+	var known []string
+	for _, part := range parts {
+		if part != "" {
+			known = append(known, part)
+		}
+	}
+	return strings.Join(known, ".")
+}
+
+// CheckTable returns an *AccessError if the table, or its schema, is not
+// allowed. A nil policy allows everything.
+func (p *Policy) CheckTable(schema, table string) error {
+	if p == nil {
+		return nil
+	}
+	if matchAny(p.deny.schemas, schema) || (len(p.allow.schemas) > 0 && !matchAny(p.allow.schemas, schema)) {
+		if schema == "" {
+			return &AccessError{Object: "table " + table}
+		}
+		return &AccessError{Object: "schema " + schema}
+	}
+	if matchAny(p.deny.tables, schema, table) || (len(p.allow.tables) > 0 && !matchAny(p.allow.tables, schema, table)) {
+		return &AccessError{Object: "table " + qualify(schema, table)}
+	}
+	return nil
+}
+
+// CheckColumn returns an *AccessError if the column is not allowed. It does
+// not check the table; see CheckTable.
+func (p *Policy) CheckColumn(schema, table, column string) error {
+	if p == nil {
+		return nil
+	}
+	if matchAny(p.deny.columns, schema, table, column) || (len(p.allow.columns) > 0 && !matchAny(p.allow.columns, schema, table, column)) {
+		return &AccessError{Object: "column " + qualify(schema, table, column)}
+	}
+	return nil
+}
+
+// restrictsObjects reports whether any schema, table or column pattern is set
+func (p *Policy) restrictsObjects() bool {
+	for _, r := range []compiledRules{p.allow, p.deny} {
+		if len(r.schemas) > 0 || len(r.tables) > 0 || len(r.columns) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// restrictsColumns reports whether any column pattern may apply to the table
+func (p *Policy) restrictsColumns(schema, table string) bool {
+	if len(p.allow.columns) > 0 {
+		return true
+	}
+	for _, c := range p.deny.columns {
+		// Drop the column part and compare the table parts, if any
+		if len(c) == 1 || pattern(c[:len(c)-1]).matches(schema, table) {
+			return true
+		}
+	}
+	return false
+}
+
+// String describes the policy for configuration diffs
+func (p *Policy) String() string {
+	if p == nil {
+		return "(none)"
+	}
+	return fmt.Sprintf("allow %s; deny %s", p.allowRules, p.denyRules)
+}
@@ -0,0 +1,164 @@
+package security
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// fakeCatalog resolves tables from a fixed schema.table -> columns map
+type fakeCatalog map[string][]string
+
+func (c fakeCatalog) ResolveTable(ctx context.Context, schema, table string) (string, []string, bool) {
+	for key, columns := range c {
+		s, name, _ := strings.Cut(key, ".")
+		if strings.EqualFold(name, table) && (schema == "" || strings.EqualFold(s, schema)) {
+			return s, columns, true
+		}
+	}
+	return "", nil, false
+}
+
+var testCatalog = fakeCatalog{
+	"public.users":    {"id", "email", "password_hash"},
+	"public.orders":   {"id", "user_id", "total"},
+	"public.payments": {"id", "order_id", "card_number"},
+	"hr.salaries":     {"employee_id", "amount"},
+}
+
+func testPolicy(t *testing.T) *Policy {
+	t.Helper()
+	p, err := NewPolicy(PolicyRules{}, PolicyRules{
+		Schemas: []string{"hr"},
+		Tables:  []string{"payments"},
+		Columns: []string{"users.password_hash"},
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+	return p
+}
+
+func TestPolicy_CheckTableAndColumn(t *testing.T) {
+	p := testPolicy(t)
+
+	if err := p.CheckTable("hr", "salaries"); err == nil || !strings.Contains(err.Error(), "schema hr") {
+		t.Errorf("Expected schema hr to be denied, got %v", err)
+	}
+	if err := p.CheckTable("public", "payments"); err == nil || !strings.Contains(err.Error(), "table public.payments") {
+		t.Errorf("Expected payments to be denied, got %v", err)
+	}
+	if err := p.CheckTable("public", "orders"); err != nil {
+		t.Errorf("Expected orders to be allowed, got %v", err)
+	}
+	if err := p.CheckColumn("public", "Users", "PASSWORD_HASH"); err == nil {
+		t.Error("Expected column match to ignore case")
+	}
+	if err := p.CheckColumn("public", "orders", "password_hash"); err != nil {
+		t.Errorf("Expected the column pattern to be limited to users, got %v", err)
+	}
+
+	var accessErr *AccessError
+	if err := p.CheckTable("public", "payments"); !errors.As(err, &accessErr) || accessErr.Object != "table public.payments" {
+		t.Errorf("Expected an AccessError naming the table, got %v", err)
+	}
+
+	var nilPolicy *Policy
+	if err := nilPolicy.CheckTable("hr", "salaries"); err != nil {
+		t.Errorf("Expected a nil policy to allow everything, got %v", err)
+	}
+}
+
+func TestPolicy_AllowLists(t *testing.T) {
+	p, err := NewPolicy(PolicyRules{Tables: []string{"public.orders", "public.users"}}, PolicyRules{})
+	if err != nil {
+		t.Fatalf("NewPolicy failed: %v", err)
+	}
+	if err := p.CheckTable("public", "orders"); err != nil {
+		t.Errorf("Expected allowed table, got %v", err)
+	}
+	if err := p.CheckTable("public", "payments"); err == nil {
+		t.Error("Expected tables outside the allow list to be denied")
+	}
+}
+
+func TestNewPolicy_InvalidPatterns(t *testing.T) {
+	for _, rules := range []PolicyRules{
+		{Tables: []string{"a.b.c"}},
+		{Columns: []string{"a..b"}},
+		{Schemas: []string{"[abc"}},
+	} {
+		if _, err := NewPolicy(PolicyRules{}, rules); err == nil {
+			t.Errorf("Expected error for %v", rules)
+		}
+	}
+}
+
+func TestPolicy_CheckQuery(t *testing.T) {
+	p := testPolicy(t)
+
+	tests := []struct {
+		name    string
+		dbType  string
+		query   string
+		blocked string // substring of the error; empty when allowed
+	}{
+		{"allowed columns", "postgres", "SELECT id, email FROM users", ""},
+		{"denied column", "postgres", "SELECT id, password_hash FROM users", "column public.users.password_hash"},
+		{"qualified by alias", "postgres", "SELECT u.password_hash FROM users AS u", "column public.users.password_hash"},
+		{"qualified by table", "mysql", "SELECT users.password_hash FROM users", "column public.users.password_hash"},
+		{"inside function", "postgres", "SELECT substring(password_hash FROM 1 FOR 3) FROM users", "password_hash"},
+		{"in where clause", "postgres", "SELECT id FROM users WHERE password_hash LIKE 'a%'", "password_hash"},
+		{"star expands", "postgres", "SELECT * FROM users", "read by * from public.users"},
+		{"qualified star", "postgres", "SELECT o.id, u.* FROM orders o JOIN users u ON u.id = o.user_id", "read by *"},
+		{"star on allowed table", "postgres", "SELECT * FROM orders", ""},
+		{"count star", "postgres", "SELECT COUNT(*) FROM users", ""},
+		{"multiplication", "postgres", "SELECT total * 2 FROM orders", ""},
+		{"whole row", "postgres", "SELECT row_to_json(u) FROM users u", "password_hash"},
+		{"denied table", "postgres", "SELECT id FROM payments", "table public.payments"},
+		{"denied table in join", "postgres", "SELECT o.id FROM orders o LEFT JOIN payments p ON p.order_id = o.id", "table public.payments"},
+		{"denied table in comma list", "mysql", "SELECT o.id FROM orders o, payments", "table public.payments"},
+		{"denied schema", "postgres", "SELECT amount FROM hr.salaries", "schema hr"},
+		{"unqualified in denied schema", "postgres", "SELECT amount FROM salaries", "schema hr"},
+		{"subquery", "postgres", "SELECT id FROM orders WHERE user_id IN (SELECT id FROM payments)", "table public.payments"},
+		{"derived table", "postgres", "SELECT x.h FROM (SELECT password_hash AS h FROM users) x", "password_hash"},
+		{"cte", "postgres", "WITH u AS (SELECT * FROM users) SELECT id FROM u", "read by *"},
+		{"cte is not a table", "postgres", "WITH payments AS (SELECT id FROM orders) SELECT id FROM payments", ""},
+		{"other scope", "postgres", "SELECT email FROM users WHERE id IN (SELECT user_id FROM orders)", ""},
+		{"correlated", "postgres", "SELECT id FROM orders o WHERE EXISTS (SELECT 1 FROM users WHERE password_hash = 'x')", "password_hash"},
+		{"string literal", "postgres", "SELECT 'password_hash' FROM users", ""},
+		{"mysql double quotes are strings", "mysql", `SELECT "password_hash" FROM users`, ""},
+		{"postgres double quotes are identifiers", "postgres", `SELECT "password_hash" FROM users`, "password_hash"},
+		{"mysql executable comment", "mysql", "SELECT id /*!, password_hash */ FROM users", "password_hash"},
+		{"table statement", "postgres", "SELECT id FROM orders UNION TABLE users", "read by *"},
+		{"describe", "mysql", "DESCRIBE payments", "table public.payments"},
+		{"is distinct from", "postgres", "SELECT id FROM orders WHERE total IS DISTINCT FROM 0", ""},
+		{"extract", "postgres", "SELECT EXTRACT(YEAR FROM now()) FROM orders", ""},
+		{"unbalanced", "postgres", "SELECT (id FROM users", "failed to parse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.CheckQuery(context.Background(), tt.dbType, tt.query, testCatalog)
+			switch {
+			case tt.blocked == "" && err != nil:
+				t.Errorf("Expected %q to be allowed, got %v", tt.query, err)
+			case tt.blocked != "" && (err == nil || !strings.Contains(err.Error(), tt.blocked)):
+				t.Errorf("Expected %q to be blocked with %q, got %v", tt.query, tt.blocked, err)
+			}
+		})
+	}
+}
+
+func TestPolicy_CheckQuery_UnknownTableColumns(t *testing.T) {
+	p := testPolicy(t)
+	catalog := fakeCatalog{}
+
+	if err := p.CheckQuery(context.Background(), "postgres", "SELECT * FROM users", catalog); err == nil || !strings.Contains(err.Error(), "cannot verify") {
+		t.Errorf("Expected * on a table with column rules to be rejected when its columns are unknown, got %v", err)
+	}
+	if err := p.CheckQuery(context.Background(), "postgres", "SELECT * FROM orders", catalog); err != nil {
+		t.Errorf("Expected * on a table without column rules to be allowed, got %v", err)
+	}
+}
@@ -0,0 +1,241 @@
+package security
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Catalog resolves the tables named in a query
+type Catalog interface {
+	// ResolveTable returns the schema and column names of a table, resolving
+	// an unqualified name the way the database would. ok is false when the
+	// table cannot be found.
+	ResolveTable(ctx context.Context, schema, table string) (resolvedSchema string, columns []string, ok bool)
+}
+
+// resolvedTable is a table reference looked up in the catalog
+type resolvedTable struct {
+	ref     tableRef
+	schema  string
+	columns []string
+	known   bool
+}
+
+// queryChecker checks the references of one query against a policy
+type queryChecker struct {
+	ctx      context.Context
+	policy   *Policy
+	catalog  Catalog
+	postgres bool
+	refs     *queryRefs
+	tables   []*resolvedTable
+	cache    map[string]*resolvedTable
+}
+
+// CheckQuery returns an error naming the first denied function or clause
+// the query uses, or denied schema, table or column it reads, including the
+// columns that * expands to. Queries that cannot be parsed are rejected. A
+// nil policy allows everything.
+func (p *Policy) CheckQuery(ctx context.Context, dbType, query string, catalog Catalog) error {
+	if p == nil {
+		return nil
+	}
+	toks, err := Tokenize(dbType, query)
+	if err != nil {
+		return fmt.Errorf("failed to parse query for the security policy: %w", err)
+	}
+	if !p.objectsOnly {
+		if err := p.checkFunctions(dbType, toks); err != nil {
+			return err
+		}
+	}
+	if !p.restrictsObjects() {
+		return nil
+	}
+
+	refs, err := parseReferences(dbType, query)
+	if err != nil {
+		return fmt.Errorf("failed to parse query for the security policy: %w", err)
+	}
+
+	c := &queryChecker{
+		ctx:      ctx,
+		policy:   p,
+		catalog:  catalog,
+		postgres: dbType == "postgres",
+		refs:     refs,
+		cache:    make(map[string]*resolvedTable),
+	}
+	for _, ref := range refs.tables {
+		if ref.derived {
+			c.tables = append(c.tables, &resolvedTable{ref: ref})
+			continue
+		}
+		t := c.resolve(ref.schema, ref.name)
+		c.tables = append(c.tables, &resolvedTable{ref: ref, schema: t.schema, columns: t.columns, known: t.known})
+	}
+
+	for _, t := range c.tables {
+		if t.ref.derived {
+			continue
+		}
+		if err := p.CheckTable(t.schema, t.ref.name); err != nil {
+			return err
+		}
+	}
+	for _, col := range refs.columns {
+		if err := c.checkColumn(col); err != nil {
+			return err
+		}
+	}
+	for _, star := range refs.stars {
+		targets := c.scopeTables(star.scope)
+		if star.qualifier != nil {
+			targets = c.qualified(star.qualifier)
+		}
+		for _, t := range targets {
+			if err := c.checkStar(t); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// resolve looks a table up in the catalog once per query
+func (c *queryChecker) resolve(schema, name string) *resolvedTable {
+	key := strings.ToLower(schema + "." + name)
+	if t, ok := c.cache[key]; ok {
+		return t
+	}
+	t := &resolvedTable{ref: tableRef{schema: schema, name: name}, schema: schema}
+	if resolved, columns, ok := c.catalog.ResolveTable(c.ctx, schema, name); ok {
+		t.schema, t.columns, t.known = resolved, columns, true
+	}
+	c.cache[key] = t
+	return t
+}
+
+// checkColumn checks a column against the tables it may belong to. A
+// PostgreSQL name that matches a table alias is also a whole-row reference.
+func (c *queryChecker) checkColumn(col columnRef) error {
+	var targets []*resolvedTable
+	if len(col.qualifier) > 0 {
+		targets = c.qualified(col.qualifier)
+	} else {
+		targets = c.visibleTables(col.scope)
+		if c.postgres {
+			for _, t := range targets {
+				if strings.EqualFold(t.visibleName(), col.name) {
+					if err := c.checkStar(t); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	for _, t := range targets {
+		if t.ref.derived || (t.known && !containsFold(t.columns, col.name)) {
+			continue
+		}
+		if err := c.policy.CheckColumn(t.schema, t.ref.name, col.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkStar checks every column of a table read through * or a whole-row
+// reference
+func (c *queryChecker) checkStar(t *resolvedTable) error {
+	if t.ref.derived {
+		return nil
+	}
+	name := qualify(t.schema, t.ref.name)
+	if !t.known {
+		if c.policy.restrictsColumns(t.schema, t.ref.name) {
+			return fmt.Errorf("cannot verify the columns of %s that * would read; list the columns explicitly", name)
+		}
+		return nil
+	}
+	for _, column := range t.columns {
+		if err := c.policy.CheckColumn(t.schema, t.ref.name, column); err != nil {
+			return fmt.Errorf("%w (read by * from %s); list the allowed columns explicitly", err, name)
+		}
+	}
+	return nil
+}
+
+// visibleName is the name a table is referred to by in expressions
+func (t *resolvedTable) visibleName() string {
+	if t.ref.alias != "" {
+		return t.ref.alias
+	}
+	return t.ref.name
+}
+
+// qualified resolves an alias, table or schema.table qualifier. A name that
+// matches no table in the query is looked up in the catalog directly.
+func (c *queryChecker) qualified(qualifier []string) []*resolvedTable {
+	var parts []string
+	for _, part := range qualifier {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	name := parts[len(parts)-1]
+	schema := ""
+	if len(parts) > 1 {
+		schema = parts[len(parts)-2]
+	}
+
+	var matches []*resolvedTable
+	for _, t := range c.tables {
+		switch {
+		case schema == "" && t.ref.alias != "" && strings.EqualFold(t.ref.alias, name):
+			matches = append(matches, t)
+		case t.ref.alias == "" && strings.EqualFold(t.ref.name, name) &&
+			(schema == "" || strings.EqualFold(t.schema, schema)):
+			matches = append(matches, t)
+		}
+	}
+	if len(matches) == 0 {
+		matches = append(matches, c.resolve(schema, name))
+	}
+	return matches
+}
+
+// scopeTables returns the tables in the FROM clause of a scope
+func (c *queryChecker) scopeTables(scope int) []*resolvedTable {
+	var tables []*resolvedTable
+	for _, t := range c.tables {
+		if t.ref.scope == scope {
+			tables = append(tables, t)
+		}
+	}
+	return tables
+}
+
+// visibleTables returns the tables of a scope and of the scopes enclosing
+// it, which correlated subqueries can refer to
+func (c *queryChecker) visibleTables(scope int) []*resolvedTable {
+	var tables []*resolvedTable
+	for s := scope; s >= 0; s = c.refs.parents[s] {
+		tables = append(tables, c.scopeTables(s)...)
+	}
+	return tables
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
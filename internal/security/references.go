@@ -0,0 +1,399 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tableRef is a table named in a FROM or JOIN clause. Derived tables,
+// table functions and CTE references are recorded with derived set, so their
+// aliases resolve but they are not checked against the catalog.
+type tableRef struct {
+	schema, name, alias string
+	derived             bool
+	scope               int
+}
+
+// columnRef is a column name, optionally qualified by an alias, a table or
+// a schema and table
+type columnRef struct {
+	qualifier []string
+	name      string
+	scope     int
+}
+
+// starRef is a * or t.* in a select list: every column of the table is read
+type starRef struct {
+	qualifier []string // nil for a bare *
+	scope     int
+}
+
+// queryRefs are the objects a query reads. Scopes number the SELECTs of the
+// query; parents links each scope to the scope enclosing it.
+type queryRefs struct {
+	tables  []tableRef
+	columns []columnRef
+	stars   []starRef
+	parents []int
+}
+
+// clauseKeywords end a table list and cannot be aliases
+var clauseKeywords = wordSet(`
+	WHERE JOIN INNER LEFT RIGHT FULL OUTER CROSS NATURAL STRAIGHT_JOIN ON USING GROUP ORDER HAVING
+	LIMIT OFFSET UNION EXCEPT INTERSECT MINUS WINDOW FETCH FOR INTO LATERAL TABLESAMPLE USE FORCE
+	IGNORE PARTITION RETURNING AS SELECT FROM WITH VALUES LOCK PROCEDURE QUALIFY CONNECT START`)
+
+// sqlKeywords are words that never name a column in expressions
+var sqlKeywords = wordSet(`
+	ALL ALLOW_FILTERING AND ANALYZE ANY ARRAY AS ASC ASYMMETRIC AT AUTHORIZATION BETWEEN BIGINT BINARY
+	BIT BOOLEAN BOTH BUFFERS BY CASE CAST CHAR CHARACTER CHECK COLLATE COLUMNS COSTS CREATE CROSS
+	CURRENT CURRENT_CATALOG CURRENT_DATE CURRENT_ROLE CURRENT_SCHEMA CURRENT_TIME CURRENT_TIMESTAMP
+	CURRENT_USER DATE DAY DAY_HOUR DAY_MINUTE DAY_SECOND DEC DECIMAL DEFAULT DESC DESCRIBE DISTINCT
+	DIV DOUBLE ELSE END EPOCH ESCAPE EXCEPT EXISTS EXPLAIN EXTENDED FALSE FETCH FILTER FIRST FLOAT FOLLOWING
+	FOR FORMAT FROM FULL GROUP GROUPING HAVING HOUR ILIKE IN INDEX INNER INT INTEGER INTERSECT INTERVAL INTO IS
+	ISNULL JOIN JSON KEY LAST LATERAL LEADING LEFT LIKE LIMIT LOCALTIME LOCALTIMESTAMP MATERIALIZED
+	MICROSECOND MILLISECOND MINUTE MOD MONTH NATURAL NOT NOTNULL NULL NULLS NUMERIC OF OFFSET ON ONLY OR
+	ORDER ORDINALITY OUTER OVER PARTITION PRECEDING PRECISION QUARTER RANGE REAL RECURSIVE REGEXP RIGHT
+	RLIKE ROLLUP ROW ROWS SECOND SELECT SESSION_USER SETTINGS SHOW SIGNED SIMILAR SMALLINT SOME
+	SYMMETRIC TABLE TABLES TEXT THEN TIES TIME TIMESTAMP TIMING TO TRAILING TRUE UNBOUNDED UNION
+	UNKNOWN UNSIGNED USER USING VALUES VARCHAR VARIADIC VERBOSE WEEK WHEN WHERE WINDOW WITH WITHIN
+	WITHOUT YEAR ZONE SEPARATOR SHARE UPDATE NOWAIT SKIP LOCKED MODE`)
+
+// queryStarters open a subquery when they follow "("
+var queryStarters = wordSet(`SELECT WITH VALUES TABLE`)
+
+func wordSet(words string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(words) {
+		set[w] = true
+	}
+	return set
+}
+
+func isWord(t Token, set map[string]bool) bool {
+	return t.Kind == TokenWord && set[strings.ToUpper(t.Value)]
+}
+
+// refParser walks the tokens of one query
+type refParser struct {
+	fold    bool // PostgreSQL folds unquoted names to lower case
+	toks    []Token
+	match   []int  // index of the matching parenthesis
+	scope   []int  // scope of each token
+	inQuery []bool // whether the innermost parenthesis is a query
+	used    []bool // tokens consumed as table names, aliases or CTE names
+	ctes    map[string]bool
+	refs    queryRefs
+}
+
+// parseReferences lists the tables, columns and star expansions of a query
+func parseReferences(dbType, query string) (*queryRefs, error) {
+	toks, err := Tokenize(dbType, query)
+	if err != nil {
+		return nil, err
+	}
+	p := &refParser{
+		fold:    dbType == "postgres",
+		toks:    toks,
+		match:   make([]int, len(toks)),
+		scope:   make([]int, len(toks)),
+		inQuery: make([]bool, len(toks)),
+		used:    make([]bool, len(toks)),
+		ctes:    make(map[string]bool),
+		refs:    queryRefs{parents: []int{-1}},
+	}
+	if err := p.scopes(); err != nil {
+		return nil, err
+	}
+	p.commonTableExpressions()
+	p.walk()
+	return &p.refs, nil
+}
+
+// scopes matches parentheses and assigns every token to a scope. A
+// parenthesis opening a subquery starts a new scope; other parentheses
+// belong to the enclosing one.
+func (p *refParser) scopes() error {
+	type frame struct {
+		open  int
+		scope int
+		query bool
+	}
+	stack := []frame{{open: -1, scope: 0, query: true}}
+	for i, t := range p.toks {
+		top := stack[len(stack)-1]
+		p.scope[i], p.inQuery[i] = top.scope, top.query
+		p.match[i] = -1
+
+		switch {
+		case t.IsSymbol("("):
+			f := frame{open: i, scope: top.scope}
+			if i+1 < len(p.toks) && isWord(p.toks[i+1], queryStarters) {
+				f.scope, f.query = len(p.refs.parents), true
+				p.refs.parents = append(p.refs.parents, top.scope)
+			}
+			stack = append(stack, f)
+		case t.IsSymbol(")"):
+			if len(stack) == 1 {
+				return fmt.Errorf("unbalanced parentheses at position %d", t.Pos)
+			}
+			p.match[i], p.match[top.open] = top.open, i
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if len(stack) != 1 {
+		return fmt.Errorf("unbalanced parentheses")
+	}
+	return nil
+}
+
+// name returns the identifier at i as the database sees it
+func (p *refParser) name(i int) string {
+	if p.fold && p.toks[i].Kind == TokenWord {
+		return strings.ToLower(p.toks[i].Value)
+	}
+	return p.toks[i].Value
+}
+
+func (p *refParser) tok(i int) Token {
+	if i < 0 || i >= len(p.toks) {
+		return Token{Kind: TokenSymbol}
+	}
+	return p.toks[i]
+}
+
+// commonTableExpressions records the names defined by WITH clauses, so that
+// references to them are not mistaken for tables
+func (p *refParser) commonTableExpressions() {
+	for i, t := range p.toks {
+		if !t.Is("WITH") {
+			continue
+		}
+		j := i + 1
+		if p.tok(j).Is("RECURSIVE") {
+			j++
+		}
+		for p.tok(j).isName() {
+			name := j
+			j++
+			if p.tok(j).IsSymbol("(") {
+				j = p.match[j] + 1
+			}
+			if !p.tok(j).Is("AS") {
+				break
+			}
+			j++
+			for p.tok(j).Is("NOT") || p.tok(j).Is("MATERIALIZED") {
+				j++
+			}
+			if !p.tok(j).IsSymbol("(") {
+				break
+			}
+			// Confirmed: mark the name and its column list as consumed
+			p.ctes[strings.ToLower(p.toks[name].Value)] = true
+			for k := name; k < j; k++ {
+				p.used[k] = true
+			}
+			j = p.match[j] + 1
+			if !p.tok(j).IsSymbol(",") {
+				break
+			}
+			j++
+		}
+	}
+}
+
+// walk finds table references after FROM and JOIN, star expansions and
+// column names
+func (p *refParser) walk() {
+	for i := 0; i < len(p.toks); i++ {
+		t := p.toks[i]
+		switch {
+		case t.Is("FROM") && p.inQuery[i] && !p.isDistinctFrom(i):
+			p.tableList(i+1, p.scope[i])
+		case t.Is("JOIN") || t.Is("STRAIGHT_JOIN"):
+			p.tableItem(i+1, p.scope[i])
+		case i == 0 && (t.Is("DESCRIBE") || t.Is("DESC") || t.Is("EXPLAIN")) && p.tok(1).isName() && !isWord(p.tok(1), sqlKeywords):
+			// MySQL's DESCRIBE t and EXPLAIN t show a table definition
+			p.tableItem(1, 0)
+		case t.Is("TABLE") && !p.tok(i-1).Is("CREATE") && p.tok(i+1).isName():
+			// PostgreSQL's TABLE t is SELECT * FROM t
+			end := p.tableItem(i+1, p.scope[i])
+			if end > i+1 {
+				last := p.refs.tables[len(p.refs.tables)-1]
+				p.refs.stars = append(p.refs.stars, starRef{qualifier: []string{last.schema, last.name}, scope: p.scope[i]})
+			}
+		case t.Is("TABLE"):
+			// SHOW CREATE TABLE t reveals the definition only
+			p.tableItem(i+1, p.scope[i])
+		case t.IsSymbol("*") && p.isSelectStar(i):
+			p.refs.stars = append(p.refs.stars, starRef{scope: p.scope[i]})
+		case t.isName() && !p.used[i]:
+			i = p.nameChain(i)
+		}
+	}
+}
+
+// isDistinctFrom reports whether the FROM at i belongs to IS [NOT] DISTINCT FROM
+func (p *refParser) isDistinctFrom(i int) bool {
+	return p.tok(i-1).Is("DISTINCT") && (p.tok(i-2).Is("IS") || p.tok(i-2).Is("NOT"))
+}
+
+// isSelectStar reports whether the * at i expands to all columns rather
+// than multiplying or counting rows
+func (p *refParser) isSelectStar(i int) bool {
+	prev := p.tok(i - 1)
+	switch {
+	case prev.Is("SELECT"), prev.Is("DISTINCT"), prev.Is("ALL"), prev.IsSymbol(","):
+		return true
+	case prev.IsSymbol(")"):
+		// SELECT DISTINCT ON (a) *
+		open := p.match[i-1]
+		return open > 1 && p.tok(open-1).Is("ON") && p.tok(open-2).Is("DISTINCT")
+	}
+	return false
+}
+
+// tableList parses comma-separated table items starting at j
+func (p *refParser) tableList(j, scope int) int {
+	for {
+		j = p.tableItem(j, scope)
+		if !p.tok(j).IsSymbol(",") {
+			return j
+		}
+		j++
+	}
+}
+
+// tableItem parses one table, subquery or table function with its alias,
+// returning the index after it
+func (p *refParser) tableItem(j, scope int) int {
+	for p.tok(j).Is("LATERAL") || p.tok(j).Is("ONLY") {
+		j++
+	}
+
+	t := p.tok(j)
+	switch {
+	case t.IsSymbol("("):
+		end := p.match[j]
+		if isWord(p.tok(j+1), queryStarters) {
+			// Derived table; the subquery is walked on its own
+			ref := tableRef{derived: true, scope: scope}
+			j = p.alias(end+1, &ref)
+			p.refs.tables = append(p.refs.tables, ref)
+			return j
+		}
+		// Parenthesized join
+		p.tableList(j+1, scope)
+		ref := tableRef{derived: true, scope: scope}
+		j = p.alias(end+1, &ref)
+		if ref.alias != "" {
+			p.refs.tables = append(p.refs.tables, ref)
+		}
+		return j
+
+	case t.isName() && !isWord(t, clauseKeywords):
+		var parts []string
+		for {
+			p.used[j] = true
+			parts = append(parts, p.name(j))
+			if !p.tok(j+1).IsSymbol(".") || !p.tok(j+2).isName() {
+				break
+			}
+			p.used[j+1] = true
+			j += 2
+		}
+		j++
+
+		ref := tableRef{name: parts[len(parts)-1], scope: scope}
+		if len(parts) > 1 {
+			ref.schema = parts[len(parts)-2]
+		}
+		if p.tok(j).IsSymbol("(") {
+			// Table function; its arguments are walked as expressions
+			ref.derived = true
+			j = p.match[j] + 1
+		} else if len(parts) == 1 && (p.ctes[strings.ToLower(ref.name)] || strings.EqualFold(ref.name, "DUAL")) {
+			ref.derived = true
+		}
+		j = p.alias(j, &ref)
+		p.refs.tables = append(p.refs.tables, ref)
+		return p.indexHints(j)
+	}
+	return j
+}
+
+// alias parses an optional [AS] alias [(column, ...)] at j
+func (p *refParser) alias(j int, ref *tableRef) int {
+	if p.tok(j).Is("AS") {
+		p.used[j] = true
+		j++
+	}
+	if t := p.tok(j); t.isName() && !isWord(t, clauseKeywords) {
+		p.used[j] = true
+		ref.alias = p.name(j)
+		j++
+		if p.tok(j).IsSymbol("(") {
+			for k := j; k <= p.match[j]; k++ {
+				p.used[k] = true
+			}
+			j = p.match[j] + 1
+		}
+	}
+	return j
+}
+
+// indexHints skips MySQL index hints such as USE INDEX (idx)
+func (p *refParser) indexHints(j int) int {
+	for (p.tok(j).Is("USE") || p.tok(j).Is("FORCE") || p.tok(j).Is("IGNORE")) &&
+		(p.tok(j+1).Is("INDEX") || p.tok(j+1).Is("KEY")) {
+		k := j + 2
+		for !p.tok(k).IsSymbol("(") && k < len(p.toks) {
+			k++
+		}
+		if k >= len(p.toks) {
+			return k
+		}
+		for m := j; m <= p.match[k]; m++ {
+			p.used[m] = true
+		}
+		j = p.match[k] + 1
+	}
+	return j
+}
+
+// nameChain records the column, qualified column or qualified star starting
+// at i and returns the index of its last token
+func (p *refParser) nameChain(i int) int {
+	parts := []string{p.name(i)}
+	j := i
+	for p.tok(j + 1).IsSymbol(".") {
+		next := p.tok(j + 2)
+		if next.IsSymbol("*") {
+			p.refs.stars = append(p.refs.stars, starRef{qualifier: parts, scope: p.scope[i]})
+			return j + 2
+		}
+		if !next.isName() {
+			break
+		}
+		parts = append(parts, p.name(j+2))
+		j += 2
+	}
+
+	prev, next := p.tok(i-1), p.tok(j+1)
+	switch {
+	case next.IsSymbol("("):
+		// Function call
+	case prev.Is("AS"), prev.IsSymbol("::"):
+		// Alias or type name
+	case len(parts) == 1 && p.toks[i].Kind == TokenWord && isWord(p.toks[i], sqlKeywords):
+		// Keyword
+	default:
+		p.refs.columns = append(p.refs.columns, columnRef{
+			qualifier: parts[:len(parts)-1],
+			name:      parts[len(parts)-1],
+			scope:     p.scope[i],
+		})
+	}
+	return j
+}
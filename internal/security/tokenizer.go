@@ -0,0 +1,304 @@
+package security
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// TokenKind classifies a lexical token of a SQL query
+type TokenKind int
+
+const (
+	TokenWord   TokenKind = iota // unquoted keyword or identifier
+	TokenIdent                   // quoted identifier
+	TokenString                  // string literal
+	TokenNumber                  // numeric literal
+	TokenParam                   // placeholder or variable: $1, ?, @name, @@name
+	TokenSymbol                  // operator or punctuation
+)
+
+// Token is one lexical token. Value holds the identifier without quotes for
+// words and quoted identifiers, and the raw text otherwise.
+type Token struct {
+	Kind  TokenKind
+	Value string
+	Pos   int // byte offset in the query
+}
+
+// Is reports whether the token is the unquoted keyword kw, ignoring case
+func (t Token) Is(kw string) bool {
+	return t.Kind == TokenWord && strings.EqualFold(t.Value, kw)
+}
+
+// IsSymbol reports whether the token is the given operator or punctuation
+func (t Token) IsSymbol(sym string) bool {
+	return t.Kind == TokenSymbol && t.Value == sym
+}
+
+// isName reports whether the token can name a table, column or alias
+func (t Token) isName() bool {
+	return t.Kind == TokenWord || t.Kind == TokenIdent
+}
+
+// Tokenize splits a query into tokens following the lexical rules of the
+// given database type ("mysql" or "postgres"). Comments are dropped, except
+// MySQL's executable /*! ... */ comments, whose content is tokenized since
+// the server runs it.
+func Tokenize(dbType, query string) ([]Token, error) {
+	mysql := dbType == "mysql"
+	var tokens []Token
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+
+		case c == '-' && strings.HasPrefix(query[i:], "--") && (!mysql || mysqlDashComment(query, i)), mysql && c == '#':
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return tokens, nil
+			}
+			i += end + 1
+
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			if mysql && strings.HasPrefix(query[i:], "/*!") {
+				// Executable comment: skip the marker and optional version
+				i += 3
+				for i < len(query) && query[i] >= '0' && query[i] <= '9' {
+					i++
+				}
+				continue
+			}
+			end, err := skipBlockComment(query, i, !mysql)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+
+		case mysql && c == '*' && strings.HasPrefix(query[i:], "*/"):
+			// End of an executable comment
+			i += 2
+
+		case c == '\'':
+			end, err := skipQuoted(query, i, '\'', mysql)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, Token{Kind: TokenString, Value: query[i:end], Pos: i})
+			i = end
+
+		case c == '"' && mysql:
+			end, err := skipQuoted(query, i, '"', true)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, Token{Kind: TokenString, Value: query[i:end], Pos: i})
+			i = end
+
+		case c == '"' || (c == '`' && mysql):
+			end, err := skipQuoted(query, i, c, false)
+			if err != nil {
+				return nil, err
+			}
+			ident := query[i+1 : end-1]
+			ident = strings.ReplaceAll(ident, string([]byte{c, c}), string(c))
+			tokens = append(tokens, Token{Kind: TokenIdent, Value: ident, Pos: i})
+			i = end
+
+		case !mysql && (c == 'E' || c == 'e') && strings.HasPrefix(query[i+1:], "'"):
+			end, err := skipQuoted(query, i+1, '\'', true)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, Token{Kind: TokenString, Value: query[i:end], Pos: i})
+			i = end
+
+		case !mysql && c == '$':
+			if j := scanDigits(query, i+1); j > i+1 {
+				tokens = append(tokens, Token{Kind: TokenParam, Value: query[i:j], Pos: i})
+				i = j
+				continue
+			}
+			tag, ok := dollarTag(query, i)
+			if !ok {
+				tokens = append(tokens, Token{Kind: TokenSymbol, Value: "$", Pos: i})
+				i++
+				continue
+			}
+			end := strings.Index(query[i+len(tag):], tag)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated dollar-quoted string at position %d", i)
+			}
+			end += i + 2*len(tag)
+			tokens = append(tokens, Token{Kind: TokenString, Value: query[i:end], Pos: i})
+			i = end
+
+		case c >= '0' && c <= '9', c == '.' && i+1 < len(query) && query[i+1] >= '0' && query[i+1] <= '9':
+			j := scanNumber(query, i)
+			tokens = append(tokens, Token{Kind: TokenNumber, Value: query[i:j], Pos: i})
+			i = j
+
+		case c == '?' && mysql:
+			tokens = append(tokens, Token{Kind: TokenParam, Value: "?", Pos: i})
+			i++
+
+		case c == '@' && mysql:
+			j := i + 1
+			if j < len(query) && query[j] == '@' {
+				j++
+			}
+			j = scanWord(query, j)
+			tokens = append(tokens, Token{Kind: TokenParam, Value: query[i:j], Pos: i})
+			i = j
+
+		case isWordStart(query, i):
+			j := scanWord(query, i)
+			tokens = append(tokens, Token{Kind: TokenWord, Value: query[i:j], Pos: i})
+			i = j
+
+		case c == ':' && strings.HasPrefix(query[i:], "::"):
+			tokens = append(tokens, Token{Kind: TokenSymbol, Value: "::", Pos: i})
+			i += 2
+
+		default:
+			tokens = append(tokens, Token{Kind: TokenSymbol, Value: string(c), Pos: i})
+			i++
+		}
+	}
+
+	return tokens, nil
+}
+
+// mysqlDashComment reports whether the "--" at i starts a comment. MySQL
+// requires whitespace after it, so 1--1 is arithmetic.
+func mysqlDashComment(query string, i int) bool {
+	if i+2 >= len(query) {
+		return true
+	}
+	c := query[i+2]
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// skipBlockComment returns the offset after the comment starting at i.
+// PostgreSQL comments nest; MySQL comments do not.
+func skipBlockComment(query string, i int, nested bool) (int, error) {
+	depth := 0
+	for j := i; j+1 < len(query); j++ {
+		switch {
+		case query[j] == '/' && query[j+1] == '*' && (nested || depth == 0):
+			depth++
+			j++
+		case query[j] == '*' && query[j+1] == '/':
+			depth--
+			j++
+			if depth == 0 {
+				return j + 1, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unterminated comment at position %d", i)
+}
+
+// skipQuoted returns the offset after the quoted text starting at i. A
+// doubled quote is an escaped quote; backslash escapes are honored when
+// backslashes is set.
+func skipQuoted(query string, i int, quote byte, backslashes bool) (int, error) {
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			if backslashes {
+				j++
+			}
+		case quote:
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated quoted text at position %d", i)
+}
+
+// dollarTag returns the $tag$ opening a dollar-quoted string at i
+func dollarTag(query string, i int) (string, bool) {
+	j := i + 1
+	if j < len(query) && isWordStart(query, j) {
+		for j < len(query) && query[j] != '$' && scanWord(query, j) > j {
+			j++
+		}
+	}
+	if j < len(query) && query[j] == '$' {
+		return query[i : j+1], true
+	}
+	return "", false
+}
+
+func isWordStart(query string, i int) bool {
+	c := rune(query[i])
+	return c == '_' || unicode.IsLetter(c) || c >= 0x80
+}
+
+func scanWord(query string, i int) int {
+	for i < len(query) {
+		c := rune(query[i])
+		if c != '_' && c != '$' && !unicode.IsLetter(c) && !unicode.IsDigit(c) && c < 0x80 {
+			break
+		}
+		i++
+	}
+	return i
+}
+
+func scanDigits(query string, i int) int {
+	for i < len(query) && query[i] >= '0' && query[i] <= '9' {
+		i++
+	}
+	return i
+}
+
+func scanNumber(query string, i int) int {
+	i = scanDigits(query, i)
+	if i < len(query) && query[i] == '.' {
+		i = scanDigits(query, i+1)
+	}
+	if i < len(query) && (query[i] == 'e' || query[i] == 'E') {
+		j := i + 1
+		if j < len(query) && (query[j] == '+' || query[j] == '-') {
+			j++
+		}
+		if k := scanDigits(query, j); k > j {
+			i = k
+		}
+	}
+	return i
+}
+
+// NormalizeQuery returns a query as it should be logged: comments removed
+// and whitespace between tokens collapsed to one space, keeping literals
+// and the case of keywords. Queries that cannot be tokenized only have
+// their whitespace collapsed.
+func NormalizeQuery(dbType, query string) string {
+	toks, err := Tokenize(dbType, query)
+	if err != nil {
+		return strings.Join(strings.Fields(query), " ")
+	}
+
+	var b strings.Builder
+	end := -1
+	for _, tok := range toks {
+		raw := tok.Value
+		if tok.Kind == TokenIdent {
+			quote := query[tok.Pos : tok.Pos+1]
+			raw = quote + strings.ReplaceAll(tok.Value, quote, quote+quote) + quote
+		}
+		if end >= 0 && tok.Pos > end {
+			b.WriteByte(' ')
+		}
+		b.WriteString(raw)
+		end = tok.Pos + len(raw)
+	}
+	return b.String()
+}
@@ -0,0 +1,72 @@
+package security
+
+import (
+	"reflect"
+	"testing"
+)
+
+func tokenValues(t *testing.T, dbType, query string) []string {
+	t.Helper()
+	toks, err := Tokenize(dbType, query)
+	if err != nil {
+		t.Fatalf("Tokenize(%q) failed: %v", query, err)
+	}
+	values := make([]string, len(toks))
+	for i, tok := range toks {
+		values[i] = tok.Value
+	}
+	return values
+}
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		dbType string
+		query  string
+		want   []string
+	}{
+		{"postgres", `SELECT "Weird ""Name""", x::text FROM t -- comment`, []string{"SELECT", `Weird "Name"`, ",", "x", "::", "text", "FROM", "t"}},
+		{"postgres", `SELECT 'it''s', E'a\'b', $tag$ x FROM y $tag$, $1`, []string{"SELECT", `'it''s'`, ",", `E'a\'b'`, ",", "$tag$ x FROM y $tag$", ",", "$1"}},
+		{"postgres", `SELECT /* outer /* nested */ still comment */ 1.5e3`, []string{"SELECT", "1.5e3"}},
+		{"mysql", "SELECT `a``b`, \"str\", 'x\\'y' FROM t # comment", []string{"SELECT", "a`b", ",", `"str"`, ",", `'x\'y'`, "FROM", "t"}},
+		{"mysql", "SELECT /*!50000 password_hash */ FROM users", []string{"SELECT", "password_hash", "FROM", "users"}},
+		{"mysql", "SELECT 1--1, a FROM t", []string{"SELECT", "1", "-", "-", "1", ",", "a", "FROM", "t"}},
+		{"mysql", "SELECT @v, @@version, ?", []string{"SELECT", "@v", ",", "@@version", ",", "?"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := tokenValues(t, tt.dbType, tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTokenize_Unterminated(t *testing.T) {
+	for _, query := range []string{"SELECT 'abc", `SELECT "abc`, "SELECT /* abc", "SELECT $$abc"} {
+		if _, err := Tokenize("postgres", query); err == nil {
+			t.Errorf("Expected error for %q", query)
+		}
+	}
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		dbType string
+		query  string
+		want   string
+	}{
+		{"postgres", "SELECT  id,\n\tname -- who\nFROM \"User \"\"Table\"\"\"  WHERE id<=$1 /* c */;", `SELECT id, name FROM "User ""Table""" WHERE id<=$1 ;`},
+		{"mysql", "select /*!50000 `pass``word` */ from users # done\n", "select `pass``word` from users"},
+		{"mysql", "SELECT 'a  b',\"c -- d\"", `SELECT 'a  b',"c -- d"`},
+		{"postgres", "SELECT 'unterminated\n  x", "SELECT 'unterminated x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			if got := NormalizeQuery(tt.dbType, tt.query); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -4,20 +4,27 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"os"
+	"strconv"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
 func main() {
-	// Using the defaults from config.go
-	host := "localhost"
-	port := 3309
-	dbName := "test"
-	user := "root"
-	password := "123456"
+	// Using the same environment variables as the server
+	host := getEnv("DB_HOST", "localhost")
+	port, _ := strconv.Atoi(getEnv("DB_PORT", "3309"))
+	dbName := getEnv("DB_NAME", "test")
+	user := getEnv("DB_USER", "root")
+	password := os.Getenv("DB_PASSWORD")
 	timeout := 10 * time.Second
 
+	if password == "" {
+		fmt.Println("Set DB_PASSWORD (and optionally DB_HOST, DB_PORT, DB_NAME, DB_USER) first.")
+		os.Exit(1)
+	}
+
 	fmt.Println("========================================")
 	fmt.Println("MySQL Connection Test")
 	fmt.Println("========================================")
@@ -59,17 +66,17 @@ func main() {
 		fmt.Printf("✗ Failed to ping database: %v\n\n", err)
 
 		fmt.Println("Common causes:")
-		fmt.Println("  1. Database 'test' does not exist")
-		fmt.Println("  2. Wrong password for user 'root'")
-		fmt.Println("  3. User 'root' doesn't have access from 'localhost'")
+		fmt.Printf("  1. Database '%s' does not exist\n", dbName)
+		fmt.Printf("  2. Wrong password for user '%s'\n", user)
+		fmt.Printf("  3. User '%s' doesn't have access from '%s'\n", user, host)
 		fmt.Println("  4. MySQL server not running")
 		fmt.Println()
 		fmt.Println("Solutions:")
 		fmt.Println("  • Try connecting without database name first:")
 		fmt.Printf("      mysql -h %s -P %d -u %s -p\n", host, port, user)
 		fmt.Println("  • Create the database:")
-		fmt.Printf("      mysql -u root -p -e \"CREATE DATABASE test;\"\n")
-		fmt.Println("  • Or update config.go to use an existing database")
+		fmt.Printf("      mysql -u root -p -e \"CREATE DATABASE %s;\"\n", dbName)
+		fmt.Println("  • Or set DB_NAME to an existing database")
 		fmt.Println()
 
 		// Try connecting without database name
@@ -100,7 +107,7 @@ func main() {
 					}
 					fmt.Println()
 					fmt.Println("ACTION REQUIRED:")
-					fmt.Println("  Either create the 'test' database or update DB_NAME in config.go")
+					fmt.Printf("  Either create the '%s' database or update DB_NAME\n", dbName)
 					fmt.Println("  to use one of the databases listed above.")
 				}
 			} else {
@@ -117,7 +124,7 @@ func main() {
 	fmt.Println()
 
 	// List tables
-	fmt.Println("3. Listing tables in database 'test'...")
+	fmt.Printf("3. Listing tables in database '%s'...\n", dbName)
 	rows, err := db.QueryContext(ctx, "SHOW TABLES")
 	if err != nil {
 		fmt.Printf("✗ Failed to list tables: %v\n", err)
@@ -147,3 +154,10 @@ func main() {
 	fmt.Println("The MCP server should work now.")
 	fmt.Println("Run: ./dbhub-mcp-server")
 }
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}